@@ -0,0 +1,105 @@
+package eventauth
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyRequest(t *testing.T) {
+	secret := []byte("secret")
+	body := `{"id":"event1","type":"user.deleted"}`
+	signed := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/internal/events", strings.NewReader(body))
+		Sign(req, secret, []byte(body))
+		return req
+	}
+	signedAt := func(at time.Time) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/internal/events", strings.NewReader(body))
+		timestamp := strconv.FormatInt(at.Unix(), 10)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, signature(secret, timestamp, []byte(body)))
+		return req
+	}
+
+	tests := []struct {
+		name     string
+		req      func() *http.Request
+		secret   []byte
+		expected error
+	}{
+		{
+			name:   "signed",
+			req:    signed,
+			secret: secret,
+		},
+		{
+			name:     "unsigned",
+			req:      func() *http.Request { return httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)) },
+			secret:   secret,
+			expected: ErrMissingSignature,
+		},
+		{
+			name:     "other secret",
+			req:      signed,
+			secret:   []byte("other"),
+			expected: ErrInvalidSignature,
+		},
+		{
+			name:     "no secret",
+			req:      signed,
+			secret:   nil,
+			expected: ErrInvalidSignature,
+		},
+		{
+			name: "tampered body",
+			req: func() *http.Request {
+				req := signed()
+				req.Body = io.NopCloser(strings.NewReader(strings.Replace(body, "event1", "event2", 1)))
+				return req
+			},
+			secret:   secret,
+			expected: ErrInvalidSignature,
+		},
+		{
+			name: "tampered timestamp",
+			req: func() *http.Request {
+				req := signed()
+				req.Header.Set(TimestampHeader, strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10))
+				return req
+			},
+			secret:   secret,
+			expected: ErrInvalidSignature,
+		},
+		{
+			name:     "replayed",
+			req:      func() *http.Request { return signedAt(time.Now().Add(-MaxAge - time.Minute)) },
+			secret:   secret,
+			expected: ErrExpiredSignature,
+		},
+		{
+			name:     "from the future",
+			req:      func() *http.Request { return signedAt(time.Now().Add(MaxAge + time.Minute)) },
+			secret:   secret,
+			expected: ErrExpiredSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req()
+			assert.ErrorIs(t, VerifyRequest(tt.secret, req), tt.expected)
+
+			// The handler can still read the body
+			read, err := io.ReadAll(req.Body)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, read)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Statuses of the service and of its dependencies
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+	// StatusDegraded is a service whose optional dependencies are down
+	StatusDegraded = "degraded"
+	// StatusShuttingDown is a service that no longer takes requests
+	StatusShuttingDown = "shutting down"
+)
+
+// Dependency is something the service uses to serve requests
+type Dependency struct {
+	Name  string
+	Check func(ctx context.Context) error
+	// Optional dependencies are reported but do not make the service unready, because it
+	// keeps serving, degraded, without them
+	Optional bool
+}
+
+// ComponentStatus is the result of checking a dependency
+type ComponentStatus struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Optional  bool    `json:"optional,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the readiness of the service and of each of its dependencies
+type Report struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components"`
+}
+
+// Ready reports whether the service should receive requests
+func (r Report) Ready() bool {
+	return r.Status == StatusUp || r.Status == StatusDegraded
+}
+
+// Checker checks the dependencies of the service
+type Checker struct {
+	dependencies []Dependency
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+}
+
+// NewChecker creates a checker that gives each dependency timeout to answer
+func NewChecker(timeout time.Duration, dependencies ...Dependency) *Checker {
+	return &Checker{
+		dependencies: dependencies,
+		timeout:      timeout,
+	}
+}
+
+// StartShutdown makes the service unready from now on, so that load balancers stop sending
+// it requests before it stops
+func (c *Checker) StartShutdown() {
+	c.shuttingDown.Store(true)
+}
+
+// Check checks every dependency in parallel
+func (c *Checker) Check(ctx context.Context) Report {
+	components := make([]ComponentStatus, len(c.dependencies))
+	var wg sync.WaitGroup
+	for i, dependency := range c.dependencies {
+		wg.Add(1)
+		go func(i int, dependency Dependency) {
+			defer wg.Done()
+			components[i] = c.check(ctx, dependency)
+		}(i, dependency)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Components: make(map[string]ComponentStatus, len(components))}
+	for i, component := range components {
+		report.Components[c.dependencies[i].Name] = component
+		if component.Status == StatusUp {
+			continue
+		}
+		if !component.Optional {
+			report.Status = StatusDown
+		} else if report.Status == StatusUp {
+			report.Status = StatusDegraded
+		}
+	}
+	if c.shuttingDown.Load() {
+		report.Status = StatusShuttingDown
+	}
+	return report
+}
+
+// check checks one dependency within the timeout
+func (c *Checker) check(ctx context.Context, dependency Dependency) ComponentStatus {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := dependency.Check(ctx)
+	status := ComponentStatus{
+		Status:    StatusUp,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+		Optional:  dependency.Optional,
+	}
+	if err != nil {
+		status.Status = StatusDown
+		status.Error = err.Error()
+	}
+	return status
+}
@@ -0,0 +1,96 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func up(ctx context.Context) error {
+	return nil
+}
+
+func down(ctx context.Context) error {
+	return errors.New("connection refused")
+}
+
+// hang answers when its deadline expires
+func hang(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestChecker_Check(t *testing.T) {
+	tests := []struct {
+		name         string
+		dependencies []Dependency
+		wantStatus   string
+		wantReady    bool
+	}{
+		{
+			name:         "every dependency up",
+			dependencies: []Dependency{{Name: "a", Check: up}, {Name: "b", Check: up, Optional: true}},
+			wantStatus:   StatusUp,
+			wantReady:    true,
+		},
+		{
+			name:         "optional dependency down",
+			dependencies: []Dependency{{Name: "a", Check: up}, {Name: "b", Check: down, Optional: true}},
+			wantStatus:   StatusDegraded,
+			wantReady:    true,
+		},
+		{
+			name:         "required dependency down",
+			dependencies: []Dependency{{Name: "a", Check: down}, {Name: "b", Check: down, Optional: true}},
+			wantStatus:   StatusDown,
+			wantReady:    false,
+		},
+		{
+			name:         "required dependency too slow",
+			dependencies: []Dependency{{Name: "a", Check: hang}, {Name: "b", Check: up}},
+			wantStatus:   StatusDown,
+			wantReady:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := NewChecker(10*time.Millisecond, tt.dependencies...).Check(context.Background())
+
+			assert.Equal(t, tt.wantStatus, report.Status)
+			assert.Equal(t, tt.wantReady, report.Ready())
+			assert.Len(t, report.Components, len(tt.dependencies))
+		})
+	}
+}
+
+func TestChecker_ChecksInParallel(t *testing.T) {
+	checker := NewChecker(50*time.Millisecond,
+		Dependency{Name: "a", Check: hang},
+		Dependency{Name: "b", Check: hang},
+		Dependency{Name: "c", Check: down},
+	)
+
+	start := time.Now()
+	report := checker.Check(context.Background())
+
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+	assert.Equal(t, StatusDown, report.Components["a"].Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Components["a"].Error)
+	assert.Equal(t, "connection refused", report.Components["c"].Error)
+	assert.GreaterOrEqual(t, report.Components["a"].LatencyMS, 50.0)
+}
+
+func TestChecker_ShuttingDown(t *testing.T) {
+	checker := NewChecker(time.Second, Dependency{Name: "a", Check: up})
+	checker.StartShutdown()
+
+	report := checker.Check(context.Background())
+
+	assert.Equal(t, StatusShuttingDown, report.Status)
+	assert.False(t, report.Ready())
+	assert.Equal(t, StatusUp, report.Components["a"].Status)
+}
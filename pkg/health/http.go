@@ -0,0 +1,29 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Livez reports that the process serves requests. It does not check dependencies, so a
+// dependency that is down does not get the service restarted.
+func (c *Checker) Livez(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": StatusUp})
+}
+
+// Readyz reports whether the service should receive requests, with the status and latency
+// of each dependency
+func (c *Checker) Readyz(w http.ResponseWriter, r *http.Request) {
+	report := c.Check(r.Context())
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,46 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecker_Readyz(t *testing.T) {
+	checker := NewChecker(time.Second,
+		Dependency{Name: "storage", Check: up},
+		Dependency{Name: "search", Check: down, Optional: true},
+	)
+
+	w := httptest.NewRecorder()
+	checker.Readyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var report Report
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, StatusDegraded, report.Status)
+	assert.Equal(t, StatusUp, report.Components["storage"].Status)
+	assert.Equal(t, StatusDown, report.Components["search"].Status)
+	assert.Equal(t, "connection refused", report.Components["search"].Error)
+}
+
+func TestChecker_LivezWhileShuttingDown(t *testing.T) {
+	checker := NewChecker(time.Second)
+
+	checker.StartShutdown()
+
+	w := httptest.NewRecorder()
+	checker.Readyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	w = httptest.NewRecorder()
+	checker.Livez(w, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status": "up"}`, w.Body.String())
+}
@@ -0,0 +1,38 @@
+// Package fibermetrics records the requests served by Fiber apps in the shared metrics
+package fibermetrics
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/lisandro/challenge/pkg/metrics"
+)
+
+// New returns a middleware that records the rate, errors and duration of the requests of
+// each route
+func New() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		// Errors are turned into responses by the error handler after this middleware
+		status := c.Response().StatusCode()
+		route := c.Route().Path
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+			if status == fiber.StatusNotFound {
+				route = metrics.UnmatchedRoute
+			}
+		}
+
+		// Fiber reuses the memory of request strings, and label values are kept
+		metrics.ObserveRequest(strings.Clone(c.Method()), route, status, start)
+		return err
+	}
+}
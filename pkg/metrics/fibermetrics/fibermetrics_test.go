@@ -0,0 +1,38 @@
+package fibermetrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/lisandro/challenge/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	app := fiber.New()
+	app.Use(New())
+	app.Get("/api/v1/things/:id", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+	app.Get("/api/v1/broken/:id", func(c *fiber.Ctx) error {
+		return fiber.NewError(fiber.StatusBadGateway, "broken")
+	})
+
+	served := metrics.HTTPRequests.WithLabelValues("GET", "/api/v1/things/:id", "204")
+	failed := metrics.HTTPRequests.WithLabelValues("GET", "/api/v1/broken/:id", "502")
+	unmatched := metrics.HTTPRequests.WithLabelValues("GET", "unmatched", "404")
+	servedBefore, failedBefore, unmatchedBefore := testutil.ToFloat64(served), testutil.ToFloat64(failed), testutil.ToFloat64(unmatched)
+
+	for _, path := range []string{"/api/v1/things/1", "/api/v1/broken/1", "/api/v1/nothing/1"} {
+		_, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+		assert.NoError(t, err)
+	}
+
+	// Requests are counted by route template rather than by path, with the status of their error
+	assert.Equal(t, servedBefore+1, testutil.ToFloat64(served))
+	assert.Equal(t, failedBefore+1, testutil.ToFloat64(failed))
+	assert.Equal(t, unmatchedBefore+1, testutil.ToFloat64(unmatched))
+}
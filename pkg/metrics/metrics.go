@@ -0,0 +1,100 @@
+// Package metrics holds the Prometheus metrics shared by the packages of the services. Their
+// names and labels are the same in every service, so that one dashboard covers all of them.
+package metrics
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// HTTPRequests counts the requests served, by route template so that IDs in paths do not
+	// make a series each
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests served, by method, route and status code.",
+	}, []string{"method", "route", "status"})
+	// HTTPRequestDuration is the time to serve requests
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Time to serve HTTP requests, by method and route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	// ClientRequests counts the requests sent to other services
+	ClientRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_client_requests_total",
+		Help: "HTTP requests sent to other services, by service and status class. The error class counts requests that got no response.",
+	}, []string{"peer", "status"})
+	// ClientRequestDuration is the time other services take to answer
+	ClientRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_client_request_duration_seconds",
+		Help:    "Time of HTTP requests sent to other services, by service.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"peer"})
+
+	// CircuitBreakerState is 1 for the current state of each circuit breaker and 0 for the others
+	CircuitBreakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "circuit_breaker_state",
+		Help: "State of the circuit breakers in front of other services: 1 for the current state, 0 for the others.",
+	}, []string{"breaker", "state"})
+	// CircuitBreakerOpened counts the times each circuit breaker opened
+	CircuitBreakerOpened = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "circuit_breaker_opened_total",
+		Help: "Times the circuit breakers in front of other services opened, by breaker.",
+	}, []string{"breaker"})
+	// CircuitBreakerRejected counts the requests each circuit breaker rejected without sending them
+	CircuitBreakerRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "circuit_breaker_rejected_requests_total",
+		Help: "Requests rejected by the circuit breakers in front of other services without being sent, by breaker.",
+	}, []string{"breaker"})
+)
+
+// CircuitBreakerStates are the states a circuit breaker reports with SetCircuitBreakerState
+var CircuitBreakerStates = []string{"closed", "open", "half-open"}
+
+// UnmatchedRoute is the route of the requests that match no route
+const UnmatchedRoute = "unmatched"
+
+// ObserveRequest records a request to route served since start
+func ObserveRequest(method, route string, statusCode int, start time.Time) {
+	CountRequest(method, route, statusCode)
+	HTTPRequestDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+}
+
+// CountRequest records a request to route without its duration, for requests such as streams
+// whose duration is up to the client
+func CountRequest(method, route string, statusCode int) {
+	HTTPRequests.WithLabelValues(method, route, strconv.Itoa(statusCode)).Inc()
+}
+
+// StatusClass returns the class of a status code, such as 5xx, or error when there is no
+// response
+func StatusClass(statusCode int, err error) string {
+	if err != nil {
+		return "error"
+	}
+	return fmt.Sprintf("%dxx", statusCode/100)
+}
+
+// ObserveClientRequest records a request sent to peer since start. statusCode is ignored when
+// err is set.
+func ObserveClientRequest(peer string, start time.Time, statusCode int, err error) {
+	ClientRequests.WithLabelValues(peer, StatusClass(statusCode, err)).Inc()
+	ClientRequestDuration.WithLabelValues(peer).Observe(time.Since(start).Seconds())
+}
+
+// SetCircuitBreakerState records that breaker moved to state, one of CircuitBreakerStates
+func SetCircuitBreakerState(breaker, state string) {
+	for _, s := range CircuitBreakerStates {
+		value := 0.0
+		if s == state {
+			value = 1
+		}
+		CircuitBreakerState.WithLabelValues(breaker, s).Set(value)
+	}
+}
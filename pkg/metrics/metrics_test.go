@@ -0,0 +1,18 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetCircuitBreakerState(t *testing.T) {
+	SetCircuitBreakerState(t.Name(), "closed")
+	SetCircuitBreakerState(t.Name(), "open")
+
+	// Only the current state is set, so that a query for the open breakers finds this one
+	assert.Equal(t, 0.0, testutil.ToFloat64(CircuitBreakerState.WithLabelValues(t.Name(), "closed")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(CircuitBreakerState.WithLabelValues(t.Name(), "open")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(CircuitBreakerState.WithLabelValues(t.Name(), "half-open")))
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/lisandro/challenge/pkg/health"
+)
+
+// newHealthChecker checks the services the timelines are built from for the readiness probe.
+// They are optional: while one is down, the last timelines built are served as degraded.
+func newHealthChecker(userServiceURL, tweetServiceURL string, timeout time.Duration) (*health.Checker, error) {
+	userService, err := livenessCheck(userServiceURL)
+	if err != nil {
+		return nil, err
+	}
+	tweetService, err := livenessCheck(tweetServiceURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return health.NewChecker(timeout,
+		health.Dependency{Name: "user-service", Check: userService, Optional: true},
+		health.Dependency{Name: "tweet-service", Check: tweetService, Optional: true},
+	), nil
+}
+
+// livenessCheck checks the liveness probe of the service at serviceURL. Its readiness is not
+// used, so that the dependencies of that service do not make this one unready too.
+func livenessCheck(serviceURL string) (func(ctx context.Context) error, error) {
+	livez, err := url.Parse(serviceURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid service URL %q: %w", serviceURL, err)
+	}
+	livez.Path = "/livez"
+	livez.RawQuery = ""
+
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, livez.String(), nil)
+		if err != nil {
+			return err
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return fmt.Errorf("%s answered %s", livez, res.Status)
+		}
+		return nil
+	}, nil
+}
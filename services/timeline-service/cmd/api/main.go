@@ -1,16 +1,28 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	nethttp "net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lisandro/challenge/pkg/eventauth"
 	"github.com/lisandro/timeline-service/config"
 	_ "github.com/lisandro/timeline-service/docs" // This is important!
+	"github.com/lisandro/timeline-service/internal/cache"
 	"github.com/lisandro/timeline-service/internal/client"
 	"github.com/lisandro/timeline-service/internal/delivery/http"
+	"github.com/lisandro/timeline-service/internal/domain"
+	"github.com/lisandro/timeline-service/internal/ranking"
+	"github.com/lisandro/timeline-service/internal/stream"
 	"github.com/lisandro/timeline-service/internal/usecase"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
@@ -42,14 +54,66 @@ func main() {
 	log.Printf("User service URL: %s", userServiceURL)
 	log.Printf("Tweet service URL: %s", tweetServiceURL)
 	
-	userClient := client.NewUserClient(userServiceURL)
-	tweetClient := client.NewTweetClient(tweetServiceURL)
+	// Slow or failing services time out, are retried and eventually skipped by their breaker
+	userClientConfig := client.DefaultConfig()
+	userClientConfig.Timeout = getDurationOrDefault("USER_SERVICE_TIMEOUT", userClientConfig.Timeout)
+	tweetClientConfig := client.DefaultConfig()
+	tweetClientConfig.Timeout = getDurationOrDefault("TWEET_SERVICE_TIMEOUT", tweetClientConfig.Timeout)
+
+	userClient := client.NewUserClient(userServiceURL, userClientConfig)
+	tweetClient := client.NewTweetClient(tweetServiceURL, tweetClientConfig)
+
+	// Initialize the hub of the timeline streams, fed by the tweet service events
+	hub := stream.NewHub(stream.Config{
+		BufferSize:            getIntOrDefault("STREAM_BUFFER_SIZE", 1000),
+		MaxConnectionsPerUser: getIntOrDefault("STREAM_MAX_CONNECTIONS_PER_USER", 3),
+		QueueSize:             64,
+	})
+
+	// Author profiles are cached briefly; renamed and deleted users are dropped on their events
+	profiles := cache.NewProfileCache(getDurationOrDefault("PROFILE_CACHE_TTL", time.Minute), 10000)
+
+	// The last timelines built are served, marked as degraded, while a service they need is down
+	lastKnownGoodMaxAge := getDurationOrDefault("LAST_KNOWN_GOOD_MAX_AGE", 10*time.Minute)
+	lastKnownGood := usecase.LastKnownGood{
+		Timelines:       cache.NewStaleCache[domain.Timeline](lastKnownGoodMaxAge, 10000),
+		RankedTimelines: cache.NewStaleCache[domain.RankedTimeline](lastKnownGoodMaxAge, 10000),
+	}
+
+	// Ranked timelines are scored by one of the registered scorers, picked per user
+	scorers := map[string]ranking.Scorer{
+		"weighted": ranking.WeightedScorer{HalfLife: 6 * time.Hour, AffinityWeight: 0.4, EngagementWeight: 0.1},
+		"recency":  ranking.RecencyScorer{HalfLife: 6 * time.Hour},
+	}
+	experiment, err := ranking.ParseExperiment(getEnvOrDefault("RANKING_EXPERIMENT", "weighted=100"), scorers)
+	if err != nil {
+		log.Fatalf("Invalid RANKING_EXPERIMENT: %v", err)
+	}
 
 	// Initialize usecase
-	timelineUseCase := usecase.NewTimelineUseCase(userClient, tweetClient)
+	timelineUseCase := usecase.NewTimelineUseCase(userClient, tweetClient, hub, profiles, lastKnownGood, usecase.RankingConfig{
+		Ranking: ranking.Config{
+			Size:          getIntOrDefault("RANKED_TIMELINE_SIZE", 50),
+			AuthorPenalty: 0.7,
+		},
+		Experiment:          experiment,
+		CandidateLimit:      200,
+		SecondDegreeSources: 20,
+		SecondDegreeAuthors: 50,
+	})
 
 	// Initialize handler
-	timelineHandler := http.NewTimelineHandler(timelineUseCase)
+	timelineHandler := http.NewTimelineHandler(timelineUseCase, http.StreamConfig{
+		HeartbeatInterval: getDurationOrDefault("STREAM_HEARTBEAT_INTERVAL", 15*time.Second),
+		RefreshInterval:   getDurationOrDefault("STREAM_REFRESH_INTERVAL", time.Minute),
+		RetryInterval:     3 * time.Second,
+	})
+
+	// Events sent between the services are signed with the secret they share
+	eventSecret, err := eventauth.SecretFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to load the event signing secret: %v", err)
+	}
 
 	// Initialize router
 	router := gin.New()
@@ -58,21 +122,65 @@ func main() {
 	router.Use(customLogger())
 	router.Use(gin.Recovery())
 
+	// Record the rate, errors and duration of requests, served on /metrics. Streams are only
+	// counted, since they last as long as their clients stay connected.
+	router.Use(http.RequestMetrics("/api/v1/timeline/stream"))
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
 	// Register routes
 	v1 := router.Group("/api/v1")
 	{
 		v1.GET("/timeline", timelineHandler.GetTimeline)
+		v1.GET("/timeline/new_count", timelineHandler.GetNewCount)
+		v1.GET("/timeline/stream", timelineHandler.StreamTimeline)
+		v1.POST("/internal/events", http.VerifyEvent(eventSecret), timelineHandler.HandleUserEvent)
+		v1.POST("/internal/tweet-events", http.VerifyEvent(eventSecret), timelineHandler.HandleTweetEvent)
+	}
+
+	// Liveness and readiness probes; /health is the readiness probe under its older name
+	checker, err := newHealthChecker(userServiceURL, tweetServiceURL, getDurationOrDefault("HEALTH_CHECK_TIMEOUT", 2*time.Second))
+	if err != nil {
+		log.Fatalf("Failed to create health checker: %v", err)
 	}
+	router.GET("/livez", gin.WrapF(checker.Livez))
+	router.GET("/readyz", gin.WrapF(checker.Readyz))
+	router.GET("/health", gin.WrapF(checker.Readyz))
 
 	// Swagger documentation
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	// Start server
+	// Start server in a goroutine
 	port := getEnvOrDefault("PORT", "8082")
-	log.Printf("Starting server on port %s", port)
-	if err := router.Run(":" + port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	server := &nethttp.Server{Addr: ":" + port, Handler: router}
+	go func() {
+		log.Printf("Starting server on port %s", port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, nethttp.ErrServerClosed) {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	}()
+
+	// Wait for interrupt signal to gracefully shutdown the server
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Println("Shutting down server...")
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), getDurationOrDefault("SHUTDOWN_TIMEOUT", 20*time.Second))
+	defer cancelShutdown()
+
+	// Keep serving while load balancers notice the failing readiness probe and stop sending requests
+	checker.StartShutdown()
+	select {
+	case <-time.After(getDurationOrDefault("SHUTDOWN_DRAIN_DELAY", 5*time.Second)):
+	case <-shutdownCtx.Done():
+	}
+
+	// Streams never end on their own, so they are ended and their clients reconnect elsewhere
+	hub.Close()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Failed to wait for requests in flight: %v", err)
 	}
+	log.Println("Server stopped")
 }
 
 // customLogger creates a custom logger middleware that matches the user-service format
@@ -106,4 +214,30 @@ func getEnvOrDefault(key, defaultValue string) string {
 		return value
 	}
 	return defaultValue
-} 
\ No newline at end of file
+}
+
+func getIntOrDefault(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid integer %q for %s, using default %d", value, key, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+func getDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid duration %q for %s, using default %s", value, key, defaultValue)
+		return defaultValue
+	}
+	return duration
+}
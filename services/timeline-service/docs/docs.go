@@ -24,9 +24,135 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/internal/events": {
+            "post": {
+                "description": "Receive an event published by the user service. A user.deleted event purges the user's timeline data, and a user.renamed event refreshes the user's profile on timelines. Events may be delivered more than once.\nEvents must be signed with the secret shared by the services.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "internal"
+                ],
+                "summary": "Handle a user service event",
+                "parameters": [
+                    {
+                        "description": "User event",
+                        "name": "event",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.UserEvent"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Unix time at which the event was signed",
+                        "name": "X-Event-Timestamp",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "HMAC-SHA256 of the timestamp and body, as sha256=\u003chex\u003e",
+                        "name": "X-Event-Signature",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/internal/tweet-events": {
+            "post": {
+                "description": "Receive an event published by the tweet service. A tweet.created event is pushed to the open timeline streams of the author's followers. Events may be delivered more than once.\nEvents must be signed with the secret shared by the services.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "internal"
+                ],
+                "summary": "Handle a tweet service event",
+                "parameters": [
+                    {
+                        "description": "Tweet event",
+                        "name": "event",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.TweetEvent"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Unix time at which the event was signed",
+                        "name": "X-Event-Timestamp",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "HMAC-SHA256 of the timestamp and body, as sha256=\u003chex\u003e",
+                        "name": "X-Event-Signature",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/timeline": {
             "get": {
-                "description": "Get the timeline of tweets from users that the current user follows",
+                "description": "Get timeline of tweets from users that the authenticated user follows, newest first. since_id returns only the tweets newer than a tweet, and max_id only those older than a tweet.\nWith mode=ranked, a domain.RankedTimeline is returned instead: tweets of followed users and of the users they follow, ordered by relevance, each with the reasons of its rank. since_id and max_id are not supported in ranked mode.\nWhen a service the timeline is built from fails, the last timeline built for the user is returned with degraded set to true and Warning headers. Older pages are never served stale.",
                 "consumes": [
                     "application/json"
                 ],
@@ -40,10 +166,32 @@ const docTemplate = `{
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "ID of the current user",
+                        "description": "User ID",
                         "name": "X-User-ID",
                         "in": "header",
                         "required": true
+                    },
+                    {
+                        "enum": [
+                            "chronological",
+                            "ranked"
+                        ],
+                        "type": "string",
+                        "description": "Timeline order",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only return tweets newer than this tweet",
+                        "name": "since_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only return tweets older than this tweet",
+                        "name": "max_id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -51,24 +199,131 @@ const docTemplate = `{
                         "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/domain.Timeline"
+                        },
+                        "headers": {
+                            "Warning": {
+                                "type": "string",
+                                "description": "Set when the timeline is stale"
+                            }
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/http.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/timeline/new_count": {
+            "get": {
+                "description": "Count the timeline tweets newer than since_id without returning them, so clients can show a \"new tweets\" banner cheaply",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "timeline"
+                ],
+                "summary": "Count new timeline tweets",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "X-User-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the newest tweet the client has seen",
+                        "name": "since_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/http.NewCountResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/timeline/stream": {
+            "get": {
+                "description": "Server-Sent Events stream of the new tweets of the users that the authenticated user follows. Each tweet is sent as a \"tweet\" event whose ID is the tweet ID. Reconnecting with the Last-Event-ID header replays the tweets missed since that event; if they can no longer be replayed, a \"reset\" event asks the client to reload GET /timeline. Comments are sent as heartbeats.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "timeline"
+                ],
+                "summary": "Stream new timeline tweets",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "X-User-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the last event received",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Event stream",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
                         }
                     }
                 }
@@ -76,9 +331,30 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "domain.Author": {
+            "type": "object",
+            "properties": {
+                "avatar_url": {
+                    "type": "string"
+                },
+                "display_name": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
         "domain.Timeline": {
             "type": "object",
             "properties": {
+                "degraded": {
+                    "description": "Degraded reports a timeline served from the last one built, because a service it is\nbuilt from failed",
+                    "type": "boolean"
+                },
                 "tweets": {
                     "type": "array",
                     "items": {
@@ -90,6 +366,14 @@ const docTemplate = `{
         "domain.Tweet": {
             "type": "object",
             "properties": {
+                "author": {
+                    "description": "Author is the profile of the user who wrote the tweet, when it could be looked up",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.Author"
+                        }
+                    ]
+                },
                 "content": {
                     "type": "string"
                 },
@@ -103,6 +387,57 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "domain.TweetEvent": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "occurred_at": {
+                    "type": "string"
+                },
+                "tweet": {
+                    "$ref": "#/definitions/domain.Tweet"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.UserEvent": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "occurred_at": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "http.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "http.NewCountResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 3
+                }
+            }
         }
     }
 }`
@@ -114,7 +449,7 @@ var SwaggerInfo = &swag.Spec{
 	BasePath:         "/api/v1",
 	Schemes:          []string{"http"},
 	Title:            "Timeline Service API",
-	Description:      "This is the timeline service API documentation.",
+	Description:      "Service that provides timeline functionality for the Twitter clone",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
 	LeftDelim:        "{{",
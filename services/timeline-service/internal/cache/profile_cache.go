@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lisandro/timeline-service/internal/domain"
+)
+
+// ProfileCache keeps author profiles in memory for a short time, so that timelines can be
+// hydrated without asking the user service for every author on every request. Renamed and
+// deleted users must be removed with Delete.
+type ProfileCache struct {
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.RWMutex
+	entries map[string]profileEntry
+}
+
+type profileEntry struct {
+	author    domain.Author
+	expiresAt time.Time
+}
+
+// NewProfileCache creates a cache keeping profiles for ttl, holding at most maxEntries profiles
+func NewProfileCache(ttl time.Duration, maxEntries int) *ProfileCache {
+	return &ProfileCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		now:        time.Now,
+		entries:    make(map[string]profileEntry),
+	}
+}
+
+// Get returns the cached profile of a user, if it has not expired
+func (c *ProfileCache) Get(id string) (domain.Author, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[id]
+	if !ok || !c.now().Before(entry.expiresAt) {
+		return domain.Author{}, false
+	}
+	return entry.author, true
+}
+
+// Set caches the profile of a user, replacing any previous one
+func (c *ProfileCache) Set(author domain.Author) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if _, ok := c.entries[author.ID]; !ok && len(c.entries) >= c.maxEntries {
+		c.evictLocked(now)
+	}
+	c.entries[author.ID] = profileEntry{author: author, expiresAt: now.Add(c.ttl)}
+}
+
+// Delete removes the profile of a user
+func (c *ProfileCache) Delete(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, id)
+}
+
+// evictLocked makes room for a new entry by dropping the expired ones, or every entry when
+// none has expired. The caller must hold c.mu.
+func (c *ProfileCache) evictLocked(now time.Time) {
+	for id, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, id)
+		}
+	}
+	if len(c.entries) >= c.maxEntries {
+		c.entries = make(map[string]profileEntry)
+	}
+}
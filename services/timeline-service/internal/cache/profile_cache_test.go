@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lisandro/timeline-service/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCache(maxEntries int) (*ProfileCache, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewProfileCache(time.Minute, maxEntries)
+	c.now = func() time.Time { return now }
+	return c, &now
+}
+
+func TestProfileCache_Expires(t *testing.T) {
+	c, now := newTestCache(10)
+	alice := domain.Author{ID: "user1", Username: "alice"}
+	c.Set(alice)
+
+	author, ok := c.Get("user1")
+	assert.True(t, ok)
+	assert.Equal(t, alice, author)
+
+	*now = now.Add(time.Minute)
+	_, ok = c.Get("user1")
+	assert.False(t, ok)
+}
+
+func TestProfileCache_Delete(t *testing.T) {
+	c, _ := newTestCache(10)
+	c.Set(domain.Author{ID: "user1", Username: "alice"})
+
+	c.Delete("user1")
+
+	_, ok := c.Get("user1")
+	assert.False(t, ok)
+}
+
+func TestProfileCache_EvictsWhenFull(t *testing.T) {
+	c, now := newTestCache(2)
+	c.Set(domain.Author{ID: "user1"})
+	*now = now.Add(30 * time.Second)
+	c.Set(domain.Author{ID: "user2"})
+
+	// user1 has expired, so it makes room for user3
+	*now = now.Add(45 * time.Second)
+	c.Set(domain.Author{ID: "user3"})
+	_, ok := c.Get("user2")
+	assert.True(t, ok)
+	_, ok = c.Get("user3")
+	assert.True(t, ok)
+	assert.Len(t, c.entries, 2)
+
+	// Nothing has expired, so the cache starts over
+	c.Set(domain.Author{ID: "user4"})
+	assert.Len(t, c.entries, 1)
+	_, ok = c.Get("user4")
+	assert.True(t, ok)
+}
@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// StaleCache keeps the last value successfully built for each key, so that it can be
+// served when building a fresh one fails. Values older than maxAge are not served.
+type StaleCache[T any] struct {
+	maxAge     time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.RWMutex
+	entries map[string]staleEntry[T]
+}
+
+type staleEntry[T any] struct {
+	value    T
+	storedAt time.Time
+}
+
+// NewStaleCache creates a cache serving values up to maxAge old, holding at most maxEntries values
+func NewStaleCache[T any](maxAge time.Duration, maxEntries int) *StaleCache[T] {
+	return &StaleCache[T]{
+		maxAge:     maxAge,
+		maxEntries: maxEntries,
+		now:        time.Now,
+		entries:    make(map[string]staleEntry[T]),
+	}
+}
+
+// Get returns the last value stored for key, if it is not older than maxAge
+func (c *StaleCache[T]) Get(key string) (T, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[key]
+	if !ok || c.now().Sub(entry.storedAt) > c.maxAge {
+		var zero T
+		return zero, false
+	}
+	return entry.value, true
+}
+
+// Set stores the last good value of key, replacing any previous one
+func (c *StaleCache[T]) Set(key string, value T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxEntries {
+		c.evictLocked(now)
+	}
+	c.entries[key] = staleEntry[T]{value: value, storedAt: now}
+}
+
+// Delete removes the value of key
+func (c *StaleCache[T]) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// evictLocked makes room for a new entry by dropping the values too old to be served, or
+// every value when none is. The caller must hold c.mu.
+func (c *StaleCache[T]) evictLocked(now time.Time) {
+	for key, entry := range c.entries {
+		if now.Sub(entry.storedAt) > c.maxAge {
+			delete(c.entries, key)
+		}
+	}
+	if len(c.entries) >= c.maxEntries {
+		c.entries = make(map[string]staleEntry[T])
+	}
+}
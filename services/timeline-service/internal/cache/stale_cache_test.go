@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestStaleCache(maxEntries int) (*StaleCache[string], *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewStaleCache[string](time.Minute, maxEntries)
+	c.now = func() time.Time { return now }
+	return c, &now
+}
+
+func TestStaleCache_ServesUpToMaxAge(t *testing.T) {
+	c, now := newTestStaleCache(10)
+	c.Set("user1", "timeline")
+
+	*now = now.Add(time.Minute)
+	value, ok := c.Get("user1")
+	assert.True(t, ok)
+	assert.Equal(t, "timeline", value)
+
+	*now = now.Add(time.Second)
+	_, ok = c.Get("user1")
+	assert.False(t, ok)
+}
+
+func TestStaleCache_SetReplacesAndRestartsAge(t *testing.T) {
+	c, now := newTestStaleCache(10)
+	c.Set("user1", "old")
+
+	*now = now.Add(50 * time.Second)
+	c.Set("user1", "new")
+	*now = now.Add(50 * time.Second)
+
+	value, ok := c.Get("user1")
+	assert.True(t, ok)
+	assert.Equal(t, "new", value)
+}
+
+func TestStaleCache_EvictsWhenFull(t *testing.T) {
+	c, now := newTestStaleCache(2)
+	c.Set("user1", "a")
+	*now = now.Add(2 * time.Minute)
+	c.Set("user2", "b")
+
+	// user1 is too old to be served, so it makes room for user3
+	c.Set("user3", "c")
+	_, ok := c.Get("user2")
+	assert.True(t, ok)
+	_, ok = c.Get("user3")
+	assert.True(t, ok)
+
+	c.Delete("user2")
+	_, ok = c.Get("user2")
+	assert.False(t, ok)
+}
@@ -0,0 +1,175 @@
+package client
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lisandro/challenge/pkg/metrics"
+)
+
+// ErrCircuitOpen is returned instead of calling a service whose circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// BreakerState is the state of a circuit breaker
+type BreakerState int
+
+const (
+	// BreakerClosed lets every request through
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects every request until the open timeout elapses
+	BreakerOpen
+	// BreakerHalfOpen lets a few probe requests through to find out whether the service recovered
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// Outcome is the result of a request sent through a circuit breaker
+type Outcome int
+
+const (
+	// OutcomeSuccess is a request answered by a healthy service
+	OutcomeSuccess Outcome = iota
+	// OutcomeFailure is a request that failed because of the service
+	OutcomeFailure
+	// OutcomeIgnored is a request that says nothing about the service, such as one canceled
+	// by the caller. It only frees its probe slot while half-open.
+	OutcomeIgnored
+)
+
+// BreakerConfig holds the thresholds of a circuit breaker
+type BreakerConfig struct {
+	// FailureThreshold is the number of consecutive failures that opens the breaker
+	FailureThreshold int
+	// OpenTimeout is how long the breaker stays open before probing the service
+	OpenTimeout time.Duration
+	// HalfOpenProbes is the number of probes let through at once while half-open, and the
+	// number of successful probes that closes the breaker again
+	HalfOpenProbes int
+}
+
+// CircuitBreaker stops calling a failing service for a while, so that a service that is
+// down fails requests fast instead of holding them until they time out
+type CircuitBreaker struct {
+	name   string
+	config BreakerConfig
+	now    func() time.Time
+
+	mu         sync.Mutex
+	state      BreakerState
+	generation uint64
+	failures   int
+	openedAt   time.Time
+	probes     int
+	successes  int
+}
+
+// NewCircuitBreaker creates a closed circuit breaker whose metrics are labeled with name.
+// An open breaker only moves to half-open, and reports it, when a request is attempted.
+func NewCircuitBreaker(name string, config BreakerConfig) *CircuitBreaker {
+	metrics.SetCircuitBreakerState(name, BreakerClosed.String())
+	return &CircuitBreaker{
+		name:   name,
+		config: config,
+		now:    time.Now,
+	}
+}
+
+// Allow reports whether a request may be sent. When it may, the returned function must be
+// called with the outcome of the request. It returns ErrCircuitOpen otherwise.
+func (b *CircuitBreaker) Allow() (func(outcome Outcome), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.config.OpenTimeout {
+		b.setState(BreakerHalfOpen)
+	}
+
+	switch b.state {
+	case BreakerOpen:
+		metrics.CircuitBreakerRejected.WithLabelValues(b.name).Inc()
+		return nil, ErrCircuitOpen
+	case BreakerHalfOpen:
+		if b.probes >= b.config.HalfOpenProbes {
+			metrics.CircuitBreakerRejected.WithLabelValues(b.name).Inc()
+			return nil, ErrCircuitOpen
+		}
+		b.probes++
+	}
+
+	generation := b.generation
+	return func(outcome Outcome) { b.record(generation, outcome) }, nil
+}
+
+// record applies the outcome of a request allowed in generation. Outcomes of requests sent
+// before the last state change are ignored.
+func (b *CircuitBreaker) record(generation uint64, outcome Outcome) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if generation != b.generation {
+		return
+	}
+
+	switch b.state {
+	case BreakerClosed:
+		switch outcome {
+		case OutcomeSuccess:
+			b.failures = 0
+		case OutcomeFailure:
+			b.failures++
+			if b.failures >= b.config.FailureThreshold {
+				b.setState(BreakerOpen)
+			}
+		}
+	case BreakerHalfOpen:
+		b.probes--
+		switch outcome {
+		case OutcomeIgnored:
+			return
+		case OutcomeFailure:
+			b.setState(BreakerOpen)
+			return
+		}
+		b.successes++
+		if b.successes >= b.config.HalfOpenProbes {
+			b.setState(BreakerClosed)
+		}
+	}
+}
+
+// setState moves the breaker to state and starts a new generation. The caller must hold b.mu.
+func (b *CircuitBreaker) setState(state BreakerState) {
+	log.Printf("Circuit breaker %s: %s -> %s", b.name, b.state, state)
+	metrics.SetCircuitBreakerState(b.name, state.String())
+	b.state = state
+	b.generation++
+	b.probes = 0
+	b.successes = 0
+	if state == BreakerOpen {
+		b.openedAt = b.now()
+		metrics.CircuitBreakerOpened.WithLabelValues(b.name).Inc()
+	}
+	if state == BreakerClosed {
+		b.failures = 0
+	}
+}
+
+// State returns the current state of the breaker
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
@@ -0,0 +1,133 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lisandro/challenge/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestBreaker(t *testing.T, config BreakerConfig) (*CircuitBreaker, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := NewCircuitBreaker(t.Name(), config)
+	b.now = func() time.Time { return now }
+	return b, &now
+}
+
+func fail(t *testing.T, b *CircuitBreaker, times int) {
+	for i := 0; i < times; i++ {
+		done, err := b.Allow()
+		assert.NoError(t, err)
+		done(OutcomeFailure)
+	}
+}
+
+func TestCircuitBreaker_OpensAfterConsecutiveFailures(t *testing.T) {
+	b, _ := newTestBreaker(t, BreakerConfig{FailureThreshold: 3, OpenTimeout: time.Minute, HalfOpenProbes: 1})
+
+	fail(t, b, 2)
+	done, err := b.Allow()
+	assert.NoError(t, err)
+	done(OutcomeSuccess)
+	fail(t, b, 2)
+	assert.Equal(t, BreakerClosed, b.State())
+
+	fail(t, b, 1)
+	assert.Equal(t, BreakerOpen, b.State())
+
+	_, err = b.Allow()
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.CircuitBreakerState.WithLabelValues(t.Name(), "open")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.CircuitBreakerState.WithLabelValues(t.Name(), "closed")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.CircuitBreakerOpened.WithLabelValues(t.Name())))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.CircuitBreakerRejected.WithLabelValues(t.Name())))
+}
+
+func TestCircuitBreaker_HalfOpenProbes(t *testing.T) {
+	t.Run("successful probes close the breaker", func(t *testing.T) {
+		b, now := newTestBreaker(t, BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenProbes: 2})
+		fail(t, b, 1)
+
+		*now = now.Add(time.Minute)
+		first, err := b.Allow()
+		assert.NoError(t, err)
+		second, err := b.Allow()
+		assert.NoError(t, err)
+		assert.Equal(t, BreakerHalfOpen, b.State())
+
+		// Only HalfOpenProbes requests are let through at once
+		_, err = b.Allow()
+		assert.ErrorIs(t, err, ErrCircuitOpen)
+
+		first(OutcomeSuccess)
+		assert.Equal(t, BreakerHalfOpen, b.State())
+		second(OutcomeSuccess)
+		assert.Equal(t, BreakerClosed, b.State())
+	})
+
+	t.Run("failed probe opens the breaker again", func(t *testing.T) {
+		b, now := newTestBreaker(t, BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenProbes: 1})
+		fail(t, b, 1)
+
+		*now = now.Add(time.Minute)
+		probe, err := b.Allow()
+		assert.NoError(t, err)
+		probe(OutcomeFailure)
+
+		assert.Equal(t, BreakerOpen, b.State())
+		_, err = b.Allow()
+		assert.ErrorIs(t, err, ErrCircuitOpen)
+		assert.Equal(t, 2.0, testutil.ToFloat64(metrics.CircuitBreakerOpened.WithLabelValues(t.Name())))
+	})
+}
+
+func TestCircuitBreaker_IgnoresOutcomesOfEarlierStates(t *testing.T) {
+	b, now := newTestBreaker(t, BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenProbes: 1})
+
+	slow, err := b.Allow()
+	assert.NoError(t, err)
+	fail(t, b, 1)
+	*now = now.Add(time.Minute)
+	probe, err := b.Allow()
+	assert.NoError(t, err)
+
+	// A request sent while the breaker was closed must not decide the probe
+	slow(OutcomeFailure)
+	assert.Equal(t, BreakerHalfOpen, b.State())
+
+	probe(OutcomeSuccess)
+	assert.Equal(t, BreakerClosed, b.State())
+}
+
+func TestCircuitBreaker_IgnoredProbeFreesItsSlot(t *testing.T) {
+	b, now := newTestBreaker(t, BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenProbes: 1})
+	fail(t, b, 1)
+
+	*now = now.Add(time.Minute)
+	canceled, err := b.Allow()
+	assert.NoError(t, err)
+	canceled(OutcomeIgnored)
+
+	// The probe neither closed nor opened the breaker, and another one can be sent
+	assert.Equal(t, BreakerHalfOpen, b.State())
+	probe, err := b.Allow()
+	assert.NoError(t, err)
+	probe(OutcomeSuccess)
+	assert.Equal(t, BreakerClosed, b.State())
+}
+
+func TestCircuitBreaker_IgnoredOutcomesKeepFailures(t *testing.T) {
+	b, _ := newTestBreaker(t, BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute, HalfOpenProbes: 1})
+
+	fail(t, b, 1)
+	done, err := b.Allow()
+	assert.NoError(t, err)
+	done(OutcomeIgnored)
+	assert.Equal(t, BreakerClosed, b.State())
+
+	// The failure before the ignored outcome still counts towards the threshold
+	fail(t, b, 1)
+	assert.Equal(t, BreakerOpen, b.State())
+}
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/lisandro/challenge/pkg/metrics"
+)
+
+// Config holds the resilience settings of a client of another service
+type Config struct {
+	// Timeout bounds each attempt of a request
+	Timeout time.Duration
+	// Retries is the number of times a failed GET request is retried
+	Retries int
+	// RetryWaitMin and RetryWaitMax bound the jittered exponential backoff between retries
+	RetryWaitMin time.Duration
+	RetryWaitMax time.Duration
+	Breaker      BreakerConfig
+}
+
+// DefaultConfig returns the settings used for the clients of the other services
+func DefaultConfig() Config {
+	return Config{
+		Timeout:      2 * time.Second,
+		Retries:      2,
+		RetryWaitMin: 50 * time.Millisecond,
+		RetryWaitMax: 500 * time.Millisecond,
+		Breaker: BreakerConfig{
+			FailureThreshold: 5,
+			OpenTimeout:      10 * time.Second,
+			HalfOpenProbes:   1,
+		},
+	}
+}
+
+// newRestyClient creates an HTTP client that times out slow attempts, retries failed GET
+// requests with jittered exponential backoff, and stops calling the service while name's
+// circuit breaker is open. Every attempt, retries included, goes through the breaker, and
+// the attempts it lets through are recorded in the client metrics.
+func newRestyClient(name string, config Config) *resty.Client {
+	breaker := NewCircuitBreaker(name, config.Breaker)
+	transport := &metricsTransport{next: http.DefaultTransport, peer: name}
+
+	return resty.New().
+		SetTransport(&breakerTransport{next: transport, breaker: breaker}).
+		SetTimeout(config.Timeout).
+		SetRetryCount(config.Retries).
+		SetRetryWaitTime(config.RetryWaitMin).
+		SetRetryMaxWaitTime(config.RetryWaitMax).
+		AddRetryCondition(shouldRetry)
+}
+
+// shouldRetry retries idempotent GET requests that failed or got a server error, unless
+// the circuit breaker rejected them
+func shouldRetry(resp *resty.Response, err error) bool {
+	if resp == nil || resp.Request == nil || resp.Request.Method != http.MethodGet {
+		return false
+	}
+	if err != nil {
+		return !errors.Is(err, ErrCircuitOpen) && !errors.Is(err, context.Canceled)
+	}
+	return resp.StatusCode() >= http.StatusInternalServerError || resp.StatusCode() == http.StatusTooManyRequests
+}
+
+// breakerTransport sends requests through a circuit breaker. Transport errors and server
+// errors count as failures; requests canceled by the caller do not count at all.
+type breakerTransport struct {
+	next    http.RoundTripper
+	breaker *CircuitBreaker
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	done, err := t.breaker.Allow()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	switch {
+	case errors.Is(err, context.Canceled):
+		done(OutcomeIgnored)
+	case err != nil:
+		done(OutcomeFailure)
+	case resp.StatusCode >= http.StatusInternalServerError:
+		done(OutcomeFailure)
+	default:
+		done(OutcomeSuccess)
+	}
+	return resp, err
+}
+
+// metricsTransport records the requests sent to peer. Requests rejected by the circuit
+// breaker never reach it; they are counted in circuit_breaker_rejected_requests_total.
+type metricsTransport struct {
+	next http.RoundTripper
+	peer string
+}
+
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		metrics.ObserveClientRequest(t.peer, start, 0, err)
+		return nil, err
+	}
+	metrics.ObserveClientRequest(t.peer, start, resp.StatusCode, nil)
+	return resp, nil
+}
@@ -0,0 +1,215 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lisandro/challenge/pkg/metrics"
+	"github.com/lisandro/timeline-service/internal/domain"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestConfig() Config {
+	return Config{
+		Timeout:      100 * time.Millisecond,
+		Retries:      2,
+		RetryWaitMin: time.Millisecond,
+		RetryWaitMax: 5 * time.Millisecond,
+		Breaker: BreakerConfig{
+			FailureThreshold: 3,
+			OpenTimeout:      time.Minute,
+			HalfOpenProbes:   1,
+		},
+	}
+}
+
+// newFlakyServer starts a server that answers each request with the next handler, and
+// with the last one once they run out. It returns the server and its request counter.
+func newFlakyServer(t *testing.T, handlers ...http.HandlerFunc) (*httptest.Server, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := int(atomic.AddInt32(&calls, 1))
+		handlers[min(call, len(handlers))-1](w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func respond(status int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}
+}
+
+func delay(d time.Duration, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(d):
+		case <-r.Context().Done():
+			return
+		}
+		next(w, r)
+	}
+}
+
+func TestClient_RetriesFailedGets(t *testing.T) {
+	server, calls := newFlakyServer(t,
+		respond(http.StatusServiceUnavailable, `{}`),
+		respond(http.StatusBadGateway, `{}`),
+		respond(http.StatusOK, `{"count": 7}`),
+	)
+	c := NewTweetClient(server.URL, newTestConfig())
+
+	count, err := c.CountUserTweets(context.Background(), []string{"user1"}, domain.TweetRange{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 7, count)
+	assert.Equal(t, int32(3), atomic.LoadInt32(calls))
+}
+
+func TestClient_RecordsAttempts(t *testing.T) {
+	server, _ := newFlakyServer(t,
+		respond(http.StatusServiceUnavailable, `{}`),
+		respond(http.StatusOK, `{"count": 7}`),
+	)
+	c := NewTweetClient(server.URL, newTestConfig())
+	failed := metrics.ClientRequests.WithLabelValues("tweet-service", "5xx")
+	succeeded := metrics.ClientRequests.WithLabelValues("tweet-service", "2xx")
+	failedBefore, succeededBefore := testutil.ToFloat64(failed), testutil.ToFloat64(succeeded)
+
+	_, err := c.CountUserTweets(context.Background(), []string{"user1"}, domain.TweetRange{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, failedBefore+1, testutil.ToFloat64(failed))
+	assert.Equal(t, succeededBefore+1, testutil.ToFloat64(succeeded))
+}
+
+func TestClient_DoesNotRetryClientErrors(t *testing.T) {
+	server, calls := newFlakyServer(t, respond(http.StatusBadRequest, `{"error": "bad"}`))
+	c := NewTweetClient(server.URL, newTestConfig())
+
+	_, err := c.CountUserTweets(context.Background(), []string{"user1"}, domain.TweetRange{SinceID: "unknown"})
+
+	assert.ErrorIs(t, err, domain.ErrInvalidTweetRange)
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+}
+
+func TestClient_TimesOutSlowAttempts(t *testing.T) {
+	server, calls := newFlakyServer(t,
+		delay(time.Second, respond(http.StatusOK, `{"following": []}`)),
+		respond(http.StatusOK, `{"following": [{"id": "user2", "username": "bob"}]}`),
+	)
+	c := NewUserClient(server.URL, newTestConfig())
+
+	start := time.Now()
+	users, err := c.GetFollowingUsers(context.Background(), "user1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.FollowingUser{{ID: "user2", Username: "bob"}}, users)
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestClient_BreakerStopsCallingFailingService(t *testing.T) {
+	server, calls := newFlakyServer(t, respond(http.StatusInternalServerError, `{}`))
+	config := newTestConfig()
+	c := NewUserClient(server.URL, config)
+
+	// The first request and its retries reach the failure threshold
+	_, err := c.GetFollowingUsers(context.Background(), "user1")
+	assert.Error(t, err)
+	assert.Equal(t, int32(config.Breaker.FailureThreshold), atomic.LoadInt32(calls))
+
+	_, err = c.GetFollowingUsers(context.Background(), "user1")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(config.Breaker.FailureThreshold), atomic.LoadInt32(calls))
+}
+
+func TestClient_BreakerProbesRecoveredService(t *testing.T) {
+	server, calls := newFlakyServer(t,
+		respond(http.StatusInternalServerError, `{}`),
+		respond(http.StatusOK, `{"users": [{"id": "user2", "username": "bob"}]}`),
+	)
+	config := newTestConfig()
+	config.Retries = 0
+	config.Breaker.FailureThreshold = 1
+	config.Breaker.OpenTimeout = 20 * time.Millisecond
+	c := NewUserClient(server.URL, config)
+
+	_, err := c.GetUsers(context.Background(), []string{"user2"})
+	assert.Error(t, err)
+	_, err = c.GetUsers(context.Background(), []string{"user2"})
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+
+	time.Sleep(config.Breaker.OpenTimeout)
+	users, err := c.GetUsers(context.Background(), []string{"user2"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Author{{ID: "user2", Username: "bob"}}, users)
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+}
+
+func TestClient_CanceledRequestsAreNotFailures(t *testing.T) {
+	server, calls := newFlakyServer(t, delay(time.Second, respond(http.StatusOK, `{"count": 1}`)))
+	config := newTestConfig()
+	config.Timeout = time.Second
+	config.Breaker.FailureThreshold = 1
+	c := NewTweetClient(server.URL, config).(*tweetClient)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	_, err := c.CountUserTweets(ctx, []string{"user1"}, domain.TweetRange{})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+	breaker := c.client.GetClient().Transport.(*breakerTransport).breaker
+	assert.Equal(t, BreakerClosed, breaker.State())
+}
+
+func TestClient_CanceledProbesDoNotCloseTheBreaker(t *testing.T) {
+	server, calls := newFlakyServer(t,
+		delay(time.Second, respond(http.StatusOK, `{"count": 1}`)),
+		respond(http.StatusOK, `{"count": 1}`),
+	)
+	config := newTestConfig()
+	config.Timeout = time.Second
+	config.Breaker.FailureThreshold = 1
+	c := NewTweetClient(server.URL, config).(*tweetClient)
+
+	// Open the breaker and let its timeout elapse
+	breaker := c.client.GetClient().Transport.(*breakerTransport).breaker
+	now := time.Now()
+	breaker.now = func() time.Time { return now }
+	done, err := breaker.Allow()
+	assert.NoError(t, err)
+	done(OutcomeFailure)
+	now = now.Add(config.Breaker.OpenTimeout)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	_, err = c.CountUserTweets(ctx, []string{"user1"}, domain.TweetRange{})
+
+	// The canceled probe says nothing about the service, so the breaker stays half-open
+	// and lets the next probe decide
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, BreakerHalfOpen, breaker.State())
+
+	count, err := c.CountUserTweets(context.Background(), []string{"user1"}, domain.TweetRange{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+	assert.Equal(t, BreakerClosed, breaker.State())
+}
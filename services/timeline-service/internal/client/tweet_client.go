@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
 
 	"github.com/go-resty/resty/v2"
@@ -11,7 +12,13 @@ import (
 )
 
 type TweetClient interface {
-	GetUserTweets(ctx context.Context, userIDs []string) ([]domain.Tweet, error)
+	GetUserTweets(ctx context.Context, userIDs []string, tweetRange domain.TweetRange) ([]domain.Tweet, error)
+	CountUserTweets(ctx context.Context, userIDs []string, tweetRange domain.TweetRange) (int, error)
+}
+
+// countResponse represents the response of the tweet service count endpoint
+type countResponse struct {
+	Count int `json:"count"`
 }
 
 type tweetClient struct {
@@ -19,14 +26,15 @@ type tweetClient struct {
 	client  *resty.Client
 }
 
-func NewTweetClient(baseURL string) TweetClient {
+// NewTweetClient creates a client of the tweet service with the given resilience settings
+func NewTweetClient(baseURL string, config Config) TweetClient {
 	return &tweetClient{
 		baseURL: baseURL,
-		client:  resty.New(),
+		client:  newRestyClient("tweet-service", config),
 	}
 }
 
-func (c *tweetClient) GetUserTweets(ctx context.Context, userIDs []string) ([]domain.Tweet, error) {
+func (c *tweetClient) GetUserTweets(ctx context.Context, userIDs []string, tweetRange domain.TweetRange) ([]domain.Tweet, error) {
 	log.Printf("Requesting tweets from tweet service")
 	
 	// Handle edge case where no user IDs are provided
@@ -41,13 +49,18 @@ func (c *tweetClient) GetUserTweets(ctx context.Context, userIDs []string) ([]do
 	resp, err := c.client.R().
 		SetContext(ctx).
 		SetResult(&tweets).
-		Get(fmt.Sprintf("%s/tweets/following?user_ids=%s", c.baseURL, strings.Join(userIDs, ",")))
+		SetQueryParams(rangeParams(userIDs, tweetRange)).
+		Get(fmt.Sprintf("%s/tweets/following", c.baseURL))
 
 	if err != nil {
 		log.Printf("Failed to get tweets from tweet service: %v", err)
 		return nil, fmt.Errorf("failed to get user tweets: %w", err)
 	}
 
+	if resp.StatusCode() == 400 && (tweetRange.SinceID != "" || tweetRange.MaxID != "") {
+		return nil, domain.ErrInvalidTweetRange
+	}
+
 	if resp.StatusCode() != 200 {
 		log.Printf("Tweet service returned non-200 status: %d", resp.StatusCode())
 		return nil, fmt.Errorf("failed to get user tweets: status code %d", resp.StatusCode())
@@ -60,4 +73,46 @@ func (c *tweetClient) GetUserTweets(ctx context.Context, userIDs []string) ([]do
 
 	log.Printf("Successfully retrieved %d tweets from tweet service", len(tweets))
 	return tweets, nil
-} 
\ No newline at end of file
+}
+
+// CountUserTweets counts the tweets of userIDs within tweetRange without fetching them
+func (c *tweetClient) CountUserTweets(ctx context.Context, userIDs []string, tweetRange domain.TweetRange) (int, error) {
+	if len(userIDs) == 0 {
+		return 0, nil
+	}
+
+	var result countResponse
+	resp, err := c.client.R().
+		SetContext(ctx).
+		SetResult(&result).
+		SetQueryParams(rangeParams(userIDs, tweetRange)).
+		Get(fmt.Sprintf("%s/tweets/following/count", c.baseURL))
+	if err != nil {
+		return 0, fmt.Errorf("failed to count user tweets: %w", err)
+	}
+
+	if resp.StatusCode() == 400 && (tweetRange.SinceID != "" || tweetRange.MaxID != "") {
+		return 0, domain.ErrInvalidTweetRange
+	}
+
+	if resp.StatusCode() != 200 {
+		return 0, fmt.Errorf("failed to count user tweets: status code %d", resp.StatusCode())
+	}
+
+	return result.Count, nil
+}
+
+// rangeParams builds the query parameters selecting the tweets of userIDs within tweetRange
+func rangeParams(userIDs []string, tweetRange domain.TweetRange) map[string]string {
+	params := map[string]string{"user_ids": strings.Join(userIDs, ",")}
+	if tweetRange.SinceID != "" {
+		params["since_id"] = tweetRange.SinceID
+	}
+	if tweetRange.MaxID != "" {
+		params["max_id"] = tweetRange.MaxID
+	}
+	if tweetRange.Limit > 0 {
+		params["page_size"] = strconv.Itoa(tweetRange.Limit)
+	}
+	return params
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/go-resty/resty/v2"
 	"github.com/lisandro/timeline-service/internal/domain"
@@ -11,8 +12,12 @@ import (
 
 type UserClient interface {
 	GetFollowingUsers(ctx context.Context, userID string) ([]domain.FollowingUser, error)
+	GetUsers(ctx context.Context, userIDs []string) ([]domain.Author, error)
 }
 
+// usersBatchSize is the number of users the user service looks up in a single batch request
+const usersBatchSize = 100
+
 type userClient struct {
 	baseURL string
 	client  *resty.Client
@@ -23,10 +28,16 @@ type FollowingResponse struct {
 	Following []domain.FollowingUser `json:"following"`
 }
 
-func NewUserClient(baseURL string) UserClient {
+// usersResponse represents the response of the user service batch lookup
+type usersResponse struct {
+	Users []domain.Author `json:"users"`
+}
+
+// NewUserClient creates a client of the user service with the given resilience settings
+func NewUserClient(baseURL string, config Config) UserClient {
 	return &userClient{
 		baseURL: baseURL,
-		client:  resty.New(),
+		client:  newRestyClient("user-service", config),
 	}
 }
 
@@ -53,4 +64,33 @@ func (c *userClient) GetFollowingUsers(ctx context.Context, userID string) ([]do
 
 	log.Printf("Successfully retrieved %d following users from user service for user %s", len(response.Following), userID)
 	return response.Following, nil
-} 
\ No newline at end of file
+}
+
+// GetUsers looks up the profiles of many users, in batches. Unknown and deactivated users
+// are left out of the result.
+func (c *userClient) GetUsers(ctx context.Context, userIDs []string) ([]domain.Author, error) {
+	authors := make([]domain.Author, 0, len(userIDs))
+	for start := 0; start < len(userIDs); start += usersBatchSize {
+		end := start + usersBatchSize
+		if end > len(userIDs) {
+			end = len(userIDs)
+		}
+
+		var response usersResponse
+		resp, err := c.client.R().
+			SetContext(ctx).
+			SetQueryParam("ids", strings.Join(userIDs[start:end], ",")).
+			SetResult(&response).
+			Get(fmt.Sprintf("%s/batch", c.baseURL))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get users: %w", err)
+		}
+
+		if resp.StatusCode() != 200 {
+			return nil, fmt.Errorf("failed to get users: status code %d", resp.StatusCode())
+		}
+
+		authors = append(authors, response.Users...)
+	}
+	return authors, nil
+}
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lisandro/challenge/pkg/eventauth"
+	"github.com/lisandro/challenge/pkg/metrics"
+)
+
+// RequestMetrics records the rate, errors and duration of the requests of each route, under
+// the same names as the other services. Requests to streamingRoutes are only counted: they
+// last as long as their clients stay connected, which says nothing about the latency of the
+// service.
+func RequestMetrics(streamingRoutes ...string) gin.HandlerFunc {
+	streaming := make(map[string]bool, len(streamingRoutes))
+	for _, route := range streamingRoutes {
+		streaming[route] = true
+	}
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = metrics.UnmatchedRoute
+		}
+		if streaming[route] {
+			metrics.CountRequest(c.Request.Method, route, c.Writer.Status())
+			return
+		}
+		metrics.ObserveRequest(c.Request.Method, route, c.Writer.Status(), start)
+	}
+}
+
+// VerifyEvent answers 401 to events that are not signed with secret, so that the internal
+// routes only act on events sent by the other services
+func VerifyEvent(secret []byte) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := eventauth.VerifyRequest(secret, c.Request); err != nil {
+			log.Printf("Rejecting event from %s: %v", c.ClientIP(), err)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{Error: "Invalid event signature"})
+			return
+		}
+		c.Next()
+	}
+}
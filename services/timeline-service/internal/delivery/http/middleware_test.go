@@ -0,0 +1,107 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lisandro/challenge/pkg/eventauth"
+	"github.com/lisandro/challenge/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestMetrics())
+	router.GET("/api/v1/things/:id", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	served := metrics.HTTPRequests.WithLabelValues("GET", "/api/v1/things/:id", "204")
+	unmatched := metrics.HTTPRequests.WithLabelValues("GET", "unmatched", "404")
+	servedBefore, unmatchedBefore := testutil.ToFloat64(served), testutil.ToFloat64(unmatched)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/things/1", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/nothing/1", nil))
+
+	// Requests are counted by route template rather than by path
+	assert.Equal(t, servedBefore+1, testutil.ToFloat64(served))
+	assert.Equal(t, unmatchedBefore+1, testutil.ToFloat64(unmatched))
+}
+
+func TestRequestMetrics_StreamingRoutesAreOnlyCounted(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestMetrics("/api/v1/things/stream"))
+	router.GET("/api/v1/things/stream", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	requests := metrics.HTTPRequests.WithLabelValues("GET", "/api/v1/things/stream", "200")
+	requestsBefore := testutil.ToFloat64(requests)
+	durationsBefore := testutil.CollectAndCount(metrics.HTTPRequestDuration)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/things/stream", nil))
+
+	// A stream lasts as long as its client, so its duration is not a latency
+	assert.Equal(t, requestsBefore+1, testutil.ToFloat64(requests))
+	assert.Equal(t, durationsBefore, testutil.CollectAndCount(metrics.HTTPRequestDuration))
+}
+
+func TestVerifyEvent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	secret := []byte("secret")
+	body := `{"id":"event1","type":"user.deleted","user_id":"user1"}`
+
+	tests := []struct {
+		name           string
+		sign           func(req *http.Request)
+		expectedStatus int
+	}{
+		{
+			name:           "signed event",
+			sign:           func(req *http.Request) { eventauth.Sign(req, secret, []byte(body)) },
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "unsigned event",
+			sign:           func(req *http.Request) {},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "event signed with another secret",
+			sign:           func(req *http.Request) { eventauth.Sign(req, []byte("other"), []byte(body)) },
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received string
+			router := gin.New()
+			router.POST("/internal/events", VerifyEvent(secret), func(c *gin.Context) {
+				read, _ := io.ReadAll(c.Request.Body)
+				received = string(read)
+				c.Status(http.StatusNoContent)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/internal/events", strings.NewReader(body))
+			tt.sign(req)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusNoContent {
+				// The handler still reads the body that was verified
+				assert.Equal(t, body, received)
+			} else {
+				assert.Empty(t, received)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lisandro/timeline-service/internal/domain"
+	"github.com/lisandro/timeline-service/internal/stream"
+)
+
+// StreamConfig holds the timing settings of the timeline streams
+type StreamConfig struct {
+	// HeartbeatInterval is how often a comment is sent on idle streams so that proxies keep them open
+	HeartbeatInterval time.Duration
+	// RefreshInterval is how often the followed users of an open stream are reloaded
+	RefreshInterval time.Duration
+	// RetryInterval is the reconnection delay advertised to clients
+	RetryInterval time.Duration
+}
+
+// StreamTimeline godoc
+// @Summary Stream new timeline tweets
+// @Description Server-Sent Events stream of the new tweets of the users that the authenticated user follows. Each tweet is sent as a "tweet" event whose ID is the tweet ID. Reconnecting with the Last-Event-ID header replays the tweets missed since that event; if they can no longer be replayed, a "reset" event asks the client to reload GET /timeline. Comments are sent as heartbeats.
+// @Tags timeline
+// @Produce text/event-stream
+// @Param X-User-ID header string true "User ID"
+// @Param Last-Event-ID header string false "ID of the last event received"
+// @Success 200 {string} string "Event stream"
+// @Failure 400 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /timeline/stream [get]
+func (h *TimelineHandler) StreamTimeline(c *gin.Context) {
+	userID := c.GetHeader("X-User-ID")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "X-User-ID header is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	sub, err := h.timelineUseCase.StreamTimeline(ctx, userID, c.GetHeader("Last-Event-ID"))
+	if errors.Is(err, stream.ErrTooManyConnections) {
+		c.JSON(http.StatusTooManyRequests, ErrorResponse{Error: "Too many open streams"})
+		return
+	}
+	if errors.Is(err, stream.ErrHubClosed) {
+		c.JSON(http.StatusServiceUnavailable, ErrorResponse{Error: "Service is shutting down"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to open timeline stream for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to open timeline stream"})
+		return
+	}
+	defer sub.Close()
+
+	log.Printf("Opened timeline stream for user %s", userID)
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	w := c.Writer
+	fmt.Fprintf(w, "retry: %d\n\n", h.streamConfig.RetryInterval.Milliseconds())
+	if sub.Reset {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, event := range sub.Replay {
+		if err := writeTweetEvent(w, event); err != nil {
+			return
+		}
+	}
+	w.Flush()
+
+	heartbeat := time.NewTicker(h.streamConfig.HeartbeatInterval)
+	defer heartbeat.Stop()
+	refresh := time.NewTicker(h.streamConfig.RefreshInterval)
+	defer refresh.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("Closed timeline stream for user %s", userID)
+			return
+		case event, ok := <-sub.Events():
+			if !ok {
+				// The stream fell behind or the service is shutting down; the client reconnects
+				// and resumes from its last event
+				log.Printf("Timeline stream for user %s was ended by the server", userID)
+				return
+			}
+			if err := writeTweetEvent(w, event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case <-refresh.C:
+			if err := h.timelineUseCase.RefreshStream(ctx, sub); err != nil {
+				log.Printf("Failed to refresh timeline stream for user %s: %v", userID, err)
+			}
+		}
+		w.Flush()
+	}
+}
+
+// writeTweetEvent writes a tweet as a Server-Sent Event
+func writeTweetEvent(w io.Writer, event stream.Event) error {
+	data, err := json.Marshal(event.Tweet)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: tweet\ndata: %s\n\n", event.ID, data)
+	return err
+}
+
+// HandleTweetEvent godoc
+// @Summary Handle a tweet service event
+// @Description Receive an event published by the tweet service. A tweet.created event is pushed to the open timeline streams of the author's followers. Events may be delivered more than once.
+// @Description Events must be signed with the secret shared by the services.
+// @Tags internal
+// @Accept json
+// @Produce json
+// @Param event body domain.TweetEvent true "Tweet event"
+// @Param X-Event-Timestamp header string true "Unix time at which the event was signed"
+// @Param X-Event-Signature header string true "HMAC-SHA256 of the timestamp and body, as sha256=<hex>"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /internal/tweet-events [post]
+func (h *TimelineHandler) HandleTweetEvent(c *gin.Context) {
+	var event domain.TweetEvent
+	if err := c.ShouldBindJSON(&event); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid event"})
+		return
+	}
+
+	switch event.Type {
+	case domain.EventTweetCreated:
+		if err := h.timelineUseCase.PublishTweet(c.Request.Context(), event.Tweet); err != nil {
+			log.Printf("Failed to publish tweet %s: %v", event.Tweet.ID, err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to publish tweet"})
+			return
+		}
+	default:
+		// Acknowledge events this service does not care about so they are not redelivered
+		log.Printf("Ignoring event %s of type %s", event.ID, event.Type)
+	}
+
+	c.Status(http.StatusNoContent)
+}
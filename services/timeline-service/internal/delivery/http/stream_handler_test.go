@@ -0,0 +1,190 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lisandro/challenge/pkg/eventauth"
+	"github.com/lisandro/timeline-service/internal/domain"
+	"github.com/lisandro/timeline-service/internal/stream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestHub() *stream.Hub {
+	return stream.NewHub(stream.Config{BufferSize: 10, MaxConnectionsPerUser: 1, QueueSize: 10})
+}
+
+func TestTimelineHandler_StreamTimeline(t *testing.T) {
+	router, mockUseCase, handler := setupTest()
+	router.GET("/timeline/stream", handler.StreamTimeline)
+
+	hub := newTestHub()
+	hub.Publish(domain.Tweet{ID: "tweet1", UserID: "user2", Content: "Seen"})
+	hub.Publish(domain.Tweet{ID: "tweet2", UserID: "user2", Content: "Missed"})
+	sub, err := hub.Subscribe("user1", []string{"user2"}, "tweet1")
+	assert.NoError(t, err)
+	hub.Publish(domain.Tweet{ID: "tweet3", UserID: "user2", Content: "New"})
+	// Closing the stream ends the handler once the queued events are written
+	sub.Close()
+
+	mockUseCase.On("StreamTimeline", mock.Anything, "user1", "tweet1").Return(sub, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/timeline/stream", nil)
+	req.Header.Set("X-User-ID", "user1")
+	req.Header.Set("Last-Event-ID", "tweet1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	body := w.Body.String()
+	assert.True(t, strings.HasPrefix(body, "retry: 3000\n\n"))
+	assert.NotContains(t, body, "tweet1")
+	assert.NotContains(t, body, "event: reset")
+	missed := strings.Index(body, "id: tweet2\nevent: tweet\ndata: {")
+	live := strings.Index(body, "id: tweet3\nevent: tweet\ndata: {")
+	assert.True(t, missed >= 0 && live > missed, "expected the missed tweet before the new one, got %q", body)
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestTimelineHandler_StreamTimeline_Reset(t *testing.T) {
+	router, mockUseCase, handler := setupTest()
+	router.GET("/timeline/stream", handler.StreamTimeline)
+
+	sub, err := newTestHub().Subscribe("user1", []string{"user2"}, "unknown")
+	assert.NoError(t, err)
+	sub.Close()
+	mockUseCase.On("StreamTimeline", mock.Anything, "user1", "unknown").Return(sub, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/timeline/stream", nil)
+	req.Header.Set("X-User-ID", "user1")
+	req.Header.Set("Last-Event-ID", "unknown")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "event: reset\n")
+}
+
+func TestTimelineHandler_StreamTimeline_Errors(t *testing.T) {
+	tests := []struct {
+		name           string
+		userID         string
+		mockError      error
+		expectedStatus int
+	}{
+		{
+			name:           "missing user ID",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "too many streams",
+			userID:         "user1",
+			mockError:      stream.ErrTooManyConnections,
+			expectedStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:           "shutting down",
+			userID:         "user1",
+			mockError:      stream.ErrHubClosed,
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "user service error",
+			userID:         "user1",
+			mockError:      errors.New("service error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, mockUseCase, handler := setupTest()
+			router.GET("/timeline/stream", handler.StreamTimeline)
+
+			if tt.mockError != nil {
+				mockUseCase.On("StreamTimeline", mock.Anything, tt.userID, "").Return(nil, tt.mockError)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/timeline/stream", nil)
+			if tt.userID != "" {
+				req.Header.Set("X-User-ID", tt.userID)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockUseCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestTimelineHandler_HandleTweetEvent(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		unsigned       bool
+		expectPublish  bool
+		mockError      error
+		expectedStatus int
+	}{
+		{
+			name:           "tweet created",
+			body:           `{"id":"event1","type":"tweet.created","tweet":{"id":"tweet1","user_id":"user2","content":"Hello"}}`,
+			expectPublish:  true,
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "publish failure is retried",
+			body:           `{"id":"event1","type":"tweet.created","tweet":{"id":"tweet1","user_id":"user2","content":"Hello"}}`,
+			expectPublish:  true,
+			mockError:      errors.New("publish error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "unknown event type is acknowledged",
+			body:           `{"id":"event2","type":"tweet.deleted","tweet":{"id":"tweet1"}}`,
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "invalid body",
+			body:           `not json`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unsigned event",
+			body:           `{"id":"event1","type":"tweet.created","tweet":{"id":"tweet1","user_id":"user2","content":"Hello"}}`,
+			unsigned:       true,
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	secret := []byte("secret")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, mockUseCase, handler := setupTest()
+			router.POST("/internal/tweet-events", VerifyEvent(secret), handler.HandleTweetEvent)
+
+			if tt.expectPublish {
+				mockUseCase.On("PublishTweet", mock.Anything, mock.MatchedBy(func(tweet domain.Tweet) bool {
+					return tweet.ID == "tweet1" && tweet.UserID == "user2"
+				})).Return(tt.mockError)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/internal/tweet-events", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if !tt.unsigned {
+				eventauth.Sign(req, secret, []byte(tt.body))
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockUseCase.AssertExpectations(t)
+		})
+	}
+}
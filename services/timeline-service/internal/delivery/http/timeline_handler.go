@@ -1,31 +1,41 @@
 package http
 
 import (
+	"errors"
 	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lisandro/timeline-service/internal/domain"
 	"github.com/lisandro/timeline-service/internal/usecase"
 )
 
 type TimelineHandler struct {
 	timelineUseCase usecase.TimelineUseCase
+	streamConfig    StreamConfig
 }
 
-func NewTimelineHandler(timelineUseCase usecase.TimelineUseCase) *TimelineHandler {
+func NewTimelineHandler(timelineUseCase usecase.TimelineUseCase, streamConfig StreamConfig) *TimelineHandler {
 	return &TimelineHandler{
 		timelineUseCase: timelineUseCase,
+		streamConfig:    streamConfig,
 	}
 }
 
 // GetTimeline godoc
 // @Summary Get user timeline
-// @Description Get timeline of tweets from users that the authenticated user follows
+// @Description Get timeline of tweets from users that the authenticated user follows, newest first. since_id returns only the tweets newer than a tweet, and max_id only those older than a tweet.
+// @Description With mode=ranked, a domain.RankedTimeline is returned instead: tweets of followed users and of the users they follow, ordered by relevance, each with the reasons of its rank. since_id and max_id are not supported in ranked mode.
+// @Description When a service the timeline is built from fails, the last timeline built for the user is returned with degraded set to true and Warning headers. Older pages are never served stale.
 // @Tags timeline
 // @Accept json
 // @Produce json
 // @Param X-User-ID header string true "User ID"
+// @Param mode query string false "Timeline order" Enums(chronological, ranked)
+// @Param since_id query string false "Only return tweets newer than this tweet"
+// @Param max_id query string false "Only return tweets older than this tweet"
 // @Success 200 {object} domain.Timeline
+// @Header 200 {string} Warning "Set when the timeline is stale"
 // @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /timeline [get]
@@ -37,8 +47,31 @@ func (h *TimelineHandler) GetTimeline(c *gin.Context) {
 		return
 	}
 
+	tweetRange := domain.TweetRange{
+		SinceID: c.Query("since_id"),
+		MaxID:   c.Query("max_id"),
+	}
+
+	switch c.Query("mode") {
+	case "", "chronological":
+	case "ranked":
+		if tweetRange.SinceID != "" || tweetRange.MaxID != "" {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "since_id and max_id are not supported in ranked mode"})
+			return
+		}
+		h.getRankedTimeline(c, userID)
+		return
+	default:
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "mode must be chronological or ranked"})
+		return
+	}
+
 	log.Printf("Getting timeline for user %s", userID)
-	timeline, err := h.timelineUseCase.GetTimeline(c.Request.Context(), userID)
+	timeline, err := h.timelineUseCase.GetTimeline(c.Request.Context(), userID, tweetRange)
+	if errors.Is(err, domain.ErrInvalidTweetRange) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
 	if err != nil {
 		log.Printf("Failed to get timeline for user %s: %v", userID, err)
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get timeline"})
@@ -46,9 +79,122 @@ func (h *TimelineHandler) GetTimeline(c *gin.Context) {
 	}
 
 	log.Printf("Successfully retrieved timeline for user %s with %d tweets", userID, len(timeline.Tweets))
+	if timeline.Degraded {
+		setStaleWarnings(c)
+	}
+	c.JSON(http.StatusOK, timeline)
+}
+
+func (h *TimelineHandler) getRankedTimeline(c *gin.Context, userID string) {
+	log.Printf("Getting ranked timeline for user %s", userID)
+	timeline, err := h.timelineUseCase.GetRankedTimeline(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("Failed to get ranked timeline for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to get timeline"})
+		return
+	}
+
+	if timeline.Degraded {
+		setStaleWarnings(c)
+	}
 	c.JSON(http.StatusOK, timeline)
 }
 
+// setStaleWarnings marks a response as a stale copy served because it could not be rebuilt,
+// with the warn codes of RFC 7234
+func setStaleWarnings(c *gin.Context) {
+	c.Writer.Header().Add("Warning", `110 - "Response is Stale"`)
+	c.Writer.Header().Add("Warning", `111 - "Revalidation Failed"`)
+}
+
+// NewCountResponse is the number of tweets newer than the newest tweet a client has seen
+type NewCountResponse struct {
+	Count int `json:"count" example:"3"`
+}
+
+// GetNewCount godoc
+// @Summary Count new timeline tweets
+// @Description Count the timeline tweets newer than since_id without returning them, so clients can show a "new tweets" banner cheaply
+// @Tags timeline
+// @Produce json
+// @Param X-User-ID header string true "User ID"
+// @Param since_id query string true "ID of the newest tweet the client has seen"
+// @Success 200 {object} NewCountResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /timeline/new_count [get]
+func (h *TimelineHandler) GetNewCount(c *gin.Context) {
+	userID := c.GetHeader("X-User-ID")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "X-User-ID header is required"})
+		return
+	}
+
+	sinceID := c.Query("since_id")
+	if sinceID == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "since_id is required"})
+		return
+	}
+
+	count, err := h.timelineUseCase.CountNewTweets(c.Request.Context(), userID, sinceID)
+	if errors.Is(err, domain.ErrInvalidTweetRange) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to count new tweets for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to count new tweets"})
+		return
+	}
+
+	c.JSON(http.StatusOK, NewCountResponse{Count: count})
+}
+
+// HandleUserEvent godoc
+// @Summary Handle a user service event
+// @Description Receive an event published by the user service. A user.deleted event purges the user's timeline data, and a user.renamed event refreshes the user's profile on timelines. Events may be delivered more than once.
+// @Description Events must be signed with the secret shared by the services.
+// @Tags internal
+// @Accept json
+// @Produce json
+// @Param event body domain.UserEvent true "User event"
+// @Param X-Event-Timestamp header string true "Unix time at which the event was signed"
+// @Param X-Event-Signature header string true "HMAC-SHA256 of the timestamp and body, as sha256=<hex>"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /internal/events [post]
+func (h *TimelineHandler) HandleUserEvent(c *gin.Context) {
+	var event domain.UserEvent
+	if err := c.ShouldBindJSON(&event); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid event"})
+		return
+	}
+
+	switch event.Type {
+	case domain.EventUserDeleted:
+		log.Printf("Handling event %s: purging user %s", event.ID, event.UserID)
+		if err := h.timelineUseCase.PurgeUser(c.Request.Context(), event.UserID); err != nil {
+			log.Printf("Failed to purge user %s: %v", event.UserID, err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to purge user"})
+			return
+		}
+	case domain.EventUserRenamed:
+		log.Printf("Handling event %s: refreshing profile of user %s", event.ID, event.UserID)
+		if err := h.timelineUseCase.InvalidateProfile(c.Request.Context(), event.UserID); err != nil {
+			log.Printf("Failed to refresh profile of user %s: %v", event.UserID, err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to refresh profile"})
+			return
+		}
+	default:
+		// Acknowledge events this service does not care about so they are not redelivered
+		log.Printf("Ignoring event %s of type %s", event.ID, event.Type)
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
 type ErrorResponse struct {
 	Error string `json:"error"`
 } 
\ No newline at end of file
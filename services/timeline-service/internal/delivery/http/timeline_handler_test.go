@@ -4,13 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lisandro/timeline-service/internal/domain"
+	"github.com/lisandro/timeline-service/internal/stream"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -20,16 +23,57 @@ type MockTimelineUseCase struct {
 	mock.Mock
 }
 
-func (m *MockTimelineUseCase) GetTimeline(ctx context.Context, userID string) (*domain.Timeline, error) {
-	args := m.Called(ctx, userID)
+func (m *MockTimelineUseCase) GetTimeline(ctx context.Context, userID string, tweetRange domain.TweetRange) (*domain.Timeline, error) {
+	args := m.Called(ctx, userID, tweetRange)
 	return args.Get(0).(*domain.Timeline), args.Error(1)
 }
 
+func (m *MockTimelineUseCase) GetRankedTimeline(ctx context.Context, userID string) (*domain.RankedTimeline, error) {
+	args := m.Called(ctx, userID)
+	timeline, _ := args.Get(0).(*domain.RankedTimeline)
+	return timeline, args.Error(1)
+}
+
+func (m *MockTimelineUseCase) CountNewTweets(ctx context.Context, userID, sinceID string) (int, error) {
+	args := m.Called(ctx, userID, sinceID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockTimelineUseCase) StreamTimeline(ctx context.Context, userID, lastEventID string) (*stream.Subscription, error) {
+	args := m.Called(ctx, userID, lastEventID)
+	sub, _ := args.Get(0).(*stream.Subscription)
+	return sub, args.Error(1)
+}
+
+func (m *MockTimelineUseCase) RefreshStream(ctx context.Context, sub *stream.Subscription) error {
+	args := m.Called(ctx, sub)
+	return args.Error(0)
+}
+
+func (m *MockTimelineUseCase) PublishTweet(ctx context.Context, tweet domain.Tweet) error {
+	args := m.Called(ctx, tweet)
+	return args.Error(0)
+}
+
+func (m *MockTimelineUseCase) InvalidateProfile(ctx context.Context, userID string) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockTimelineUseCase) PurgeUser(ctx context.Context, userID string) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
 func setupTest() (*gin.Engine, *MockTimelineUseCase, *TimelineHandler) {
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
 	mockUseCase := new(MockTimelineUseCase)
-	handler := NewTimelineHandler(mockUseCase)
+	handler := NewTimelineHandler(mockUseCase, StreamConfig{
+		HeartbeatInterval: time.Minute,
+		RefreshInterval:   time.Minute,
+		RetryInterval:     3 * time.Second,
+	})
 	return router, mockUseCase, handler
 }
 
@@ -107,7 +151,7 @@ func TestTimelineHandler_GetTimeline(t *testing.T) {
 
 			// Set up mock expectations only if userID is provided
 			if tt.userID != "" {
-				mockUseCase.On("GetTimeline", mock.Anything, tt.userID).Return(tt.mockTimeline, tt.mockError)
+				mockUseCase.On("GetTimeline", mock.Anything, tt.userID, domain.TweetRange{}).Return(tt.mockTimeline, tt.mockError)
 			}
 
 			// Create request
@@ -167,7 +211,7 @@ func TestTimelineHandler_GetTimeline_ContextPropagation(t *testing.T) {
 	mockTimeline := &domain.Timeline{Tweets: []domain.Tweet{}}
 
 	// Set up mock to capture the context
-	mockUseCase.On("GetTimeline", mock.Anything, userID).
+	mockUseCase.On("GetTimeline", mock.Anything, userID, domain.TweetRange{}).
 		Run(func(args mock.Arguments) {
 			ctx := args.Get(0).(context.Context)
 			// Verify that the context is properly passed
@@ -192,7 +236,7 @@ func TestTimelineHandler_GetTimeline_ErrorResponse(t *testing.T) {
 	userID := "user1"
 	expectedError := errors.New("external service unavailable")
 
-	mockUseCase.On("GetTimeline", mock.Anything, userID).
+	mockUseCase.On("GetTimeline", mock.Anything, userID, domain.TweetRange{}).
 		Return((*domain.Timeline)(nil), expectedError)
 
 	req := httptest.NewRequest("GET", "/timeline", nil)
@@ -209,4 +253,295 @@ func TestTimelineHandler_GetTimeline_ErrorResponse(t *testing.T) {
 	assert.Equal(t, "Failed to get timeline", response.Error)
 
 	mockUseCase.AssertExpectations(t)
-} 
\ No newline at end of file
+}
+
+func TestTimelineHandler_GetTimeline_TweetRange(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockError      error
+		expectedStatus int
+	}{
+		{
+			name:           "range is passed to the use case",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unknown tweet in range",
+			mockError:      domain.ErrInvalidTweetRange,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, mockUseCase, handler := setupTest()
+			router.GET("/timeline", handler.GetTimeline)
+
+			mockUseCase.On("GetTimeline", mock.Anything, "user1", domain.TweetRange{SinceID: "tweet1", MaxID: "tweet9"}).
+				Return(&domain.Timeline{Tweets: []domain.Tweet{}}, tt.mockError)
+
+			req := httptest.NewRequest(http.MethodGet, "/timeline?since_id=tweet1&max_id=tweet9", nil)
+			req.Header.Set("X-User-ID", "user1")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockUseCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestTimelineHandler_GetTimeline_Ranked(t *testing.T) {
+	rankedTimeline := &domain.RankedTimeline{
+		Ranker: "weighted",
+		Entries: []domain.RankedEntry{
+			{
+				Tweet:   domain.Tweet{ID: "tweet1", UserID: "user2", Content: "Hello"},
+				Score:   0.8,
+				Reasons: []string{"From someone you follow"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		expectRanked   bool
+		mockError      error
+		expectedStatus int
+	}{
+		{
+			name:           "ranked timeline",
+			query:          "?mode=ranked",
+			expectRanked:   true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "ranking error",
+			query:          "?mode=ranked",
+			expectRanked:   true,
+			mockError:      errors.New("service error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "range in ranked mode",
+			query:          "?mode=ranked&since_id=tweet1",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown mode",
+			query:          "?mode=popular",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, mockUseCase, handler := setupTest()
+			router.GET("/timeline", handler.GetTimeline)
+
+			if tt.expectRanked {
+				mockUseCase.On("GetRankedTimeline", mock.Anything, "user1").Return(rankedTimeline, tt.mockError)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/timeline"+tt.query, nil)
+			req.Header.Set("X-User-ID", "user1")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				var response domain.RankedTimeline
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, *rankedTimeline, response)
+			}
+			mockUseCase.AssertExpectations(t)
+			mockUseCase.AssertNotCalled(t, "GetTimeline", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestTimelineHandler_GetTimeline_Degraded(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		mockCall func(m *MockTimelineUseCase, degraded bool)
+	}{
+		{
+			name: "chronological",
+			mockCall: func(m *MockTimelineUseCase, degraded bool) {
+				m.On("GetTimeline", mock.Anything, "user1", domain.TweetRange{}).Return(&domain.Timeline{Tweets: []domain.Tweet{}, Degraded: degraded}, nil)
+			},
+		},
+		{
+			name:  "ranked",
+			query: "?mode=ranked",
+			mockCall: func(m *MockTimelineUseCase, degraded bool) {
+				m.On("GetRankedTimeline", mock.Anything, "user1").Return(&domain.RankedTimeline{Ranker: "weighted", Entries: []domain.RankedEntry{}, Degraded: degraded}, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, degraded := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s degraded=%t", tt.name, degraded), func(t *testing.T) {
+				router, mockUseCase, handler := setupTest()
+				router.GET("/timeline", handler.GetTimeline)
+				tt.mockCall(mockUseCase, degraded)
+
+				req := httptest.NewRequest(http.MethodGet, "/timeline"+tt.query, nil)
+				req.Header.Set("X-User-ID", "user1")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, http.StatusOK, w.Code)
+				var response struct {
+					Degraded bool `json:"degraded"`
+				}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, degraded, response.Degraded)
+				if degraded {
+					assert.Equal(t, []string{`110 - "Response is Stale"`, `111 - "Revalidation Failed"`}, w.Header().Values("Warning"))
+				} else {
+					assert.Empty(t, w.Header().Values("Warning"))
+				}
+			})
+		}
+	}
+}
+
+func TestTimelineHandler_GetNewCount(t *testing.T) {
+	tests := []struct {
+		name           string
+		userID         string
+		query          string
+		expectCount    bool
+		mockCount      int
+		mockError      error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "new tweets",
+			userID:         "user1",
+			query:          "?since_id=tweet1",
+			expectCount:    true,
+			mockCount:      3,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"count":3}`,
+		},
+		{
+			name:           "unknown since ID",
+			userID:         "user1",
+			query:          "?since_id=tweet1",
+			expectCount:    true,
+			mockError:      domain.ErrInvalidTweetRange,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "service error",
+			userID:         "user1",
+			query:          "?since_id=tweet1",
+			expectCount:    true,
+			mockError:      errors.New("service error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "missing since ID",
+			userID:         "user1",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing user ID",
+			query:          "?since_id=tweet1",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, mockUseCase, handler := setupTest()
+			router.GET("/timeline/new_count", handler.GetNewCount)
+
+			if tt.expectCount {
+				mockUseCase.On("CountNewTweets", mock.Anything, tt.userID, "tweet1").Return(tt.mockCount, tt.mockError)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/timeline/new_count"+tt.query, nil)
+			if tt.userID != "" {
+				req.Header.Set("X-User-ID", tt.userID)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+			mockUseCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestTimelineHandler_HandleUserEvent(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		expectPurge      bool
+		expectInvalidate bool
+		mockError        error
+		expectedStatus   int
+	}{
+		{
+			name:           "user deleted",
+			body:           `{"id":"event1","type":"user.deleted","user_id":"user1"}`,
+			expectPurge:    true,
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "purge failure is retried",
+			body:           `{"id":"event1","type":"user.deleted","user_id":"user1"}`,
+			expectPurge:    true,
+			mockError:      errors.New("purge error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:             "user renamed",
+			body:             `{"id":"event2","type":"user.renamed","user_id":"user1"}`,
+			expectInvalidate: true,
+			expectedStatus:   http.StatusNoContent,
+		},
+		{
+			name:           "unknown event type is acknowledged",
+			body:           `{"id":"event3","type":"user.followed","user_id":"user1"}`,
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "invalid body",
+			body:           `not json`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, mockUseCase, handler := setupTest()
+			router.POST("/internal/events", handler.HandleUserEvent)
+
+			if tt.expectPurge {
+				mockUseCase.On("PurgeUser", mock.Anything, "user1").Return(tt.mockError)
+			}
+			if tt.expectInvalidate {
+				mockUseCase.On("InvalidateProfile", mock.Anything, "user1").Return(tt.mockError)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/internal/events", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockUseCase.AssertExpectations(t)
+		})
+	}
+}
@@ -1,9 +1,15 @@
 package domain
 
-import "time"
+import (
+	"errors"
+	"time"
+)
 
 type Timeline struct {
 	Tweets []Tweet `json:"tweets"`
+	// Degraded reports a timeline served from the last one built, because a service it is
+	// built from failed
+	Degraded bool `json:"degraded,omitempty"`
 }
 
 type Tweet struct {
@@ -11,10 +17,78 @@ type Tweet struct {
 	UserID    string    `json:"user_id"`
 	Content   string    `json:"content"`
 	CreatedAt time.Time `json:"created_at"`
+	// Author is the profile of the user who wrote the tweet, when it could be looked up
+	Author *Author `json:"author,omitempty"`
 }
 
+// RankedTimeline is a timeline ordered by relevance instead of time
+type RankedTimeline struct {
+	// Ranker is the name of the scorer that ranked the timeline
+	Ranker  string        `json:"ranker"`
+	Entries []RankedEntry `json:"entries"`
+	// Degraded reports a timeline served from the last one ranked, because a service it is
+	// built from failed
+	Degraded bool `json:"degraded,omitempty"`
+}
+
+// RankedEntry is a tweet of a ranked timeline with the reasons of its rank
+type RankedEntry struct {
+	Tweet   Tweet    `json:"tweet"`
+	Score   float64  `json:"score"`
+	Reasons []string `json:"reasons"`
+}
+
+// Author is the public profile of the author of a tweet
+type Author struct {
+	ID          string `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	AvatarURL   string `json:"avatar_url"`
+}
+
+// TweetRange limits a timeline to the tweets newer than SinceID and older than MaxID.
+// Empty bounds are not applied.
+type TweetRange struct {
+	SinceID string
+	MaxID   string
+	// Limit caps the number of tweets; zero uses the default page size of the tweet service
+	Limit int
+}
+
+// ErrInvalidTweetRange is returned when a bound of a TweetRange is not a known tweet
+var ErrInvalidTweetRange = errors.New("since_id or max_id does not match a tweet")
+
 // FollowingUser represents a user that someone follows - matches the User struct from user-service
 type FollowingUser struct {
-	ID       string `json:"id"`
-	Username string `json:"username"`
-} 
\ No newline at end of file
+	ID          string `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	AvatarURL   string `json:"avatar_url"`
+}
+
+// Types of the user service events handled by the timeline service
+const (
+	EventUserDeleted = "user.deleted"
+	EventUserRenamed = "user.renamed"
+)
+
+// UserEvent is an event published by the user service
+type UserEvent struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	UserID     string    `json:"user_id"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Types of the tweet service events handled by the timeline service
+const (
+	EventTweetCreated = "tweet.created"
+)
+
+// TweetEvent is an event published by the tweet service
+type TweetEvent struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	Tweet      Tweet     `json:"tweet"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
@@ -0,0 +1,77 @@
+package ranking
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// Arm is a scorer of an experiment and the share of users assigned to it
+type Arm struct {
+	Scorer Scorer
+	Weight int
+}
+
+// Experiment assigns each user to a scorer, so that rankers can be compared. A user
+// always gets the same scorer while the arms do not change.
+type Experiment struct {
+	arms  []Arm
+	total int
+}
+
+// NewExperiment creates an experiment over the given arms
+func NewExperiment(arms []Arm) (*Experiment, error) {
+	total := 0
+	for _, arm := range arms {
+		if arm.Weight < 0 {
+			return nil, fmt.Errorf("negative weight for scorer %s", arm.Scorer.Name())
+		}
+		total += arm.Weight
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("experiment needs at least one scorer with a positive weight")
+	}
+	return &Experiment{arms: arms, total: total}, nil
+}
+
+// ParseExperiment builds an experiment from a comma separated list of name=weight pairs,
+// such as "weighted=90,recency=10", using the scorers registered under those names
+func ParseExperiment(value string, scorers map[string]Scorer) (*Experiment, error) {
+	var arms []Arm
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, weightStr, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid experiment arm %q, expected name=weight", entry)
+		}
+		scorer, ok := scorers[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown scorer %q", name)
+		}
+		weight, err := strconv.Atoi(strings.TrimSpace(weightStr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid weight for scorer %q: %w", name, err)
+		}
+		arms = append(arms, Arm{Scorer: scorer, Weight: weight})
+	}
+	return NewExperiment(arms)
+}
+
+// ScorerFor returns the scorer assigned to a user
+func (e *Experiment) ScorerFor(userID string) Scorer {
+	h := fnv.New32a()
+	h.Write([]byte(userID))
+	bucket := int(h.Sum32() % uint32(e.total))
+
+	for _, arm := range e.arms {
+		if bucket < arm.Weight {
+			return arm.Scorer
+		}
+		bucket -= arm.Weight
+	}
+	return e.arms[len(e.arms)-1].Scorer
+}
@@ -0,0 +1,70 @@
+package ranking
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testScorers = map[string]Scorer{
+	"weighted": WeightedScorer{HalfLife: time.Hour},
+	"recency":  RecencyScorer{HalfLife: time.Hour},
+}
+
+func TestParseExperiment(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expectError bool
+	}{
+		{name: "single scorer", value: "weighted=100"},
+		{name: "split", value: "weighted=90, recency=10"},
+		{name: "unknown scorer", value: "magic=100", expectError: true},
+		{name: "missing weight", value: "weighted", expectError: true},
+		{name: "invalid weight", value: "weighted=lots", expectError: true},
+		{name: "negative weight", value: "weighted=-1,recency=10", expectError: true},
+		{name: "no scorer", value: "", expectError: true},
+		{name: "zero weights", value: "weighted=0", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			experiment, err := ParseExperiment(tt.value, testScorers)
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.Nil(t, experiment)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, experiment)
+			}
+		})
+	}
+}
+
+func TestExperiment_ScorerFor(t *testing.T) {
+	experiment, err := ParseExperiment("weighted=50,recency=50", testScorers)
+	assert.NoError(t, err)
+
+	assigned := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		userID := fmt.Sprintf("user%d", i)
+		scorer := experiment.ScorerFor(userID)
+		// A user keeps the same scorer across requests
+		assert.Equal(t, scorer.Name(), experiment.ScorerFor(userID).Name())
+		assigned[scorer.Name()]++
+	}
+
+	assert.InDelta(t, 500, assigned["weighted"], 100)
+	assert.InDelta(t, 500, assigned["recency"], 100)
+}
+
+func TestExperiment_ScorerFor_SkipsZeroWeights(t *testing.T) {
+	experiment, err := ParseExperiment("weighted=0,recency=1", testScorers)
+	assert.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, "recency", experiment.ScorerFor(fmt.Sprintf("user%d", i)).Name())
+	}
+}
@@ -0,0 +1,95 @@
+package ranking
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/lisandro/timeline-service/internal/domain"
+)
+
+// Candidate is a tweet that may be shown on a ranked timeline, with the signals known about it
+type Candidate struct {
+	Tweet domain.Tweet
+	// Followed reports whether the viewer follows the author
+	Followed bool
+	// FollowedBy is the number of users followed by the viewer who follow the author.
+	// It is how second-degree authors are found.
+	FollowedBy int
+	// Engagement is the number of interactions with the tweet
+	Engagement int
+}
+
+// Score is the score of a candidate and the reasons behind it
+type Score struct {
+	Value   float64
+	Reasons []string
+}
+
+// Scorer scores candidate tweets. Implementations are compared with an Experiment.
+type Scorer interface {
+	// Name identifies the scorer in responses, so results can be attributed to it
+	Name() string
+	Score(ctx context.Context, candidate Candidate, now time.Time) Score
+}
+
+// Config holds the limits applied when ranking a timeline
+type Config struct {
+	// Size is the number of entries of a ranked timeline
+	Size int
+	// AuthorPenalty multiplies the score of a tweet for each tweet of the same author ranked
+	// above it, so that a few prolific authors do not fill the timeline
+	AuthorPenalty float64
+}
+
+// Rank scores the candidates and orders them, best first, applying the diversity constraints
+// of config. Ties are broken by recency.
+func Rank(ctx context.Context, scorer Scorer, candidates []Candidate, config Config, now time.Time) []domain.RankedEntry {
+	scored := make([]domain.RankedEntry, 0, len(candidates))
+	for _, candidate := range candidates {
+		score := scorer.Score(ctx, candidate, now)
+		scored = append(scored, domain.RankedEntry{
+			Tweet:   candidate.Tweet,
+			Score:   score.Value,
+			Reasons: score.Reasons,
+		})
+	}
+	sort.SliceStable(scored, func(i, j int) bool {
+		if scored[i].Score != scored[j].Score {
+			return scored[i].Score > scored[j].Score
+		}
+		return scored[i].Tweet.CreatedAt.After(scored[j].Tweet.CreatedAt)
+	})
+
+	// Greedily pick the best entry once the scores are discounted by how many tweets of
+	// the same author are already ranked
+	ranked := make([]domain.RankedEntry, 0, min(config.Size, len(scored)))
+	picked := make(map[string]int)
+	for len(ranked) < config.Size && len(scored) > 0 {
+		best, bestScore := 0, -1.0
+		for i, entry := range scored {
+			score := entry.Score * pow(config.AuthorPenalty, picked[entry.Tweet.UserID])
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+
+		entry := scored[best]
+		if picked[entry.Tweet.UserID] > 0 {
+			entry.Reasons = append(entry.Reasons, "Ranked lower to show a variety of authors")
+		}
+		entry.Score = bestScore
+		picked[entry.Tweet.UserID]++
+		ranked = append(ranked, entry)
+		scored = append(scored[:best], scored[best+1:]...)
+	}
+	return ranked
+}
+
+func pow(base float64, exp int) float64 {
+	result := 1.0
+	for i := 0; i < exp; i++ {
+		result *= base
+	}
+	return result
+}
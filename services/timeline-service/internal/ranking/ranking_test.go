@@ -0,0 +1,79 @@
+package ranking
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lisandro/timeline-service/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+// fixedScorer scores tweets with a score given per tweet ID
+type fixedScorer map[string]float64
+
+func (s fixedScorer) Name() string { return "fixed" }
+
+func (s fixedScorer) Score(ctx context.Context, candidate Candidate, now time.Time) Score {
+	return Score{Value: s[candidate.Tweet.ID], Reasons: []string{"fixed"}}
+}
+
+func candidate(id, userID string, createdAt time.Time) Candidate {
+	return Candidate{Tweet: domain.Tweet{ID: id, UserID: userID, CreatedAt: createdAt}, Followed: true}
+}
+
+func rankedIDs(entries []domain.RankedEntry) []string {
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.Tweet.ID)
+	}
+	return ids
+}
+
+func TestRank_OrdersByScore(t *testing.T) {
+	now := time.Now()
+	scorer := fixedScorer{"tweet1": 0.2, "tweet2": 0.9, "tweet3": 0.5}
+	candidates := []Candidate{
+		candidate("tweet1", "user1", now),
+		candidate("tweet2", "user2", now),
+		candidate("tweet3", "user3", now),
+	}
+
+	entries := Rank(context.Background(), scorer, candidates, Config{Size: 10, AuthorPenalty: 1}, now)
+
+	assert.Equal(t, []string{"tweet2", "tweet3", "tweet1"}, rankedIDs(entries))
+	assert.Equal(t, 0.9, entries[0].Score)
+	assert.Equal(t, []string{"fixed"}, entries[0].Reasons)
+}
+
+func TestRank_BreaksTiesByRecency(t *testing.T) {
+	now := time.Now()
+	scorer := fixedScorer{"tweet1": 0.5, "tweet2": 0.5}
+	candidates := []Candidate{
+		candidate("tweet1", "user1", now.Add(-time.Hour)),
+		candidate("tweet2", "user2", now),
+	}
+
+	entries := Rank(context.Background(), scorer, candidates, Config{Size: 10, AuthorPenalty: 1}, now)
+
+	assert.Equal(t, []string{"tweet2", "tweet1"}, rankedIDs(entries))
+}
+
+func TestRank_VariesAuthors(t *testing.T) {
+	now := time.Now()
+	scorer := fixedScorer{"tweet1": 1, "tweet2": 0.9, "tweet3": 0.8, "tweet4": 0.6}
+	candidates := []Candidate{
+		candidate("tweet1", "user1", now),
+		candidate("tweet2", "user1", now),
+		candidate("tweet3", "user1", now),
+		candidate("tweet4", "user2", now),
+	}
+
+	entries := Rank(context.Background(), scorer, candidates, Config{Size: 3, AuthorPenalty: 0.5}, now)
+
+	// user1's second tweet drops to 0.45, below user2's tweet
+	assert.Equal(t, []string{"tweet1", "tweet4", "tweet2"}, rankedIDs(entries))
+	assert.InDelta(t, 0.45, entries[2].Score, 1e-9)
+	assert.Contains(t, entries[2].Reasons, "Ranked lower to show a variety of authors")
+	assert.NotContains(t, entries[0].Reasons, "Ranked lower to show a variety of authors")
+}
@@ -0,0 +1,81 @@
+package ranking
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// WeightedScorer combines recency, author affinity and engagement into a single score
+type WeightedScorer struct {
+	// HalfLife is the age at which the recency of a tweet is halved
+	HalfLife time.Duration
+	// AffinityWeight is the share of the score given by author affinity, between 0 and 1
+	AffinityWeight float64
+	// EngagementWeight scales the boost given by the logarithm of the engagement count
+	EngagementWeight float64
+}
+
+// Name implements Scorer
+func (s WeightedScorer) Name() string {
+	return "weighted"
+}
+
+// Score implements Scorer
+func (s WeightedScorer) Score(ctx context.Context, candidate Candidate, now time.Time) Score {
+	age := now.Sub(candidate.Tweet.CreatedAt)
+	recency := decay(age, s.HalfLife)
+	affinity := Affinity(candidate)
+	engagement := math.Log1p(float64(candidate.Engagement))
+
+	value := recency*((1-s.AffinityWeight)+s.AffinityWeight*affinity) + s.EngagementWeight*engagement
+
+	reasons := []string{fmt.Sprintf("Posted %s ago", age.Round(time.Minute))}
+	if candidate.Followed {
+		reasons = append(reasons, "From someone you follow")
+	} else {
+		reasons = append(reasons, fmt.Sprintf("Followed by %d people you follow", candidate.FollowedBy))
+	}
+	if candidate.Engagement > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d interactions", candidate.Engagement))
+	}
+	return Score{Value: value, Reasons: reasons}
+}
+
+// RecencyScorer ranks tweets from newest to oldest. It is the control for ranking experiments.
+type RecencyScorer struct {
+	HalfLife time.Duration
+}
+
+// Name implements Scorer
+func (s RecencyScorer) Name() string {
+	return "recency"
+}
+
+// Score implements Scorer
+func (s RecencyScorer) Score(ctx context.Context, candidate Candidate, now time.Time) Score {
+	age := now.Sub(candidate.Tweet.CreatedAt)
+	return Score{
+		Value:   decay(age, s.HalfLife),
+		Reasons: []string{fmt.Sprintf("Posted %s ago", age.Round(time.Minute))},
+	}
+}
+
+// Affinity estimates how close the viewer is to the author of a candidate, between 0 and 1.
+// Followed authors have full affinity; second-degree authors get more of it the more of
+// the viewer's followed users follow them.
+func Affinity(candidate Candidate) float64 {
+	if candidate.Followed {
+		return 1
+	}
+	return 0.5 * math.Min(1, float64(candidate.FollowedBy)/3)
+}
+
+// decay halves with every halfLife of age. Tweets from the future count as new.
+func decay(age, halfLife time.Duration) float64 {
+	if age < 0 {
+		age = 0
+	}
+	return math.Pow(0.5, float64(age)/float64(halfLife))
+}
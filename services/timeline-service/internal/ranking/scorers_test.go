@@ -0,0 +1,52 @@
+package ranking
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lisandro/timeline-service/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWeightedScorer_Score(t *testing.T) {
+	now := time.Now()
+	scorer := WeightedScorer{HalfLife: time.Hour, AffinityWeight: 0.5, EngagementWeight: 0.1}
+
+	followed := scorer.Score(context.Background(), Candidate{
+		Tweet:    domain.Tweet{CreatedAt: now.Add(-time.Hour)},
+		Followed: true,
+	}, now)
+	assert.InDelta(t, 0.5, followed.Value, 1e-9)
+	assert.Equal(t, []string{"Posted 1h0m0s ago", "From someone you follow"}, followed.Reasons)
+
+	secondDegree := scorer.Score(context.Background(), Candidate{
+		Tweet:      domain.Tweet{CreatedAt: now.Add(-time.Hour)},
+		FollowedBy: 3,
+		Engagement: 9,
+	}, now)
+	// Half the affinity of a followed author, plus the engagement boost
+	assert.InDelta(t, 0.5*(0.5+0.5*0.5)+0.1*2.302585, secondDegree.Value, 1e-6)
+	assert.Contains(t, secondDegree.Reasons, "Followed by 3 people you follow")
+	assert.Contains(t, secondDegree.Reasons, "9 interactions")
+}
+
+func TestRecencyScorer_Score(t *testing.T) {
+	now := time.Now()
+	scorer := RecencyScorer{HalfLife: time.Hour}
+
+	newer := scorer.Score(context.Background(), Candidate{Tweet: domain.Tweet{CreatedAt: now}}, now)
+	older := scorer.Score(context.Background(), Candidate{Tweet: domain.Tweet{CreatedAt: now.Add(-2 * time.Hour)}}, now)
+	future := scorer.Score(context.Background(), Candidate{Tweet: domain.Tweet{CreatedAt: now.Add(time.Hour)}}, now)
+
+	assert.Equal(t, 1.0, newer.Value)
+	assert.InDelta(t, 0.25, older.Value, 1e-9)
+	assert.Equal(t, 1.0, future.Value)
+}
+
+func TestAffinity(t *testing.T) {
+	assert.Equal(t, 1.0, Affinity(Candidate{Followed: true}))
+	assert.Equal(t, 0.0, Affinity(Candidate{}))
+	assert.InDelta(t, 0.5/3, Affinity(Candidate{FollowedBy: 1}), 1e-9)
+	assert.Equal(t, 0.5, Affinity(Candidate{FollowedBy: 10}))
+}
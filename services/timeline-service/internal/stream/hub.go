@@ -0,0 +1,210 @@
+package stream
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/lisandro/timeline-service/internal/domain"
+)
+
+// ErrTooManyConnections is returned when a user already has the maximum number of open streams
+var ErrTooManyConnections = errors.New("too many open streams for this user")
+
+// ErrHubClosed is returned when a stream is opened after the hub was closed
+var ErrHubClosed = errors.New("timeline streams are closed")
+
+// Config holds the limits of a Hub
+type Config struct {
+	// BufferSize is the number of recent events kept to resume streams after a reconnect
+	BufferSize int
+	// MaxConnectionsPerUser is the number of streams a user can have open at once
+	MaxConnectionsPerUser int
+	// QueueSize is the number of events waiting to be written to a stream. A stream that
+	// falls further behind is closed, and its client resumes from its last event.
+	QueueSize int
+}
+
+// Event is a new tweet sent on the timeline streams. Its ID is the tweet ID.
+type Event struct {
+	ID    string
+	Tweet domain.Tweet
+}
+
+// Hub fans new tweets out to the open timeline streams of the followers of their authors.
+// It only knows the tweets published to this instance, so every instance serving streams
+// must receive the tweet events.
+type Hub struct {
+	config        Config
+	mu            sync.Mutex
+	recent        []Event
+	published     map[string]bool
+	subscriptions map[*Subscription]struct{}
+	connections   map[string]int
+	closed        bool
+}
+
+// NewHub creates a new hub
+func NewHub(config Config) *Hub {
+	return &Hub{
+		config:        config,
+		published:     make(map[string]bool),
+		subscriptions: make(map[*Subscription]struct{}),
+		connections:   make(map[string]int),
+	}
+}
+
+// Subscription is an open timeline stream of a user
+type Subscription struct {
+	hub     *Hub
+	userID  string
+	authors map[string]bool
+	events  chan Event
+	// closed and released are guarded by hub.mu
+	closed   bool
+	released bool
+
+	// Replay holds the buffered events after the last event seen by the client
+	Replay []Event
+	// Reset reports that the last event seen by the client is no longer buffered, so the
+	// events missed since then cannot be replayed and the client must reload its timeline
+	Reset bool
+}
+
+// Subscribe opens a stream of the tweets written by authors for a user. When lastEventID is
+// set, the buffered events that followed it are returned in Replay, so no event published
+// while the client was reconnecting is lost.
+func (h *Hub) Subscribe(userID string, authors []string, lastEventID string) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, ErrHubClosed
+	}
+	if h.connections[userID] >= h.config.MaxConnectionsPerUser {
+		return nil, ErrTooManyConnections
+	}
+
+	sub := &Subscription{
+		hub:     h,
+		userID:  userID,
+		authors: toSet(authors),
+		events:  make(chan Event, h.config.QueueSize),
+	}
+	if lastEventID != "" {
+		sub.Replay, sub.Reset = h.replay(sub, lastEventID)
+	}
+
+	h.connections[userID]++
+	h.subscriptions[sub] = struct{}{}
+	return sub, nil
+}
+
+// replay returns the buffered events of the subscription's authors published after
+// lastEventID, or reports a reset when lastEventID is not buffered
+func (h *Hub) replay(sub *Subscription, lastEventID string) ([]Event, bool) {
+	for i, event := range h.recent {
+		if event.ID != lastEventID {
+			continue
+		}
+		var missed []Event
+		for _, event := range h.recent[i+1:] {
+			if sub.authors[event.Tweet.UserID] {
+				missed = append(missed, event)
+			}
+		}
+		return missed, false
+	}
+	return nil, true
+}
+
+// Publish sends a tweet to the streams of the followers of its author. A tweet that
+// was already published is ignored, so duplicated deliveries are harmless.
+func (h *Hub) Publish(tweet domain.Tweet) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.published[tweet.ID] {
+		return
+	}
+	event := Event{ID: tweet.ID, Tweet: tweet}
+	h.recent = append(h.recent, event)
+	h.published[tweet.ID] = true
+	if len(h.recent) > h.config.BufferSize {
+		delete(h.published, h.recent[0].ID)
+		h.recent = h.recent[1:]
+	}
+
+	for sub := range h.subscriptions {
+		if !sub.authors[tweet.UserID] {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			// The client is not keeping up; end its stream so it resumes from its last event
+			h.closeLocked(sub)
+		}
+	}
+}
+
+// Close ends every open stream and refuses new ones, so that the service can shut down
+// without waiting for its streams. Clients reconnect and resume from their last event.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for sub := range h.subscriptions {
+		h.closeLocked(sub)
+	}
+}
+
+// UserID returns the ID of the user who opened the stream
+func (s *Subscription) UserID() string {
+	return s.userID
+}
+
+// Events returns the channel of new events. It is closed when the stream falls behind or is closed.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// SetAuthors replaces the users whose tweets are sent on the stream
+func (s *Subscription) SetAuthors(authors []string) {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.authors = toSet(authors)
+}
+
+// Close ends the stream and releases its connection slot. It can be called more than once.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	s.hub.closeLocked(s)
+	if !s.released {
+		s.released = true
+		s.hub.connections[s.userID]--
+		if s.hub.connections[s.userID] == 0 {
+			delete(s.hub.connections, s.userID)
+		}
+	}
+}
+
+// closeLocked stops sending events to a subscription. The caller must hold h.mu.
+func (h *Hub) closeLocked(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	delete(h.subscriptions, sub)
+	close(sub.events)
+}
+
+func toSet(ids []string) map[string]bool {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
@@ -0,0 +1,119 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/lisandro/timeline-service/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestHub() *Hub {
+	return NewHub(Config{BufferSize: 3, MaxConnectionsPerUser: 2, QueueSize: 2})
+}
+
+func TestHub_PublishToFollowers(t *testing.T) {
+	hub := newTestHub()
+	follower, err := hub.Subscribe("user1", []string{"user2"}, "")
+	require.NoError(t, err)
+	other, err := hub.Subscribe("user3", []string{"user4"}, "")
+	require.NoError(t, err)
+
+	hub.Publish(domain.Tweet{ID: "tweet1", UserID: "user2"})
+	// A redelivered event is ignored
+	hub.Publish(domain.Tweet{ID: "tweet1", UserID: "user2"})
+
+	assert.Equal(t, "tweet1", (<-follower.Events()).ID)
+	assert.Empty(t, follower.Events())
+	assert.Empty(t, other.Events())
+}
+
+func TestHub_Resume(t *testing.T) {
+	hub := newTestHub()
+	hub.Publish(domain.Tweet{ID: "tweet1", UserID: "user2"})
+	hub.Publish(domain.Tweet{ID: "tweet2", UserID: "user9"})
+	hub.Publish(domain.Tweet{ID: "tweet3", UserID: "user2"})
+
+	t.Run("buffered last event replays the missed events", func(t *testing.T) {
+		sub, err := hub.Subscribe("user1", []string{"user2"}, "tweet1")
+		require.NoError(t, err)
+		defer sub.Close()
+
+		assert.False(t, sub.Reset)
+		require.Len(t, sub.Replay, 1)
+		assert.Equal(t, "tweet3", sub.Replay[0].ID)
+	})
+
+	t.Run("evicted last event resets the stream", func(t *testing.T) {
+		hub.Publish(domain.Tweet{ID: "tweet4", UserID: "user2"})
+
+		sub, err := hub.Subscribe("user1", []string{"user2"}, "tweet1")
+		require.NoError(t, err)
+		defer sub.Close()
+
+		assert.True(t, sub.Reset)
+		assert.Empty(t, sub.Replay)
+	})
+}
+
+func TestHub_ConnectionLimit(t *testing.T) {
+	hub := newTestHub()
+	first, err := hub.Subscribe("user1", nil, "")
+	require.NoError(t, err)
+	_, err = hub.Subscribe("user1", nil, "")
+	require.NoError(t, err)
+
+	_, err = hub.Subscribe("user1", nil, "")
+	assert.ErrorIs(t, err, ErrTooManyConnections)
+
+	// Closing a stream frees its slot, once
+	first.Close()
+	first.Close()
+	_, err = hub.Subscribe("user1", nil, "")
+	assert.NoError(t, err)
+	_, err = hub.Subscribe("user1", nil, "")
+	assert.ErrorIs(t, err, ErrTooManyConnections)
+}
+
+func TestHub_SlowSubscriberIsClosed(t *testing.T) {
+	hub := newTestHub()
+	sub, err := hub.Subscribe("user1", []string{"user2"}, "")
+	require.NoError(t, err)
+
+	for _, id := range []string{"tweet1", "tweet2", "tweet3"} {
+		hub.Publish(domain.Tweet{ID: id, UserID: "user2"})
+	}
+
+	var received []string
+	for event := range sub.Events() {
+		received = append(received, event.ID)
+	}
+	assert.Equal(t, []string{"tweet1", "tweet2"}, received)
+}
+
+func TestHub_Close(t *testing.T) {
+	hub := newTestHub()
+	sub, err := hub.Subscribe("user1", []string{"user2"}, "")
+	require.NoError(t, err)
+
+	hub.Close()
+
+	_, open := <-sub.Events()
+	assert.False(t, open)
+	_, err = hub.Subscribe("user3", []string{"user2"}, "")
+	assert.ErrorIs(t, err, ErrHubClosed)
+	sub.Close()
+}
+
+func TestSubscription_SetAuthors(t *testing.T) {
+	hub := newTestHub()
+	sub, err := hub.Subscribe("user1", []string{"user2"}, "")
+	require.NoError(t, err)
+
+	sub.SetAuthors([]string{"user3"})
+	hub.Publish(domain.Tweet{ID: "tweet1", UserID: "user2"})
+	hub.Publish(domain.Tweet{ID: "tweet2", UserID: "user3"})
+
+	assert.Equal(t, "tweet2", (<-sub.Events()).ID)
+	assert.Empty(t, sub.Events())
+}
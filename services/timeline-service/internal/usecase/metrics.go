@@ -0,0 +1,50 @@
+package usecase
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	buildDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "timeline_build_duration_seconds",
+		Help:    "Time to get a timeline, by kind of timeline and outcome: built, stale when the last one built was served instead, or error.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"timeline", "outcome"})
+	buildStageDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "timeline_build_stage_duration_seconds",
+		Help:    "Time of each stage of building a timeline, by kind of timeline and stage.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"timeline", "stage"})
+)
+
+// Kinds of timelines in metrics
+const (
+	chronologicalTimeline = "chronological"
+	rankedTimeline        = "ranked"
+)
+
+// stageTimer times the stages of building a timeline one after the other
+type stageTimer struct {
+	timeline string
+	start    time.Time
+}
+
+func startStages(timeline string) *stageTimer {
+	return &stageTimer{timeline: timeline, start: time.Now()}
+}
+
+// done records the time since the previous stage as the time of stage. Stages that fail are
+// not recorded.
+func (t *stageTimer) done(stage string) {
+	now := time.Now()
+	buildStageDuration.WithLabelValues(t.timeline, stage).Observe(now.Sub(t.start).Seconds())
+	t.start = now
+}
+
+// observeBuild records the time to get a timeline since start
+func observeBuild(timeline, outcome string, start time.Time) {
+	buildDuration.WithLabelValues(timeline, outcome).Observe(time.Since(start).Seconds())
+}
@@ -2,30 +2,102 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"log"
+	"sort"
+	"time"
 
+	"github.com/lisandro/timeline-service/internal/cache"
 	"github.com/lisandro/timeline-service/internal/client"
 	"github.com/lisandro/timeline-service/internal/domain"
+	"github.com/lisandro/timeline-service/internal/ranking"
+	"github.com/lisandro/timeline-service/internal/stream"
 )
 
 type TimelineUseCase interface {
-	GetTimeline(ctx context.Context, userID string) (*domain.Timeline, error)
+	GetTimeline(ctx context.Context, userID string, tweetRange domain.TweetRange) (*domain.Timeline, error)
+	GetRankedTimeline(ctx context.Context, userID string) (*domain.RankedTimeline, error)
+	CountNewTweets(ctx context.Context, userID, sinceID string) (int, error)
+	StreamTimeline(ctx context.Context, userID, lastEventID string) (*stream.Subscription, error)
+	RefreshStream(ctx context.Context, sub *stream.Subscription) error
+	PublishTweet(ctx context.Context, tweet domain.Tweet) error
+	InvalidateProfile(ctx context.Context, userID string) error
+	PurgeUser(ctx context.Context, userID string) error
+}
+
+// RankingConfig holds the settings of ranked timelines
+type RankingConfig struct {
+	Ranking ranking.Config
+	// Experiment assigns each user to the scorer ranking their timeline
+	Experiment *ranking.Experiment
+	// CandidateLimit is the number of recent tweets fetched from followed authors, and
+	// again from second-degree authors
+	CandidateLimit int
+	// SecondDegreeSources is the number of followed users whose follows are explored to
+	// find second-degree authors
+	SecondDegreeSources int
+	// SecondDegreeAuthors is the number of second-degree authors whose tweets are candidates
+	SecondDegreeAuthors int
+}
+
+// LastKnownGood holds the last timelines built for each user. They are served, marked as
+// degraded, when a service needed to build a fresh timeline fails.
+type LastKnownGood struct {
+	Timelines       *cache.StaleCache[domain.Timeline]
+	RankedTimelines *cache.StaleCache[domain.RankedTimeline]
 }
 
 type timelineUseCase struct {
-	userClient  client.UserClient
-	tweetClient client.TweetClient
+	userClient    client.UserClient
+	tweetClient   client.TweetClient
+	hub           *stream.Hub
+	profiles      *cache.ProfileCache
+	lastKnownGood LastKnownGood
+	ranking       RankingConfig
 }
 
-func NewTimelineUseCase(userClient client.UserClient, tweetClient client.TweetClient) TimelineUseCase {
+func NewTimelineUseCase(userClient client.UserClient, tweetClient client.TweetClient, hub *stream.Hub, profiles *cache.ProfileCache, lastKnownGood LastKnownGood, rankingConfig RankingConfig) TimelineUseCase {
 	return &timelineUseCase{
-		userClient:  userClient,
-		tweetClient: tweetClient,
+		userClient:    userClient,
+		tweetClient:   tweetClient,
+		hub:           hub,
+		profiles:      profiles,
+		lastKnownGood: lastKnownGood,
+		ranking:       rankingConfig,
+	}
+}
+
+// GetTimeline returns the tweets of the users that userID follows within tweetRange, newest
+// first. It returns domain.ErrInvalidTweetRange when a bound of the range is not a known tweet.
+// When the timeline cannot be built, the last one built for the user is returned instead,
+// marked as degraded. Only the newest page is kept for that.
+func (uc *timelineUseCase) GetTimeline(ctx context.Context, userID string, tweetRange domain.TweetRange) (*domain.Timeline, error) {
+	start := time.Now()
+	newest := tweetRange == domain.TweetRange{}
+	timeline, err := uc.buildTimeline(ctx, userID, tweetRange)
+	if err != nil {
+		if newest && isDependencyFailure(ctx, err) {
+			if stale, ok := uc.lastKnownGood.Timelines.Get(userID); ok {
+				log.Printf("Serving the last known timeline of user %s: %v", userID, err)
+				observeBuild(chronologicalTimeline, "stale", start)
+				stale.Degraded = true
+				return &stale, nil
+			}
+		}
+		observeBuild(chronologicalTimeline, "error", start)
+		return nil, err
+	}
+
+	if newest {
+		uc.lastKnownGood.Timelines.Set(userID, *timeline)
 	}
+	observeBuild(chronologicalTimeline, "built", start)
+	return timeline, nil
 }
 
-func (uc *timelineUseCase) GetTimeline(ctx context.Context, userID string) (*domain.Timeline, error) {
+func (uc *timelineUseCase) buildTimeline(ctx context.Context, userID string, tweetRange domain.TweetRange) (*domain.Timeline, error) {
 	log.Printf("Starting timeline generation for user %s", userID)
+	stages := startStages(chronologicalTimeline)
 	
 	// Get following users
 	log.Printf("Fetching following users for user %s", userID)
@@ -35,6 +107,7 @@ func (uc *timelineUseCase) GetTimeline(ctx context.Context, userID string) (*dom
 		return nil, err
 	}
 	log.Printf("Found %d following users for user %s", len(followingUsers), userID)
+	stages.done("following")
 	
 	// If user follows no one, return empty timeline
 	if len(followingUsers) == 0 {
@@ -47,20 +120,276 @@ func (uc *timelineUseCase) GetTimeline(ctx context.Context, userID string) (*dom
 	var followingUserIDs []string
 	for _, followingUser := range followingUsers {
 		followingUserIDs = append(followingUserIDs, followingUser.ID)
+		// The following list carries fresh profiles of the authors of most timeline tweets
+		uc.profiles.Set(domain.Author(followingUser))
 	}
 	
 	// Get tweets for each following user
 	log.Printf("Fetching tweets for following user %s", followingUserIDs)
-	tweets, err := uc.tweetClient.GetUserTweets(ctx, followingUserIDs)
+	tweets, err := uc.tweetClient.GetUserTweets(ctx, followingUserIDs, tweetRange)
 	if err != nil {
 		log.Printf("Error fetching tweets for user %s: %v", followingUserIDs, err)
 		return nil, err
 	}
 	log.Printf("Tweets: %v", tweets)
 	log.Printf("Found %d tweets for user %s", len(tweets), followingUserIDs)
+	stages.done("tweets")
+
+	uc.hydrateAuthors(ctx, tweets)
+	stages.done("authors")
 
 	log.Printf("Timeline generation completed for user %s with %d total tweets", userID, len(tweets))
 	return &domain.Timeline{
 		Tweets: tweets,
 	}, nil
-} 
\ No newline at end of file
+}
+
+// GetRankedTimeline returns the timeline of userID ordered by relevance. Candidates are the
+// recent tweets of followed users and of the users they follow most, scored by the scorer
+// the user is assigned to. Second-degree candidates are best effort: failing to load them
+// only narrows the timeline. When the timeline cannot be built, the last one built for the
+// user is returned instead, marked as degraded.
+func (uc *timelineUseCase) GetRankedTimeline(ctx context.Context, userID string) (*domain.RankedTimeline, error) {
+	start := time.Now()
+	timeline, err := uc.buildRankedTimeline(ctx, userID)
+	if err != nil {
+		if isDependencyFailure(ctx, err) {
+			if stale, ok := uc.lastKnownGood.RankedTimelines.Get(userID); ok {
+				log.Printf("Serving the last known ranked timeline of user %s: %v", userID, err)
+				observeBuild(rankedTimeline, "stale", start)
+				stale.Degraded = true
+				return &stale, nil
+			}
+		}
+		observeBuild(rankedTimeline, "error", start)
+		return nil, err
+	}
+
+	uc.lastKnownGood.RankedTimelines.Set(userID, *timeline)
+	observeBuild(rankedTimeline, "built", start)
+	return timeline, nil
+}
+
+func (uc *timelineUseCase) buildRankedTimeline(ctx context.Context, userID string) (*domain.RankedTimeline, error) {
+	stages := startStages(rankedTimeline)
+	scorer := uc.ranking.Experiment.ScorerFor(userID)
+	timeline := &domain.RankedTimeline{
+		Ranker:  scorer.Name(),
+		Entries: []domain.RankedEntry{},
+	}
+
+	following, err := uc.followingIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	stages.done("following")
+	if len(following) == 0 {
+		return timeline, nil
+	}
+
+	tweets, err := uc.tweetClient.GetUserTweets(ctx, following, domain.TweetRange{Limit: uc.ranking.CandidateLimit})
+	if err != nil {
+		return nil, err
+	}
+	stages.done("tweets")
+	followed := make(map[string]bool, len(following))
+	for _, id := range following {
+		followed[id] = true
+	}
+
+	followedBy := uc.secondDegreeAuthors(ctx, userID, following, followed)
+	if len(followedBy) > 0 {
+		authors := make([]string, 0, len(followedBy))
+		for id := range followedBy {
+			authors = append(authors, id)
+		}
+		sort.Strings(authors)
+
+		more, err := uc.tweetClient.GetUserTweets(ctx, authors, domain.TweetRange{Limit: uc.ranking.CandidateLimit})
+		if err != nil {
+			log.Printf("Failed to get second-degree tweets for user %s: %v", userID, err)
+		}
+		tweets = append(tweets, more...)
+	}
+	stages.done("second_degree")
+
+	uc.hydrateAuthors(ctx, tweets)
+	stages.done("authors")
+
+	candidates := make([]ranking.Candidate, 0, len(tweets))
+	for _, tweet := range tweets {
+		candidates = append(candidates, ranking.Candidate{
+			Tweet:      tweet,
+			Followed:   followed[tweet.UserID],
+			FollowedBy: followedBy[tweet.UserID],
+			// The tweet service does not count interactions yet
+			Engagement: 0,
+		})
+	}
+
+	timeline.Entries = ranking.Rank(ctx, scorer, candidates, uc.ranking.Ranking, time.Now())
+	stages.done("rank")
+	log.Printf("Ranked %d of %d candidate tweets for user %s with %s", len(timeline.Entries), len(candidates), userID, scorer.Name())
+	return timeline, nil
+}
+
+// secondDegreeAuthors returns the users followed by the users that userID follows, mapped to
+// how many of them follow each one, keeping the most followed authors. Users whose follows
+// cannot be loaded are skipped.
+func (uc *timelineUseCase) secondDegreeAuthors(ctx context.Context, userID string, following []string, followed map[string]bool) map[string]int {
+	counts := make(map[string]int)
+	sources := following
+	if len(sources) > uc.ranking.SecondDegreeSources {
+		sources = sources[:uc.ranking.SecondDegreeSources]
+	}
+	for _, source := range sources {
+		users, err := uc.userClient.GetFollowingUsers(ctx, source)
+		if err != nil {
+			log.Printf("Failed to get users followed by %s: %v", source, err)
+			continue
+		}
+		for _, user := range users {
+			if user.ID == userID || followed[user.ID] {
+				continue
+			}
+			counts[user.ID]++
+			uc.profiles.Set(domain.Author(user))
+		}
+	}
+
+	if len(counts) <= uc.ranking.SecondDegreeAuthors {
+		return counts
+	}
+	authors := make([]string, 0, len(counts))
+	for id := range counts {
+		authors = append(authors, id)
+	}
+	sort.Slice(authors, func(i, j int) bool {
+		if counts[authors[i]] != counts[authors[j]] {
+			return counts[authors[i]] > counts[authors[j]]
+		}
+		return authors[i] < authors[j]
+	})
+	top := make(map[string]int, uc.ranking.SecondDegreeAuthors)
+	for _, id := range authors[:uc.ranking.SecondDegreeAuthors] {
+		top[id] = counts[id]
+	}
+	return top
+}
+
+// CountNewTweets returns how many tweets of the users that userID follows are newer than
+// sinceID, so clients can announce new tweets without fetching them. It returns
+// domain.ErrInvalidTweetRange when sinceID is not a known tweet.
+func (uc *timelineUseCase) CountNewTweets(ctx context.Context, userID, sinceID string) (int, error) {
+	authors, err := uc.followingIDs(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return uc.tweetClient.CountUserTweets(ctx, authors, domain.TweetRange{SinceID: sinceID})
+}
+
+// StreamTimeline opens a stream of the new tweets of the users that userID follows. When
+// lastEventID is set, the tweets published since that event are replayed first. It returns
+// stream.ErrTooManyConnections when the user already has too many open streams, and
+// stream.ErrHubClosed when the service is shutting down.
+func (uc *timelineUseCase) StreamTimeline(ctx context.Context, userID, lastEventID string) (*stream.Subscription, error) {
+	authors, err := uc.followingIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.hub.Subscribe(userID, authors, lastEventID)
+}
+
+// RefreshStream reloads the users followed by the owner of a stream, so that follows and
+// unfollows made while the stream is open are taken into account
+func (uc *timelineUseCase) RefreshStream(ctx context.Context, sub *stream.Subscription) error {
+	authors, err := uc.followingIDs(ctx, sub.UserID())
+	if err != nil {
+		return err
+	}
+
+	sub.SetAuthors(authors)
+	return nil
+}
+
+// PublishTweet sends a newly created tweet to the open streams of its author's followers.
+// Publishing the same tweet again does nothing.
+func (uc *timelineUseCase) PublishTweet(ctx context.Context, tweet domain.Tweet) error {
+	tweets := []domain.Tweet{tweet}
+	uc.hydrateAuthors(ctx, tweets)
+	uc.hub.Publish(tweets[0])
+	return nil
+}
+
+// InvalidateProfile drops the cached profile of a user whose profile changed, such as a
+// renamed user, so that timelines pick up the change
+func (uc *timelineUseCase) InvalidateProfile(ctx context.Context, userID string) error {
+	uc.profiles.Delete(userID)
+	return nil
+}
+
+// hydrateAuthors embeds the profile of its author in each tweet. Profiles are read from the
+// profile cache, and the missing ones are looked up with a single batch request. A failed
+// lookup leaves the tweets without an author rather than failing the timeline.
+func (uc *timelineUseCase) hydrateAuthors(ctx context.Context, tweets []domain.Tweet) {
+	var missing []string
+	seen := make(map[string]bool)
+	for _, tweet := range tweets {
+		if _, ok := uc.profiles.Get(tweet.UserID); !ok && !seen[tweet.UserID] {
+			seen[tweet.UserID] = true
+			missing = append(missing, tweet.UserID)
+		}
+	}
+
+	if len(missing) > 0 {
+		authors, err := uc.userClient.GetUsers(ctx, missing)
+		if err != nil {
+			log.Printf("Failed to look up %d tweet authors: %v", len(missing), err)
+		}
+		for _, author := range authors {
+			uc.profiles.Set(author)
+		}
+	}
+
+	for i := range tweets {
+		if author, ok := uc.profiles.Get(tweets[i].UserID); ok {
+			tweets[i].Author = &author
+		}
+	}
+}
+
+func (uc *timelineUseCase) followingIDs(ctx context.Context, userID string) ([]string, error) {
+	followingUsers, err := uc.userClient.GetFollowingUsers(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(followingUsers))
+	for _, followingUser := range followingUsers {
+		ids = append(ids, followingUser.ID)
+		uc.profiles.Set(domain.Author(followingUser))
+	}
+	return ids, nil
+}
+
+// PurgeUser drops everything the timeline service keeps about a deleted user. It may be
+// called more than once for the same user.
+func (uc *timelineUseCase) PurgeUser(ctx context.Context, userID string) error {
+	// Timelines are assembled from the user and tweet services on every request, so the
+	// cached profile and last known timelines are the only data kept about the user. Caches
+	// added to this service must be purged here. Tweets of the user in the last known
+	// timelines of others expire with them.
+	uc.profiles.Delete(userID)
+	uc.lastKnownGood.Timelines.Delete(userID)
+	uc.lastKnownGood.RankedTimelines.Delete(userID)
+	log.Printf("Purged timeline data of deleted user %s", userID)
+	return nil
+}
+
+// isDependencyFailure reports whether err comes from a service the timeline depends on,
+// rather than from a bad request or from the caller giving up
+func isDependencyFailure(ctx context.Context, err error) bool {
+	return ctx.Err() == nil && !errors.Is(err, domain.ErrInvalidTweetRange)
+}
@@ -6,7 +6,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/lisandro/timeline-service/internal/cache"
 	"github.com/lisandro/timeline-service/internal/domain"
+	"github.com/lisandro/timeline-service/internal/ranking"
+	"github.com/lisandro/timeline-service/internal/stream"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -21,16 +24,27 @@ func (m *MockUserClient) GetFollowingUsers(ctx context.Context, userID string) (
 	return args.Get(0).([]domain.FollowingUser), args.Error(1)
 }
 
+func (m *MockUserClient) GetUsers(ctx context.Context, userIDs []string) ([]domain.Author, error) {
+	args := m.Called(ctx, userIDs)
+	authors, _ := args.Get(0).([]domain.Author)
+	return authors, args.Error(1)
+}
+
 // MockTweetClient is a mock implementation of client.TweetClient
 type MockTweetClient struct {
 	mock.Mock
 }
 
-func (m *MockTweetClient) GetUserTweets(ctx context.Context, userIDs []string) ([]domain.Tweet, error) {
-	args := m.Called(ctx, userIDs)
+func (m *MockTweetClient) GetUserTweets(ctx context.Context, userIDs []string, tweetRange domain.TweetRange) ([]domain.Tweet, error) {
+	args := m.Called(ctx, userIDs, tweetRange)
 	return args.Get(0).([]domain.Tweet), args.Error(1)
 }
 
+func (m *MockTweetClient) CountUserTweets(ctx context.Context, userIDs []string, tweetRange domain.TweetRange) (int, error) {
+	args := m.Called(ctx, userIDs, tweetRange)
+	return args.Int(0), args.Error(1)
+}
+
 func TestTimelineUseCase_GetTimeline(t *testing.T) {
 	mockFollowingUsers := []domain.FollowingUser{
 		{ID: "user2", Username: "alice"},
@@ -137,12 +151,12 @@ func TestTimelineUseCase_GetTimeline(t *testing.T) {
 
 			// Set up tweet client mock only if we have following users and no following error
 			if tt.mockFollowingError == nil && len(tt.mockFollowingUsers) > 0 {
-				mockTweetClient.On("GetUserTweets", mock.Anything, mock.Anything).
+				mockTweetClient.On("GetUserTweets", mock.Anything, mock.Anything, domain.TweetRange{}).
 					Return(tt.mockTweets, tt.mockTweetsError)
 			}
 
-			useCase := NewTimelineUseCase(mockUserClient, mockTweetClient)
-			timeline, err := useCase.GetTimeline(context.Background(), tt.userID)
+			useCase := NewTimelineUseCase(mockUserClient, mockTweetClient, newTestHub(), newTestProfiles(), newTestLastKnownGood(), newTestRankingConfig(t))
+			timeline, err := useCase.GetTimeline(context.Background(), tt.userID, domain.TweetRange{})
 
 			if tt.expectedError {
 				assert.Error(t, err)
@@ -198,15 +212,15 @@ func TestTimelineUseCase_GetTimeline_ContextPropagation(t *testing.T) {
 		}).
 		Return(followingUsers, nil)
 
-	mockTweetClient.On("GetUserTweets", ctx, []string{"user2"}).
+	mockTweetClient.On("GetUserTweets", ctx, []string{"user2"}, domain.TweetRange{}).
 		Run(func(args mock.Arguments) {
 			receivedCtx := args.Get(0).(context.Context)
 			assert.Equal(t, "test_value", receivedCtx.Value("test_key"))
 		}).
 		Return(tweets, nil)
 
-	useCase := NewTimelineUseCase(mockUserClient, mockTweetClient)
-	timeline, err := useCase.GetTimeline(ctx, userID)
+	useCase := NewTimelineUseCase(mockUserClient, mockTweetClient, newTestHub(), newTestProfiles(), newTestLastKnownGood(), newTestRankingConfig(t))
+	timeline, err := useCase.GetTimeline(ctx, userID, domain.TweetRange{})
 
 	assert.NoError(t, err)
 	assert.NotNil(t, timeline)
@@ -238,15 +252,15 @@ func TestTimelineUseCase_GetTimeline_UserIDsCollection(t *testing.T) {
 		Return(followingUsers, nil)
 
 	// Verify that the exact user IDs are passed to the tweet client
-	mockTweetClient.On("GetUserTweets", mock.Anything, expectedUserIDs).
+	mockTweetClient.On("GetUserTweets", mock.Anything, expectedUserIDs, domain.TweetRange{}).
 		Run(func(args mock.Arguments) {
 			receivedUserIDs := args.Get(1).([]string)
 			assert.ElementsMatch(t, expectedUserIDs, receivedUserIDs)
 		}).
 		Return(tweets, nil)
 
-	useCase := NewTimelineUseCase(mockUserClient, mockTweetClient)
-	timeline, err := useCase.GetTimeline(context.Background(), userID)
+	useCase := NewTimelineUseCase(mockUserClient, mockTweetClient, newTestHub(), newTestProfiles(), newTestLastKnownGood(), newTestRankingConfig(t))
+	timeline, err := useCase.GetTimeline(context.Background(), userID, domain.TweetRange{})
 
 	assert.NoError(t, err)
 	assert.NotNil(t, timeline)
@@ -254,4 +268,371 @@ func TestTimelineUseCase_GetTimeline_UserIDsCollection(t *testing.T) {
 
 	mockUserClient.AssertExpectations(t)
 	mockTweetClient.AssertExpectations(t)
-} 
\ No newline at end of file
+}
+
+func TestTimelineUseCase_GetTimeline_TweetRange(t *testing.T) {
+	mockUserClient := new(MockUserClient)
+	mockTweetClient := new(MockTweetClient)
+	tweetRange := domain.TweetRange{SinceID: "tweet1", MaxID: "tweet9"}
+
+	mockUserClient.On("GetFollowingUsers", mock.Anything, "user1").
+		Return([]domain.FollowingUser{{ID: "user2"}}, nil)
+	mockTweetClient.On("GetUserTweets", mock.Anything, []string{"user2"}, tweetRange).
+		Return([]domain.Tweet(nil), domain.ErrInvalidTweetRange)
+
+	useCase := NewTimelineUseCase(mockUserClient, mockTweetClient, newTestHub(), newTestProfiles(), newTestLastKnownGood(), newTestRankingConfig(t))
+	timeline, err := useCase.GetTimeline(context.Background(), "user1", tweetRange)
+
+	assert.ErrorIs(t, err, domain.ErrInvalidTweetRange)
+	assert.Nil(t, timeline)
+	mockTweetClient.AssertExpectations(t)
+}
+
+func TestTimelineUseCase_CountNewTweets(t *testing.T) {
+	mockUserClient := new(MockUserClient)
+	mockTweetClient := new(MockTweetClient)
+
+	mockUserClient.On("GetFollowingUsers", mock.Anything, "user1").
+		Return([]domain.FollowingUser{{ID: "user2"}, {ID: "user3"}}, nil)
+	mockTweetClient.On("CountUserTweets", mock.Anything, []string{"user2", "user3"}, domain.TweetRange{SinceID: "tweet1"}).
+		Return(5, nil)
+
+	useCase := NewTimelineUseCase(mockUserClient, mockTweetClient, newTestHub(), newTestProfiles(), newTestLastKnownGood(), newTestRankingConfig(t))
+	count, err := useCase.CountNewTweets(context.Background(), "user1", "tweet1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, count)
+	mockUserClient.AssertExpectations(t)
+	mockTweetClient.AssertExpectations(t)
+}
+
+func TestTimelineUseCase_GetTimeline_HydratesAuthors(t *testing.T) {
+	mockUserClient := new(MockUserClient)
+	mockTweetClient := new(MockTweetClient)
+	profiles := newTestProfiles()
+
+	mockUserClient.On("GetFollowingUsers", mock.Anything, "user1").
+		Return([]domain.FollowingUser{{ID: "user2", Username: "alice", DisplayName: "Alice", AvatarURL: "https://example.com/alice.png"}}, nil)
+	mockTweetClient.On("GetUserTweets", mock.Anything, []string{"user2"}, domain.TweetRange{}).
+		Return([]domain.Tweet{
+			{ID: "tweet1", UserID: "user2"},
+			{ID: "tweet2", UserID: "user3"},
+			{ID: "tweet3", UserID: "user3"},
+		}, nil)
+	// Authors missing from the following list are looked up once, then cached
+	mockUserClient.On("GetUsers", mock.Anything, []string{"user3"}).
+		Return([]domain.Author{{ID: "user3", Username: "bob", DisplayName: "Bob"}}, nil).Once()
+
+	useCase := NewTimelineUseCase(mockUserClient, mockTweetClient, newTestHub(), profiles, newTestLastKnownGood(), newTestRankingConfig(t))
+	for i := 0; i < 2; i++ {
+		timeline, err := useCase.GetTimeline(context.Background(), "user1", domain.TweetRange{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, &domain.Author{ID: "user2", Username: "alice", DisplayName: "Alice", AvatarURL: "https://example.com/alice.png"}, timeline.Tweets[0].Author)
+		assert.Equal(t, &domain.Author{ID: "user3", Username: "bob", DisplayName: "Bob"}, timeline.Tweets[1].Author)
+		assert.Equal(t, timeline.Tweets[1].Author, timeline.Tweets[2].Author)
+	}
+	mockUserClient.AssertExpectations(t)
+}
+
+func TestTimelineUseCase_GetTimeline_AuthorLookupFailure(t *testing.T) {
+	mockUserClient := new(MockUserClient)
+	mockTweetClient := new(MockTweetClient)
+
+	mockUserClient.On("GetFollowingUsers", mock.Anything, "user1").
+		Return([]domain.FollowingUser{{ID: "user2", Username: "alice"}}, nil)
+	mockTweetClient.On("GetUserTweets", mock.Anything, []string{"user2"}, domain.TweetRange{}).
+		Return([]domain.Tweet{{ID: "tweet1", UserID: "user2"}, {ID: "tweet2", UserID: "user3"}}, nil)
+	mockUserClient.On("GetUsers", mock.Anything, []string{"user3"}).
+		Return(nil, errors.New("user service unavailable"))
+
+	useCase := NewTimelineUseCase(mockUserClient, mockTweetClient, newTestHub(), newTestProfiles(), newTestLastKnownGood(), newTestRankingConfig(t))
+	timeline, err := useCase.GetTimeline(context.Background(), "user1", domain.TweetRange{})
+
+	assert.NoError(t, err)
+	assert.NotNil(t, timeline.Tweets[0].Author)
+	assert.Nil(t, timeline.Tweets[1].Author)
+}
+
+func TestTimelineUseCase_InvalidateProfile(t *testing.T) {
+	tests := []struct {
+		name       string
+		invalidate func(uc TimelineUseCase) error
+	}{
+		{
+			name:       "renamed user",
+			invalidate: func(uc TimelineUseCase) error { return uc.InvalidateProfile(context.Background(), "user2") },
+		},
+		{
+			name:       "deleted user",
+			invalidate: func(uc TimelineUseCase) error { return uc.PurgeUser(context.Background(), "user2") },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUserClient := new(MockUserClient)
+			mockTweetClient := new(MockTweetClient)
+			profiles := newTestProfiles()
+			profiles.Set(domain.Author{ID: "user2", Username: "alice"})
+
+			assert.NoError(t, tt.invalidate(NewTimelineUseCase(mockUserClient, mockTweetClient, newTestHub(), profiles, newTestLastKnownGood(), newTestRankingConfig(t))))
+
+			_, ok := profiles.Get("user2")
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestTimelineUseCase_GetRankedTimeline(t *testing.T) {
+	mockUserClient := new(MockUserClient)
+	mockTweetClient := new(MockTweetClient)
+	now := time.Now()
+
+	mockUserClient.On("GetFollowingUsers", mock.Anything, "user1").
+		Return([]domain.FollowingUser{{ID: "user2", Username: "alice"}, {ID: "user3", Username: "bob"}}, nil)
+	mockUserClient.On("GetFollowingUsers", mock.Anything, "user2").
+		Return([]domain.FollowingUser{{ID: "user1"}, {ID: "user3", Username: "bob"}, {ID: "user4", Username: "carol"}}, nil)
+	mockUserClient.On("GetFollowingUsers", mock.Anything, "user3").
+		Return([]domain.FollowingUser{{ID: "user4", Username: "carol"}, {ID: "user5", Username: "dave"}}, nil)
+	mockTweetClient.On("GetUserTweets", mock.Anything, []string{"user2", "user3"}, domain.TweetRange{Limit: 100}).
+		Return([]domain.Tweet{
+			{ID: "tweet1", UserID: "user2", CreatedAt: now.Add(-3 * time.Hour)},
+			{ID: "tweet2", UserID: "user3", CreatedAt: now.Add(-time.Hour)},
+		}, nil)
+	// Only the second-degree author followed by most followed users is a candidate
+	mockTweetClient.On("GetUserTweets", mock.Anything, []string{"user4"}, domain.TweetRange{Limit: 100}).
+		Return([]domain.Tweet{{ID: "tweet4", UserID: "user4", CreatedAt: now.Add(-time.Minute)}}, nil)
+
+	useCase := NewTimelineUseCase(mockUserClient, mockTweetClient, newTestHub(), newTestProfiles(), newTestLastKnownGood(), newTestRankingConfig(t))
+	timeline, err := useCase.GetRankedTimeline(context.Background(), "user1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "weighted", timeline.Ranker)
+	ids := make([]string, 0, len(timeline.Entries))
+	for _, entry := range timeline.Entries {
+		ids = append(ids, entry.Tweet.ID)
+	}
+	assert.Equal(t, []string{"tweet4", "tweet2", "tweet1"}, ids)
+	assert.Contains(t, timeline.Entries[0].Reasons, "Followed by 2 people you follow")
+	assert.Contains(t, timeline.Entries[1].Reasons, "From someone you follow")
+	assert.Equal(t, "carol", timeline.Entries[0].Tweet.Author.Username)
+	mockUserClient.AssertExpectations(t)
+	mockTweetClient.AssertExpectations(t)
+}
+
+func TestTimelineUseCase_GetRankedTimeline_SecondDegreeFailure(t *testing.T) {
+	mockUserClient := new(MockUserClient)
+	mockTweetClient := new(MockTweetClient)
+
+	mockUserClient.On("GetFollowingUsers", mock.Anything, "user1").
+		Return([]domain.FollowingUser{{ID: "user2", Username: "alice"}}, nil)
+	mockUserClient.On("GetFollowingUsers", mock.Anything, "user2").
+		Return([]domain.FollowingUser(nil), errors.New("user service unavailable"))
+	mockTweetClient.On("GetUserTweets", mock.Anything, []string{"user2"}, domain.TweetRange{Limit: 100}).
+		Return([]domain.Tweet{{ID: "tweet1", UserID: "user2", CreatedAt: time.Now()}}, nil)
+
+	useCase := NewTimelineUseCase(mockUserClient, mockTweetClient, newTestHub(), newTestProfiles(), newTestLastKnownGood(), newTestRankingConfig(t))
+	timeline, err := useCase.GetRankedTimeline(context.Background(), "user1")
+
+	assert.NoError(t, err)
+	assert.Len(t, timeline.Entries, 1)
+	mockTweetClient.AssertNumberOfCalls(t, "GetUserTweets", 1)
+}
+
+func TestTimelineUseCase_GetRankedTimeline_FollowsNoOne(t *testing.T) {
+	mockUserClient := new(MockUserClient)
+	mockTweetClient := new(MockTweetClient)
+	mockUserClient.On("GetFollowingUsers", mock.Anything, "user1").Return([]domain.FollowingUser{}, nil)
+
+	useCase := NewTimelineUseCase(mockUserClient, mockTweetClient, newTestHub(), newTestProfiles(), newTestLastKnownGood(), newTestRankingConfig(t))
+	timeline, err := useCase.GetRankedTimeline(context.Background(), "user1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "weighted", timeline.Ranker)
+	assert.Empty(t, timeline.Entries)
+	mockTweetClient.AssertNotCalled(t, "GetUserTweets", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func newTestRankingConfig(t *testing.T) RankingConfig {
+	experiment, err := ranking.NewExperiment([]ranking.Arm{
+		{Scorer: ranking.WeightedScorer{HalfLife: time.Hour, AffinityWeight: 0.5}, Weight: 1},
+	})
+	assert.NoError(t, err)
+	return RankingConfig{
+		Ranking:             ranking.Config{Size: 10, AuthorPenalty: 0.5},
+		Experiment:          experiment,
+		CandidateLimit:      100,
+		SecondDegreeSources: 2,
+		SecondDegreeAuthors: 1,
+	}
+}
+
+func newTestProfiles() *cache.ProfileCache {
+	return cache.NewProfileCache(time.Minute, 100)
+}
+
+func newTestLastKnownGood() LastKnownGood {
+	return LastKnownGood{
+		Timelines:       cache.NewStaleCache[domain.Timeline](time.Minute, 100),
+		RankedTimelines: cache.NewStaleCache[domain.RankedTimeline](time.Minute, 100),
+	}
+}
+
+func newTestHub() *stream.Hub {
+	return stream.NewHub(stream.Config{BufferSize: 10, MaxConnectionsPerUser: 2, QueueSize: 10})
+}
+
+func TestTimelineUseCase_StreamTimeline(t *testing.T) {
+	mockUserClient := new(MockUserClient)
+	mockTweetClient := new(MockTweetClient)
+	mockUserClient.On("GetFollowingUsers", mock.Anything, "user1").
+		Return([]domain.FollowingUser{{ID: "user2", Username: "alice"}}, nil)
+	mockUserClient.On("GetUsers", mock.Anything, []string{"user3"}).
+		Return([]domain.Author{{ID: "user3", Username: "bob"}}, nil)
+
+	useCase := NewTimelineUseCase(mockUserClient, mockTweetClient, newTestHub(), newTestProfiles(), newTestLastKnownGood(), newTestRankingConfig(t))
+	sub, err := useCase.StreamTimeline(context.Background(), "user1", "")
+	assert.NoError(t, err)
+	defer sub.Close()
+
+	followed := domain.Tweet{ID: "tweet1", UserID: "user2", Content: "Hello"}
+	assert.NoError(t, useCase.PublishTweet(context.Background(), domain.Tweet{ID: "tweet0", UserID: "user3"}))
+	assert.NoError(t, useCase.PublishTweet(context.Background(), followed))
+
+	select {
+	case event := <-sub.Events():
+		assert.Equal(t, "tweet1", event.ID)
+		assert.Equal(t, "Hello", event.Tweet.Content)
+		assert.Equal(t, &domain.Author{ID: "user2", Username: "alice"}, event.Tweet.Author)
+	case <-time.After(time.Second):
+		t.Fatal("expected an event for the followed user's tweet")
+	}
+	mockTweetClient.AssertNotCalled(t, "GetUserTweets", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestTimelineUseCase_StreamTimeline_UserClientError(t *testing.T) {
+	mockUserClient := new(MockUserClient)
+	mockTweetClient := new(MockTweetClient)
+	mockUserClient.On("GetFollowingUsers", mock.Anything, "user1").
+		Return([]domain.FollowingUser(nil), errors.New("user service down"))
+
+	useCase := NewTimelineUseCase(mockUserClient, mockTweetClient, newTestHub(), newTestProfiles(), newTestLastKnownGood(), newTestRankingConfig(t))
+	sub, err := useCase.StreamTimeline(context.Background(), "user1", "")
+
+	assert.Error(t, err)
+	assert.Nil(t, sub)
+}
+
+func TestTimelineUseCase_RefreshStream(t *testing.T) {
+	mockUserClient := new(MockUserClient)
+	mockTweetClient := new(MockTweetClient)
+	mockUserClient.On("GetFollowingUsers", mock.Anything, "user1").
+		Return([]domain.FollowingUser{{ID: "user2"}}, nil).Once()
+	mockUserClient.On("GetFollowingUsers", mock.Anything, "user1").
+		Return([]domain.FollowingUser{{ID: "user3"}}, nil).Once()
+
+	useCase := NewTimelineUseCase(mockUserClient, mockTweetClient, newTestHub(), newTestProfiles(), newTestLastKnownGood(), newTestRankingConfig(t))
+	sub, err := useCase.StreamTimeline(context.Background(), "user1", "")
+	assert.NoError(t, err)
+	defer sub.Close()
+
+	assert.NoError(t, useCase.RefreshStream(context.Background(), sub))
+	assert.NoError(t, useCase.PublishTweet(context.Background(), domain.Tweet{ID: "tweet1", UserID: "user2"}))
+	assert.NoError(t, useCase.PublishTweet(context.Background(), domain.Tweet{ID: "tweet2", UserID: "user3"}))
+
+	event := <-sub.Events()
+	assert.Equal(t, "tweet2", event.ID)
+	assert.Empty(t, sub.Events())
+	mockUserClient.AssertExpectations(t)
+}
+
+func TestTimelineUseCase_GetTimeline_LastKnownGood(t *testing.T) {
+	ctx := context.Background()
+	following := []domain.FollowingUser{{ID: "user2", Username: "alice"}}
+	tweets := []domain.Tweet{{ID: "tweet1", UserID: "user2", Content: "Hello"}}
+
+	t.Run("serves the last timeline when the user service fails", func(t *testing.T) {
+		mockUserClient := new(MockUserClient)
+		mockTweetClient := new(MockTweetClient)
+		useCase := NewTimelineUseCase(mockUserClient, mockTweetClient, newTestHub(), newTestProfiles(), newTestLastKnownGood(), newTestRankingConfig(t))
+
+		mockUserClient.On("GetFollowingUsers", ctx, "user1").Return(following, nil).Once()
+		mockTweetClient.On("GetUserTweets", ctx, []string{"user2"}, domain.TweetRange{}).Return(tweets, nil).Once()
+		fresh, err := useCase.GetTimeline(ctx, "user1", domain.TweetRange{})
+		assert.NoError(t, err)
+		assert.False(t, fresh.Degraded)
+
+		mockUserClient.On("GetFollowingUsers", ctx, "user1").Return([]domain.FollowingUser(nil), errors.New("user service unavailable")).Once()
+		stale, err := useCase.GetTimeline(ctx, "user1", domain.TweetRange{})
+
+		assert.NoError(t, err)
+		assert.True(t, stale.Degraded)
+		assert.Equal(t, fresh.Tweets, stale.Tweets)
+	})
+
+	t.Run("fails without a last timeline", func(t *testing.T) {
+		mockUserClient := new(MockUserClient)
+		mockTweetClient := new(MockTweetClient)
+		useCase := NewTimelineUseCase(mockUserClient, mockTweetClient, newTestHub(), newTestProfiles(), newTestLastKnownGood(), newTestRankingConfig(t))
+
+		mockUserClient.On("GetFollowingUsers", ctx, "user1").Return(following, nil)
+		mockTweetClient.On("GetUserTweets", ctx, []string{"user2"}, domain.TweetRange{}).Return([]domain.Tweet(nil), errors.New("tweet service unavailable"))
+
+		_, err := useCase.GetTimeline(ctx, "user1", domain.TweetRange{})
+
+		assert.EqualError(t, err, "tweet service unavailable")
+	})
+
+	t.Run("older pages are not served stale", func(t *testing.T) {
+		mockUserClient := new(MockUserClient)
+		mockTweetClient := new(MockTweetClient)
+		useCase := NewTimelineUseCase(mockUserClient, mockTweetClient, newTestHub(), newTestProfiles(), newTestLastKnownGood(), newTestRankingConfig(t))
+		older := domain.TweetRange{MaxID: "tweet1"}
+
+		mockUserClient.On("GetFollowingUsers", ctx, "user1").Return(following, nil)
+		mockTweetClient.On("GetUserTweets", ctx, []string{"user2"}, domain.TweetRange{}).Return(tweets, nil)
+		mockTweetClient.On("GetUserTweets", ctx, []string{"user2"}, older).Return([]domain.Tweet(nil), errors.New("tweet service unavailable"))
+		_, err := useCase.GetTimeline(ctx, "user1", domain.TweetRange{})
+		assert.NoError(t, err)
+
+		_, err = useCase.GetTimeline(ctx, "user1", older)
+
+		assert.EqualError(t, err, "tweet service unavailable")
+	})
+
+	t.Run("purged users lose their last timeline", func(t *testing.T) {
+		mockUserClient := new(MockUserClient)
+		mockTweetClient := new(MockTweetClient)
+		useCase := NewTimelineUseCase(mockUserClient, mockTweetClient, newTestHub(), newTestProfiles(), newTestLastKnownGood(), newTestRankingConfig(t))
+
+		mockUserClient.On("GetFollowingUsers", ctx, "user1").Return(following, nil).Once()
+		mockTweetClient.On("GetUserTweets", ctx, []string{"user2"}, domain.TweetRange{}).Return(tweets, nil)
+		_, err := useCase.GetTimeline(ctx, "user1", domain.TweetRange{})
+		assert.NoError(t, err)
+		assert.NoError(t, useCase.PurgeUser(ctx, "user1"))
+
+		mockUserClient.On("GetFollowingUsers", ctx, "user1").Return([]domain.FollowingUser(nil), errors.New("user service unavailable"))
+		_, err = useCase.GetTimeline(ctx, "user1", domain.TweetRange{})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestTimelineUseCase_GetRankedTimeline_LastKnownGood(t *testing.T) {
+	ctx := context.Background()
+	mockUserClient := new(MockUserClient)
+	mockTweetClient := new(MockTweetClient)
+	useCase := NewTimelineUseCase(mockUserClient, mockTweetClient, newTestHub(), newTestProfiles(), newTestLastKnownGood(), newTestRankingConfig(t))
+
+	mockUserClient.On("GetFollowingUsers", ctx, "user1").Return([]domain.FollowingUser{}, nil).Once()
+	fresh, err := useCase.GetRankedTimeline(ctx, "user1")
+	assert.NoError(t, err)
+
+	mockUserClient.On("GetFollowingUsers", ctx, "user1").Return([]domain.FollowingUser(nil), errors.New("user service unavailable")).Once()
+	stale, err := useCase.GetRankedTimeline(ctx, "user1")
+
+	assert.NoError(t, err)
+	assert.True(t, stale.Degraded)
+	assert.Equal(t, fresh.Ranker, stale.Ranker)
+}
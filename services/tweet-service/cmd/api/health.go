@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/lisandro/challenge/pkg/health"
+	"github.com/opensearch-project/opensearch-go/v2"
+)
+
+// newHealthChecker checks the dependencies of the service for the readiness probe. The search
+// index is optional, since lists of tweets are read from DynamoDB while it is down.
+func newHealthChecker(dynamoClient *dynamodb.Client, table string, opensearchClient *opensearch.Client, timeout time.Duration) *health.Checker {
+	return health.NewChecker(timeout,
+		health.Dependency{
+			Name: "dynamodb",
+			Check: func(ctx context.Context) error {
+				_, err := dynamoClient.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(table)})
+				return err
+			},
+		},
+		health.Dependency{
+			Name:     "opensearch",
+			Check:    opensearchHealth(opensearchClient),
+			Optional: true,
+		},
+	)
+}
+
+// opensearchHealth checks that the OpenSearch cluster answers and that its status is not red,
+// which means that some data cannot be searched
+func opensearchHealth(client *opensearch.Client) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		res, err := client.Cluster.Health(client.Cluster.Health.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		if res.IsError() {
+			return fmt.Errorf("cluster health: %s", res.Status())
+		}
+
+		var cluster struct {
+			Status string `json:"status"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&cluster); err != nil {
+			return fmt.Errorf("cluster health: %w", err)
+		}
+		if cluster.Status == "red" {
+			return fmt.Errorf("cluster status is %s", cluster.Status)
+		}
+		return nil
+	}
+}
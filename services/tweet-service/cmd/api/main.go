@@ -3,17 +3,22 @@ package main
 import (
 	"context"
 	"log"
+	nethttp "net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/lisandro/challenge/pkg/eventauth"
 	"github.com/lisandro/challenge/services/tweet-service/config"
 	_ "github.com/lisandro/challenge/services/tweet-service/docs" // Import generated docs
 	"github.com/lisandro/challenge/services/tweet-service/internal/delivery/http"
+	"github.com/lisandro/challenge/services/tweet-service/internal/events"
 	dynamorepo "github.com/lisandro/challenge/services/tweet-service/internal/repository/dynamodb"
 	opensearchrepo "github.com/lisandro/challenge/services/tweet-service/internal/repository/opensearch"
 	"github.com/lisandro/challenge/services/tweet-service/internal/usecase"
@@ -40,9 +45,13 @@ func main() {
 	config.InitLogger()
 	log.Println("Starting tweet service...")
 
+	// DynamoDB and OpenSearch share the connections of one transport, closed on shutdown
+	transport := nethttp.DefaultTransport.(*nethttp.Transport).Clone()
+
 	// Initialize AWS SDK with static credentials for LocalStack
 	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background(),
 		awsconfig.WithRegion(getEnvOrDefault("AWS_REGION", "us-east-1")),
+		awsconfig.WithHTTPClient(&nethttp.Client{Transport: transport}),
 		awsconfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
 			getEnvOrDefault("AWS_ACCESS_KEY_ID", "test"),
 			getEnvOrDefault("AWS_SECRET_ACCESS_KEY", "test"),
@@ -53,28 +62,89 @@ func main() {
 		log.Fatalf("Failed to load AWS config: %v", err)
 	}
 
-	// Initialize DynamoDB client with custom endpoint
+	// Initialize DynamoDB client with custom endpoint, recording the latency of its calls
 	dynamoClient := dynamodb.NewFromConfig(awsCfg, func(o *dynamodb.Options) {
 		o.BaseEndpoint = aws.String(getEnvOrDefault("DYNAMODB_ENDPOINT", "http://localhost:4566"))
-	})
+	}, dynamorepo.WithMetrics)
 
-	// Initialize OpenSearch client
+	// Initialize OpenSearch client, recording the latency of its requests
 	opensearchClient, err := opensearch.NewClient(opensearch.Config{
 		Addresses: []string{getEnvOrDefault("OPENSEARCH_ENDPOINT", "http://localhost:9200")},
+		Transport: opensearchrepo.NewInstrumentedTransport(transport),
 	})
 	if err != nil {
 		log.Fatalf("Failed to create OpenSearch client: %v", err)
 	}
 
+	// tweet-service reindex rebuilds the search index instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "reindex" {
+		runReindex(dynamoClient, opensearchClient, os.Args[2:])
+		return
+	}
+
 	// Initialize repositories
-	tweetRepo := dynamorepo.NewTweetRepository(dynamoClient, getEnvOrDefault("DYNAMODB_TABLE", "tweets"))
-	searchRepo := opensearchrepo.NewSearchRepository(opensearchClient)
+	tweetsTable := getEnvOrDefault("DYNAMODB_TABLE", "tweets")
+	tweetRepo := dynamorepo.NewTweetRepository(dynamoClient, tweetsTable)
+	pinRepo := dynamorepo.NewPinRepository(dynamoClient, getEnvOrDefault("PINS_TABLE", "pinned_tweets"))
+
+	// Background workers run until ctx is canceled on shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var workers sync.WaitGroup
+
+	// Index tweets through the _bulk API in the background instead of within each request
+	searchRepo := opensearchrepo.NewBulkIndexer(opensearchClient, opensearchrepo.NewSearchRepository(opensearchClient), opensearchrepo.BulkConfig{
+		BatchSize:      500,
+		FlushInterval:  time.Second,
+		QueueSize:      10000,
+		EnqueueTimeout: 100 * time.Millisecond,
+		MaxAttempts:    3,
+		RetryDelay:     time.Second,
+		Timeout:        10 * time.Second,
+	})
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		searchRepo.Run(ctx)
+	}()
+
+	// Events sent between the services are signed with the secret they share
+	eventSecret, err := eventauth.SecretFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to load the event signing secret: %v", err)
+	}
+
+	// Deliver tweet events to the services that react to new tweets
+	subscribers, err := events.ParseSubscribers(getEnvOrDefault("TWEET_EVENT_SUBSCRIBERS",
+		"timeline-service=http://localhost:8082/api/v1/internal/tweet-events"))
+	if err != nil {
+		log.Fatalf("Failed to parse tweet event subscribers: %v", err)
+	}
+	publisher := events.NewPublisher(subscribers, events.Config{
+		QueueSize:     1000,
+		MaxAttempts:   3,
+		RetryDelay:    time.Second,
+		Timeout:       5 * time.Second,
+		SigningSecret: eventSecret,
+	})
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		publisher.Run(ctx)
+	}()
+
+	// Lists of tweets are read from OpenSearch, or from DynamoDB only
+	readSource, err := usecase.ParseReadSource(getEnvOrDefault("TWEET_READ_SOURCE", string(usecase.ReadFromSearch)))
+	if err != nil {
+		log.Fatalf("Invalid TWEET_READ_SOURCE: %v", err)
+	}
 
 	// Initialize usecase with its dependencies
-	tweetUsecase := usecase.NewTweetUseCase(tweetRepo, searchRepo)
+	tweetUsecase := usecase.NewTweetUseCase(tweetRepo, searchRepo, pinRepo, publisher, readSource)
 
 	// Initialize HTTP server with its dependencies
-	server := http.NewServer(tweetUsecase)
+	checker := newHealthChecker(dynamoClient, tweetsTable, opensearchClient, getDurationOrDefault("HEALTH_CHECK_TIMEOUT", 2*time.Second))
+	server := http.NewServer(tweetUsecase, checker, 10*time.Second, eventSecret)
 
 	// Start server in a goroutine
 	go func() {
@@ -90,6 +160,38 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), getDurationOrDefault("SHUTDOWN_TIMEOUT", 20*time.Second))
+	defer cancelShutdown()
+
+	// Stop taking requests and wait for the ones in flight
+	if err := server.Shutdown(shutdownCtx, getDurationOrDefault("SHUTDOWN_DRAIN_DELAY", 5*time.Second)); err != nil {
+		log.Printf("Failed to wait for requests in flight: %v", err)
+	}
+
+	// No request queues work anymore, so the workers index and publish what is left and stop
+	cancel()
+	if err := waitGroup(shutdownCtx, &workers); err != nil {
+		log.Printf("Failed to wait for background workers: %v", err)
+	}
+
+	transport.CloseIdleConnections()
+	log.Println("Server stopped")
+}
+
+// waitGroup waits for wg until ctx is done
+func waitGroup(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func getEnvOrDefault(key, defaultValue string) string {
@@ -97,4 +199,17 @@ func getEnvOrDefault(key, defaultValue string) string {
 		return value
 	}
 	return defaultValue
-} 
\ No newline at end of file
+}
+
+func getDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid duration %q for %s, using default %s", value, key, defaultValue)
+		return defaultValue
+	}
+	return duration
+}
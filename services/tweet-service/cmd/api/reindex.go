@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/lisandro/challenge/services/tweet-service/internal/reindex"
+	dynamorepo "github.com/lisandro/challenge/services/tweet-service/internal/repository/dynamodb"
+	opensearchrepo "github.com/lisandro/challenge/services/tweet-service/internal/repository/opensearch"
+	"github.com/opensearch-project/opensearch-go/v2"
+)
+
+// runReindex indexes the tweets table into a new versioned index and swaps the tweets
+// alias to it. Interrupting it leaves the alias untouched until the swap.
+func runReindex(dynamoClient *dynamodb.Client, opensearchClient *opensearch.Client, args []string) {
+	flags := flag.NewFlagSet("reindex", flag.ExitOnError)
+	segments := flags.Int("segments", 4, "number of parallel segments to scan the tweets table in")
+	rolloverAge := flags.String("rollover-age", getEnvOrDefault("OPENSEARCH_ROLLOVER_AGE", "30d"), "age at which the write index rolls over")
+	flags.Parse(args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	scanner := dynamorepo.NewTweetScanner(dynamoClient, getEnvOrDefault("DYNAMODB_TABLE", "tweets"))
+	indexer := opensearchrepo.NewIndexManager(opensearchClient, *rolloverAge)
+
+	result, err := reindex.Run(ctx, scanner, indexer, reindex.Config{Segments: *segments})
+	if err != nil {
+		log.Fatalf("Reindex failed: %v", err)
+	}
+	log.Printf("Reindexed %d tweets into %s", result.Indexed, result.Index)
+	if len(result.Previous) > 0 {
+		log.Printf("Previous indices %v were kept; delete them once %s is verified", result.Previous, result.Index)
+	}
+}
@@ -24,6 +24,69 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/internal/events": {
+            "post": {
+                "description": "Receive an event published by the user service. A user.deleted event removes every tweet of the user. Events may be delivered more than once.\nEvents must be signed with the secret shared by the services.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "internal"
+                ],
+                "summary": "Handle a user service event",
+                "parameters": [
+                    {
+                        "description": "User event",
+                        "name": "event",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/domain.UserEvent"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Unix time at which the event was signed",
+                        "name": "X-Event-Timestamp",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "HMAC-SHA256 of the timestamp and body, as sha256=\u003chex\u003e",
+                        "name": "X-Event-Signature",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/tweets": {
             "post": {
                 "description": "Create a new tweet for a user",
@@ -79,7 +142,7 @@ const docTemplate = `{
         },
         "/tweets/following": {
             "get": {
-                "description": "Get tweets from a list of user IDs with pagination",
+                "description": "Get tweets from a list of user IDs with pagination, newest first. since_id and max_id limit the tweets to those newer or older than a tweet; polling with the newest tweet seen as since_id returns only the tweets written since.\nWhen the search index is unavailable, the tweets are read from storage and a Warning header is set.",
                 "consumes": [
                     "application/json"
                 ],
@@ -102,15 +165,27 @@ const docTemplate = `{
                         "in": "query",
                         "required": true
                     },
+                    {
+                        "type": "string",
+                        "description": "Only return tweets newer than this tweet",
+                        "name": "since_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only return tweets older than this tweet",
+                        "name": "max_id",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "Page number (default: 1)",
+                        "description": "Page number (default: 1). Pages must end within the 1000 newest tweets of the range; older tweets are read with max_id.",
                         "name": "page",
                         "in": "query"
                     },
                     {
                         "type": "integer",
-                        "description": "Page size (default: 10)",
+                        "description": "Page size (default: 10, max: 200)",
                         "name": "page_size",
                         "in": "query"
                     }
@@ -139,9 +214,365 @@ const docTemplate = `{
                     }
                 }
             }
+        },
+        "/tweets/following/count": {
+            "get": {
+                "description": "Count the tweets from a list of user IDs without returning them. With since_id, this is the number of tweets written since that tweet.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tweets"
+                ],
+                "summary": "Count tweets by user IDs",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "List of user IDs",
+                        "name": "user_ids",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only count tweets newer than this tweet",
+                        "name": "since_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only count tweets older than this tweet",
+                        "name": "max_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/http.CountResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tweets/users/{userID}": {
+            "get": {
+                "description": "Get every tweet written by a user, oldest first, paginated with a cursor. Tweets are read from storage, not from the search index.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tweets"
+                ],
+                "summary": "Get the tweets of a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor returned as next_cursor by the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default: 100, max: 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/http.UserTweetsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me/pinned-tweet": {
+            "put": {
+                "description": "Pin one of the tweets of the current user to their profile, replacing the tweet pinned before",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Pin a tweet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the current user",
+                        "name": "X-User-ID",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Tweet to pin",
+                        "name": "pin",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/http.PinTweetRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Remove the pinned tweet from the profile of the current user. Succeeds when nothing is pinned.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Unpin the pinned tweet",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the current user",
+                        "name": "X-User-ID",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{userID}/tweets": {
+            "get": {
+                "description": "Get a page of the tweets shown on the profile of a user, newest first. The first page also carries the pinned tweet, which is then left out of its tweets. The replies, media and likes tabs are not supported yet.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get the profile timeline of a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Profile tab: tweets (default), replies, media or likes",
+                        "name": "tab",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor returned as next_cursor by the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default: 20, max: 100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/domain.ProfileTweets"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/http.ErrorResponse"
+                        }
+                    }
+                }
+            }
         }
     },
     "definitions": {
+        "domain.ProfileTweets": {
+            "type": "object",
+            "properties": {
+                "degraded": {
+                    "description": "Degraded reports a page read from storage because the search index is unavailable",
+                    "type": "boolean"
+                },
+                "next_cursor": {
+                    "description": "NextCursor is the cursor of the next page, empty on the last page",
+                    "type": "string"
+                },
+                "pinned_tweet": {
+                    "description": "PinnedTweet is the tweet the user pinned. It is only set on the first page.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.Tweet"
+                        }
+                    ]
+                },
+                "tweets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.Tweet"
+                    }
+                }
+            }
+        },
+        "domain.Tweet": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.UserEvent": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "occurred_at": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "http.CountResponse": {
+            "description": "Number of tweets",
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
         "http.CreateTweetRequest": {
             "description": "Request body for creating a tweet",
             "type": "object",
@@ -165,6 +596,16 @@ const docTemplate = `{
                 }
             }
         },
+        "http.PinTweetRequest": {
+            "description": "Request body for pinning a tweet to the profile of the current user",
+            "type": "object",
+            "properties": {
+                "tweet_id": {
+                    "type": "string",
+                    "example": "123e4567-e89b-12d3-a456-426614174000"
+                }
+            }
+        },
         "http.Tweet": {
             "description": "Tweet information",
             "type": "object",
@@ -190,6 +631,22 @@ const docTemplate = `{
                     "example": "123e4567-e89b-12d3-a456-426614174000"
                 }
             }
+        },
+        "http.UserTweetsResponse": {
+            "description": "Page of the tweets written by a user",
+            "type": "object",
+            "properties": {
+                "next_cursor": {
+                    "type": "string",
+                    "example": "2024-06-07T22:04:25Z_123e4567-e89b-12d3-a456-426614174000"
+                },
+                "tweets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.Tweet"
+                    }
+                }
+            }
         }
     }
 }`
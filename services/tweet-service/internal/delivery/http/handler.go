@@ -1,6 +1,8 @@
 package http
 
 import (
+	"errors"
+	"log"
 	"strconv"
 	"strings"
 
@@ -9,6 +11,14 @@ import (
 	"github.com/lisandro/challenge/services/tweet-service/internal/domain"
 )
 
+const (
+	// maxPageSize caps the page size of the tweet listings by user IDs
+	maxPageSize = 200
+	// maxPageDepth caps the tweets before the end of a requested page. Storage has no
+	// offsets and reads every earlier page, so deeper pages must be read with max_id.
+	maxPageDepth = 1000
+)
+
 // Handler handles HTTP requests
 type Handler struct {
 	tweetUseCase domain.TweetUseCase
@@ -83,7 +93,7 @@ func (h *Handler) CreateTweet(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: "invalid user_id format"})
 	}
 
-	tweet, err := h.tweetUseCase.CreateTweet(userIDUUID, req.Content)
+	tweet, err := h.tweetUseCase.CreateTweet(c.UserContext(), userIDUUID, req.Content)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Error: err.Error()})
 	}
@@ -93,30 +103,29 @@ func (h *Handler) CreateTweet(c *fiber.Ctx) error {
 
 // GetTweetsByUsersID godoc
 // @Summary Get tweets by user IDs
-// @Description Get tweets from a list of user IDs with pagination
+// @Description Get tweets from a list of user IDs with pagination, newest first. since_id and max_id limit the tweets to those newer or older than a tweet; polling with the newest tweet seen as since_id returns only the tweets written since.
+// @Description When the search index is unavailable, the tweets are read from storage and a Warning header is set.
 // @Tags tweets
 // @Accept json
 // @Produce json
 // @Param user_ids query []string true "List of user IDs"
-// @Param page query int false "Page number (default: 1)"
-// @Param page_size query int false "Page size (default: 10)"
+// @Param since_id query string false "Only return tweets newer than this tweet"
+// @Param max_id query string false "Only return tweets older than this tweet"
+// @Param page query int false "Page number (default: 1). Pages must end within the 1000 newest tweets of the range; older tweets are read with max_id."
+// @Param page_size query int false "Page size (default: 10, max: 200)"
 // @Success 200 {array} Tweet
 // @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /tweets/following [get]
 func (h *Handler) GetTweetsByUsersID(c *fiber.Ctx) error {
-	userIDsStr := c.Query("user_ids")
-	if userIDsStr == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: "user_ids parameter is required"})
+	userIDs, err := parseUserIDs(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: err.Error()})
 	}
 
-	userIDs := make([]uuid.UUID, 0)
-	for _, idStr := range strings.Split(userIDsStr, ",") {
-		id, err := uuid.Parse(strings.TrimSpace(idStr))
-		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: "invalid user ID format"})
-		}
-		userIDs = append(userIDs, id)
+	tweetRange, err := parseTweetRange(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: err.Error()})
 	}
 
 	page := 1
@@ -132,11 +141,317 @@ func (h *Handler) GetTweetsByUsersID(c *fiber.Ctx) error {
 			pageSize = ps
 		}
 	}
+	if pageSize > maxPageSize {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: "invalid page_size"})
+	}
+	if page > maxPageDepth/pageSize {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: "page is too deep, use max_id to read older tweets"})
+	}
+
+	list, err := h.tweetUseCase.GetTweetsByUsersID(c.UserContext(), userIDs, tweetRange, page, pageSize)
+	if errors.Is(err, domain.ErrTweetNotFound) {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: "since_id or max_id does not match a tweet"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Error: "failed to get tweets"})
+	}
+
+	if list.Degraded {
+		setSearchUnavailableWarning(c)
+	}
+	return c.JSON(list.Tweets)
+}
+
+// setSearchUnavailableWarning marks a response read from storage because the search index
+// failed. The tweets are complete, so only a miscellaneous warning is set.
+func setSearchUnavailableWarning(c *fiber.Ctx) {
+	c.Set(fiber.HeaderWarning, `199 - "Search unavailable, tweets were read from storage"`)
+}
+
+// CountResponse represents a number of tweets
+// @Description Number of tweets
+type CountResponse struct {
+	Count int `json:"count" example:"3"`
+}
+
+// CountTweetsByUsersID godoc
+// @Summary Count tweets by user IDs
+// @Description Count the tweets from a list of user IDs without returning them. With since_id, this is the number of tweets written since that tweet.
+// @Tags tweets
+// @Accept json
+// @Produce json
+// @Param user_ids query []string true "List of user IDs"
+// @Param since_id query string false "Only count tweets newer than this tweet"
+// @Param max_id query string false "Only count tweets older than this tweet"
+// @Success 200 {object} CountResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /tweets/following/count [get]
+func (h *Handler) CountTweetsByUsersID(c *fiber.Ctx) error {
+	userIDs, err := parseUserIDs(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: err.Error()})
+	}
+
+	tweetRange, err := parseTweetRange(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: err.Error()})
+	}
+
+	count, err := h.tweetUseCase.CountTweetsByUsersID(c.UserContext(), userIDs, tweetRange)
+	if errors.Is(err, domain.ErrTweetNotFound) {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: "since_id or max_id does not match a tweet"})
+	}
+	if err != nil {
+		log.Printf("Failed to count tweets: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Error: "failed to count tweets"})
+	}
+
+	return c.JSON(CountResponse{Count: count})
+}
+
+// parseUserIDs reads the comma separated user_ids query parameter
+func parseUserIDs(c *fiber.Ctx) ([]uuid.UUID, error) {
+	userIDsStr := c.Query("user_ids")
+	if userIDsStr == "" {
+		return nil, errors.New("user_ids parameter is required")
+	}
+
+	userIDs := make([]uuid.UUID, 0)
+	for _, idStr := range strings.Split(userIDsStr, ",") {
+		id, err := uuid.Parse(strings.TrimSpace(idStr))
+		if err != nil {
+			return nil, errors.New("invalid user ID format")
+		}
+		userIDs = append(userIDs, id)
+	}
+	return userIDs, nil
+}
+
+// parseTweetRange reads the optional since_id and max_id query parameters
+func parseTweetRange(c *fiber.Ctx) (domain.TweetRange, error) {
+	var tweetRange domain.TweetRange
+	if sinceID := c.Query("since_id"); sinceID != "" {
+		id, err := uuid.Parse(sinceID)
+		if err != nil {
+			return tweetRange, errors.New("invalid since_id format")
+		}
+		tweetRange.SinceID = id
+	}
+	if maxID := c.Query("max_id"); maxID != "" {
+		id, err := uuid.Parse(maxID)
+		if err != nil {
+			return tweetRange, errors.New("invalid max_id format")
+		}
+		tweetRange.MaxID = id
+	}
+	return tweetRange, nil
+}
+
+// UserTweetsResponse represents a page of the tweets written by a user
+// @Description Page of the tweets written by a user
+type UserTweetsResponse struct {
+	Tweets     []domain.Tweet `json:"tweets"`
+	NextCursor string         `json:"next_cursor,omitempty" example:"2024-06-07T22:04:25Z_123e4567-e89b-12d3-a456-426614174000"`
+}
+
+// GetTweetsByUser godoc
+// @Summary Get the tweets of a user
+// @Description Get every tweet written by a user, oldest first, paginated with a cursor. Tweets are read from storage, not from the search index.
+// @Tags tweets
+// @Accept json
+// @Produce json
+// @Param userID path string true "User ID"
+// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+// @Param limit query int false "Page size (default: 100, max: 1000)"
+// @Success 200 {object} UserTweetsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /tweets/users/{userID} [get]
+func (h *Handler) GetTweetsByUser(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("userID"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: "invalid user ID format"})
+	}
 
-	tweets, err := h.tweetUseCase.GetTweetsByUsersID(userIDs, page, pageSize)
+	limit := c.QueryInt("limit", 100)
+	if limit < 1 || limit > 1000 {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: "invalid limit"})
+	}
+
+	tweets, nextCursor, err := h.tweetUseCase.GetTweetsByUser(c.UserContext(), userID, c.Query("cursor"), limit)
+	if errors.Is(err, domain.ErrInvalidCursor) {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: "invalid cursor"})
+	}
 	if err != nil {
+		log.Printf("Failed to get tweets of user %s: %v", userID, err)
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Error: "failed to get tweets"})
 	}
 
-	return c.JSON(tweets)
+	return c.JSON(UserTweetsResponse{
+		Tweets:     tweets,
+		NextCursor: nextCursor,
+	})
+}
+
+// PinTweetRequest represents the request body for pinning a tweet
+// @Description Request body for pinning a tweet to the profile of the current user
+type PinTweetRequest struct {
+	TweetID uuid.UUID `json:"tweet_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+}
+
+// GetProfileTweets godoc
+// @Summary Get the profile timeline of a user
+// @Description Get a page of the tweets shown on the profile of a user, newest first. The first page also carries the pinned tweet, which is then left out of its tweets. The replies, media and likes tabs are not supported yet.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param userID path string true "User ID"
+// @Param tab query string false "Profile tab: tweets (default), replies, media or likes"
+// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+// @Param limit query int false "Page size (default: 20, max: 100)"
+// @Success 200 {object} domain.ProfileTweets
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 501 {object} ErrorResponse
+// @Router /users/{userID}/tweets [get]
+func (h *Handler) GetProfileTweets(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("userID"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: "invalid user ID format"})
+	}
+
+	tab := c.Query("tab", domain.ProfileTabTweets)
+	switch tab {
+	case domain.ProfileTabTweets, domain.ProfileTabReplies, domain.ProfileTabMedia, domain.ProfileTabLikes:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: "invalid tab"})
+	}
+
+	var cursor uuid.UUID
+	if c.Query("cursor") != "" {
+		if cursor, err = uuid.Parse(c.Query("cursor")); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: "invalid cursor"})
+		}
+	}
+
+	limit := c.QueryInt("limit", 20)
+	if limit < 1 || limit > 100 {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: "invalid limit"})
+	}
+
+	profile, err := h.tweetUseCase.GetProfileTweets(c.UserContext(), userID, tab, cursor, limit)
+	switch {
+	case errors.Is(err, domain.ErrProfileTabNotSupported):
+		return c.Status(fiber.StatusNotImplemented).JSON(ErrorResponse{Error: "the " + tab + " tab is not supported yet"})
+	case errors.Is(err, domain.ErrTweetNotFound):
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: "cursor does not match a tweet"})
+	case err != nil:
+		log.Printf("Failed to get profile tweets of user %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Error: "failed to get tweets"})
+	}
+
+	if profile.Degraded {
+		setSearchUnavailableWarning(c)
+	}
+	return c.JSON(profile)
+}
+
+// PinTweet godoc
+// @Summary Pin a tweet
+// @Description Pin one of the tweets of the current user to their profile, replacing the tweet pinned before
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param X-User-ID header string true "ID of the current user"
+// @Param pin body PinTweetRequest true "Tweet to pin"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /users/me/pinned-tweet [put]
+func (h *Handler) PinTweet(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Get("X-User-ID"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: "invalid user_id format"})
+	}
+
+	var req PinTweetRequest
+	if err := c.BodyParser(&req); err != nil || req.TweetID == uuid.Nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: "tweet_id is required"})
+	}
+
+	err = h.tweetUseCase.PinTweet(c.UserContext(), userID, req.TweetID)
+	switch {
+	case errors.Is(err, domain.ErrTweetNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Error: err.Error()})
+	case errors.Is(err, domain.ErrNotTweetAuthor):
+		return c.Status(fiber.StatusForbidden).JSON(ErrorResponse{Error: "only your own tweets can be pinned"})
+	case err != nil:
+		log.Printf("Failed to pin tweet %s of user %s: %v", req.TweetID, userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Error: "failed to pin tweet"})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// UnpinTweet godoc
+// @Summary Unpin the pinned tweet
+// @Description Remove the pinned tweet from the profile of the current user. Succeeds when nothing is pinned.
+// @Tags users
+// @Produce json
+// @Param X-User-ID header string true "ID of the current user"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /users/me/pinned-tweet [delete]
+func (h *Handler) UnpinTweet(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Get("X-User-ID"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: "invalid user_id format"})
+	}
+
+	if err := h.tweetUseCase.UnpinTweet(c.UserContext(), userID); err != nil {
+		log.Printf("Failed to unpin tweet of user %s: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Error: "failed to unpin tweet"})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// HandleUserEvent godoc
+// @Summary Handle a user service event
+// @Description Receive an event published by the user service. A user.deleted event removes every tweet of the user. Events may be delivered more than once.
+// @Description Events must be signed with the secret shared by the services.
+// @Tags internal
+// @Accept json
+// @Produce json
+// @Param event body domain.UserEvent true "User event"
+// @Param X-Event-Timestamp header string true "Unix time at which the event was signed"
+// @Param X-Event-Signature header string true "HMAC-SHA256 of the timestamp and body, as sha256=<hex>"
+// @Success 204
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /internal/events [post]
+func (h *Handler) HandleUserEvent(c *fiber.Ctx) error {
+	var event domain.UserEvent
+	if err := c.BodyParser(&event); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: "invalid event"})
+	}
+
+	switch event.Type {
+	case domain.EventUserDeleted:
+		log.Printf("Handling event %s: deleting tweets of user %s", event.ID, event.UserID)
+		if err := h.tweetUseCase.DeleteUserTweets(c.UserContext(), event.UserID); err != nil {
+			log.Printf("Failed to delete tweets of user %s: %v", event.UserID, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Error: "failed to delete user tweets"})
+		}
+	default:
+		// Acknowledge events this service does not care about so they are not redelivered
+		log.Printf("Ignoring event %s of type %s", event.ID, event.Type)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
 }
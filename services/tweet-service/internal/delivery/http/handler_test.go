@@ -2,14 +2,18 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/lisandro/challenge/pkg/eventauth"
+	"github.com/lisandro/challenge/pkg/health"
 	"github.com/lisandro/challenge/services/tweet-service/internal/domain"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -20,33 +24,120 @@ type MockTweetUseCase struct {
 	mock.Mock
 }
 
-func (m *MockTweetUseCase) CreateTweet(userID uuid.UUID, content string) (*domain.Tweet, error) {
-	args := m.Called(userID, content)
+func (m *MockTweetUseCase) CreateTweet(ctx context.Context, userID uuid.UUID, content string) (*domain.Tweet, error) {
+	args := m.Called(ctx, userID, content)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*domain.Tweet), args.Error(1)
 }
 
-func (m *MockTweetUseCase) GetTweetsByUsersID(userIDs []uuid.UUID, page, pageSize int) ([]domain.Tweet, error) {
-	args := m.Called(userIDs, page, pageSize)
+func (m *MockTweetUseCase) GetTweetsByUsersID(ctx context.Context, userIDs []uuid.UUID, tweetRange domain.TweetRange, page, pageSize int) (*domain.TweetList, error) {
+	args := m.Called(ctx, userIDs, tweetRange, page, pageSize)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).([]domain.Tweet), args.Error(1)
+	return args.Get(0).(*domain.TweetList), args.Error(1)
 }
 
+func (m *MockTweetUseCase) CountTweetsByUsersID(ctx context.Context, userIDs []uuid.UUID, tweetRange domain.TweetRange) (int, error) {
+	args := m.Called(ctx, userIDs, tweetRange)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockTweetUseCase) GetTweetsByUser(ctx context.Context, userID uuid.UUID, cursor string, limit int) ([]domain.Tweet, string, error) {
+	args := m.Called(ctx, userID, cursor, limit)
+	if args.Get(0) == nil {
+		return nil, args.String(1), args.Error(2)
+	}
+	return args.Get(0).([]domain.Tweet), args.String(1), args.Error(2)
+}
+
+func (m *MockTweetUseCase) GetProfileTweets(ctx context.Context, userID uuid.UUID, tab string, cursor uuid.UUID, limit int) (*domain.ProfileTweets, error) {
+	args := m.Called(ctx, userID, tab, cursor, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ProfileTweets), args.Error(1)
+}
+
+func (m *MockTweetUseCase) PinTweet(ctx context.Context, userID, tweetID uuid.UUID) error {
+	args := m.Called(ctx, userID, tweetID)
+	return args.Error(0)
+}
+
+func (m *MockTweetUseCase) UnpinTweet(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockTweetUseCase) DeleteUserTweets(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+// testEventSecret signs the internal events sent in tests
+var testEventSecret = []byte("secret")
+
 func setupTest() (*fiber.App, *MockTweetUseCase) {
 	app := fiber.New()
 	mockUseCase := new(MockTweetUseCase)
 	handler := NewHandler(mockUseCase)
 
 	// Register routes
-	RegisterRoutes(app, handler)
+	RegisterRoutes(app, handler, testEventSecret)
 
 	return app, mockUseCase
 }
 
+func TestRequestContext(t *testing.T) {
+	app := fiber.New()
+	mockUseCase := new(MockTweetUseCase)
+	app.Use(requestContext(time.Minute))
+	RegisterRoutes(app, NewHandler(mockUseCase), testEventSecret)
+
+	userID := uuid.New()
+	hasDeadline := mock.MatchedBy(func(ctx context.Context) bool {
+		_, ok := ctx.Deadline()
+		return ok
+	})
+	mockUseCase.On("UnpinTweet", hasDeadline, userID).Return(nil)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/users/me/pinned-tweet", nil)
+	req.Header.Set("X-User-ID", userID.String())
+	resp, err := app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	mockUseCase.AssertExpectations(t)
+}
+
+func TestServer_ShutdownFailsReadinessFirst(t *testing.T) {
+	server := NewServer(new(MockTweetUseCase), health.NewChecker(time.Second), time.Minute, testEventSecret)
+
+	resp, err := server.app.Test(httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// The server keeps serving while it drains, but is no longer ready
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error)
+	go func() {
+		stopped <- server.Shutdown(ctx, time.Hour)
+	}()
+	assert.Eventually(t, func() bool {
+		resp, err := server.app.Test(httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return err == nil && resp.StatusCode == http.StatusServiceUnavailable
+	}, time.Second, 10*time.Millisecond)
+
+	resp, err = server.app.Test(httptest.NewRequest(http.MethodGet, "/livez", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	cancel()
+	assert.NoError(t, <-stopped)
+}
+
 func TestCreateTweet(t *testing.T) {
 	// Setup
 	app, mockUseCase := setupTest()
@@ -64,7 +155,7 @@ func TestCreateTweet(t *testing.T) {
 	}
 
 	// Expectations
-	mockUseCase.On("CreateTweet", userID, content).Return(expectedTweet, nil)
+	mockUseCase.On("CreateTweet", mock.Anything, userID, content).Return(expectedTweet, nil)
 
 	// Create request
 	reqBody := CreateTweetRequest{
@@ -118,7 +209,7 @@ func TestCreateTweet_InvalidRequest(t *testing.T) {
 	assert.NotEmpty(t, response.Error)
 
 	// Verify that CreateTweet was not called
-	mockUseCase.AssertNotCalled(t, "CreateTweet")
+	mockUseCase.AssertNotCalled(t, "CreateTweet", mock.Anything)
 }
 
 func TestGetTweetsByUsersID(t *testing.T) {
@@ -147,7 +238,7 @@ func TestGetTweetsByUsersID(t *testing.T) {
 	}
 
 	// Expectations
-	mockUseCase.On("GetTweetsByUsersID", userIDs, page, pageSize).Return(expectedTweets, nil)
+	mockUseCase.On("GetTweetsByUsersID", mock.Anything, userIDs, domain.TweetRange{}, page, pageSize).Return(&domain.TweetList{Tweets: expectedTweets}, nil)
 
 	// Execute
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/tweets/following?user_ids="+userIDs[0].String()+","+userIDs[1].String()+"&page=1&page_size=10", nil)
@@ -168,6 +259,22 @@ func TestGetTweetsByUsersID(t *testing.T) {
 	mockUseCase.AssertExpectations(t)
 }
 
+func TestGetTweetsByUsersID_Degraded(t *testing.T) {
+	app, mockUseCase := setupTest()
+	userID := uuid.New()
+	tweets := []domain.Tweet{{ID: uuid.New(), UserID: userID, Content: "Hello"}}
+	mockUseCase.On("GetTweetsByUsersID", mock.Anything, []uuid.UUID{userID}, domain.TweetRange{}, 1, 10).Return(&domain.TweetList{Tweets: tweets, Degraded: true}, nil)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/v1/tweets/following?user_ids="+userID.String(), nil))
+	assert.NoError(t, err)
+
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, `199 - "Search unavailable, tweets were read from storage"`, resp.Header.Get("Warning"))
+	var response []Tweet
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Len(t, response, 1)
+}
+
 func TestGetTweetsByUsersID_MissingUserIDs(t *testing.T) {
 	// Setup
 	app, mockUseCase := setupTest()
@@ -186,7 +293,7 @@ func TestGetTweetsByUsersID_MissingUserIDs(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotEmpty(t, response.Error)
 
-	mockUseCase.AssertNotCalled(t, "GetTweetsByUsersID")
+	mockUseCase.AssertNotCalled(t, "GetTweetsByUsersID", mock.Anything)
 }
 
 func TestGetTweetsByUsersID_InvalidUserID(t *testing.T) {
@@ -207,7 +314,46 @@ func TestGetTweetsByUsersID_InvalidUserID(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotEmpty(t, response.Error)
 
-	mockUseCase.AssertNotCalled(t, "GetTweetsByUsersID")
+	mockUseCase.AssertNotCalled(t, "GetTweetsByUsersID", mock.Anything)
+}
+
+func TestGetTweetsByUsersID_Paging(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name             string
+		query            string
+		expectedStatus   int
+		expectedPage     int
+		expectedPageSize int
+	}{
+		{name: "largest page size", query: "&page_size=200", expectedStatus: fiber.StatusOK, expectedPage: 1, expectedPageSize: 200},
+		{name: "deepest page", query: "&page=5&page_size=200", expectedStatus: fiber.StatusOK, expectedPage: 5, expectedPageSize: 200},
+		{name: "page size too large", query: "&page_size=201", expectedStatus: fiber.StatusBadRequest},
+		{name: "page too deep", query: "&page=6&page_size=200", expectedStatus: fiber.StatusBadRequest},
+		{name: "page too deep with the default page size", query: "&page=101", expectedStatus: fiber.StatusBadRequest},
+		{name: "page that overflows", query: "&page=9223372036854775807&page_size=200", expectedStatus: fiber.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mockUseCase := setupTest()
+			if tt.expectedStatus == fiber.StatusOK {
+				mockUseCase.On("GetTweetsByUsersID", mock.Anything, []uuid.UUID{userID}, domain.TweetRange{}, tt.expectedPage, tt.expectedPageSize).
+					Return(&domain.TweetList{Tweets: []domain.Tweet{}}, nil)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/tweets/following?user_ids="+userID.String()+tt.query, nil)
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if tt.expectedStatus != fiber.StatusOK {
+				mockUseCase.AssertNotCalled(t, "GetTweetsByUsersID", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+			mockUseCase.AssertExpectations(t)
+		})
+	}
 }
 
 func TestGetTweetsByUsersID_UseCaseError(t *testing.T) {
@@ -217,7 +363,7 @@ func TestGetTweetsByUsersID_UseCaseError(t *testing.T) {
 	userID := uuid.New()
 
 	// Expectations
-	mockUseCase.On("GetTweetsByUsersID", []uuid.UUID{userID}, 1, 10).Return(nil, assert.AnError)
+	mockUseCase.On("GetTweetsByUsersID", mock.Anything, []uuid.UUID{userID}, domain.TweetRange{}, 1, 10).Return(nil, assert.AnError)
 
 	// Execute
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/tweets/following?user_ids="+userID.String(), nil)
@@ -234,4 +380,465 @@ func TestGetTweetsByUsersID_UseCaseError(t *testing.T) {
 	assert.NotEmpty(t, response.Error)
 
 	mockUseCase.AssertExpectations(t)
-} 
\ No newline at end of file
+}
+
+func TestGetTweetsByUsersID_TweetRange(t *testing.T) {
+	userID := uuid.New()
+	sinceID := uuid.New()
+	maxID := uuid.New()
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedRange  *domain.TweetRange
+		mockError      error
+		expectedStatus int
+	}{
+		{
+			name:           "since and max IDs",
+			query:          "&since_id=" + sinceID.String() + "&max_id=" + maxID.String(),
+			expectedRange:  &domain.TweetRange{SinceID: sinceID, MaxID: maxID},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "unknown since ID",
+			query:          "&since_id=" + sinceID.String(),
+			expectedRange:  &domain.TweetRange{SinceID: sinceID},
+			mockError:      domain.ErrTweetNotFound,
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:           "invalid since ID",
+			query:          "&since_id=invalid-uuid",
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:           "invalid max ID",
+			query:          "&max_id=invalid-uuid",
+			expectedStatus: fiber.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mockUseCase := setupTest()
+			if tt.expectedRange != nil {
+				mockUseCase.On("GetTweetsByUsersID", mock.Anything, []uuid.UUID{userID}, *tt.expectedRange, 1, 10).
+					Return(&domain.TweetList{Tweets: []domain.Tweet{}}, tt.mockError)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/tweets/following?user_ids="+userID.String()+tt.query, nil)
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			mockUseCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCountTweetsByUsersID(t *testing.T) {
+	userID := uuid.New()
+	sinceID := uuid.New()
+
+	tests := []struct {
+		name           string
+		query          string
+		expectCount    bool
+		mockCount      int
+		mockError      error
+		expectedStatus int
+		expectedCount  int
+	}{
+		{
+			name:           "new tweets since a tweet",
+			query:          "?user_ids=" + userID.String() + "&since_id=" + sinceID.String(),
+			expectCount:    true,
+			mockCount:      3,
+			expectedStatus: fiber.StatusOK,
+			expectedCount:  3,
+		},
+		{
+			name:           "unknown since ID",
+			query:          "?user_ids=" + userID.String() + "&since_id=" + sinceID.String(),
+			expectCount:    true,
+			mockError:      domain.ErrTweetNotFound,
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:           "count error",
+			query:          "?user_ids=" + userID.String() + "&since_id=" + sinceID.String(),
+			expectCount:    true,
+			mockError:      assert.AnError,
+			expectedStatus: fiber.StatusInternalServerError,
+		},
+		{
+			name:           "missing user IDs",
+			query:          "?since_id=" + sinceID.String(),
+			expectedStatus: fiber.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mockUseCase := setupTest()
+			if tt.expectCount {
+				mockUseCase.On("CountTweetsByUsersID", mock.Anything, []uuid.UUID{userID}, domain.TweetRange{SinceID: sinceID}).
+					Return(tt.mockCount, tt.mockError)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/tweets/following/count"+tt.query, nil)
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if tt.expectedStatus == fiber.StatusOK {
+				var response CountResponse
+				assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+				assert.Equal(t, tt.expectedCount, response.Count)
+			}
+			mockUseCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandleUserEvent(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name           string
+		body           string
+		unsigned       bool
+		signedWith     []byte
+		expectDelete   bool
+		mockError      error
+		expectedStatus int
+	}{
+		{
+			name:           "user deleted",
+			body:           `{"id":"event1","type":"user.deleted","user_id":"` + userID.String() + `"}`,
+			expectDelete:   true,
+			expectedStatus: fiber.StatusNoContent,
+		},
+		{
+			name:           "user deleted failure is retried",
+			body:           `{"id":"event1","type":"user.deleted","user_id":"` + userID.String() + `"}`,
+			expectDelete:   true,
+			mockError:      assert.AnError,
+			expectedStatus: fiber.StatusInternalServerError,
+		},
+		{
+			name:           "unknown event type is acknowledged",
+			body:           `{"id":"event2","type":"user.renamed","user_id":"` + userID.String() + `"}`,
+			expectedStatus: fiber.StatusNoContent,
+		},
+		{
+			name:           "invalid body",
+			body:           `{"id":"event3","type":"user.deleted","user_id":"invalid-uuid"}`,
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:           "unsigned event",
+			body:           `{"id":"event1","type":"user.deleted","user_id":"` + userID.String() + `"}`,
+			unsigned:       true,
+			expectedStatus: fiber.StatusUnauthorized,
+		},
+		{
+			name:           "event signed with another secret",
+			body:           `{"id":"event1","type":"user.deleted","user_id":"` + userID.String() + `"}`,
+			signedWith:     []byte("other"),
+			expectedStatus: fiber.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mockUseCase := setupTest()
+			if tt.expectDelete {
+				mockUseCase.On("DeleteUserTweets", mock.Anything, userID).Return(tt.mockError)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/internal/events", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if !tt.unsigned {
+				secret := testEventSecret
+				if tt.signedWith != nil {
+					secret = tt.signedWith
+				}
+				eventauth.Sign(req, secret, []byte(tt.body))
+			}
+
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			mockUseCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetTweetsByUser(t *testing.T) {
+	userID := uuid.New()
+	tweets := []domain.Tweet{{ID: uuid.New(), UserID: userID, Content: "Hello"}}
+
+	tests := []struct {
+		name           string
+		path           string
+		expectCall     bool
+		cursor         string
+		limit          int
+		mockTweets     []domain.Tweet
+		mockCursor     string
+		mockError      error
+		expectedStatus int
+	}{
+		{
+			name:           "first page",
+			path:           "/api/v1/tweets/users/" + userID.String(),
+			expectCall:     true,
+			limit:          100,
+			mockTweets:     tweets,
+			mockCursor:     "next",
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "page after cursor",
+			path:           "/api/v1/tweets/users/" + userID.String() + "?cursor=abc&limit=5",
+			expectCall:     true,
+			cursor:         "abc",
+			limit:          5,
+			mockTweets:     []domain.Tweet{},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "invalid cursor",
+			path:           "/api/v1/tweets/users/" + userID.String() + "?cursor=abc",
+			expectCall:     true,
+			cursor:         "abc",
+			limit:          100,
+			mockError:      domain.ErrInvalidCursor,
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:           "invalid user ID",
+			path:           "/api/v1/tweets/users/invalid-uuid",
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:           "invalid limit",
+			path:           "/api/v1/tweets/users/" + userID.String() + "?limit=5000",
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:           "use case error",
+			path:           "/api/v1/tweets/users/" + userID.String(),
+			expectCall:     true,
+			limit:          100,
+			mockError:      assert.AnError,
+			expectedStatus: fiber.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mockUseCase := setupTest()
+			if tt.expectCall {
+				mockUseCase.On("GetTweetsByUser", mock.Anything, userID, tt.cursor, tt.limit).Return(tt.mockTweets, tt.mockCursor, tt.mockError)
+			}
+
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, tt.path, nil))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			if tt.expectedStatus == fiber.StatusOK {
+				var response UserTweetsResponse
+				assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+				assert.Len(t, response.Tweets, len(tt.mockTweets))
+				assert.Equal(t, tt.mockCursor, response.NextCursor)
+			}
+
+			mockUseCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetProfileTweets(t *testing.T) {
+	userID := uuid.New()
+	cursor := uuid.New()
+	profile := &domain.ProfileTweets{
+		PinnedTweet: &domain.Tweet{ID: uuid.New(), UserID: userID, Content: "Pinned"},
+		Tweets:      []domain.Tweet{{ID: uuid.New(), UserID: userID, Content: "Hello"}},
+		NextCursor:  "next",
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		expectCall     bool
+		tab            string
+		cursor         uuid.UUID
+		limit          int
+		mockError      error
+		expectedStatus int
+	}{
+		{
+			name:           "first page of the default tab",
+			expectCall:     true,
+			tab:            domain.ProfileTabTweets,
+			limit:          20,
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "page after cursor",
+			query:          "?tab=tweets&cursor=" + cursor.String() + "&limit=5",
+			expectCall:     true,
+			tab:            domain.ProfileTabTweets,
+			cursor:         cursor,
+			limit:          5,
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "unknown tab",
+			query:          "?tab=retweets",
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:           "invalid cursor",
+			query:          "?cursor=abc",
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:           "invalid limit",
+			query:          "?limit=500",
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:           "unknown cursor",
+			query:          "?cursor=" + cursor.String(),
+			expectCall:     true,
+			tab:            domain.ProfileTabTweets,
+			cursor:         cursor,
+			limit:          20,
+			mockError:      domain.ErrTweetNotFound,
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:           "unsupported tab",
+			query:          "?tab=likes",
+			expectCall:     true,
+			tab:            domain.ProfileTabLikes,
+			limit:          20,
+			mockError:      domain.ErrProfileTabNotSupported,
+			expectedStatus: fiber.StatusNotImplemented,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mockUseCase := setupTest()
+			if tt.expectCall {
+				if tt.mockError != nil {
+					mockUseCase.On("GetProfileTweets", mock.Anything, userID, tt.tab, tt.cursor, tt.limit).Return(nil, tt.mockError)
+				} else {
+					mockUseCase.On("GetProfileTweets", mock.Anything, userID, tt.tab, tt.cursor, tt.limit).Return(profile, nil)
+				}
+			}
+
+			path := "/api/v1/users/" + userID.String() + "/tweets" + tt.query
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			if tt.expectedStatus == fiber.StatusOK {
+				var response domain.ProfileTweets
+				assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+				assert.Equal(t, profile.PinnedTweet.ID, response.PinnedTweet.ID)
+				assert.Len(t, response.Tweets, 1)
+				assert.Equal(t, "next", response.NextCursor)
+			}
+
+			mockUseCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPinTweet(t *testing.T) {
+	userID := uuid.New()
+	tweetID := uuid.New()
+
+	tests := []struct {
+		name           string
+		userID         string
+		body           string
+		expectCall     bool
+		mockError      error
+		expectedStatus int
+	}{
+		{
+			name:           "pins the tweet",
+			userID:         userID.String(),
+			body:           `{"tweet_id":"` + tweetID.String() + `"}`,
+			expectCall:     true,
+			expectedStatus: fiber.StatusNoContent,
+		},
+		{
+			name:           "missing user",
+			body:           `{"tweet_id":"` + tweetID.String() + `"}`,
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:           "missing tweet ID",
+			userID:         userID.String(),
+			body:           `{}`,
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:           "unknown tweet",
+			userID:         userID.String(),
+			body:           `{"tweet_id":"` + tweetID.String() + `"}`,
+			expectCall:     true,
+			mockError:      domain.ErrTweetNotFound,
+			expectedStatus: fiber.StatusNotFound,
+		},
+		{
+			name:           "tweet of another user",
+			userID:         userID.String(),
+			body:           `{"tweet_id":"` + tweetID.String() + `"}`,
+			expectCall:     true,
+			mockError:      domain.ErrNotTweetAuthor,
+			expectedStatus: fiber.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mockUseCase := setupTest()
+			if tt.expectCall {
+				mockUseCase.On("PinTweet", mock.Anything, userID, tweetID).Return(tt.mockError)
+			}
+
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/users/me/pinned-tweet", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-User-ID", tt.userID)
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			mockUseCase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUnpinTweet(t *testing.T) {
+	app, mockUseCase := setupTest()
+	userID := uuid.New()
+	mockUseCase.On("UnpinTweet", mock.Anything, userID).Return(nil)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/users/me/pinned-tweet", nil)
+	req.Header.Set("X-User-ID", userID.String())
+	resp, err := app.Test(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+	mockUseCase.AssertExpectations(t)
+}
@@ -0,0 +1,39 @@
+package http
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/lisandro/challenge/pkg/eventauth"
+)
+
+// requestContext gives each request a context that is canceled after timeout, so that a slow
+// dependency does not hold a request, and the queries it started, forever. Handlers pass
+// c.UserContext() down to the use cases and repositories.
+//
+// fasthttp does not report clients that disconnect, so the deadline is what stops the work
+// of an abandoned request.
+func requestContext(timeout time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.UserContext(), timeout)
+		defer cancel()
+
+		c.SetUserContext(ctx)
+		return c.Next()
+	}
+}
+
+// verifyEvent answers 401 to events that are not signed with secret, so that the internal
+// routes only act on events sent by the other services
+func verifyEvent(secret []byte) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := eventauth.Verify(secret, c.Get(eventauth.TimestampHeader), c.Get(eventauth.SignatureHeader), c.Body())
+		if err != nil {
+			log.Printf("Rejecting event from %s: %v", c.IP(), err)
+			return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{Error: "invalid event signature"})
+		}
+		return c.Next()
+	}
+}
@@ -2,11 +2,14 @@ package http
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	"github.com/gofiber/swagger"
+	"github.com/lisandro/challenge/pkg/health"
 )
 
-// RegisterRoutes registers all the tweet routes
-func RegisterRoutes(app *fiber.App, handler *Handler) {
+// RegisterRoutes registers all the tweet routes. Internal events must be signed with
+// eventSecret.
+func RegisterRoutes(app *fiber.App, handler *Handler, eventSecret []byte) {
 	// Swagger documentation
 	app.Get("/swagger/*", swagger.HandlerDefault)
 
@@ -27,11 +30,14 @@ func RegisterRoutes(app *fiber.App, handler *Handler) {
 	tweets.Post("", handler.CreateTweet)
 
 	// @Summary Get tweets by user IDs
-	// @Description Get tweets from a list of user IDs with pagination
+	// @Description Get tweets from a list of user IDs with pagination, newest first. since_id and max_id limit the tweets to those newer or older than a tweet.
+	// @Description When the search index is unavailable, the tweets are read from storage and a Warning header is set.
 	// @Tags tweets
 	// @Accept json
 	// @Produce json
 	// @Param user_ids query []string true "List of user IDs"
+	// @Param since_id query string false "Only return tweets newer than this tweet"
+	// @Param max_id query string false "Only return tweets older than this tweet"
 	// @Param page query int false "Page number (default: 1)"
 	// @Param page_size query int false "Page size (default: 10)"
 	// @Success 200 {array} Tweet
@@ -40,10 +46,101 @@ func RegisterRoutes(app *fiber.App, handler *Handler) {
 	// @Router /api/v1/tweets/following [get]
 	tweets.Get("/following", handler.GetTweetsByUsersID)
 
-	// Health check endpoint
-	app.Get("/health", func(c *fiber.Ctx) error {
-		return c.JSON(fiber.Map{
-			"status": "ok",
-		})
-	})
-} 
\ No newline at end of file
+	// @Summary Count tweets by user IDs
+	// @Description Count the tweets from a list of user IDs without returning them. With since_id, this is the number of tweets written since that tweet.
+	// @Tags tweets
+	// @Accept json
+	// @Produce json
+	// @Param user_ids query []string true "List of user IDs"
+	// @Param since_id query string false "Only count tweets newer than this tweet"
+	// @Param max_id query string false "Only count tweets older than this tweet"
+	// @Success 200 {object} CountResponse
+	// @Failure 400 {object} ErrorResponse
+	// @Failure 500 {object} ErrorResponse
+	// @Router /api/v1/tweets/following/count [get]
+	tweets.Get("/following/count", handler.CountTweetsByUsersID)
+
+	// @Summary Get the tweets of a user
+	// @Description Get every tweet written by a user, oldest first, paginated with a cursor. Tweets are read from storage, not from the search index.
+	// @Tags tweets
+	// @Accept json
+	// @Produce json
+	// @Param userID path string true "User ID"
+	// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+	// @Param limit query int false "Page size (default: 100, max: 1000)"
+	// @Success 200 {object} UserTweetsResponse
+	// @Failure 400 {object} ErrorResponse
+	// @Failure 500 {object} ErrorResponse
+	// @Router /api/v1/tweets/users/{userID} [get]
+	tweets.Get("/users/:userID", handler.GetTweetsByUser)
+
+	users := api.Group("/users")
+
+	// @Summary Pin a tweet
+	// @Description Pin one of the tweets of the current user to their profile, replacing the tweet pinned before
+	// @Tags users
+	// @Accept json
+	// @Produce json
+	// @Param X-User-ID header string true "ID of the current user"
+	// @Param pin body PinTweetRequest true "Tweet to pin"
+	// @Success 204
+	// @Failure 400 {object} ErrorResponse
+	// @Failure 403 {object} ErrorResponse
+	// @Failure 404 {object} ErrorResponse
+	// @Failure 500 {object} ErrorResponse
+	// @Router /api/v1/users/me/pinned-tweet [put]
+	users.Put("/me/pinned-tweet", handler.PinTweet)
+
+	// @Summary Unpin the pinned tweet
+	// @Description Remove the pinned tweet from the profile of the current user. Succeeds when nothing is pinned.
+	// @Tags users
+	// @Produce json
+	// @Param X-User-ID header string true "ID of the current user"
+	// @Success 204
+	// @Failure 400 {object} ErrorResponse
+	// @Failure 500 {object} ErrorResponse
+	// @Router /api/v1/users/me/pinned-tweet [delete]
+	users.Delete("/me/pinned-tweet", handler.UnpinTweet)
+
+	// @Summary Get the profile timeline of a user
+	// @Description Get a page of the tweets shown on the profile of a user, newest first. The first page also carries the pinned tweet, which is then left out of its tweets.
+	// @Description The replies, media and likes tabs are not supported yet.
+	// @Tags users
+	// @Accept json
+	// @Produce json
+	// @Param userID path string true "User ID"
+	// @Param tab query string false "Profile tab: tweets (default), replies, media or likes"
+	// @Param cursor query string false "Cursor returned as next_cursor by the previous page"
+	// @Param limit query int false "Page size (default: 20, max: 100)"
+	// @Success 200 {object} domain.ProfileTweets
+	// @Failure 400 {object} ErrorResponse
+	// @Failure 500 {object} ErrorResponse
+	// @Failure 501 {object} ErrorResponse
+	// @Router /api/v1/users/{userID}/tweets [get]
+	users.Get("/:userID/tweets", handler.GetProfileTweets)
+
+	// Events published by the user service
+	// @Summary Handle a user service event
+	// @Description Receive an event published by the user service. A user.deleted event removes every tweet of the user. Events may be delivered more than once.
+	// @Description Events must be signed with the secret shared by the services.
+	// @Tags internal
+	// @Accept json
+	// @Produce json
+	// @Param event body domain.UserEvent true "User event"
+	// @Param X-Event-Timestamp header string true "Unix time at which the event was signed"
+	// @Param X-Event-Signature header string true "HMAC-SHA256 of the timestamp and body, as sha256=<hex>"
+	// @Success 204
+	// @Failure 400 {object} ErrorResponse
+	// @Failure 401 {object} ErrorResponse
+	// @Failure 500 {object} ErrorResponse
+	// @Router /api/v1/internal/events [post]
+	api.Post("/internal/events", verifyEvent(eventSecret), handler.HandleUserEvent)
+}
+
+// RegisterHealthRoutes registers the liveness and readiness probes. /health is the readiness
+// probe under the name it had before.
+func RegisterHealthRoutes(app *fiber.App, checker *health.Checker) {
+	app.Get("/livez", adaptor.HTTPHandlerFunc(checker.Livez))
+	app.Get("/readyz", adaptor.HTTPHandlerFunc(checker.Readyz))
+	app.Get("/health", adaptor.HTTPHandlerFunc(checker.Readyz))
+}
@@ -1,19 +1,29 @@
 package http
 
 import (
+	"context"
 	"log"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/gofiber/fiber/v2/middleware/expvar"
 	"github.com/gofiber/fiber/v2/middleware/logger"
+	"github.com/lisandro/challenge/pkg/health"
+	"github.com/lisandro/challenge/pkg/metrics/fibermetrics"
 	"github.com/lisandro/challenge/services/tweet-service/internal/domain"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 type Server struct {
 	app         *fiber.App
 	tweetHandler *Handler
+	checker     *health.Checker
 }
 
-func NewServer(tu domain.TweetUseCase) *Server {
+// NewServer creates the HTTP server. Requests are canceled after requestTimeout, checker
+// tells whether the service is ready and eventSecret signs the events of other services.
+func NewServer(tu domain.TweetUseCase, checker *health.Checker, requestTimeout time.Duration, eventSecret []byte) *Server {
 	// Create Fiber app with custom config
 	app := fiber.New(fiber.Config{
 		DisableStartupMessage: true,
@@ -26,19 +36,44 @@ func NewServer(tu domain.TweetUseCase) *Server {
 		TimeZone:   "Local",
 	}))
 	
+	// Record the rate, errors and duration of requests, served on /metrics
+	app.Use(fibermetrics.New())
+	app.Get("/metrics", adaptor.HTTPHandler(promhttp.Handler()))
+	
+	// Cancel the downstream work of requests that take too long
+	app.Use(requestContext(requestTimeout))
+	
+	// Serve the indexing stats on /debug/vars
+	app.Use(expvar.New())
+	
 	// Create handlers with dependencies
 	handler := NewHandler(tu)
 	
 	// Register routes
-	RegisterRoutes(app, handler)
+	RegisterRoutes(app, handler, eventSecret)
+	RegisterHealthRoutes(app, checker)
 	
 	return &Server{
 		app:         app,
 		tweetHandler: handler,
+		checker:     checker,
 	}
 }
 
 func (s *Server) Start(address string) error {
 	log.Printf("Starting server on %s", address)
 	return s.app.Listen(address)
-} 
\ No newline at end of file
+}
+
+// Shutdown stops the server gracefully. The readiness probe fails right away while requests
+// are still served for drainDelay, so that load balancers stop sending new ones, and the
+// requests in flight are then waited for until ctx is done.
+func (s *Server) Shutdown(ctx context.Context, drainDelay time.Duration) error {
+	s.checker.StartShutdown()
+
+	select {
+	case <-time.After(drainDelay):
+	case <-ctx.Done():
+	}
+	return s.app.ShutdownWithContext(ctx)
+}
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Types of the user service events handled by the tweet service
+const (
+	EventUserDeleted = "user.deleted"
+)
+
+// UserEvent is an event published by the user service
+type UserEvent struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	UserID     uuid.UUID `json:"user_id"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Types of the events published by the tweet service
+const (
+	EventTweetCreated = "tweet.created"
+)
+
+// TweetEvent is a change to a tweet that other services react to
+type TweetEvent struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	Tweet      Tweet     `json:"tweet"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// EventPublisher delivers tweet events to the services interested in them
+type EventPublisher interface {
+	Publish(event TweetEvent)
+}
@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"context"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,18 +17,89 @@ type Tweet struct {
 	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
 }
 
+// ErrTweetNotFound is returned when a tweet, such as a bound of a TweetRange, does not exist
+var ErrTweetNotFound = errors.New("tweet not found")
+
+// ErrNotTweetAuthor is returned when a user acts on a tweet that someone else wrote
+var ErrNotTweetAuthor = errors.New("tweet was written by another user")
+
+// ErrInvalidCursor is returned when a page cursor was not returned by an earlier page
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrProfileTabNotSupported is returned for profile tabs whose content does not exist yet
+var ErrProfileTabNotSupported = errors.New("profile tab is not supported")
+
+// Tabs of a user's profile timeline
+const (
+	ProfileTabTweets  = "tweets"
+	ProfileTabReplies = "replies"
+	ProfileTabMedia   = "media"
+	ProfileTabLikes   = "likes"
+)
+
+// ProfileTweets is a page of a user's profile timeline
+type ProfileTweets struct {
+	// PinnedTweet is the tweet the user pinned. It is only set on the first page.
+	PinnedTweet *Tweet  `json:"pinned_tweet,omitempty"`
+	Tweets      []Tweet `json:"tweets"`
+	// NextCursor is the cursor of the next page, empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+	// Degraded reports a page read from storage because the search index is unavailable
+	Degraded bool `json:"degraded,omitempty"`
+}
+
+// TweetList is a page of tweets from many users
+type TweetList struct {
+	Tweets []Tweet
+	// Degraded reports tweets read from storage because the search index is unavailable.
+	// They are the same tweets, but reading them is slower.
+	Degraded bool
+}
+
+// TweetRange limits a list of tweets, sorted newest first, to the tweets between two tweets.
+// Tweets created at the same time are ordered by ID. A uuid.Nil bound is not applied.
+type TweetRange struct {
+	// SinceID keeps only the tweets newer than this tweet
+	SinceID uuid.UUID
+	// MaxID keeps only the tweets older than this tweet
+	MaxID uuid.UUID
+}
+
 // TweetRepository defines the interface for tweet data operations
 type TweetRepository interface {
-	Create(tweet *Tweet) error
+	Create(ctx context.Context, tweet *Tweet) error
+	GetByID(ctx context.Context, id uuid.UUID) (*Tweet, error)
+	GetByUser(ctx context.Context, userID uuid.UUID, cursor string, limit int) ([]Tweet, string, error)
+	// GetByUsers returns up to limit tweets of many users within a range, newest first. It
+	// returns ErrTweetNotFound when a bound of the range does not exist.
+	GetByUsers(ctx context.Context, userIDs []uuid.UUID, tweetRange TweetRange, limit int) ([]Tweet, error)
+	CountByUsers(ctx context.Context, userIDs []uuid.UUID, tweetRange TweetRange) (int, error)
+	DeleteByUser(ctx context.Context, userID uuid.UUID) (int, error)
+}
+
+// PinRepository stores the tweet each user pinned to their profile
+type PinRepository interface {
+	// GetPinnedTweetID returns the pinned tweet of a user, or uuid.Nil when there is none
+	GetPinnedTweetID(ctx context.Context, userID uuid.UUID) (uuid.UUID, error)
+	PinTweet(ctx context.Context, userID, tweetID uuid.UUID) error
+	UnpinTweet(ctx context.Context, userID uuid.UUID) error
 }
 
 type SearchRepository interface {
-	GetTweetsByUsersID(userIDs []uuid.UUID, page, pageSize int) ([]Tweet, error)
-	IndexTweet(tweet *Tweet) error
+	GetTweetsByUsersID(ctx context.Context, userIDs []uuid.UUID, tweetRange TweetRange, page, pageSize int) ([]Tweet, error)
+	CountTweetsByUsersID(ctx context.Context, userIDs []uuid.UUID, tweetRange TweetRange) (int, error)
+	IndexTweet(ctx context.Context, tweet *Tweet) error
+	DeleteByUser(ctx context.Context, userID uuid.UUID) error
 }
 
 // TweetUseCase defines the interface for tweet business logic
 type TweetUseCase interface {
-	CreateTweet(userID uuid.UUID, content string) (*Tweet, error)
-	GetTweetsByUsersID(userIDs []uuid.UUID, page, pageSize int) ([]Tweet, error)
+	CreateTweet(ctx context.Context, userID uuid.UUID, content string) (*Tweet, error)
+	GetTweetsByUsersID(ctx context.Context, userIDs []uuid.UUID, tweetRange TweetRange, page, pageSize int) (*TweetList, error)
+	CountTweetsByUsersID(ctx context.Context, userIDs []uuid.UUID, tweetRange TweetRange) (int, error)
+	GetTweetsByUser(ctx context.Context, userID uuid.UUID, cursor string, limit int) ([]Tweet, string, error)
+	GetProfileTweets(ctx context.Context, userID uuid.UUID, tab string, cursor uuid.UUID, limit int) (*ProfileTweets, error)
+	PinTweet(ctx context.Context, userID, tweetID uuid.UUID) error
+	UnpinTweet(ctx context.Context, userID uuid.UUID) error
+	DeleteUserTweets(ctx context.Context, userID uuid.UUID) error
 } 
\ No newline at end of file
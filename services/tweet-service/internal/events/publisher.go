@@ -0,0 +1,172 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lisandro/challenge/pkg/eventauth"
+	"github.com/lisandro/challenge/pkg/metrics"
+	"github.com/lisandro/challenge/services/tweet-service/internal/domain"
+)
+
+// Subscriber is a service that receives tweet events over HTTP
+type Subscriber struct {
+	Name string
+	URL  string
+}
+
+// Config holds the delivery settings of a Publisher
+type Config struct {
+	// QueueSize is the number of events waiting for delivery before new ones are dropped
+	QueueSize   int
+	MaxAttempts int
+	RetryDelay  time.Duration
+	Timeout     time.Duration
+	// SigningSecret signs every delivery, so that subscribers can tell it comes from this service
+	SigningSecret []byte
+}
+
+// Publisher delivers tweet events to every subscriber in the background. Delivery is best
+// effort: an event is retried up to MaxAttempts times and is lost if the queue is full or
+// cannot be delivered while the service stops, so subscribers must not rely on receiving
+// every event.
+type Publisher struct {
+	subscribers []Subscriber
+	config      Config
+	client      *http.Client
+	queue       chan domain.TweetEvent
+}
+
+// NewPublisher creates a new event publisher. Events are delivered once Run is started.
+func NewPublisher(subscribers []Subscriber, config Config) *Publisher {
+	return &Publisher{
+		subscribers: subscribers,
+		config:      config,
+		client:      &http.Client{Timeout: config.Timeout},
+		queue:       make(chan domain.TweetEvent, config.QueueSize),
+	}
+}
+
+// ParseSubscribers parses a comma separated list of name=url pairs
+func ParseSubscribers(value string) ([]Subscriber, error) {
+	var subscribers []Subscriber
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, url, ok := strings.Cut(pair, "=")
+		if !ok || name == "" || url == "" {
+			return nil, fmt.Errorf("invalid subscriber %q, expected name=url", pair)
+		}
+		subscribers = append(subscribers, Subscriber{Name: name, URL: url})
+	}
+	return subscribers, nil
+}
+
+// Publish queues an event for delivery without blocking. The event is dropped when the queue is full.
+func (p *Publisher) Publish(event domain.TweetEvent) {
+	select {
+	case p.queue <- event:
+	default:
+		log.Printf("Event queue is full, dropping event %s", event.ID)
+	}
+}
+
+// Run delivers queued events until ctx is done, and then makes one attempt at delivering
+// the events left in the queue
+func (p *Publisher) Run(ctx context.Context) {
+	for {
+		// Stopping takes priority, since events delivered by Run are given up on once ctx is done
+		if ctx.Err() != nil {
+			p.drain()
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+		case event := <-p.queue:
+			for _, subscriber := range p.subscribers {
+				p.deliverWithRetry(ctx, subscriber, event)
+			}
+		}
+	}
+}
+
+// drain delivers the events left in the queue once, giving up on the rest after Timeout
+func (p *Publisher) drain() {
+	ctx, cancel := context.WithTimeout(context.Background(), p.config.Timeout)
+	defer cancel()
+
+	for {
+		select {
+		case event := <-p.queue:
+			if ctx.Err() != nil {
+				log.Printf("Dropping %d queued events on shutdown", len(p.queue)+1)
+				return
+			}
+			for _, subscriber := range p.subscribers {
+				if err := p.deliver(ctx, subscriber, event); err != nil {
+					log.Printf("Failed to deliver event %s to %s on shutdown: %v", event.ID, subscriber.Name, err)
+				}
+			}
+		default:
+			return
+		}
+	}
+}
+
+// deliverWithRetry posts an event to a subscriber, retrying failed attempts after RetryDelay
+func (p *Publisher) deliverWithRetry(ctx context.Context, subscriber Subscriber, event domain.TweetEvent) {
+	for attempt := 1; ; attempt++ {
+		err := p.deliver(ctx, subscriber, event)
+		if err == nil {
+			return
+		}
+		if attempt >= p.config.MaxAttempts {
+			log.Printf("Giving up delivering event %s to %s after %d attempts: %v", event.ID, subscriber.Name, attempt, err)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(p.config.RetryDelay):
+		}
+	}
+}
+
+// deliver posts an event to a subscriber
+func (p *Publisher) deliver(ctx context.Context, subscriber Subscriber, event domain.TweetEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscriber.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	eventauth.Sign(req, p.config.SigningSecret, body)
+
+	start := time.Now()
+	resp, err := p.client.Do(req)
+	if err != nil {
+		metrics.ObserveClientRequest(subscriber.Name, start, 0, err)
+		return err
+	}
+	metrics.ObserveClientRequest(subscriber.Name, start, resp.StatusCode, nil)
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lisandro/challenge/pkg/eventauth"
+	"github.com/lisandro/challenge/services/tweet-service/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublisher_DeliversWithRetry(t *testing.T) {
+	tweet := domain.Tweet{ID: uuid.New(), UserID: uuid.New(), Content: "hello"}
+	received := make(chan domain.TweetEvent, 1)
+	var attempts int32
+	secret := []byte("secret")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Fail the first attempt to exercise the retry
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		// Subscribers can tell the event comes from this service
+		require.NoError(t, eventauth.VerifyRequest(secret, r))
+		var event domain.TweetEvent
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		received <- event
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	publisher := NewPublisher([]Subscriber{{Name: "timeline", URL: server.URL}}, Config{
+		QueueSize:     10,
+		MaxAttempts:   3,
+		RetryDelay:    time.Millisecond,
+		Timeout:       time.Second,
+		SigningSecret: secret,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go publisher.Run(ctx)
+
+	publisher.Publish(domain.TweetEvent{ID: tweet.ID.String(), Type: domain.EventTweetCreated, Tweet: tweet})
+
+	select {
+	case event := <-received:
+		assert.Equal(t, domain.EventTweetCreated, event.Type)
+		assert.Equal(t, tweet.ID, event.Tweet.ID)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+	case <-time.After(time.Second):
+		t.Fatal("event was not delivered")
+	}
+}
+
+func TestPublisher_DeliversQueuedEventsWhenStopped(t *testing.T) {
+	received := make(chan domain.TweetEvent, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event domain.TweetEvent
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		received <- event
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	publisher := NewPublisher([]Subscriber{{Name: "timeline", URL: server.URL}}, Config{
+		QueueSize:   10,
+		MaxAttempts: 3,
+		RetryDelay:  time.Millisecond,
+		Timeout:     time.Second,
+	})
+	publisher.Publish(domain.TweetEvent{ID: "event1"})
+	publisher.Publish(domain.TweetEvent{ID: "event2"})
+
+	// Run stops right away and only delivers what is left in the queue
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	publisher.Run(ctx)
+
+	require.Len(t, received, 2)
+	assert.Equal(t, "event1", (<-received).ID)
+	assert.Equal(t, "event2", (<-received).ID)
+}
+
+func TestPublisher_DropsWhenQueueIsFull(t *testing.T) {
+	publisher := NewPublisher(nil, Config{QueueSize: 1})
+
+	publisher.Publish(domain.TweetEvent{ID: "event1"})
+	publisher.Publish(domain.TweetEvent{ID: "event2"})
+
+	assert.Len(t, publisher.queue, 1)
+	assert.Equal(t, "event1", (<-publisher.queue).ID)
+}
+
+func TestParseSubscribers(t *testing.T) {
+	subscribers, err := ParseSubscribers("timeline=http://localhost:8082/api/v1/internal/tweet-events, ")
+	assert.NoError(t, err)
+	assert.Equal(t, []Subscriber{{Name: "timeline", URL: "http://localhost:8082/api/v1/internal/tweet-events"}}, subscribers)
+
+	_, err = ParseSubscribers("timeline")
+	assert.Error(t, err)
+}
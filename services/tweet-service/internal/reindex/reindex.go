@@ -0,0 +1,101 @@
+// Package reindex rebuilds the search index of tweets from DynamoDB, the source of truth
+package reindex
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/lisandro/challenge/services/tweet-service/internal/domain"
+)
+
+// catchUpMargin is subtracted from the start of a reindex when catching up with the tweets
+// created while it ran, since created_at only has a precision of seconds
+const catchUpMargin = time.Minute
+
+// Scanner reads the tweets to index. since limits the scan to tweets created at or after
+// it, unless it is zero. handle may be called from several goroutines at once.
+type Scanner interface {
+	Scan(ctx context.Context, segments int, since time.Time, handle func([]domain.Tweet) error) error
+}
+
+// Indexer manages the versioned indices behind the alias that searches use
+type Indexer interface {
+	Alias() string
+	EnsureTemplate(ctx context.Context) error
+	CreateIndex(ctx context.Context, name string) error
+	BulkIndex(ctx context.Context, index string, tweets []domain.Tweet) error
+	SwapAlias(ctx context.Context, index string) ([]string, error)
+}
+
+// Config configures a reindex
+type Config struct {
+	// Segments is the number of parallel segments the table is scanned in
+	Segments int
+	// Now returns the current time; it defaults to time.Now
+	Now func() time.Time
+}
+
+// Result describes a finished reindex
+type Result struct {
+	// Index is the new index the alias points to
+	Index string
+	// Indexed is the number of tweets indexed, including those indexed again while catching up
+	Indexed int64
+	// Previous are the indices the alias pointed to before. They are not deleted, so that
+	// the alias can be swapped back to them.
+	Previous []string
+}
+
+// Run indexes every tweet into a new versioned index and then points the alias at it.
+// Tweets created while the table was scanned are indexed by the search repository into the
+// previous index, so they are scanned again once the alias has been swapped.
+func Run(ctx context.Context, scanner Scanner, indexer Indexer, cfg Config) (*Result, error) {
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	started := cfg.Now().UTC()
+	// Rollovers number the next indices from the suffix of the first one
+	index := fmt.Sprintf("%s-%s-000001", indexer.Alias(), started.Format("20060102150405"))
+
+	if err := indexer.EnsureTemplate(ctx); err != nil {
+		return nil, err
+	}
+	if err := indexer.CreateIndex(ctx, index); err != nil {
+		return nil, err
+	}
+
+	var indexed atomic.Int64
+	bulk := func(tweets []domain.Tweet) error {
+		if err := indexer.BulkIndex(ctx, index, tweets); err != nil {
+			return err
+		}
+		if total := indexed.Add(int64(len(tweets))); total/10000 != (total-int64(len(tweets)))/10000 {
+			log.Printf("Reindex: indexed %d tweets into %s", total, index)
+		}
+		return nil
+	}
+
+	log.Printf("Reindex: scanning tweets into %s with %d segments", index, cfg.Segments)
+	if err := scanner.Scan(ctx, cfg.Segments, time.Time{}, bulk); err != nil {
+		return nil, fmt.Errorf("failed to index tweets into %s: %w", index, err)
+	}
+
+	previous, err := indexer.SwapAlias(ctx, index)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Reindex: alias %s now points to %s", indexer.Alias(), index)
+
+	if err := scanner.Scan(ctx, cfg.Segments, started.Add(-catchUpMargin), bulk); err != nil {
+		return nil, fmt.Errorf("failed to index tweets created during the reindex into %s: %w", index, err)
+	}
+
+	return &Result{
+		Index:    index,
+		Indexed:  indexed.Load(),
+		Previous: previous,
+	}, nil
+}
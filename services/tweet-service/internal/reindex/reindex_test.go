@@ -0,0 +1,119 @@
+package reindex
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lisandro/challenge/services/tweet-service/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeScanner struct {
+	tweets []domain.Tweet
+	scans  []time.Time
+}
+
+func (s *fakeScanner) Scan(ctx context.Context, segments int, since time.Time, handle func([]domain.Tweet) error) error {
+	s.scans = append(s.scans, since)
+	for _, tweet := range s.tweets {
+		if !since.IsZero() && tweet.CreatedAt.Before(since) {
+			continue
+		}
+		if err := handle([]domain.Tweet{tweet}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type fakeIndexer struct {
+	mu       sync.Mutex
+	calls    []string
+	indices  map[string][]uuid.UUID
+	alias    []string
+	bulkErr  error
+	template error
+}
+
+func newFakeIndexer(current ...string) *fakeIndexer {
+	return &fakeIndexer{indices: make(map[string][]uuid.UUID), alias: current}
+}
+
+func (i *fakeIndexer) Alias() string { return "tweets" }
+
+func (i *fakeIndexer) EnsureTemplate(ctx context.Context) error {
+	i.calls = append(i.calls, "template")
+	return i.template
+}
+
+func (i *fakeIndexer) CreateIndex(ctx context.Context, name string) error {
+	i.calls = append(i.calls, "create "+name)
+	i.indices[name] = nil
+	return nil
+}
+
+func (i *fakeIndexer) BulkIndex(ctx context.Context, index string, tweets []domain.Tweet) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.bulkErr != nil {
+		return i.bulkErr
+	}
+	for _, tweet := range tweets {
+		i.indices[index] = append(i.indices[index], tweet.ID)
+	}
+	return nil
+}
+
+func (i *fakeIndexer) SwapAlias(ctx context.Context, index string) ([]string, error) {
+	i.calls = append(i.calls, "swap "+index)
+	previous := i.alias
+	i.alias = []string{index}
+	return previous, nil
+}
+
+func TestRun(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	old := domain.Tweet{ID: uuid.New(), CreatedAt: now.Add(-24 * time.Hour)}
+	recent := domain.Tweet{ID: uuid.New(), CreatedAt: now.Add(time.Second)}
+	scanner := &fakeScanner{tweets: []domain.Tweet{old, recent}}
+	indexer := newFakeIndexer("tweets-20240101000000-000001")
+
+	result, err := Run(context.Background(), scanner, indexer, Config{Segments: 4, Now: func() time.Time { return now }})
+
+	require.NoError(t, err)
+	assert.Equal(t, "tweets-20240301120000-000001", result.Index)
+	assert.Equal(t, []string{"tweets-20240101000000-000001"}, result.Previous)
+	assert.Equal(t, []string{"template", "create tweets-20240301120000-000001", "swap tweets-20240301120000-000001"}, indexer.calls)
+	assert.Equal(t, []string{result.Index}, indexer.alias)
+	// The catch-up scan indexes the tweets created since the reindex started again
+	assert.Equal(t, []time.Time{{}, now.Add(-catchUpMargin)}, scanner.scans)
+	assert.Equal(t, []uuid.UUID{old.ID, recent.ID, recent.ID}, indexer.indices[result.Index])
+	assert.Equal(t, int64(3), result.Indexed)
+}
+
+func TestRun_BulkFailureKeepsAlias(t *testing.T) {
+	scanner := &fakeScanner{tweets: []domain.Tweet{{ID: uuid.New()}}}
+	indexer := newFakeIndexer("tweets-20240101000000-000001")
+	indexer.bulkErr = errors.New("mapper_parsing_exception")
+
+	result, err := Run(context.Background(), scanner, indexer, Config{Segments: 1})
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	assert.Equal(t, []string{"tweets-20240101000000-000001"}, indexer.alias)
+}
+
+func TestRun_TemplateFailure(t *testing.T) {
+	indexer := newFakeIndexer()
+	indexer.template = errors.New("unavailable")
+
+	_, err := Run(context.Background(), &fakeScanner{}, indexer, Config{Segments: 1})
+
+	assert.Error(t, err)
+	assert.Equal(t, []string{"template"}, indexer.calls)
+}
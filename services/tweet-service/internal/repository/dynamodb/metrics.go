@@ -0,0 +1,38 @@
+package dynamodb
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "dynamodb_request_duration_seconds",
+	Help:    "Time of DynamoDB calls including their retries, by operation and outcome.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"operation", "outcome"})
+
+// WithMetrics records the latency of every call of a DynamoDB client. Pass it to
+// dynamodb.NewFromConfig.
+func WithMetrics(o *dynamodb.Options) {
+	o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+		// The stack of an operation is named after the operation
+		operation := stack.ID()
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("RequestMetrics",
+			func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+				start := time.Now()
+				out, metadata, err := next.HandleInitialize(ctx, in)
+
+				outcome := "success"
+				if err != nil {
+					outcome = "error"
+				}
+				requestDuration.WithLabelValues(operation, outcome).Observe(time.Since(start).Seconds())
+				return out, metadata, err
+			}), middleware.Before)
+	})
+}
@@ -0,0 +1,79 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/lisandro/challenge/services/tweet-service/internal/domain"
+)
+
+type pinRepository struct {
+	client    *dynamodb.Client
+	tableName string
+}
+
+// NewPinRepository creates a new instance of pin repository. The table is keyed by user_id
+// and holds the tweet_id each user pinned.
+func NewPinRepository(client *dynamodb.Client, tableName string) domain.PinRepository {
+	return &pinRepository{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+func (r *pinRepository) GetPinnedTweetID(ctx context.Context, userID uuid.UUID) (uuid.UUID, error) {
+	output, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(r.tableName),
+		Key:       r.key(userID),
+	})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get pinned tweet of user %s: %w", userID, err)
+	}
+
+	value, ok := output.Item["tweet_id"].(*types.AttributeValueMemberS)
+	if !ok {
+		return uuid.Nil, nil
+	}
+	tweetID, err := uuid.Parse(value.Value)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to parse pinned tweet of user %s: %w", userID, err)
+	}
+	return tweetID, nil
+}
+
+// PinTweet pins a tweet to the profile of a user, replacing the tweet pinned before
+func (r *pinRepository) PinTweet(ctx context.Context, userID, tweetID uuid.UUID) error {
+	item := r.key(userID)
+	item["tweet_id"] = &types.AttributeValueMemberS{Value: tweetID.String()}
+
+	_, err := r.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(r.tableName),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to pin tweet %s of user %s: %w", tweetID, userID, err)
+	}
+	return nil
+}
+
+// UnpinTweet removes the pinned tweet of a user. It succeeds when nothing is pinned.
+func (r *pinRepository) UnpinTweet(ctx context.Context, userID uuid.UUID) error {
+	_, err := r.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(r.tableName),
+		Key:       r.key(userID),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to unpin tweet of user %s: %w", userID, err)
+	}
+	return nil
+}
+
+func (r *pinRepository) key(userID uuid.UUID) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"user_id": &types.AttributeValueMemberS{Value: userID.String()},
+	}
+}
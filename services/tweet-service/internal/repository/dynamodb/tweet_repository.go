@@ -2,14 +2,37 @@ package dynamodb
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
 	"github.com/lisandro/challenge/services/tweet-service/internal/domain"
 )
 
+const (
+	// userIDCreatedAtIndex is the global secondary index of tweets by author, sorted by creation time
+	userIDCreatedAtIndex = "user_id-created_at-index"
+	// userCursorSeparator separates the creation time and the ID of the last tweet of a page
+	// in the cursors of GetByUser, which are the keys to resume a query of userIDCreatedAtIndex
+	userCursorSeparator = "_"
+	// maxBatchWriteItems is the maximum number of requests DynamoDB accepts in a BatchWriteItem call
+	maxBatchWriteItems = 25
+	// maxBatchWriteAttempts bounds the retries of items left unprocessed by throttling
+	maxBatchWriteAttempts = 5
+	// maxParallelQueries bounds the queries run at once when reading the tweets of many users
+	maxParallelQueries = 8
+	// maxQueryPageSize caps the items requested per query page when reading up to a limit,
+	// which keeps large limits from overflowing the int32 of the request
+	maxQueryPageSize = 1000
+)
+
 type tweetRepository struct {
 	client    *dynamodb.Client
 	tableName string
@@ -23,8 +46,9 @@ func NewTweetRepository(client *dynamodb.Client, tableName string) domain.TweetR
 	}
 }
 
-func (r *tweetRepository) Create(tweet *domain.Tweet) error {
-	now := time.Now()
+func (r *tweetRepository) Create(ctx context.Context, tweet *domain.Tweet) error {
+	// created_at is the sort key of userIDCreatedAtIndex, so it is stored in UTC to sort as a string
+	now := time.Now().UTC()
 	tweet.CreatedAt = now
 	tweet.UpdatedAt = now
 
@@ -46,10 +70,353 @@ func (r *tweetRepository) Create(tweet *domain.Tweet) error {
 		},
 	}
 
-	_, err := r.client.PutItem(context.Background(), &dynamodb.PutItemInput{
+	_, err := r.client.PutItem(ctx, &dynamodb.PutItemInput{
 		TableName: aws.String(r.tableName),
 		Item:      item,
 	})
 
 	return err
-} 
\ No newline at end of file
+}
+
+// GetByID returns a tweet by ID, or domain.ErrTweetNotFound when it does not exist
+func (r *tweetRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Tweet, error) {
+	output, err := r.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(r.tableName),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: id.String()},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tweet %s: %w", id, err)
+	}
+	if output.Item == nil {
+		return nil, domain.ErrTweetNotFound
+	}
+
+	tweet, err := tweetFromItem(output.Item)
+	if err != nil {
+		return nil, err
+	}
+	return &tweet, nil
+}
+
+// GetByUser returns up to limit tweets written by a user, oldest first, starting after
+// cursor, together with the cursor of the next page. An empty next cursor means there are
+// no more tweets. It returns domain.ErrInvalidCursor when cursor was not returned by an
+// earlier page.
+func (r *tweetRepository) GetByUser(ctx context.Context, userID uuid.UUID, cursor string, limit int) ([]domain.Tweet, string, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(r.tableName),
+		IndexName:              aws.String(userIDCreatedAtIndex),
+		KeyConditionExpression: aws.String("user_id = :user_id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":user_id": &types.AttributeValueMemberS{Value: userID.String()},
+		},
+		Limit: aws.Int32(int32(limit)),
+	}
+	if cursor != "" {
+		createdAt, id, err := parseUserCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		// The last evaluated key of a GSI query holds the index key and the table key
+		input.ExclusiveStartKey = map[string]types.AttributeValue{
+			"user_id":    &types.AttributeValueMemberS{Value: userID.String()},
+			"created_at": &types.AttributeValueMemberS{Value: createdAt},
+			"id":         &types.AttributeValueMemberS{Value: id},
+		}
+	}
+
+	output, err := r.client.Query(ctx, input)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query tweets of user %s: %w", userID, err)
+	}
+
+	tweets := make([]domain.Tweet, 0, len(output.Items))
+	for _, item := range output.Items {
+		tweet, err := tweetFromItem(item)
+		if err != nil {
+			return nil, "", err
+		}
+		tweets = append(tweets, tweet)
+	}
+
+	nextCursor := ""
+	createdAt, hasCreatedAt := output.LastEvaluatedKey["created_at"].(*types.AttributeValueMemberS)
+	id, hasID := output.LastEvaluatedKey["id"].(*types.AttributeValueMemberS)
+	if hasCreatedAt && hasID {
+		nextCursor = createdAt.Value + userCursorSeparator + id.Value
+	}
+	return tweets, nextCursor, nil
+}
+
+// parseUserCursor returns the creation time and the ID held by a cursor of GetByUser
+func parseUserCursor(cursor string) (createdAt, id string, err error) {
+	createdAt, id, ok := strings.Cut(cursor, userCursorSeparator)
+	if !ok {
+		return "", "", domain.ErrInvalidCursor
+	}
+	if _, err := time.Parse(time.RFC3339, createdAt); err != nil {
+		return "", "", domain.ErrInvalidCursor
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return "", "", domain.ErrInvalidCursor
+	}
+	return createdAt, id, nil
+}
+
+// GetByUsers returns up to limit tweets of many users within a range, newest first. The
+// users are queried on userIDCreatedAtIndex in parallel and their tweets merged. Tweets
+// created in the same second are ordered by ID, as in the search index. It returns
+// domain.ErrTweetNotFound when a bound of the range does not exist.
+func (r *tweetRepository) GetByUsers(ctx context.Context, userIDs []uuid.UUID, tweetRange domain.TweetRange, limit int) ([]domain.Tweet, error) {
+	since, max, err := r.rangeBounds(ctx, tweetRange)
+	if err != nil {
+		return nil, err
+	}
+
+	perUser, err := r.queryUsers(ctx, userIDs, since, max, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var tweets []domain.Tweet
+	for _, userTweets := range perUser {
+		tweets = append(tweets, userTweets...)
+	}
+	sort.Slice(tweets, func(i, j int) bool { return newerThan(tweets[i], tweets[j]) })
+	if len(tweets) > limit {
+		tweets = tweets[:limit]
+	}
+	return tweets, nil
+}
+
+// CountByUsers counts the tweets of many users within a range. Every tweet in the range is
+// read, so it is meant for short ranges such as the tweets since the newest one a client has
+// seen. It returns domain.ErrTweetNotFound when a bound of the range does not exist.
+func (r *tweetRepository) CountByUsers(ctx context.Context, userIDs []uuid.UUID, tweetRange domain.TweetRange) (int, error) {
+	since, max, err := r.rangeBounds(ctx, tweetRange)
+	if err != nil {
+		return 0, err
+	}
+
+	perUser, err := r.queryUsers(ctx, userIDs, since, max, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, userTweets := range perUser {
+		count += len(userTweets)
+	}
+	return count, nil
+}
+
+// rangeBounds loads the tweets bounding a range. Unset bounds are nil.
+func (r *tweetRepository) rangeBounds(ctx context.Context, tweetRange domain.TweetRange) (since, max *domain.Tweet, err error) {
+	if tweetRange.SinceID != uuid.Nil {
+		if since, err = r.GetByID(ctx, tweetRange.SinceID); err != nil {
+			return nil, nil, err
+		}
+	}
+	if tweetRange.MaxID != uuid.Nil {
+		if max, err = r.GetByID(ctx, tweetRange.MaxID); err != nil {
+			return nil, nil, err
+		}
+	}
+	return since, max, nil
+}
+
+// queryUsers runs queryByUser for each user, at most maxParallelQueries at a time, and
+// returns their tweets in the order of userIDs. The first failure cancels the other queries.
+func (r *tweetRepository) queryUsers(ctx context.Context, userIDs []uuid.UUID, since, max *domain.Tweet, limit int) ([][]domain.Tweet, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([][]domain.Tweet, len(userIDs))
+	errs := make([]error, len(userIDs))
+	slots := make(chan struct{}, maxParallelQueries)
+
+	var wg sync.WaitGroup
+	for i, userID := range userIDs {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, userID uuid.UUID) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if results[i], errs[i] = r.queryByUser(ctx, userID, since, max, limit); errs[i] != nil {
+				cancel()
+			}
+		}(i, userID)
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// queryByUser returns up to limit tweets of a user newer than since and older than max,
+// newest first. Nil bounds are not applied, and a zero limit returns every tweet.
+func (r *tweetRepository) queryByUser(ctx context.Context, userID uuid.UUID, since, max *domain.Tweet, limit int) ([]domain.Tweet, error) {
+	keyCondition := "user_id = :user_id"
+	values := map[string]types.AttributeValue{
+		":user_id": &types.AttributeValueMemberS{Value: userID.String()},
+	}
+	// created_at only has a precision of seconds, so tweets of the same second as a bound
+	// are queried and then filtered by ID
+	switch {
+	case since != nil && max != nil:
+		keyCondition += " AND created_at BETWEEN :since AND :max"
+	case since != nil:
+		keyCondition += " AND created_at >= :since"
+	case max != nil:
+		keyCondition += " AND created_at <= :max"
+	}
+	if since != nil {
+		values[":since"] = &types.AttributeValueMemberS{Value: since.CreatedAt.UTC().Format(time.RFC3339)}
+	}
+	if max != nil {
+		values[":max"] = &types.AttributeValueMemberS{Value: max.CreatedAt.UTC().Format(time.RFC3339)}
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(r.tableName),
+		IndexName:                 aws.String(userIDCreatedAtIndex),
+		KeyConditionExpression:    aws.String(keyCondition),
+		ExpressionAttributeValues: values,
+		ScanIndexForward:          aws.Bool(false),
+	}
+	if limit > 0 {
+		input.Limit = aws.Int32(int32(min(limit, maxQueryPageSize)))
+	}
+	paginator := dynamodb.NewQueryPaginator(r.client, input)
+
+	var tweets []domain.Tweet
+	for paginator.HasMorePages() && (limit == 0 || len(tweets) < limit) {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query tweets of user %s: %w", userID, err)
+		}
+		for _, item := range page.Items {
+			tweet, err := tweetFromItem(item)
+			if err != nil {
+				return nil, err
+			}
+			if (since != nil && !newerThan(tweet, *since)) || (max != nil && !newerThan(*max, tweet)) {
+				continue
+			}
+			tweets = append(tweets, tweet)
+		}
+	}
+	if limit > 0 && len(tweets) > limit {
+		tweets = tweets[:limit]
+	}
+	return tweets, nil
+}
+
+// newerThan reports whether a comes before b in a list of tweets sorted newest first
+func newerThan(a, b domain.Tweet) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.After(b.CreatedAt)
+	}
+	return a.ID.String() > b.ID.String()
+}
+
+// DeleteByUser deletes every tweet written by a user and returns how many were deleted.
+// Deleting tweets that are already gone succeeds, so a partial run can be retried.
+func (r *tweetRepository) DeleteByUser(ctx context.Context, userID uuid.UUID) (int, error) {
+	paginator := dynamodb.NewQueryPaginator(r.client, &dynamodb.QueryInput{
+		TableName:              aws.String(r.tableName),
+		IndexName:              aws.String(userIDCreatedAtIndex),
+		KeyConditionExpression: aws.String("user_id = :user_id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":user_id": &types.AttributeValueMemberS{Value: userID.String()},
+		},
+		ProjectionExpression: aws.String("id"),
+	})
+
+	deleted := 0
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return deleted, fmt.Errorf("failed to query tweets of user %s: %w", userID, err)
+		}
+
+		for start := 0; start < len(page.Items); start += maxBatchWriteItems {
+			end := min(start+maxBatchWriteItems, len(page.Items))
+			if err := r.batchDelete(ctx, page.Items[start:end]); err != nil {
+				return deleted, err
+			}
+			deleted += end - start
+		}
+	}
+	return deleted, nil
+}
+
+// batchDelete deletes up to maxBatchWriteItems tweets by key, retrying unprocessed items
+func (r *tweetRepository) batchDelete(ctx context.Context, keys []map[string]types.AttributeValue) error {
+	requests := make([]types.WriteRequest, 0, len(keys))
+	for _, key := range keys {
+		requests = append(requests, types.WriteRequest{
+			DeleteRequest: &types.DeleteRequest{Key: map[string]types.AttributeValue{"id": key["id"]}},
+		})
+	}
+
+	pending := map[string][]types.WriteRequest{r.tableName: requests}
+	for attempt := 1; len(pending) > 0; attempt++ {
+		if attempt > maxBatchWriteAttempts {
+			return fmt.Errorf("failed to delete %d tweets after %d attempts", len(pending[r.tableName]), maxBatchWriteAttempts)
+		}
+		if attempt > 1 {
+			select {
+			case <-time.After(time.Duration(attempt) * 100 * time.Millisecond):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		output, err := r.client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{RequestItems: pending})
+		if err != nil {
+			return fmt.Errorf("failed to delete tweets: %w", err)
+		}
+		pending = output.UnprocessedItems
+	}
+	return nil
+}
+
+// tweetFromItem converts a DynamoDB item written by Create back into a tweet
+func tweetFromItem(item map[string]types.AttributeValue) (domain.Tweet, error) {
+	attr := func(name string) string {
+		if value, ok := item[name].(*types.AttributeValueMemberS); ok {
+			return value.Value
+		}
+		return ""
+	}
+
+	id, err := uuid.Parse(attr("id"))
+	if err != nil {
+		return domain.Tweet{}, fmt.Errorf("failed to parse tweet ID: %w", err)
+	}
+	userID, err := uuid.Parse(attr("user_id"))
+	if err != nil {
+		return domain.Tweet{}, fmt.Errorf("failed to parse user ID of tweet %s: %w", id, err)
+	}
+	createdAt, err := time.Parse(time.RFC3339, attr("created_at"))
+	if err != nil {
+		return domain.Tweet{}, fmt.Errorf("failed to parse created_at of tweet %s: %w", id, err)
+	}
+	updatedAt, err := time.Parse(time.RFC3339, attr("updated_at"))
+	if err != nil {
+		return domain.Tweet{}, fmt.Errorf("failed to parse updated_at of tweet %s: %w", id, err)
+	}
+
+	return domain.Tweet{
+		ID:        id,
+		UserID:    userID,
+		Content:   attr("content"),
+		CreatedAt: createdAt,
+		UpdatedAt: updatedAt,
+	}, nil
+}
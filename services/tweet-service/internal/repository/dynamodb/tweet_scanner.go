@@ -0,0 +1,101 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/lisandro/challenge/services/tweet-service/internal/domain"
+)
+
+// TweetScanner reads the whole tweets table, for jobs such as reindexing search
+type TweetScanner struct {
+	client    *dynamodb.Client
+	tableName string
+}
+
+// NewTweetScanner creates a new tweet scanner
+func NewTweetScanner(client *dynamodb.Client, tableName string) *TweetScanner {
+	return &TweetScanner{
+		client:    client,
+		tableName: tableName,
+	}
+}
+
+// Scan reads every tweet created at or after since, or every tweet when since is zero, in
+// parallel segments of the table. handle is called with each page of tweets, from as many
+// goroutines as there are segments. The first error stops the other segments.
+func (s *TweetScanner) Scan(ctx context.Context, segments int, since time.Time, handle func([]domain.Tweet) error) error {
+	if segments < 1 {
+		segments = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make([]error, segments)
+	var wg sync.WaitGroup
+	for segment := 0; segment < segments; segment++ {
+		wg.Add(1)
+		go func(segment int) {
+			defer wg.Done()
+			if errs[segment] = s.scanSegment(ctx, segment, segments, since, handle); errs[segment] != nil {
+				cancel()
+			}
+		}(segment)
+	}
+	wg.Wait()
+
+	// Segments stopped by the failure of another one only report the cancellation
+	var failures []error
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, context.Canceled) {
+			failures = append(failures, err)
+		}
+	}
+	if len(failures) > 0 {
+		return errors.Join(failures...)
+	}
+	return errors.Join(errs...)
+}
+
+func (s *TweetScanner) scanSegment(ctx context.Context, segment, segments int, since time.Time, handle func([]domain.Tweet) error) error {
+	input := &dynamodb.ScanInput{
+		TableName:     aws.String(s.tableName),
+		Segment:       aws.Int32(int32(segment)),
+		TotalSegments: aws.Int32(int32(segments)),
+	}
+	if !since.IsZero() {
+		input.FilterExpression = aws.String("created_at >= :since")
+		input.ExpressionAttributeValues = map[string]types.AttributeValue{
+			":since": &types.AttributeValueMemberS{Value: since.UTC().Format(time.RFC3339)},
+		}
+	}
+	paginator := dynamodb.NewScanPaginator(s.client, input)
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to scan segment %d of tweets: %w", segment, err)
+		}
+		tweets := make([]domain.Tweet, 0, len(page.Items))
+		for _, item := range page.Items {
+			tweet, err := tweetFromItem(item)
+			if err != nil {
+				return err
+			}
+			tweets = append(tweets, tweet)
+		}
+		if len(tweets) == 0 {
+			continue
+		}
+		if err := handle(tweets); err != nil {
+			return err
+		}
+	}
+	return nil
+}
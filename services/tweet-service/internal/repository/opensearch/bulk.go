@@ -0,0 +1,85 @@
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/lisandro/challenge/services/tweet-service/internal/domain"
+	"github.com/opensearch-project/opensearch-go/v2"
+	"github.com/opensearch-project/opensearch-go/v2/opensearchapi"
+)
+
+// bulkFailure is the outcome of a document that a _bulk request did not index
+type bulkFailure struct {
+	Status int
+	Reason string
+}
+
+// retryable reports whether indexing the document again may succeed, such as after the
+// cluster rejected it for being overloaded
+func (f bulkFailure) retryable() bool {
+	return f.Status == http.StatusTooManyRequests || f.Status >= http.StatusInternalServerError
+}
+
+// bulkIndex indexes tweets into index with a single _bulk request. A request that fails as a
+// whole returns an error; otherwise the documents that failed are returned by tweet ID.
+func bulkIndex(ctx context.Context, client *opensearch.Client, index string, tweets []domain.Tweet) (map[string]bulkFailure, error) {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for i := range tweets {
+		action := map[string]interface{}{
+			"index": map[string]interface{}{"_index": index, "_id": tweets[i].ID.String()},
+		}
+		if err := encoder.Encode(action); err != nil {
+			return nil, fmt.Errorf("failed to marshal bulk action: %w", err)
+		}
+		if err := encoder.Encode(tweetDocument(&tweets[i])); err != nil {
+			return nil, fmt.Errorf("failed to marshal tweet: %w", err)
+		}
+	}
+
+	req := opensearchapi.BulkRequest{Body: &body}
+	res, err := req.Do(ctx, client)
+	if err != nil {
+		return nil, transportError("bulk index tweets", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("error bulk indexing tweets: %w", responseError(res))
+	}
+
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			ID     string `json:"_id"`
+			Status int    `json:"status"`
+			Error  *struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode bulk response: %w", err)
+	}
+
+	failures := make(map[string]bulkFailure)
+	if !result.Errors {
+		return failures, nil
+	}
+	for _, item := range result.Items {
+		for _, outcome := range item {
+			if outcome.Error != nil {
+				failures[outcome.ID] = bulkFailure{
+					Status: outcome.Status,
+					Reason: fmt.Sprintf("%s: %s", outcome.Error.Type, outcome.Error.Reason),
+				}
+			}
+		}
+	}
+	return failures, nil
+}
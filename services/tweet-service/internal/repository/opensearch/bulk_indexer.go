@@ -0,0 +1,251 @@
+package opensearch
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lisandro/challenge/services/tweet-service/internal/domain"
+	"github.com/opensearch-project/opensearch-go/v2"
+)
+
+// ErrIndexQueueFull is returned when a tweet could not be queued for indexing in time
+var ErrIndexQueueFull = errors.New("search indexing queue is full")
+
+// indexingVars publishes the stats of every bulk indexer on /debug/vars
+var indexingVars = expvar.NewMap("search_indexing")
+
+// BulkConfig holds the batching settings of a BulkIndexer
+type BulkConfig struct {
+	// BatchSize is the number of tweets that triggers a flush
+	BatchSize int
+	// FlushInterval is the longest a queued tweet waits before a flush
+	FlushInterval time.Duration
+	// QueueSize is the number of tweets waiting to be indexed before IndexTweet blocks
+	QueueSize int
+	// EnqueueTimeout is how long IndexTweet blocks on a full queue before giving up
+	EnqueueTimeout time.Duration
+	// MaxAttempts bounds the attempts to index a tweet the cluster rejected temporarily
+	MaxAttempts int
+	RetryDelay  time.Duration
+	// Timeout bounds each _bulk request, and how long a deletion waits for the queue to flush
+	Timeout time.Duration
+}
+
+// BulkStats are the counters of a BulkIndexer
+type BulkStats struct {
+	Queued  int   `json:"queued"`
+	Indexed int64 `json:"indexed"`
+	Retried int64 `json:"retried"`
+	Failed  int64 `json:"failed"`
+	Dropped int64 `json:"dropped"`
+	// LagSeconds is how long the oldest tweet of the last flush waited to be indexed
+	LagSeconds float64 `json:"lag_seconds"`
+}
+
+type queuedTweet struct {
+	tweet    domain.Tweet
+	queuedAt time.Time
+}
+
+// BulkIndexer indexes tweets in the background through the _bulk API, so that creating a
+// tweet does not wait for OpenSearch. Reads and deletions go to the wrapped repository.
+// Tweets are searchable up to FlushInterval after they are created, and tweets still
+// queued when the service stops without Run draining the queue are not indexed until the
+// next reindex.
+type BulkIndexer struct {
+	domain.SearchRepository
+	client  *opensearch.Client
+	config  BulkConfig
+	queue   chan queuedTweet
+	flushes chan chan struct{}
+
+	indexed atomic.Int64
+	retried atomic.Int64
+	failed  atomic.Int64
+	dropped atomic.Int64
+	lag     atomic.Int64
+}
+
+// NewBulkIndexer creates a bulk indexer in front of repo. Tweets are indexed once Run is started.
+func NewBulkIndexer(client *opensearch.Client, repo domain.SearchRepository, config BulkConfig) *BulkIndexer {
+	b := &BulkIndexer{
+		SearchRepository: repo,
+		client:           client,
+		config:           config,
+		queue:            make(chan queuedTweet, config.QueueSize),
+		flushes:          make(chan chan struct{}),
+	}
+	indexingVars.Set(tweetsIndex, expvar.Func(func() any { return b.Stats() }))
+	return b
+}
+
+// Stats returns the counters of the indexer
+func (b *BulkIndexer) Stats() BulkStats {
+	return BulkStats{
+		Queued:     len(b.queue),
+		Indexed:    b.indexed.Load(),
+		Retried:    b.retried.Load(),
+		Failed:     b.failed.Load(),
+		Dropped:    b.dropped.Load(),
+		LagSeconds: time.Duration(b.lag.Load()).Seconds(),
+	}
+}
+
+// IndexTweet queues a tweet for indexing. When the queue is full it blocks for up to
+// EnqueueTimeout or until ctx is done, slowing writers down to the pace of the cluster, and
+// then returns ErrIndexQueueFull. The tweet is indexed in the background, regardless of ctx.
+func (b *BulkIndexer) IndexTweet(ctx context.Context, tweet *domain.Tweet) error {
+	item := queuedTweet{tweet: *tweet, queuedAt: time.Now()}
+	select {
+	case b.queue <- item:
+		return nil
+	default:
+	}
+
+	timer := time.NewTimer(b.config.EnqueueTimeout)
+	defer timer.Stop()
+	select {
+	case b.queue <- item:
+		return nil
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	b.dropped.Add(1)
+	return ErrIndexQueueFull
+}
+
+// DeleteByUser indexes the queued tweets first, so that tweets of the user still in the
+// queue are not indexed again after the deletion
+func (b *BulkIndexer) DeleteByUser(ctx context.Context, userID uuid.UUID) error {
+	flushCtx, cancel := context.WithTimeout(ctx, b.config.Timeout)
+	defer cancel()
+	if err := b.Flush(flushCtx); err != nil {
+		log.Printf("Failed to flush the indexing queue before deleting tweets of user %s: %v", userID, err)
+	}
+	return b.SearchRepository.DeleteByUser(ctx, userID)
+}
+
+// Flush waits until the tweets queued so far have been sent to OpenSearch
+func (b *BulkIndexer) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	select {
+	case b.flushes <- done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Run indexes queued tweets until ctx is done, and then indexes the tweets left in the queue
+func (b *BulkIndexer) Run(ctx context.Context) {
+	ticker := time.NewTicker(b.config.FlushInterval)
+	defer ticker.Stop()
+
+	var batch []queuedTweet
+	flush := func(ctx context.Context) {
+		if len(batch) > 0 {
+			b.flush(ctx, batch)
+			batch = nil
+		}
+	}
+	drain := func() {
+		for {
+			select {
+			case item := <-b.queue:
+				batch = append(batch, item)
+			default:
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			drain()
+			flush(context.Background())
+			return
+		case item := <-b.queue:
+			batch = append(batch, item)
+			if len(batch) >= b.config.BatchSize {
+				flush(ctx)
+			}
+		case <-ticker.C:
+			flush(ctx)
+		case done := <-b.flushes:
+			drain()
+			flush(ctx)
+			close(done)
+		}
+	}
+}
+
+// flush indexes a batch, retrying the tweets that failed temporarily. Tweets rejected for
+// good, such as for not matching the mappings, are logged and counted as failed.
+func (b *BulkIndexer) flush(ctx context.Context, batch []queuedTweet) {
+	pending := batch
+	for attempt := 1; len(pending) > 0; attempt++ {
+		tweets := make([]domain.Tweet, len(pending))
+		for i, item := range pending {
+			tweets[i] = item.tweet
+		}
+
+		requestCtx, cancel := context.WithTimeout(ctx, b.config.Timeout)
+		failures, err := bulkIndex(requestCtx, b.client, tweetsIndex, tweets)
+		cancel()
+
+		var retry []queuedTweet
+		if err != nil {
+			log.Printf("Failed to bulk index %d tweets (attempt %d): %v", len(pending), attempt, err)
+			retry = pending
+		} else {
+			var oldest time.Time
+			for _, item := range pending {
+				failure, ok := failures[item.tweet.ID.String()]
+				switch {
+				case !ok:
+					b.indexed.Add(1)
+					if oldest.IsZero() || item.queuedAt.Before(oldest) {
+						oldest = item.queuedAt
+					}
+				case failure.retryable():
+					retry = append(retry, item)
+				default:
+					b.failed.Add(1)
+					log.Printf("Failed to index tweet %s: %s", item.tweet.ID, failure.Reason)
+				}
+			}
+			if !oldest.IsZero() {
+				b.lag.Store(int64(time.Since(oldest)))
+			}
+		}
+
+		if len(retry) == 0 {
+			return
+		}
+		if attempt >= b.config.MaxAttempts {
+			b.failed.Add(int64(len(retry)))
+			log.Printf("Giving up indexing %d tweets after %d attempts", len(retry), attempt)
+			return
+		}
+		b.retried.Add(int64(len(retry)))
+		pending = retry
+
+		select {
+		case <-ctx.Done():
+			b.failed.Add(int64(len(pending)))
+			return
+		case <-time.After(b.config.RetryDelay * time.Duration(attempt)):
+		}
+	}
+}
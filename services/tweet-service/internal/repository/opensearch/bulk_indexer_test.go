@@ -0,0 +1,226 @@
+package opensearch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lisandro/challenge/services/tweet-service/internal/domain"
+	"github.com/opensearch-project/opensearch-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBulkServer answers _bulk requests, failing documents with the statuses of fail
+type fakeBulkServer struct {
+	mu       sync.Mutex
+	requests [][]string
+	// fail returns the status to fail a document with on an attempt, or 0 to index it
+	fail func(id string, attempt int) int
+	seen map[string]int
+}
+
+func newFakeBulkServer(t *testing.T) (*fakeBulkServer, *opensearch.Client) {
+	fake := &fakeBulkServer{seen: make(map[string]int)}
+	server := httptest.NewServer(http.HandlerFunc(fake.handle))
+	t.Cleanup(server.Close)
+
+	client, err := opensearch.NewClient(opensearch.Config{Addresses: []string{server.URL}})
+	require.NoError(t, err)
+	return fake, client
+}
+
+func (f *fakeBulkServer) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var ids []string
+	type item struct {
+		ID     string            `json:"_id"`
+		Status int               `json:"status"`
+		Error  map[string]string `json:"error,omitempty"`
+	}
+	var items []map[string]item
+	failed := false
+
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		var action struct {
+			Index struct {
+				ID string `json:"_id"`
+			} `json:"index"`
+		}
+		json.Unmarshal(scanner.Bytes(), &action)
+		scanner.Scan() // the document
+
+		id := action.Index.ID
+		ids = append(ids, id)
+		f.seen[id]++
+		outcome := item{ID: id, Status: http.StatusCreated}
+		if f.fail != nil {
+			if status := f.fail(id, f.seen[id]); status != 0 {
+				outcome = item{ID: id, Status: status, Error: map[string]string{"type": "error", "reason": "rejected"}}
+				failed = true
+			}
+		}
+		items = append(items, map[string]item{"index": outcome})
+	}
+	f.requests = append(f.requests, ids)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"errors": failed, "items": items})
+}
+
+func (f *fakeBulkServer) Requests() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]string(nil), f.requests...)
+}
+
+func testBulkConfig() BulkConfig {
+	return BulkConfig{
+		BatchSize:      2,
+		FlushInterval:  time.Hour,
+		QueueSize:      10,
+		EnqueueTimeout: 10 * time.Millisecond,
+		MaxAttempts:    3,
+		RetryDelay:     time.Millisecond,
+		Timeout:        time.Second,
+	}
+}
+
+func runIndexer(t *testing.T, b *BulkIndexer) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		b.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func newTweet() *domain.Tweet {
+	return &domain.Tweet{ID: uuid.New(), UserID: uuid.New(), Content: "hello"}
+}
+
+func TestBulkIndexer_FlushesBySize(t *testing.T) {
+	fake, client := newFakeBulkServer(t)
+	indexer := NewBulkIndexer(client, nil, testBulkConfig())
+	runIndexer(t, indexer)
+
+	first, second := newTweet(), newTweet()
+	require.NoError(t, indexer.IndexTweet(context.Background(), first))
+	require.NoError(t, indexer.IndexTweet(context.Background(), second))
+
+	assert.Eventually(t, func() bool { return len(fake.Requests()) == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, []string{first.ID.String(), second.ID.String()}, fake.Requests()[0])
+	assert.Eventually(t, func() bool { return indexer.Stats().Indexed == 2 }, time.Second, time.Millisecond)
+}
+
+func TestBulkIndexer_FlushesByTime(t *testing.T) {
+	fake, client := newFakeBulkServer(t)
+	config := testBulkConfig()
+	config.BatchSize = 100
+	config.FlushInterval = 10 * time.Millisecond
+	indexer := NewBulkIndexer(client, nil, config)
+	runIndexer(t, indexer)
+
+	tweet := newTweet()
+	require.NoError(t, indexer.IndexTweet(context.Background(), tweet))
+
+	assert.Eventually(t, func() bool { return len(fake.Requests()) == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, []string{tweet.ID.String()}, fake.Requests()[0])
+}
+
+func TestBulkIndexer_RetriesOnlyTemporaryFailures(t *testing.T) {
+	fake, client := newFakeBulkServer(t)
+	throttled, malformed := newTweet(), newTweet()
+	fake.fail = func(id string, attempt int) int {
+		switch {
+		case id == throttled.ID.String() && attempt == 1:
+			return http.StatusTooManyRequests
+		case id == malformed.ID.String():
+			return http.StatusBadRequest
+		}
+		return 0
+	}
+	indexer := NewBulkIndexer(client, nil, testBulkConfig())
+	runIndexer(t, indexer)
+
+	require.NoError(t, indexer.IndexTweet(context.Background(), throttled))
+	require.NoError(t, indexer.IndexTweet(context.Background(), malformed))
+
+	assert.Eventually(t, func() bool { return indexer.Stats().Indexed == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, [][]string{
+		{throttled.ID.String(), malformed.ID.String()},
+		{throttled.ID.String()},
+	}, fake.Requests())
+	stats := indexer.Stats()
+	assert.Equal(t, int64(1), stats.Retried)
+	assert.Equal(t, int64(1), stats.Failed)
+}
+
+func TestBulkIndexer_GivesUpAfterMaxAttempts(t *testing.T) {
+	fake, client := newFakeBulkServer(t)
+	fake.fail = func(id string, attempt int) int { return http.StatusServiceUnavailable }
+	indexer := NewBulkIndexer(client, nil, testBulkConfig())
+	runIndexer(t, indexer)
+
+	require.NoError(t, indexer.IndexTweet(context.Background(), newTweet()))
+	require.NoError(t, indexer.Flush(context.Background()))
+
+	assert.Len(t, fake.Requests(), 3)
+	assert.Equal(t, int64(1), indexer.Stats().Failed)
+}
+
+func TestBulkIndexer_FullQueue(t *testing.T) {
+	_, client := newFakeBulkServer(t)
+	config := testBulkConfig()
+	config.QueueSize = 1
+	// Without Run nothing takes tweets off the queue
+	indexer := NewBulkIndexer(client, nil, config)
+
+	require.NoError(t, indexer.IndexTweet(context.Background(), newTweet()))
+	err := indexer.IndexTweet(context.Background(), newTweet())
+
+	assert.ErrorIs(t, err, ErrIndexQueueFull)
+	assert.Equal(t, int64(1), indexer.Stats().Dropped)
+	assert.Equal(t, 1, indexer.Stats().Queued)
+}
+
+type fakeSearchRepository struct {
+	domain.SearchRepository
+	deleted func(userID uuid.UUID)
+}
+
+func (r *fakeSearchRepository) DeleteByUser(ctx context.Context, userID uuid.UUID) error {
+	r.deleted(userID)
+	return nil
+}
+
+func TestBulkIndexer_DeleteByUserFlushesFirst(t *testing.T) {
+	fake, client := newFakeBulkServer(t)
+	tweet := newTweet()
+	var flushedBeforeDelete bool
+	repo := &fakeSearchRepository{deleted: func(userID uuid.UUID) {
+		flushedBeforeDelete = len(fake.Requests()) == 1
+	}}
+	config := testBulkConfig()
+	config.BatchSize = 100
+	indexer := NewBulkIndexer(client, repo, config)
+	runIndexer(t, indexer)
+
+	require.NoError(t, indexer.IndexTweet(context.Background(), tweet))
+	require.NoError(t, indexer.DeleteByUser(context.Background(), tweet.UserID))
+
+	assert.True(t, flushedBeforeDelete)
+}
@@ -0,0 +1,140 @@
+package opensearch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/opensearch-project/opensearch-go/v2/opensearchapi"
+)
+
+// Kinds of search errors, to be matched with errors.Is
+var (
+	// ErrIndexNotFound is returned when the tweets index or alias does not exist
+	ErrIndexNotFound = errors.New("search index not found")
+	// ErrMalformedQuery is returned when OpenSearch rejects a query, which is a bug of this service
+	ErrMalformedQuery = errors.New("malformed search query")
+	// ErrUnavailable is returned when OpenSearch cannot be reached, is overloaded or failed
+	// on every shard. Retrying later may succeed.
+	ErrUnavailable = errors.New("search is unavailable")
+	// ErrPartialResults is returned when some shards failed or the search timed out, so
+	// that the hits are incomplete
+	ErrPartialResults = errors.New("search results are partial")
+)
+
+// ResponseError is an error response of OpenSearch
+type ResponseError struct {
+	Status int
+	Type   string
+	Reason string
+	// Kind is one of the kinds of search errors, or nil when the error is not classified
+	Kind error
+}
+
+func (e *ResponseError) Error() string {
+	if e.Type == "" {
+		return fmt.Sprintf("opensearch returned %d: %s", e.Status, e.Reason)
+	}
+	return fmt.Sprintf("opensearch returned %d %s: %s", e.Status, e.Type, e.Reason)
+}
+
+func (e *ResponseError) Unwrap() error {
+	return e.Kind
+}
+
+// errorBody is the body of an OpenSearch error response
+type errorBody struct {
+	Error struct {
+		Type      string `json:"type"`
+		Reason    string `json:"reason"`
+		RootCause []struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"root_cause"`
+	} `json:"error"`
+}
+
+// responseError turns an error response into a classified ResponseError
+func responseError(res *opensearchapi.Response) error {
+	body, _ := io.ReadAll(res.Body)
+	err := &ResponseError{Status: res.StatusCode, Reason: strings.TrimSpace(string(body))}
+
+	// Some errors, such as those of proxies in front of the cluster, are not JSON
+	var payload errorBody
+	if json.Unmarshal(body, &payload) == nil && payload.Error.Type != "" {
+		err.Type, err.Reason = payload.Error.Type, payload.Error.Reason
+		// Search phase errors describe the failure of a shard in their root cause
+		if len(payload.Error.RootCause) > 0 && err.Type == "search_phase_execution_exception" {
+			err.Type, err.Reason = payload.Error.RootCause[0].Type, payload.Error.RootCause[0].Reason
+		}
+	}
+
+	switch {
+	case err.Type == "index_not_found_exception":
+		err.Kind = ErrIndexNotFound
+	case res.StatusCode == http.StatusBadRequest:
+		err.Kind = ErrMalformedQuery
+	case res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= http.StatusInternalServerError:
+		err.Kind = ErrUnavailable
+	}
+	return err
+}
+
+// transportError wraps the failure to get a response from OpenSearch
+func transportError(action string, err error) error {
+	return fmt.Errorf("failed to %s: %w: %w", action, ErrUnavailable, err)
+}
+
+// shardStats reports how many shards answered a search or count
+type shardStats struct {
+	Total      int `json:"total"`
+	Successful int `json:"successful"`
+	Skipped    int `json:"skipped"`
+	Failed     int `json:"failed"`
+	Failures   []struct {
+		Index  string `json:"index"`
+		Shard  int    `json:"shard"`
+		Reason struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"reason"`
+	} `json:"failures"`
+}
+
+// check returns an error when a shard failed, so that a response missing the hits of the
+// failed shards is not mistaken for a complete one
+func (s shardStats) check(timedOut bool) error {
+	if s.Failed == 0 && !timedOut {
+		return nil
+	}
+	reason := "timed out"
+	if len(s.Failures) > 0 {
+		failure := s.Failures[0]
+		reason = fmt.Sprintf("shard %d of %s: %s: %s", failure.Shard, failure.Index, failure.Reason.Type, failure.Reason.Reason)
+	}
+	if s.Total > 0 && s.Failed >= s.Total {
+		return fmt.Errorf("%w: all %d shards failed, such as %s", ErrUnavailable, s.Total, reason)
+	}
+	return fmt.Errorf("%w: %d of %d shards failed, %s", ErrPartialResults, s.Failed, s.Total, reason)
+}
+
+// searchResponse is the body of a search response with hits of type S
+type searchResponse[S any] struct {
+	TimedOut bool       `json:"timed_out"`
+	Shards   shardStats `json:"_shards"`
+	Hits     struct {
+		Hits []struct {
+			ID     string `json:"_id"`
+			Source S      `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+// countResponse is the body of a count response
+type countResponse struct {
+	Count  int        `json:"count"`
+	Shards shardStats `json:"_shards"`
+}
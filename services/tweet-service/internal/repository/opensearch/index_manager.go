@@ -0,0 +1,223 @@
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/lisandro/challenge/services/tweet-service/internal/domain"
+	"github.com/opensearch-project/opensearch-go/v2"
+	"github.com/opensearch-project/opensearch-go/v2/opensearchapi"
+)
+
+const (
+	// tweetsTemplate is the index template applied to every versioned tweets index
+	tweetsTemplate = "tweets"
+	// rolloverPolicy is the index state management policy rolling tweets indices over by age
+	rolloverPolicy = "tweets-rollover"
+	// bulkBatchSize is the number of tweets sent in each _bulk request
+	bulkBatchSize = 500
+)
+
+// tweetsMappings are the explicit mappings of the tweets indices. Changing them, such as the
+// analyzer of content, takes a reindex.
+var tweetsMappings = map[string]interface{}{
+	"dynamic": "strict",
+	"properties": map[string]interface{}{
+		"id":         map[string]interface{}{"type": "keyword"},
+		"user_id":    map[string]interface{}{"type": "keyword"},
+		"content":    map[string]interface{}{"type": "text", "analyzer": "standard"},
+		"created_at": map[string]interface{}{"type": "date"},
+		"updated_at": map[string]interface{}{"type": "date"},
+	},
+}
+
+// IndexManager manages the versioned tweets indices behind the tweets alias
+type IndexManager struct {
+	client *opensearch.Client
+	// RolloverAge is the age at which the write index is rolled over to a new index
+	RolloverAge string
+}
+
+// NewIndexManager creates a new index manager
+func NewIndexManager(client *opensearch.Client, rolloverAge string) *IndexManager {
+	return &IndexManager{
+		client:      client,
+		RolloverAge: rolloverAge,
+	}
+}
+
+// Alias returns the alias that the search repository reads and writes
+func (m *IndexManager) Alias() string {
+	return tweetsIndex
+}
+
+// EnsureTemplate creates or updates the index template of the tweets indices and the policy
+// rolling them over. Clusters without the index state management plugin only get the template.
+func (m *IndexManager) EnsureTemplate(ctx context.Context) error {
+	template := map[string]interface{}{
+		"index_patterns": []string{tweetsIndex + "-*"},
+		"priority":       100,
+		"template": map[string]interface{}{
+			"settings": map[string]interface{}{
+				"plugins.index_state_management.rollover_alias": tweetsIndex,
+			},
+			"mappings": tweetsMappings,
+		},
+	}
+	if err := m.put(ctx, opensearchapi.IndicesPutIndexTemplateRequest{Name: tweetsTemplate, Body: jsonBody(template)}); err != nil {
+		return fmt.Errorf("failed to put index template: %w", err)
+	}
+
+	policy := map[string]interface{}{
+		"policy": map[string]interface{}{
+			"description":   "Rolls the tweets write index over by age",
+			"default_state": "hot",
+			"states": []interface{}{
+				map[string]interface{}{
+					"name":        "hot",
+					"actions":     []interface{}{map[string]interface{}{"rollover": map[string]interface{}{"min_index_age": m.RolloverAge}}},
+					"transitions": []interface{}{},
+				},
+			},
+			"ism_template": []interface{}{
+				map[string]interface{}{"index_patterns": []string{tweetsIndex + "-*"}, "priority": 100},
+			},
+		},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, "/_plugins/_ism/policies/"+rolloverPolicy, jsonBody(policy))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := m.client.Perform(req)
+	if err != nil {
+		return fmt.Errorf("failed to put rollover policy: %w", err)
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusConflict:
+		// The policy exists; ISM only updates a policy given its sequence number, so changes
+		// to the rollover age are applied by deleting the policy first
+	case res.StatusCode == http.StatusNotFound || res.StatusCode == http.StatusBadRequest:
+		body, _ := io.ReadAll(res.Body)
+		log.Printf("Index state management is not available, tweets indices will not roll over: %s", body)
+	case res.StatusCode >= 300:
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("error putting rollover policy: %d %s", res.StatusCode, body)
+	}
+	return nil
+}
+
+// CreateIndex creates a tweets index. Its name must start with the alias and end with a
+// number, such as tweets-20240101-000001, so that rollovers can number the next indices.
+func (m *IndexManager) CreateIndex(ctx context.Context, name string) error {
+	if err := m.put(ctx, opensearchapi.IndicesCreateRequest{Index: name}); err != nil {
+		return fmt.Errorf("failed to create index %s: %w", name, err)
+	}
+	return nil
+}
+
+// BulkIndex indexes tweets into an index, in batches. It fails when any tweet is not indexed.
+func (m *IndexManager) BulkIndex(ctx context.Context, index string, tweets []domain.Tweet) error {
+	for start := 0; start < len(tweets); start += bulkBatchSize {
+		end := min(start+bulkBatchSize, len(tweets))
+		failures, err := bulkIndex(ctx, m.client, index, tweets[start:end])
+		if err != nil {
+			return err
+		}
+		for id, failure := range failures {
+			return fmt.Errorf("failed to index %d tweets, such as %s: %s", len(failures), id, failure.Reason)
+		}
+	}
+	return nil
+}
+
+// SwapAlias points the tweets alias at index alone, atomically, and returns the indices it
+// pointed to before. They are kept so that a bad reindex can be rolled back. An index
+// named like the alias, created before indices were versioned, is deleted in the same step.
+func (m *IndexManager) SwapAlias(ctx context.Context, index string) ([]string, error) {
+	previous, legacy, err := m.aliasIndices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	actions := []interface{}{}
+	for _, old := range previous {
+		actions = append(actions, map[string]interface{}{
+			"remove": map[string]interface{}{"index": old, "alias": tweetsIndex},
+		})
+	}
+	if legacy {
+		actions = append(actions, map[string]interface{}{
+			"remove_index": map[string]interface{}{"index": tweetsIndex},
+		})
+	}
+	actions = append(actions, map[string]interface{}{
+		"add": map[string]interface{}{"index": index, "alias": tweetsIndex, "is_write_index": true},
+	})
+
+	if err := m.put(ctx, opensearchapi.IndicesUpdateAliasesRequest{Body: jsonBody(map[string]interface{}{"actions": actions})}); err != nil {
+		return nil, fmt.Errorf("failed to swap alias %s to %s: %w", tweetsIndex, index, err)
+	}
+	return previous, nil
+}
+
+// aliasIndices returns the indices the tweets alias points to, and whether a concrete index
+// has the name of the alias instead
+func (m *IndexManager) aliasIndices(ctx context.Context) ([]string, bool, error) {
+	res, err := opensearchapi.IndicesGetAliasRequest{Name: []string{tweetsIndex}}.Do(ctx, m.client)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get alias %s: %w", tweetsIndex, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		exists, err := opensearchapi.IndicesExistsRequest{Index: []string{tweetsIndex}}.Do(ctx, m.client)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to check index %s: %w", tweetsIndex, err)
+		}
+		defer exists.Body.Close()
+		return nil, exists.StatusCode == http.StatusOK, nil
+	}
+	if res.IsError() {
+		return nil, false, fmt.Errorf("error getting alias %s: %s", tweetsIndex, res.String())
+	}
+
+	var indices map[string]json.RawMessage
+	if err := json.NewDecoder(res.Body).Decode(&indices); err != nil {
+		return nil, false, fmt.Errorf("failed to decode alias %s: %w", tweetsIndex, err)
+	}
+	names := make([]string, 0, len(indices))
+	for name := range indices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, false, nil
+}
+
+// put runs an index administration request and fails on error responses
+func (m *IndexManager) put(ctx context.Context, req opensearchapi.Request) error {
+	res, err := req.Do(ctx, m.client)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("%s", strings.TrimSpace(res.String()))
+	}
+	return nil
+}
+
+func jsonBody(v interface{}) io.Reader {
+	body, _ := json.Marshal(v)
+	return bytes.NewReader(body)
+}
@@ -0,0 +1,54 @@
+package opensearch
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lisandro/challenge/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "opensearch_request_duration_seconds",
+	Help:    "Time of OpenSearch requests, by API and status class.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"operation", "status"})
+
+// instrumentedTransport records the latency of every request sent to OpenSearch
+type instrumentedTransport struct {
+	next http.RoundTripper
+}
+
+// NewInstrumentedTransport wraps the transport of an OpenSearch client to record the latency
+// of its requests. Retried requests are recorded once per attempt.
+func NewInstrumentedTransport(next http.RoundTripper) http.RoundTripper {
+	return &instrumentedTransport{next: next}
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	res, err := t.next.RoundTrip(req)
+
+	statusCode := 0
+	if err == nil {
+		statusCode = res.StatusCode
+	}
+	requestDuration.WithLabelValues(operation(req.URL.Path), metrics.StatusClass(statusCode, err)).Observe(time.Since(start).Seconds())
+	return res, err
+}
+
+// operation names the API of a request path after its first underscored segment, such as
+// search in /tweets/_search. Paths without one address an index.
+func operation(path string) string {
+	for _, segment := range strings.Split(strings.Trim(path, "/"), "/") {
+		if strings.HasPrefix(segment, "_") {
+			return strings.TrimPrefix(segment, "_")
+		}
+	}
+	if strings.Trim(path, "/") == "" {
+		return "root"
+	}
+	return "index"
+}
@@ -14,6 +14,8 @@ import (
 )
 
 const (
+	// tweetsIndex is the alias of the versioned tweets indices. Writes go to its write index
+	// and reads span every index it points to.
 	tweetsIndex = "tweets"
 )
 
@@ -27,17 +29,29 @@ func NewSearchRepository(client *opensearch.Client) domain.SearchRepository {
 	}
 }
 
-// IndexTweet indexes a tweet in OpenSearch
-func (r *searchRepository) IndexTweet(tweet *domain.Tweet) error {
-	doc := map[string]interface{}{
+// tweetDocument is the indexed form of a tweet
+func tweetDocument(tweet *domain.Tweet) map[string]interface{} {
+	return map[string]interface{}{
 		"id":         tweet.ID.String(),
 		"user_id":    tweet.UserID.String(),
 		"content":    tweet.Content,
 		"created_at": tweet.CreatedAt.Format(time.RFC3339),
 		"updated_at": tweet.UpdatedAt.Format(time.RFC3339),
 	}
+}
+
+// tweetSource is the source of an indexed tweet, as written by tweetDocument
+type tweetSource struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
 
-	docJSON, err := json.Marshal(doc)
+// IndexTweet indexes a tweet in OpenSearch
+func (r *searchRepository) IndexTweet(ctx context.Context, tweet *domain.Tweet) error {
+	docJSON, err := json.Marshal(tweetDocument(tweet))
 	if err != nil {
 		return fmt.Errorf("failed to marshal tweet: %w", err)
 	}
@@ -48,34 +62,78 @@ func (r *searchRepository) IndexTweet(tweet *domain.Tweet) error {
 		Body:       strings.NewReader(string(docJSON)),
 	}
 
-	res, err := req.Do(context.Background(), r.client)
+	res, err := req.Do(ctx, r.client)
 	if err != nil {
-		return fmt.Errorf("failed to index tweet: %w", err)
+		return transportError("index tweet", err)
 	}
 	defer res.Body.Close()
 
 	if res.IsError() {
-		return fmt.Errorf("error indexing tweet: %s", res.String())
+		return fmt.Errorf("error indexing tweet: %w", responseError(res))
 	}
 
 	return nil
 }
 
-func (r *searchRepository) GetTweetsByUsersID(userIDs []uuid.UUID, page, pageSize int) ([]domain.Tweet, error) {
-	from := (page - 1) * pageSize
-
+// DeleteByUser removes every indexed tweet written by a user
+func (r *searchRepository) DeleteByUser(ctx context.Context, userID uuid.UUID) error {
 	query := map[string]interface{}{
 		"query": map[string]interface{}{
-			"terms": map[string]interface{}{
-				"user_id": userIDs,
+			"term": map[string]interface{}{
+				"user_id": userID.String(),
 			},
 		},
+	}
+
+	queryJSON, err := json.Marshal(query)
+	if err != nil {
+		return fmt.Errorf("failed to marshal query: %w", err)
+	}
+
+	// Conflicts with concurrent updates are skipped instead of aborting the whole deletion
+	req := opensearchapi.DeleteByQueryRequest{
+		Index:     []string{tweetsIndex},
+		Body:      strings.NewReader(string(queryJSON)),
+		Conflicts: "proceed",
+		Refresh:   opensearchapi.BoolPtr(true),
+	}
+
+	res, err := req.Do(ctx, r.client)
+	if err != nil {
+		return transportError("delete tweets", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("error deleting tweets: %w", responseError(res))
+	}
+
+	return nil
+}
+
+// GetTweetsByUsersID returns a page of the tweets of userIDs within tweetRange, newest first
+func (r *searchRepository) GetTweetsByUsersID(ctx context.Context, userIDs []uuid.UUID, tweetRange domain.TweetRange, page, pageSize int) ([]domain.Tweet, error) {
+	from := (page - 1) * pageSize
+
+	filter, err := r.tweetsQuery(ctx, userIDs, tweetRange)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tweets created in the same second are ordered by ID, matching the range bounds
+	query := map[string]interface{}{
+		"query": filter,
 		"sort": []map[string]interface{}{
 			{
 				"created_at": map[string]interface{}{
 					"order": "desc",
 				},
 			},
+			{
+				"id": map[string]interface{}{
+					"order": "desc",
+				},
+			},
 		},
 		"from": from,
 		"size": pageSize,
@@ -91,52 +149,187 @@ func (r *searchRepository) GetTweetsByUsersID(userIDs []uuid.UUID, page, pageSiz
 		Body:  strings.NewReader(string(queryJSON)),
 	}
 
-	response, err := searchRequest.Do(context.Background(), r.client)
+	var result searchResponse[tweetSource]
+	if err := r.search(ctx, searchRequest, "search tweets", &result); err != nil {
+		return nil, err
+	}
+
+	tweets := make([]domain.Tweet, 0, len(result.Hits.Hits))
+	for _, hit := range result.Hits.Hits {
+		source := hit.Source
+		if source.ID == uuid.Nil || source.UserID == uuid.Nil {
+			return nil, fmt.Errorf("indexed tweet %s is missing its ID or user ID", hit.ID)
+		}
+		tweets = append(tweets, domain.Tweet{
+			ID:        source.ID,
+			UserID:    source.UserID,
+			Content:   source.Content,
+			CreatedAt: source.CreatedAt,
+			UpdatedAt: source.UpdatedAt,
+		})
+	}
+
+	return tweets, nil
+}
+
+// search runs a search request for tweets and decodes its response into result. Responses
+// missing the hits of some shards are errors.
+func (r *searchRepository) search(ctx context.Context, req opensearchapi.SearchRequest, action string, result *searchResponse[tweetSource]) error {
+	res, err := req.Do(ctx, r.client)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search tweets: %w", err)
+		return transportError(action, err)
 	}
-	defer response.Body.Close()
+	defer res.Body.Close()
 
-	var result map[string]interface{}
-	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if res.IsError() {
+		return fmt.Errorf("failed to %s: %w", action, responseError(res))
 	}
+	if err := json.NewDecoder(res.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if err := result.Shards.check(result.TimedOut); err != nil {
+		return fmt.Errorf("failed to %s: %w", action, err)
+	}
+	return nil
+}
 
-	hits := result["hits"].(map[string]interface{})["hits"].([]interface{})
-	tweets := make([]domain.Tweet, 0, len(hits))
+// CountTweetsByUsersID counts the tweets of userIDs within tweetRange
+func (r *searchRepository) CountTweetsByUsersID(ctx context.Context, userIDs []uuid.UUID, tweetRange domain.TweetRange) (int, error) {
+	filter, err := r.tweetsQuery(ctx, userIDs, tweetRange)
+	if err != nil {
+		return 0, err
+	}
 
-	for _, hit := range hits {
-		hitMap := hit.(map[string]interface{})
-		source := hitMap["_source"].(map[string]interface{})
+	queryJSON, err := json.Marshal(map[string]interface{}{"query": filter})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal query: %w", err)
+	}
 
-		tweetID, err := uuid.Parse(source["id"].(string))
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse tweet ID: %w", err)
-		}
+	req := opensearchapi.CountRequest{
+		Index: []string{tweetsIndex},
+		Body:  strings.NewReader(string(queryJSON)),
+	}
 
-		userID, err := uuid.Parse(source["user_id"].(string))
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse user ID: %w", err)
-		}
+	res, err := req.Do(ctx, r.client)
+	if err != nil {
+		return 0, transportError("count tweets", err)
+	}
+	defer res.Body.Close()
 
-		createdAt, err := time.Parse(time.RFC3339, source["created_at"].(string))
+	if res.IsError() {
+		return 0, fmt.Errorf("failed to count tweets: %w", responseError(res))
+	}
+
+	var result countResponse
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if err := result.Shards.check(false); err != nil {
+		return 0, fmt.Errorf("failed to count tweets: %w", err)
+	}
+
+	return result.Count, nil
+}
+
+// tweetsQuery builds the query matching the tweets of userIDs within tweetRange
+func (r *searchRepository) tweetsQuery(ctx context.Context, userIDs []uuid.UUID, tweetRange domain.TweetRange) (map[string]interface{}, error) {
+	filters := []interface{}{
+		map[string]interface{}{
+			"terms": map[string]interface{}{
+				"user_id": userIDs,
+			},
+		},
+	}
+
+	if tweetRange.SinceID != uuid.Nil {
+		since, err := r.position(ctx, tweetRange.SinceID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse created_at: %w", err)
+			return nil, err
 		}
+		filters = append(filters, since.bound("gt"))
+	}
 
-		updatedAt, err := time.Parse(time.RFC3339, source["updated_at"].(string))
+	if tweetRange.MaxID != uuid.Nil {
+		until, err := r.position(ctx, tweetRange.MaxID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse updated_at: %w", err)
+			return nil, err
 		}
+		filters = append(filters, until.bound("lt"))
+	}
 
-		tweets = append(tweets, domain.Tweet{
-			ID:        tweetID,
-			UserID:    userID,
-			Content:   source["content"].(string),
-			CreatedAt: createdAt,
-			UpdatedAt: updatedAt,
-		})
+	return map[string]interface{}{
+		"bool": map[string]interface{}{
+			"filter": filters,
+		},
+	}, nil
+}
+
+// tweetPosition is the place of a tweet in the tweet lists, sorted by creation time then ID
+type tweetPosition struct {
+	id        string
+	createdAt string
+}
+
+// position looks up the indexed creation time of a tweet. The indexed value is used so that
+// range bounds compare exactly with the indexed documents. The tweet is searched by ID rather
+// than fetched, since the alias may span several indices.
+func (r *searchRepository) position(ctx context.Context, id uuid.UUID) (*tweetPosition, error) {
+	queryJSON, err := json.Marshal(map[string]interface{}{
+		"query": map[string]interface{}{
+			"ids": map[string]interface{}{"values": []string{id.String()}},
+		},
+		"_source": []string{"created_at"},
+		"size":    1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal query: %w", err)
 	}
 
-	return tweets, nil
+	req := opensearchapi.SearchRequest{
+		Index: []string{tweetsIndex},
+		Body:  strings.NewReader(string(queryJSON)),
+	}
+
+	var result searchResponse[tweetSource]
+	if err := r.search(ctx, req, "get tweet", &result); err != nil {
+		return nil, err
+	}
+	if len(result.Hits.Hits) == 0 {
+		return nil, domain.ErrTweetNotFound
+	}
+
+	// created_at is compared in the indexed format, which has a precision of seconds
+	createdAt := result.Hits.Hits[0].Source.CreatedAt.Format(time.RFC3339)
+	return &tweetPosition{id: id.String(), createdAt: createdAt}, nil
+}
+
+// bound matches the tweets after ("gt") or before ("lt") the position: created later or
+// earlier, or created at the same time with a greater or lower ID
+func (p *tweetPosition) bound(op string) map[string]interface{} {
+	return map[string]interface{}{
+		"bool": map[string]interface{}{
+			"should": []interface{}{
+				map[string]interface{}{
+					"range": map[string]interface{}{
+						"created_at": map[string]interface{}{op: p.createdAt},
+					},
+				},
+				map[string]interface{}{
+					"bool": map[string]interface{}{
+						"filter": []interface{}{
+							map[string]interface{}{
+								"term": map[string]interface{}{"created_at": p.createdAt},
+							},
+							map[string]interface{}{
+								"range": map[string]interface{}{
+									"id": map[string]interface{}{op: p.id},
+								},
+							},
+						},
+					},
+				},
+			},
+			"minimum_should_match": 1,
+		},
+	}
 }
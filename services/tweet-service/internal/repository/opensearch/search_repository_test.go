@@ -0,0 +1,267 @@
+package opensearch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lisandro/challenge/services/tweet-service/internal/domain"
+	"github.com/opensearch-project/opensearch-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeResponse struct {
+	status int
+	body   string
+}
+
+// fakeOpenSearch answers requests with canned responses, in order, repeating the last one
+type fakeOpenSearch struct {
+	mu        sync.Mutex
+	responses []fakeResponse
+	paths     []string
+	bodies    []string
+}
+
+func newFakeOpenSearch(t *testing.T, responses ...fakeResponse) (*fakeOpenSearch, domain.SearchRepository) {
+	fake := &fakeOpenSearch{responses: responses}
+	server := httptest.NewServer(http.HandlerFunc(fake.handle))
+	t.Cleanup(server.Close)
+
+	// Retries would replay the canned error responses
+	client, err := opensearch.NewClient(opensearch.Config{Addresses: []string{server.URL}, DisableRetry: true})
+	require.NoError(t, err)
+	return fake, NewSearchRepository(client)
+}
+
+func (f *fakeOpenSearch) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	f.paths = append(f.paths, r.URL.Path)
+	f.bodies = append(f.bodies, string(body))
+
+	response := f.responses[0]
+	if len(f.responses) > 1 {
+		f.responses = f.responses[1:]
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(response.status)
+	io.WriteString(w, response.body)
+}
+
+// hitsResponse is a search response with hits, from shards of which failed have failed
+func hitsResponse(shards, failed int, timedOut bool, sources ...string) fakeResponse {
+	hits := ""
+	for i, source := range sources {
+		if i > 0 {
+			hits += ","
+		}
+		hits += fmt.Sprintf(`{"_index":"tweets-1","_id":"%d","_source":%s}`, i, source)
+	}
+	failures := ""
+	if failed > 0 {
+		failures = `,"failures":[{"shard":1,"index":"tweets-1","reason":{"type":"node_disconnected_exception","reason":"node left"}}]`
+	}
+	return fakeResponse{status: http.StatusOK, body: fmt.Sprintf(
+		`{"took":3,"timed_out":%t,"_shards":{"total":%d,"successful":%d,"skipped":0,"failed":%d%s},"hits":{"total":{"value":%d,"relation":"eq"},"hits":[%s]}}`,
+		timedOut, shards, shards-failed, failed, failures, len(sources), hits)}
+}
+
+func tweetHit(tweet domain.Tweet) string {
+	return fmt.Sprintf(`{"id":"%s","user_id":"%s","content":"%s","created_at":"%s","updated_at":"%s"}`,
+		tweet.ID, tweet.UserID, tweet.Content, tweet.CreatedAt.Format(time.RFC3339), tweet.UpdatedAt.Format(time.RFC3339))
+}
+
+func TestGetTweetsByUsersID_DecodesHits(t *testing.T) {
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tweet := domain.Tweet{ID: uuid.New(), UserID: uuid.New(), Content: "hello", CreatedAt: createdAt, UpdatedAt: createdAt}
+	fake, repo := newFakeOpenSearch(t, hitsResponse(2, 0, false, tweetHit(tweet)))
+
+	tweets, err := repo.GetTweetsByUsersID(context.Background(), []uuid.UUID{tweet.UserID}, domain.TweetRange{}, 1, 10)
+
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Tweet{tweet}, tweets)
+	assert.Equal(t, []string{"/tweets/_search"}, fake.paths)
+}
+
+func TestGetTweetsByUsersID_ErrorResponses(t *testing.T) {
+	tests := []struct {
+		name     string
+		response fakeResponse
+		kind     error
+		errType  string
+	}{
+		{
+			name: "index missing",
+			response: fakeResponse{http.StatusNotFound,
+				`{"error":{"root_cause":[{"type":"index_not_found_exception","reason":"no such index [tweets]"}],"type":"index_not_found_exception","reason":"no such index [tweets]"},"status":404}`},
+			kind:    ErrIndexNotFound,
+			errType: "index_not_found_exception",
+		},
+		{
+			name: "malformed query",
+			response: fakeResponse{http.StatusBadRequest,
+				`{"error":{"root_cause":[{"type":"parsing_exception","reason":"unknown query [term]"}],"type":"parsing_exception","reason":"unknown query [term]"},"status":400}`},
+			kind:    ErrMalformedQuery,
+			errType: "parsing_exception",
+		},
+		{
+			name: "search phase failure reports its root cause",
+			response: fakeResponse{http.StatusBadRequest,
+				`{"error":{"root_cause":[{"type":"query_shard_exception","reason":"failed to create query"}],"type":"search_phase_execution_exception","reason":"all shards failed"},"status":400}`},
+			kind:    ErrMalformedQuery,
+			errType: "query_shard_exception",
+		},
+		{
+			name: "overloaded",
+			response: fakeResponse{http.StatusTooManyRequests,
+				`{"error":{"type":"es_rejected_execution_exception","reason":"rejected execution"},"status":429}`},
+			kind:    ErrUnavailable,
+			errType: "es_rejected_execution_exception",
+		},
+		{
+			name:     "proxy error without a JSON body",
+			response: fakeResponse{http.StatusBadGateway, `<html>502 Bad Gateway</html>`},
+			kind:     ErrUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, repo := newFakeOpenSearch(t, tt.response)
+
+			tweets, err := repo.GetTweetsByUsersID(context.Background(), []uuid.UUID{uuid.New()}, domain.TweetRange{}, 1, 10)
+
+			assert.Nil(t, tweets)
+			assert.ErrorIs(t, err, tt.kind)
+			var responseErr *ResponseError
+			require.ErrorAs(t, err, &responseErr)
+			assert.Equal(t, tt.response.status, responseErr.Status)
+			assert.Equal(t, tt.errType, responseErr.Type)
+		})
+	}
+}
+
+func TestGetTweetsByUsersID_Unreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	client, err := opensearch.NewClient(opensearch.Config{Addresses: []string{server.URL}, DisableRetry: true})
+	require.NoError(t, err)
+
+	_, err = NewSearchRepository(client).GetTweetsByUsersID(context.Background(), []uuid.UUID{uuid.New()}, domain.TweetRange{}, 1, 10)
+
+	assert.ErrorIs(t, err, ErrUnavailable)
+}
+
+func TestGetTweetsByUsersID_ShardFailures(t *testing.T) {
+	tweet := domain.Tweet{ID: uuid.New(), UserID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	tests := []struct {
+		name     string
+		response fakeResponse
+		kind     error
+	}{
+		{"some shards failed", hitsResponse(3, 1, false, tweetHit(tweet)), ErrPartialResults},
+		{"timed out", hitsResponse(3, 0, true, tweetHit(tweet)), ErrPartialResults},
+		{"every shard failed", hitsResponse(3, 3, false), ErrUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, repo := newFakeOpenSearch(t, tt.response)
+
+			tweets, err := repo.GetTweetsByUsersID(context.Background(), []uuid.UUID{tweet.UserID}, domain.TweetRange{}, 1, 10)
+
+			// Partial hits would silently leave tweets out of a timeline
+			assert.Nil(t, tweets)
+			assert.ErrorIs(t, err, tt.kind)
+		})
+	}
+}
+
+func TestGetTweetsByUsersID_MalformedHit(t *testing.T) {
+	_, repo := newFakeOpenSearch(t,
+		hitsResponse(1, 0, false, `{"content":"no ids"}`),
+	)
+
+	tweets, err := repo.GetTweetsByUsersID(context.Background(), []uuid.UUID{uuid.New()}, domain.TweetRange{}, 1, 10)
+
+	assert.Nil(t, tweets)
+	assert.Error(t, err)
+}
+
+func TestGetTweetsByUsersID_UnknownBound(t *testing.T) {
+	fake, repo := newFakeOpenSearch(t, hitsResponse(1, 0, false))
+
+	_, err := repo.GetTweetsByUsersID(context.Background(), []uuid.UUID{uuid.New()}, domain.TweetRange{SinceID: uuid.New()}, 1, 10)
+
+	assert.ErrorIs(t, err, domain.ErrTweetNotFound)
+	assert.Len(t, fake.paths, 1)
+}
+
+func TestGetTweetsByUsersID_BoundsUseIndexedTime(t *testing.T) {
+	bound := domain.Tweet{ID: uuid.New(), UserID: uuid.New(), CreatedAt: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	fake, repo := newFakeOpenSearch(t,
+		hitsResponse(1, 0, false, tweetHit(bound)),
+		hitsResponse(1, 0, false),
+	)
+
+	_, err := repo.GetTweetsByUsersID(context.Background(), []uuid.UUID{bound.UserID}, domain.TweetRange{MaxID: bound.ID}, 1, 10)
+
+	require.NoError(t, err)
+	require.Len(t, fake.bodies, 2)
+	assert.Contains(t, fake.bodies[1], `{"lt":"2024-03-01T12:00:00Z"}`)
+}
+
+func TestCountTweetsByUsersID(t *testing.T) {
+	t.Run("counts", func(t *testing.T) {
+		_, repo := newFakeOpenSearch(t, fakeResponse{http.StatusOK,
+			`{"count":7,"_shards":{"total":2,"successful":2,"skipped":0,"failed":0}}`})
+
+		count, err := repo.CountTweetsByUsersID(context.Background(), []uuid.UUID{uuid.New()}, domain.TweetRange{})
+
+		require.NoError(t, err)
+		assert.Equal(t, 7, count)
+	})
+
+	t.Run("partial count", func(t *testing.T) {
+		_, repo := newFakeOpenSearch(t, fakeResponse{http.StatusOK,
+			`{"count":3,"_shards":{"total":2,"successful":1,"skipped":0,"failed":1}}`})
+
+		_, err := repo.CountTweetsByUsersID(context.Background(), []uuid.UUID{uuid.New()}, domain.TweetRange{})
+
+		assert.ErrorIs(t, err, ErrPartialResults)
+	})
+
+	t.Run("index missing", func(t *testing.T) {
+		_, repo := newFakeOpenSearch(t, fakeResponse{http.StatusNotFound,
+			`{"error":{"type":"index_not_found_exception","reason":"no such index [tweets]"},"status":404}`})
+
+		_, err := repo.CountTweetsByUsersID(context.Background(), []uuid.UUID{uuid.New()}, domain.TweetRange{})
+
+		assert.ErrorIs(t, err, ErrIndexNotFound)
+	})
+}
+
+func TestOperation(t *testing.T) {
+	tests := map[string]string{
+		"/tweets/_search":           "search",
+		"/tweets/_count":            "count",
+		"/_bulk":                    "bulk",
+		"/_cluster/health":          "cluster",
+		"/tweets-20240101-000001":   "index",
+		"/":                         "root",
+		"/_plugins/_ism/policies/x": "plugins",
+	}
+	for path, want := range tests {
+		assert.Equal(t, want, operation(path), path)
+	}
+}
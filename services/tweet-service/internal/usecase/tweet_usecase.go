@@ -1,30 +1,59 @@
 package usecase
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/lisandro/challenge/services/tweet-service/internal/domain"
+	opensearchrepo "github.com/lisandro/challenge/services/tweet-service/internal/repository/opensearch"
 )
 
+// ReadSource is where lists of tweets are read from
+type ReadSource string
+
+const (
+	// ReadFromSearch reads lists from the search index, and from storage while it fails
+	ReadFromSearch ReadSource = "search"
+	// ReadFromStorage reads lists from storage only. Unlike the search index, storage shows a
+	// tweet as soon as it is created.
+	ReadFromStorage ReadSource = "storage"
+)
+
+// ParseReadSource parses the name of a read source
+func ParseReadSource(name string) (ReadSource, error) {
+	switch source := ReadSource(name); source {
+	case ReadFromSearch, ReadFromStorage:
+		return source, nil
+	}
+	return "", fmt.Errorf("unknown read source %q, want %q or %q", name, ReadFromSearch, ReadFromStorage)
+}
+
 // tweetUsecase implements domain.TweetUseCase
 type tweetUsecase struct {
 	repo      domain.TweetRepository
 	searchRepo domain.SearchRepository
+	pins      domain.PinRepository
+	publisher domain.EventPublisher
+	readSource ReadSource
 }
 
-// NewTweetUseCase creates a new tweet usecase instance
-func NewTweetUseCase(repo domain.TweetRepository, searchRepo domain.SearchRepository) domain.TweetUseCase {
+// NewTweetUseCase creates a new tweet usecase instance reading lists of tweets from readSource
+func NewTweetUseCase(repo domain.TweetRepository, searchRepo domain.SearchRepository, pins domain.PinRepository, publisher domain.EventPublisher, readSource ReadSource) domain.TweetUseCase {
 	return &tweetUsecase{
 		repo:      repo,
 		searchRepo: searchRepo,
+		pins:      pins,
+		publisher: publisher,
+		readSource: readSource,
 	}
 }
 
 // CreateTweet creates a new tweet for a user
-func (u *tweetUsecase) CreateTweet(userID uuid.UUID, content string) (*domain.Tweet, error) {
+func (u *tweetUsecase) CreateTweet(ctx context.Context, userID uuid.UUID, content string) (*domain.Tweet, error) {
 	// Validate content length (Twitter-like limit of 240 characters)
 	if len(content) > 240 {
 		return nil, errors.New("tweet content cannot exceed 240 characters")
@@ -43,26 +72,31 @@ func (u *tweetUsecase) CreateTweet(userID uuid.UUID, content string) (*domain.Tw
 		UpdatedAt: time.Now(),
 	}
 
-	if err := u.repo.Create(tweet); err != nil {
+	if err := u.repo.Create(ctx, tweet); err != nil {
 		return nil, err
 	}
 
-	// Index the tweet in OpenSearch
-	if err := u.searchRepo.IndexTweet(tweet); err != nil {
-		// Log the error but don't fail the request
-		// In a production environment, you might want to handle this differently
-		// For example, you could use a message queue to retry the indexing
+	// Index the tweet in OpenSearch. The tweet is stored either way, so a failure does not
+	// fail the request; a tweet that could not be indexed is searchable after a reindex.
+	if err := u.searchRepo.IndexTweet(ctx, tweet); err != nil {
 		log.Printf("Failed to index tweet in OpenSearch: %v", err)
 	}
 
-	// TODO: Publish tweet event to SNS
-	// This will be implemented when we set up the AWS SNS client
+	// Let subscribers such as the timeline stream know about the tweet
+	u.publisher.Publish(domain.TweetEvent{
+		ID:         tweet.ID.String(),
+		Type:       domain.EventTweetCreated,
+		Tweet:      *tweet,
+		OccurredAt: tweet.CreatedAt,
+	})
 
 	return tweet, nil
 }
 
-// GetTweetsByUsersID retrieves tweets from a list of user IDs
-func (u *tweetUsecase) GetTweetsByUsersID(userIDs []uuid.UUID, page, pageSize int) ([]domain.Tweet, error) {
+// GetTweetsByUsersID retrieves tweets from a list of user IDs within a range. It returns
+// domain.ErrTweetNotFound when a bound of the range does not exist. When the search index
+// fails, the tweets are read from storage instead and the list is marked as degraded.
+func (u *tweetUsecase) GetTweetsByUsersID(ctx context.Context, userIDs []uuid.UUID, tweetRange domain.TweetRange, page, pageSize int) (*domain.TweetList, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -70,5 +104,169 @@ func (u *tweetUsecase) GetTweetsByUsersID(userIDs []uuid.UUID, page, pageSize in
 		pageSize = 10
 	}
 	
-	return u.searchRepo.GetTweetsByUsersID(userIDs, page, pageSize)
+	return u.listTweets(ctx, userIDs, tweetRange, page, pageSize)
+}
+
+// listTweets reads a page of tweets from the read source. The search index falls back to
+// storage when it is unavailable, missing or only some of its shards answer, and the list is
+// then marked as degraded. Other errors, such as queries the index rejects, are returned.
+func (u *tweetUsecase) listTweets(ctx context.Context, userIDs []uuid.UUID, tweetRange domain.TweetRange, page, pageSize int) (*domain.TweetList, error) {
+	if u.readSource == ReadFromStorage {
+		return u.storedTweets(ctx, userIDs, tweetRange, page, pageSize)
+	}
+
+	tweets, err := u.searchRepo.GetTweetsByUsersID(ctx, userIDs, tweetRange, page, pageSize)
+	if err == nil {
+		return &domain.TweetList{Tweets: tweets}, nil
+	}
+	// Nobody is waiting for the tweets of a canceled request, so storage is not read either
+	if !searchDown(err) || ctx.Err() != nil {
+		return nil, err
+	}
+	log.Printf("Search index failed, reading tweets from storage: %v", err)
+
+	list, err := u.storedTweets(ctx, userIDs, tweetRange, page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	list.Degraded = true
+	return list, nil
+}
+
+// searchDown reports whether err means that the search index cannot answer for now, rather
+// than that the request or this service is wrong
+func searchDown(err error) bool {
+	return errors.Is(err, opensearchrepo.ErrUnavailable) ||
+		errors.Is(err, opensearchrepo.ErrPartialResults) ||
+		errors.Is(err, opensearchrepo.ErrIndexNotFound)
+}
+
+// storedTweets reads a page of tweets from storage. Storage has no offsets, so reading a
+// later page reads every page before it.
+func (u *tweetUsecase) storedTweets(ctx context.Context, userIDs []uuid.UUID, tweetRange domain.TweetRange, page, pageSize int) (*domain.TweetList, error) {
+	tweets, err := u.repo.GetByUsers(ctx, userIDs, tweetRange, page*pageSize)
+	if err != nil {
+		return nil, err
+	}
+	start := min((page-1)*pageSize, len(tweets))
+	return &domain.TweetList{Tweets: tweets[start:]}, nil
+}
+
+// CountTweetsByUsersID counts the tweets from a list of user IDs within a range, so that
+// clients can tell how many new tweets there are without fetching them. It returns
+// domain.ErrTweetNotFound when a bound of the range does not exist.
+func (u *tweetUsecase) CountTweetsByUsersID(ctx context.Context, userIDs []uuid.UUID, tweetRange domain.TweetRange) (int, error) {
+	if u.readSource == ReadFromStorage {
+		return u.repo.CountByUsers(ctx, userIDs, tweetRange)
+	}
+	return u.searchRepo.CountTweetsByUsersID(ctx, userIDs, tweetRange)
+}
+
+// GetTweetsByUser returns a page of the tweets written by a user from storage
+func (u *tweetUsecase) GetTweetsByUser(ctx context.Context, userID uuid.UUID, cursor string, limit int) ([]domain.Tweet, string, error) {
+	if limit < 1 {
+		limit = 10
+	}
+
+	return u.repo.GetByUser(ctx, userID, cursor, limit)
+}
+
+// GetProfileTweets returns a page of the profile timeline of a user, newest first, starting
+// after the tweet cursor. The first page also carries the pinned tweet, which is then left
+// out of its tweets. It returns domain.ErrTweetNotFound when cursor is not a known tweet,
+// and domain.ErrProfileTabNotSupported for tabs whose content does not exist yet.
+func (u *tweetUsecase) GetProfileTweets(ctx context.Context, userID uuid.UUID, tab string, cursor uuid.UUID, limit int) (*domain.ProfileTweets, error) {
+	// Replies do not exist yet, so only the tweets tab has content
+	if tab != "" && tab != domain.ProfileTabTweets {
+		return nil, domain.ErrProfileTabNotSupported
+	}
+	if limit < 1 {
+		limit = 10
+	}
+
+	// One extra tweet tells whether there is a next page
+	list, err := u.listTweets(ctx, []uuid.UUID{userID}, domain.TweetRange{MaxID: cursor}, 1, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	tweets := list.Tweets
+	profile := &domain.ProfileTweets{Tweets: tweets, Degraded: list.Degraded}
+	if len(tweets) > limit {
+		profile.Tweets = tweets[:limit]
+		profile.NextCursor = tweets[limit-1].ID.String()
+	}
+
+	if cursor == uuid.Nil {
+		pinned, err := u.pinnedTweet(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		profile.PinnedTweet = pinned
+		if pinned != nil {
+			profile.Tweets = withoutTweet(profile.Tweets, pinned.ID)
+		}
+	}
+	return profile, nil
+}
+
+// withoutTweet returns tweets without the tweet with the given ID
+func withoutTweet(tweets []domain.Tweet, id uuid.UUID) []domain.Tweet {
+	kept := make([]domain.Tweet, 0, len(tweets))
+	for _, tweet := range tweets {
+		if tweet.ID != id {
+			kept = append(kept, tweet)
+		}
+	}
+	return kept
+}
+
+// pinnedTweet returns the tweet pinned by a user, or nil when there is none or it was deleted
+func (u *tweetUsecase) pinnedTweet(ctx context.Context, userID uuid.UUID) (*domain.Tweet, error) {
+	tweetID, err := u.pins.GetPinnedTweetID(ctx, userID)
+	if err != nil || tweetID == uuid.Nil {
+		return nil, err
+	}
+
+	tweet, err := u.repo.GetByID(ctx, tweetID)
+	if errors.Is(err, domain.ErrTweetNotFound) {
+		return nil, nil
+	}
+	return tweet, err
+}
+
+// PinTweet pins one of the tweets of a user to their profile, replacing the tweet pinned
+// before. It returns domain.ErrTweetNotFound when the tweet does not exist and
+// domain.ErrNotTweetAuthor when another user wrote it.
+func (u *tweetUsecase) PinTweet(ctx context.Context, userID, tweetID uuid.UUID) error {
+	tweet, err := u.repo.GetByID(ctx, tweetID)
+	if err != nil {
+		return err
+	}
+	if tweet.UserID != userID {
+		return domain.ErrNotTweetAuthor
+	}
+
+	return u.pins.PinTweet(ctx, userID, tweetID)
+}
+
+// UnpinTweet removes the pinned tweet of a user. It succeeds when nothing is pinned.
+func (u *tweetUsecase) UnpinTweet(ctx context.Context, userID uuid.UUID) error {
+	return u.pins.UnpinTweet(ctx, userID)
+}
+
+// DeleteUserTweets removes every tweet of a user from storage and from the search index.
+// It is safe to call again after a partial failure.
+func (u *tweetUsecase) DeleteUserTweets(ctx context.Context, userID uuid.UUID) error {
+	if err := u.pins.UnpinTweet(ctx, userID); err != nil {
+		return err
+	}
+
+	deleted, err := u.repo.DeleteByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	log.Printf("Deleted %d tweets of user %s", deleted, userID)
+
+	return u.searchRepo.DeleteByUser(ctx, userID)
 }
@@ -1,12 +1,15 @@
 package usecase
 
 import (
+	"context"
+	"fmt"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/lisandro/challenge/services/tweet-service/internal/domain"
+	opensearchrepo "github.com/lisandro/challenge/services/tweet-service/internal/repository/opensearch"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -16,44 +19,131 @@ type MockTweetRepository struct {
 	mock.Mock
 }
 
-func (m *MockTweetRepository) Create(tweet *domain.Tweet) error {
-	args := m.Called(tweet)
+func (m *MockTweetRepository) Create(ctx context.Context, tweet *domain.Tweet) error {
+	args := m.Called(ctx, tweet)
 	return args.Error(0)
 }
 
+func (m *MockTweetRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Tweet, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Tweet), args.Error(1)
+}
+
+func (m *MockTweetRepository) GetByUser(ctx context.Context, userID uuid.UUID, cursor string, limit int) ([]domain.Tweet, string, error) {
+	args := m.Called(ctx, userID, cursor, limit)
+	if args.Get(0) == nil {
+		return nil, args.String(1), args.Error(2)
+	}
+	return args.Get(0).([]domain.Tweet), args.String(1), args.Error(2)
+}
+
+func (m *MockTweetRepository) GetByUsers(ctx context.Context, userIDs []uuid.UUID, tweetRange domain.TweetRange, limit int) ([]domain.Tweet, error) {
+	args := m.Called(ctx, userIDs, tweetRange, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Tweet), args.Error(1)
+}
+
+func (m *MockTweetRepository) CountByUsers(ctx context.Context, userIDs []uuid.UUID, tweetRange domain.TweetRange) (int, error) {
+	args := m.Called(ctx, userIDs, tweetRange)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockTweetRepository) DeleteByUser(ctx context.Context, userID uuid.UUID) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
 // MockSearchRepository is a mock implementation of domain.SearchRepository
 type MockSearchRepository struct {
 	mock.Mock
 }
 
-func (m *MockSearchRepository) GetTweetsByUsersID(userIDs []uuid.UUID, page, pageSize int) ([]domain.Tweet, error) {
-	args := m.Called(userIDs, page, pageSize)
+func (m *MockSearchRepository) GetTweetsByUsersID(ctx context.Context, userIDs []uuid.UUID, tweetRange domain.TweetRange, page, pageSize int) ([]domain.Tweet, error) {
+	args := m.Called(ctx, userIDs, tweetRange, page, pageSize)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).([]domain.Tweet), args.Error(1)
 }
 
-func (m *MockSearchRepository) IndexTweet(tweet *domain.Tweet) error {
-	args := m.Called(tweet)
+func (m *MockSearchRepository) CountTweetsByUsersID(ctx context.Context, userIDs []uuid.UUID, tweetRange domain.TweetRange) (int, error) {
+	args := m.Called(ctx, userIDs, tweetRange)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockSearchRepository) IndexTweet(ctx context.Context, tweet *domain.Tweet) error {
+	args := m.Called(ctx, tweet)
 	return args.Error(0)
 }
 
+func (m *MockSearchRepository) DeleteByUser(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+// MockPinRepository is a mock implementation of domain.PinRepository
+type MockPinRepository struct {
+	mock.Mock
+}
+
+func (m *MockPinRepository) GetPinnedTweetID(ctx context.Context, userID uuid.UUID) (uuid.UUID, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *MockPinRepository) PinTweet(ctx context.Context, userID, tweetID uuid.UUID) error {
+	args := m.Called(ctx, userID, tweetID)
+	return args.Error(0)
+}
+
+func (m *MockPinRepository) UnpinTweet(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+// MockEventPublisher is a mock implementation of domain.EventPublisher
+type MockEventPublisher struct {
+	mock.Mock
+}
+
+func (m *MockEventPublisher) Publish(event domain.TweetEvent) {
+	m.Called(event)
+}
+
+// newTestTweetUseCase returns a usecase whose published events are accepted and recorded
+func newTestTweetUseCase(repo *MockTweetRepository, searchRepo *MockSearchRepository) (domain.TweetUseCase, *MockEventPublisher) {
+	pins := new(MockPinRepository)
+	pins.On("UnpinTweet", mock.Anything, mock.Anything).Return(nil).Maybe()
+	return newTestTweetUseCaseWithPins(repo, searchRepo, pins)
+}
+
+// newTestTweetUseCaseWithPins is newTestTweetUseCase for tests that check the pinned tweets
+func newTestTweetUseCaseWithPins(repo *MockTweetRepository, searchRepo *MockSearchRepository, pins *MockPinRepository) (domain.TweetUseCase, *MockEventPublisher) {
+	publisher := new(MockEventPublisher)
+	publisher.On("Publish", mock.AnythingOfType("domain.TweetEvent")).Maybe()
+	return NewTweetUseCase(repo, searchRepo, pins, publisher, ReadFromSearch), publisher
+}
+
 func TestCreateTweet(t *testing.T) {
 	// Setup
 	mockRepo := new(MockTweetRepository)
 	mockSearchRepo := new(MockSearchRepository)
-	usecase := NewTweetUseCase(mockRepo, mockSearchRepo)
+	usecase, _ := newTestTweetUseCase(mockRepo, mockSearchRepo)
 
 	userID := uuid.New()
 	content := "Test tweet content"
 
 	// Expectations
-	mockRepo.On("Create", mock.AnythingOfType("*domain.Tweet")).Return(nil)
-	mockSearchRepo.On("IndexTweet", mock.AnythingOfType("*domain.Tweet")).Return(nil)
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.Tweet")).Return(nil)
+	mockSearchRepo.On("IndexTweet", mock.Anything, mock.AnythingOfType("*domain.Tweet")).Return(nil)
 
 	// Execute
-	tweet, err := usecase.CreateTweet(userID, content)
+	tweet, err := usecase.CreateTweet(context.Background(), userID, content)
 
 	// Assert
 	assert.NoError(t, err)
@@ -72,16 +162,16 @@ func TestCreateTweet_RepositoryError(t *testing.T) {
 	// Setup
 	mockRepo := new(MockTweetRepository)
 	mockSearchRepo := new(MockSearchRepository)
-	usecase := NewTweetUseCase(mockRepo, mockSearchRepo)
+	usecase, _ := newTestTweetUseCase(mockRepo, mockSearchRepo)
 
 	userID := uuid.New()
 	content := "Test tweet content"
 
 	// Expectations
-	mockRepo.On("Create", mock.AnythingOfType("*domain.Tweet")).Return(assert.AnError)
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.Tweet")).Return(assert.AnError)
 
 	// Execute
-	tweet, err := usecase.CreateTweet(userID, content)
+	tweet, err := usecase.CreateTweet(context.Background(), userID, content)
 
 	// Assert
 	assert.Error(t, err)
@@ -89,14 +179,14 @@ func TestCreateTweet_RepositoryError(t *testing.T) {
 	assert.Equal(t, assert.AnError, err)
 
 	mockRepo.AssertExpectations(t)
-	mockSearchRepo.AssertNotCalled(t, "IndexTweet")
+	mockSearchRepo.AssertNotCalled(t, "IndexTweet", mock.Anything)
 }
 
 func TestGetTweetsByUsersID(t *testing.T) {
 	// Setup
 	mockRepo := new(MockTweetRepository)
 	mockSearchRepo := new(MockSearchRepository)
-	usecase := NewTweetUseCase(mockRepo, mockSearchRepo)
+	usecase, _ := newTestTweetUseCase(mockRepo, mockSearchRepo)
 
 	userIDs := []uuid.UUID{uuid.New(), uuid.New()}
 	page := 1
@@ -120,14 +210,14 @@ func TestGetTweetsByUsersID(t *testing.T) {
 	}
 
 	// Expectations
-	mockSearchRepo.On("GetTweetsByUsersID", userIDs, page, pageSize).Return(expectedTweets, nil)
+	mockSearchRepo.On("GetTweetsByUsersID", mock.Anything, userIDs, domain.TweetRange{}, page, pageSize).Return(expectedTweets, nil)
 
 	// Execute
-	tweets, err := usecase.GetTweetsByUsersID(userIDs, page, pageSize)
+	tweets, err := usecase.GetTweetsByUsersID(context.Background(), userIDs, domain.TweetRange{}, page, pageSize)
 
 	// Assert
 	assert.NoError(t, err)
-	assert.Equal(t, expectedTweets, tweets)
+	assert.Equal(t, &domain.TweetList{Tweets: expectedTweets}, tweets)
 
 	mockSearchRepo.AssertExpectations(t)
 }
@@ -136,7 +226,7 @@ func TestGetTweetsByUsersID_InvalidPage(t *testing.T) {
 	// Setup
 	mockRepo := new(MockTweetRepository)
 	mockSearchRepo := new(MockSearchRepository)
-	usecase := NewTweetUseCase(mockRepo, mockSearchRepo)
+	usecase, _ := newTestTweetUseCase(mockRepo, mockSearchRepo)
 
 	userIDs := []uuid.UUID{uuid.New()}
 	page := 0
@@ -145,14 +235,14 @@ func TestGetTweetsByUsersID_InvalidPage(t *testing.T) {
 	expectedTweets := []domain.Tweet{}
 
 	// Expectations
-	mockSearchRepo.On("GetTweetsByUsersID", userIDs, 1, pageSize).Return(expectedTweets, nil)
+	mockSearchRepo.On("GetTweetsByUsersID", mock.Anything, userIDs, domain.TweetRange{}, 1, pageSize).Return(expectedTweets, nil)
 
 	// Execute
-	tweets, err := usecase.GetTweetsByUsersID(userIDs, page, pageSize)
+	tweets, err := usecase.GetTweetsByUsersID(context.Background(), userIDs, domain.TweetRange{}, page, pageSize)
 
 	// Assert
 	assert.NoError(t, err)
-	assert.Equal(t, expectedTweets, tweets)
+	assert.Equal(t, &domain.TweetList{Tweets: expectedTweets}, tweets)
 
 	mockSearchRepo.AssertExpectations(t)
 }
@@ -161,7 +251,7 @@ func TestGetTweetsByUsersID_InvalidPageSize(t *testing.T) {
 	// Setup
 	mockRepo := new(MockTweetRepository)
 	mockSearchRepo := new(MockSearchRepository)
-	usecase := NewTweetUseCase(mockRepo, mockSearchRepo)
+	usecase, _ := newTestTweetUseCase(mockRepo, mockSearchRepo)
 
 	userIDs := []uuid.UUID{uuid.New()}
 	page := 1
@@ -170,14 +260,14 @@ func TestGetTweetsByUsersID_InvalidPageSize(t *testing.T) {
 	expectedTweets := []domain.Tweet{}
 
 	// Expectations
-	mockSearchRepo.On("GetTweetsByUsersID", userIDs, page, 10).Return(expectedTweets, nil)
+	mockSearchRepo.On("GetTweetsByUsersID", mock.Anything, userIDs, domain.TweetRange{}, page, 10).Return(expectedTweets, nil)
 
 	// Execute
-	tweets, err := usecase.GetTweetsByUsersID(userIDs, page, pageSize)
+	tweets, err := usecase.GetTweetsByUsersID(context.Background(), userIDs, domain.TweetRange{}, page, pageSize)
 
 	// Assert
 	assert.NoError(t, err)
-	assert.Equal(t, expectedTweets, tweets)
+	assert.Equal(t, &domain.TweetList{Tweets: expectedTweets}, tweets)
 
 	mockSearchRepo.AssertExpectations(t)
 }
@@ -186,17 +276,18 @@ func TestGetTweetsByUsersID_SearchError(t *testing.T) {
 	// Setup
 	mockRepo := new(MockTweetRepository)
 	mockSearchRepo := new(MockSearchRepository)
-	usecase := NewTweetUseCase(mockRepo, mockSearchRepo)
+	usecase, _ := newTestTweetUseCase(mockRepo, mockSearchRepo)
 
 	userIDs := []uuid.UUID{uuid.New()}
 	page := 1
 	pageSize := 10
 
 	// Expectations
-	mockSearchRepo.On("GetTweetsByUsersID", userIDs, page, pageSize).Return(nil, assert.AnError)
+	mockSearchRepo.On("GetTweetsByUsersID", mock.Anything, userIDs, domain.TweetRange{}, page, pageSize).Return(nil, opensearchrepo.ErrUnavailable)
+	mockRepo.On("GetByUsers", mock.Anything, userIDs, domain.TweetRange{}, page*pageSize).Return(nil, assert.AnError)
 
 	// Execute
-	tweets, err := usecase.GetTweetsByUsersID(userIDs, page, pageSize)
+	tweets, err := usecase.GetTweetsByUsersID(context.Background(), userIDs, domain.TweetRange{}, page, pageSize)
 
 	// Assert
 	assert.Error(t, err)
@@ -204,20 +295,133 @@ func TestGetTweetsByUsersID_SearchError(t *testing.T) {
 	assert.Equal(t, assert.AnError, err)
 
 	mockSearchRepo.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetTweetsByUsersID_TweetRange(t *testing.T) {
+	mockRepo := new(MockTweetRepository)
+	mockSearchRepo := new(MockSearchRepository)
+	usecase, _ := newTestTweetUseCase(mockRepo, mockSearchRepo)
+
+	userIDs := []uuid.UUID{uuid.New()}
+	tweetRange := domain.TweetRange{SinceID: uuid.New(), MaxID: uuid.New()}
+
+	mockSearchRepo.On("GetTweetsByUsersID", mock.Anything, userIDs, tweetRange, 1, 10).Return(nil, domain.ErrTweetNotFound)
+
+	tweets, err := usecase.GetTweetsByUsersID(context.Background(), userIDs, tweetRange, 1, 10)
+
+	assert.ErrorIs(t, err, domain.ErrTweetNotFound)
+	assert.Nil(t, tweets)
+	mockSearchRepo.AssertExpectations(t)
+}
+
+func TestGetTweetsByUsersID_StorageFallback(t *testing.T) {
+	userIDs := []uuid.UUID{uuid.New()}
+	tweetRange := domain.TweetRange{SinceID: uuid.New()}
+	tweets := []domain.Tweet{{ID: uuid.New()}, {ID: uuid.New()}, {ID: uuid.New()}}
+
+	for _, searchErr := range []error{opensearchrepo.ErrUnavailable, opensearchrepo.ErrPartialResults, opensearchrepo.ErrIndexNotFound} {
+		t.Run(searchErr.Error(), func(t *testing.T) {
+			mockRepo := new(MockTweetRepository)
+			mockSearchRepo := new(MockSearchRepository)
+			usecase, _ := newTestTweetUseCase(mockRepo, mockSearchRepo)
+
+			mockSearchRepo.On("GetTweetsByUsersID", mock.Anything, userIDs, tweetRange, 2, 2).Return(nil, fmt.Errorf("failed to search: %w", searchErr))
+			mockRepo.On("GetByUsers", mock.Anything, userIDs, tweetRange, 4).Return(tweets, nil)
+
+			list, err := usecase.GetTweetsByUsersID(context.Background(), userIDs, tweetRange, 2, 2)
+
+			assert.NoError(t, err)
+			assert.Equal(t, &domain.TweetList{Tweets: tweets[2:], Degraded: true}, list)
+		})
+	}
+}
+
+func TestGetTweetsByUsersID_SearchErrorsWithoutFallback(t *testing.T) {
+	userIDs := []uuid.UUID{uuid.New()}
+
+	// A rejected query is a bug that storage would hide
+	for _, searchErr := range []error{opensearchrepo.ErrMalformedQuery, assert.AnError} {
+		t.Run(searchErr.Error(), func(t *testing.T) {
+			mockRepo := new(MockTweetRepository)
+			mockSearchRepo := new(MockSearchRepository)
+			usecase, _ := newTestTweetUseCase(mockRepo, mockSearchRepo)
+
+			mockSearchRepo.On("GetTweetsByUsersID", mock.Anything, userIDs, domain.TweetRange{}, 1, 10).Return(nil, searchErr)
+
+			list, err := usecase.GetTweetsByUsersID(context.Background(), userIDs, domain.TweetRange{}, 1, 10)
+
+			assert.ErrorIs(t, err, searchErr)
+			assert.Nil(t, list)
+			mockRepo.AssertNotCalled(t, "GetByUsers", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestGetTweetsByUsersID_StorageFallbackPastLastPage(t *testing.T) {
+	mockRepo := new(MockTweetRepository)
+	mockSearchRepo := new(MockSearchRepository)
+	usecase, _ := newTestTweetUseCase(mockRepo, mockSearchRepo)
+
+	userIDs := []uuid.UUID{uuid.New()}
+	tweets := []domain.Tweet{{ID: uuid.New()}}
+
+	mockSearchRepo.On("GetTweetsByUsersID", mock.Anything, userIDs, domain.TweetRange{}, 3, 10).Return(nil, opensearchrepo.ErrUnavailable)
+	mockRepo.On("GetByUsers", mock.Anything, userIDs, domain.TweetRange{}, 30).Return(tweets, nil)
+
+	list, err := usecase.GetTweetsByUsersID(context.Background(), userIDs, domain.TweetRange{}, 3, 10)
+
+	assert.NoError(t, err)
+	assert.Empty(t, list.Tweets)
+	assert.True(t, list.Degraded)
+}
+
+func TestGetTweetsByUsersID_CanceledRequestSkipsFallback(t *testing.T) {
+	mockRepo := new(MockTweetRepository)
+	mockSearchRepo := new(MockSearchRepository)
+	usecase, _ := newTestTweetUseCase(mockRepo, mockSearchRepo)
+	userIDs := []uuid.UUID{uuid.New()}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	mockSearchRepo.On("GetTweetsByUsersID", ctx, userIDs, domain.TweetRange{}, 1, 10).Return(nil, context.Canceled)
+
+	list, err := usecase.GetTweetsByUsersID(ctx, userIDs, domain.TweetRange{}, 1, 10)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, list)
+	mockRepo.AssertNotCalled(t, "GetByUsers", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCountTweetsByUsersID(t *testing.T) {
+	mockRepo := new(MockTweetRepository)
+	mockSearchRepo := new(MockSearchRepository)
+	usecase, _ := newTestTweetUseCase(mockRepo, mockSearchRepo)
+
+	userIDs := []uuid.UUID{uuid.New(), uuid.New()}
+	tweetRange := domain.TweetRange{SinceID: uuid.New()}
+
+	mockSearchRepo.On("CountTweetsByUsersID", mock.Anything, userIDs, tweetRange).Return(4, nil)
+
+	count, err := usecase.CountTweetsByUsersID(context.Background(), userIDs, tweetRange)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4, count)
+	mockSearchRepo.AssertExpectations(t)
 }
 
 func TestCreateTweet_ContentTooLong(t *testing.T) {
 	// Setup
 	mockRepo := new(MockTweetRepository)
 	mockSearchRepo := new(MockSearchRepository)
-	usecase := NewTweetUseCase(mockRepo, mockSearchRepo)
+	usecase, _ := newTestTweetUseCase(mockRepo, mockSearchRepo)
 
 	userID := uuid.New()
 	// Create content longer than 240 characters
 	content := strings.Repeat("a", 241)
 
 	// Execute
-	tweet, err := usecase.CreateTweet(userID, content)
+	tweet, err := usecase.CreateTweet(context.Background(), userID, content)
 
 	// Assert
 	assert.Error(t, err)
@@ -225,21 +429,21 @@ func TestCreateTweet_ContentTooLong(t *testing.T) {
 	assert.Contains(t, err.Error(), "tweet content cannot exceed 240 characters")
 
 	// Verify that no repository methods were called
-	mockRepo.AssertNotCalled(t, "Create")
-	mockSearchRepo.AssertNotCalled(t, "IndexTweet")
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+	mockSearchRepo.AssertNotCalled(t, "IndexTweet", mock.Anything)
 }
 
 func TestCreateTweet_EmptyContent(t *testing.T) {
 	// Setup
 	mockRepo := new(MockTweetRepository)
 	mockSearchRepo := new(MockSearchRepository)
-	usecase := NewTweetUseCase(mockRepo, mockSearchRepo)
+	usecase, _ := newTestTweetUseCase(mockRepo, mockSearchRepo)
 
 	userID := uuid.New()
 	content := ""
 
 	// Execute
-	tweet, err := usecase.CreateTweet(userID, content)
+	tweet, err := usecase.CreateTweet(context.Background(), userID, content)
 
 	// Assert
 	assert.Error(t, err)
@@ -247,26 +451,26 @@ func TestCreateTweet_EmptyContent(t *testing.T) {
 	assert.Contains(t, err.Error(), "tweet content cannot be empty")
 
 	// Verify that no repository methods were called
-	mockRepo.AssertNotCalled(t, "Create")
-	mockSearchRepo.AssertNotCalled(t, "IndexTweet")
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+	mockSearchRepo.AssertNotCalled(t, "IndexTweet", mock.Anything)
 }
 
 func TestCreateTweet_ExactlyMaxLength(t *testing.T) {
 	// Setup
 	mockRepo := new(MockTweetRepository)
 	mockSearchRepo := new(MockSearchRepository)
-	usecase := NewTweetUseCase(mockRepo, mockSearchRepo)
+	usecase, _ := newTestTweetUseCase(mockRepo, mockSearchRepo)
 
 	userID := uuid.New()
 	// Create content exactly 240 characters
 	content := strings.Repeat("a", 240)
 
 	// Expectations
-	mockRepo.On("Create", mock.AnythingOfType("*domain.Tweet")).Return(nil)
-	mockSearchRepo.On("IndexTweet", mock.AnythingOfType("*domain.Tweet")).Return(nil)
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.Tweet")).Return(nil)
+	mockSearchRepo.On("IndexTweet", mock.Anything, mock.AnythingOfType("*domain.Tweet")).Return(nil)
 
 	// Execute
-	tweet, err := usecase.CreateTweet(userID, content)
+	tweet, err := usecase.CreateTweet(context.Background(), userID, content)
 
 	// Assert
 	assert.NoError(t, err)
@@ -277,4 +481,258 @@ func TestCreateTweet_ExactlyMaxLength(t *testing.T) {
 
 	mockRepo.AssertExpectations(t)
 	mockSearchRepo.AssertExpectations(t)
-} 
\ No newline at end of file
+}
+
+func TestDeleteUserTweets(t *testing.T) {
+	t.Run("deletes from storage and search index", func(t *testing.T) {
+		mockRepo := new(MockTweetRepository)
+		mockSearchRepo := new(MockSearchRepository)
+		usecase, _ := newTestTweetUseCase(mockRepo, mockSearchRepo)
+		userID := uuid.New()
+
+		mockRepo.On("DeleteByUser", mock.Anything, userID).Return(3, nil)
+		mockSearchRepo.On("DeleteByUser", mock.Anything, userID).Return(nil)
+
+		err := usecase.DeleteUserTweets(context.Background(), userID)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+		mockSearchRepo.AssertExpectations(t)
+	})
+
+	t.Run("storage error skips the search index", func(t *testing.T) {
+		mockRepo := new(MockTweetRepository)
+		mockSearchRepo := new(MockSearchRepository)
+		usecase, _ := newTestTweetUseCase(mockRepo, mockSearchRepo)
+		userID := uuid.New()
+
+		mockRepo.On("DeleteByUser", mock.Anything, userID).Return(1, assert.AnError)
+
+		err := usecase.DeleteUserTweets(context.Background(), userID)
+
+		assert.ErrorIs(t, err, assert.AnError)
+		mockSearchRepo.AssertNotCalled(t, "DeleteByUser", mock.Anything, userID)
+	})
+}
+
+func TestCreateTweet_PublishesEvent(t *testing.T) {
+	mockRepo := new(MockTweetRepository)
+	mockSearchRepo := new(MockSearchRepository)
+	usecase, publisher := newTestTweetUseCase(mockRepo, mockSearchRepo)
+
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.Tweet")).Return(nil)
+	mockSearchRepo.On("IndexTweet", mock.Anything, mock.AnythingOfType("*domain.Tweet")).Return(nil)
+
+	tweet, err := usecase.CreateTweet(context.Background(), uuid.New(), "Hello")
+
+	assert.NoError(t, err)
+	publisher.AssertCalled(t, "Publish", domain.TweetEvent{
+		ID:         tweet.ID.String(),
+		Type:       domain.EventTweetCreated,
+		Tweet:      *tweet,
+		OccurredAt: tweet.CreatedAt,
+	})
+}
+
+func TestCreateTweet_RepositoryErrorPublishesNothing(t *testing.T) {
+	mockRepo := new(MockTweetRepository)
+	mockSearchRepo := new(MockSearchRepository)
+	usecase, publisher := newTestTweetUseCase(mockRepo, mockSearchRepo)
+
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.Tweet")).Return(assert.AnError)
+
+	_, err := usecase.CreateTweet(context.Background(), uuid.New(), "Hello")
+
+	assert.Error(t, err)
+	publisher.AssertNotCalled(t, "Publish", mock.Anything)
+}
+
+func TestGetProfileTweets(t *testing.T) {
+	userID := uuid.New()
+	tweets := []domain.Tweet{
+		{ID: uuid.New(), UserID: userID, Content: "third"},
+		{ID: uuid.New(), UserID: userID, Content: "second"},
+		{ID: uuid.New(), UserID: userID, Content: "first"},
+	}
+
+	t.Run("first page carries the pinned tweet and the next cursor", func(t *testing.T) {
+		mockRepo := new(MockTweetRepository)
+		mockSearchRepo := new(MockSearchRepository)
+		pins := new(MockPinRepository)
+		usecase, _ := newTestTweetUseCaseWithPins(mockRepo, mockSearchRepo, pins)
+		pinned := domain.Tweet{ID: uuid.New(), UserID: userID, Content: "pinned"}
+
+		mockSearchRepo.On("GetTweetsByUsersID", mock.Anything, []uuid.UUID{userID}, domain.TweetRange{}, 1, 3).Return(tweets, nil)
+		pins.On("GetPinnedTweetID", mock.Anything, userID).Return(pinned.ID, nil)
+		mockRepo.On("GetByID", mock.Anything, pinned.ID).Return(&pinned, nil)
+
+		profile, err := usecase.GetProfileTweets(context.Background(), userID, domain.ProfileTabTweets, uuid.Nil, 2)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &pinned, profile.PinnedTweet)
+		assert.Equal(t, tweets[:2], profile.Tweets)
+		assert.Equal(t, tweets[1].ID.String(), profile.NextCursor)
+	})
+
+	t.Run("later pages start after the cursor without the pinned tweet", func(t *testing.T) {
+		mockRepo := new(MockTweetRepository)
+		mockSearchRepo := new(MockSearchRepository)
+		pins := new(MockPinRepository)
+		usecase, _ := newTestTweetUseCaseWithPins(mockRepo, mockSearchRepo, pins)
+
+		mockSearchRepo.On("GetTweetsByUsersID", mock.Anything, []uuid.UUID{userID}, domain.TweetRange{MaxID: tweets[1].ID}, 1, 3).Return(tweets[2:], nil)
+
+		profile, err := usecase.GetProfileTweets(context.Background(), userID, domain.ProfileTabTweets, tweets[1].ID, 2)
+
+		assert.NoError(t, err)
+		assert.Nil(t, profile.PinnedTweet)
+		assert.Equal(t, tweets[2:], profile.Tweets)
+		assert.Empty(t, profile.NextCursor)
+		pins.AssertNotCalled(t, "GetPinnedTweetID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("pinned tweet is not repeated in the first page", func(t *testing.T) {
+		mockRepo := new(MockTweetRepository)
+		mockSearchRepo := new(MockSearchRepository)
+		pins := new(MockPinRepository)
+		usecase, _ := newTestTweetUseCaseWithPins(mockRepo, mockSearchRepo, pins)
+		pinned := tweets[1]
+
+		mockSearchRepo.On("GetTweetsByUsersID", mock.Anything, []uuid.UUID{userID}, domain.TweetRange{}, 1, 4).Return(tweets, nil)
+		pins.On("GetPinnedTweetID", mock.Anything, userID).Return(pinned.ID, nil)
+		mockRepo.On("GetByID", mock.Anything, pinned.ID).Return(&pinned, nil)
+
+		profile, err := usecase.GetProfileTweets(context.Background(), userID, domain.ProfileTabTweets, uuid.Nil, 3)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &pinned, profile.PinnedTweet)
+		assert.Equal(t, []domain.Tweet{tweets[0], tweets[2]}, profile.Tweets)
+	})
+
+	t.Run("deleted pinned tweet is left out", func(t *testing.T) {
+		mockRepo := new(MockTweetRepository)
+		mockSearchRepo := new(MockSearchRepository)
+		pins := new(MockPinRepository)
+		usecase, _ := newTestTweetUseCaseWithPins(mockRepo, mockSearchRepo, pins)
+		pinnedID := uuid.New()
+
+		mockSearchRepo.On("GetTweetsByUsersID", mock.Anything, []uuid.UUID{userID}, domain.TweetRange{}, 1, 11).Return(tweets, nil)
+		pins.On("GetPinnedTweetID", mock.Anything, userID).Return(pinnedID, nil)
+		mockRepo.On("GetByID", mock.Anything, pinnedID).Return(nil, domain.ErrTweetNotFound)
+
+		profile, err := usecase.GetProfileTweets(context.Background(), userID, "", uuid.Nil, 0)
+
+		assert.NoError(t, err)
+		assert.Nil(t, profile.PinnedTweet)
+		assert.Equal(t, tweets, profile.Tweets)
+	})
+
+	for _, tab := range []string{domain.ProfileTabReplies, domain.ProfileTabMedia, domain.ProfileTabLikes} {
+		t.Run("unsupported "+tab+" tab", func(t *testing.T) {
+			mockRepo := new(MockTweetRepository)
+			mockSearchRepo := new(MockSearchRepository)
+			usecase, _ := newTestTweetUseCase(mockRepo, mockSearchRepo)
+
+			_, err := usecase.GetProfileTweets(context.Background(), userID, tab, uuid.Nil, 20)
+
+			assert.ErrorIs(t, err, domain.ErrProfileTabNotSupported)
+			mockSearchRepo.AssertNotCalled(t, "GetTweetsByUsersID", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestPinTweet(t *testing.T) {
+	userID := uuid.New()
+
+	t.Run("pins own tweet", func(t *testing.T) {
+		mockRepo := new(MockTweetRepository)
+		pins := new(MockPinRepository)
+		usecase, _ := newTestTweetUseCaseWithPins(mockRepo, new(MockSearchRepository), pins)
+		tweet := &domain.Tweet{ID: uuid.New(), UserID: userID}
+
+		mockRepo.On("GetByID", mock.Anything, tweet.ID).Return(tweet, nil)
+		pins.On("PinTweet", mock.Anything, userID, tweet.ID).Return(nil)
+
+		assert.NoError(t, usecase.PinTweet(context.Background(), userID, tweet.ID))
+		pins.AssertExpectations(t)
+	})
+
+	t.Run("rejects the tweet of another user", func(t *testing.T) {
+		mockRepo := new(MockTweetRepository)
+		pins := new(MockPinRepository)
+		usecase, _ := newTestTweetUseCaseWithPins(mockRepo, new(MockSearchRepository), pins)
+		tweet := &domain.Tweet{ID: uuid.New(), UserID: uuid.New()}
+
+		mockRepo.On("GetByID", mock.Anything, tweet.ID).Return(tweet, nil)
+
+		assert.ErrorIs(t, usecase.PinTweet(context.Background(), userID, tweet.ID), domain.ErrNotTweetAuthor)
+		pins.AssertNotCalled(t, "PinTweet", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("unknown tweet", func(t *testing.T) {
+		mockRepo := new(MockTweetRepository)
+		usecase, _ := newTestTweetUseCaseWithPins(mockRepo, new(MockSearchRepository), new(MockPinRepository))
+		tweetID := uuid.New()
+
+		mockRepo.On("GetByID", mock.Anything, tweetID).Return(nil, domain.ErrTweetNotFound)
+
+		assert.ErrorIs(t, usecase.PinTweet(context.Background(), userID, tweetID), domain.ErrTweetNotFound)
+	})
+}
+
+func TestReadFromStorage(t *testing.T) {
+	userIDs := []uuid.UUID{uuid.New(), uuid.New()}
+	tweetRange := domain.TweetRange{SinceID: uuid.New()}
+	newStorageUseCase := func(mockRepo *MockTweetRepository, mockSearchRepo *MockSearchRepository) domain.TweetUseCase {
+		return NewTweetUseCase(mockRepo, mockSearchRepo, new(MockPinRepository), new(MockEventPublisher), ReadFromStorage)
+	}
+
+	t.Run("lists tweets from storage", func(t *testing.T) {
+		mockRepo := new(MockTweetRepository)
+		mockSearchRepo := new(MockSearchRepository)
+		usecase := newStorageUseCase(mockRepo, mockSearchRepo)
+		tweets := []domain.Tweet{{ID: uuid.New()}, {ID: uuid.New()}}
+
+		mockRepo.On("GetByUsers", mock.Anything, userIDs, tweetRange, 10).Return(tweets, nil)
+
+		list, err := usecase.GetTweetsByUsersID(context.Background(), userIDs, tweetRange, 1, 10)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &domain.TweetList{Tweets: tweets}, list)
+		mockSearchRepo.AssertNotCalled(t, "GetTweetsByUsersID", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("counts tweets in storage", func(t *testing.T) {
+		mockRepo := new(MockTweetRepository)
+		mockSearchRepo := new(MockSearchRepository)
+		usecase := newStorageUseCase(mockRepo, mockSearchRepo)
+
+		mockRepo.On("CountByUsers", mock.Anything, userIDs, tweetRange).Return(4, nil)
+
+		count, err := usecase.CountTweetsByUsersID(context.Background(), userIDs, tweetRange)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 4, count)
+		mockSearchRepo.AssertNotCalled(t, "CountTweetsByUsersID", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("unknown bound", func(t *testing.T) {
+		mockRepo := new(MockTweetRepository)
+		usecase := newStorageUseCase(mockRepo, new(MockSearchRepository))
+
+		mockRepo.On("GetByUsers", mock.Anything, userIDs, tweetRange, 10).Return(nil, domain.ErrTweetNotFound)
+
+		_, err := usecase.GetTweetsByUsersID(context.Background(), userIDs, tweetRange, 1, 10)
+
+		assert.ErrorIs(t, err, domain.ErrTweetNotFound)
+	})
+}
+
+func TestParseReadSource(t *testing.T) {
+	source, err := ParseReadSource("storage")
+	assert.NoError(t, err)
+	assert.Equal(t, ReadFromStorage, source)
+
+	_, err = ParseReadSource("cache")
+	assert.Error(t, err)
+}
@@ -6,18 +6,14 @@ until curl -s http://localhost:9200/_cluster/health | grep -q '"status":"green"\
     sleep 1
 done
 
-# Create tweets index with mapping
+# The tweets alias, its index template and its first versioned index are created by the
+# reindex command, which also indexes any tweets already in DynamoDB
+if [ "$(curl -s -o /dev/null -w '%{http_code}' http://localhost:9200/tweets)" = "200" ]; then
+    echo "OpenSearch index already exists, run make reindex to rebuild it"
+    exit 0
+fi
+
 echo "Creating tweets index..."
-curl -X PUT "http://localhost:9200/tweets" -H "Content-Type: application/json" -d '{
-  "mappings": {
-    "properties": {
-      "id": { "type": "keyword" },
-      "user_id": { "type": "keyword" },
-      "content": { "type": "text" },
-      "created_at": { "type": "date" },
-      "updated_at": { "type": "date" }
-    }
-  }
-}'
+go run ./cmd/api reindex
 
-echo "OpenSearch index created successfully!" 
\ No newline at end of file
+echo "OpenSearch index created successfully!"
@@ -13,14 +13,16 @@ aws dynamodb create-table \
     --attribute-definitions \
         AttributeName=id,AttributeType=S \
         AttributeName=user_id,AttributeType=S \
+        AttributeName=created_at,AttributeType=S \
     --key-schema \
         AttributeName=id,KeyType=HASH \
     --global-secondary-indexes \
         "[
             {
-                \"IndexName\": \"user_id-index\",
+                \"IndexName\": \"user_id-created_at-index\",
                 \"KeySchema\": [
-                    {\"AttributeName\":\"user_id\",\"KeyType\":\"HASH\"}
+                    {\"AttributeName\":\"user_id\",\"KeyType\":\"HASH\"},
+                    {\"AttributeName\":\"created_at\",\"KeyType\":\"RANGE\"}
                 ],
                 \"Projection\": {
                     \"ProjectionType\":\"ALL\"
@@ -34,6 +36,18 @@ aws dynamodb create-table \
     --provisioned-throughput \
         ReadCapacityUnits=5,WriteCapacityUnits=5
 
+# Create DynamoDB table for the tweets pinned to user profiles
+aws dynamodb create-table \
+    --endpoint-url http://localhost:4566 \
+    --region us-east-1 \
+    --table-name pinned_tweets \
+    --attribute-definitions \
+        AttributeName=user_id,AttributeType=S \
+    --key-schema \
+        AttributeName=user_id,KeyType=HASH \
+    --provisioned-throughput \
+        ReadCapacityUnits=5,WriteCapacityUnits=5
+
 # Verify table creation
 echo "Verifying table creation..."
 aws dynamodb describe-table \
@@ -0,0 +1,83 @@
+#!/bin/bash
+
+# Migrates a tweets table created by an earlier create-table.sh: adds the
+# user_id-created_at-index the service now reads and deletes the tweets of a user
+# through, then drops the user_id-index it no longer uses. Running it again is a no-op.
+
+set -e
+
+# Default to the AWS credentials and endpoint of LocalStack
+export AWS_ACCESS_KEY_ID=${AWS_ACCESS_KEY_ID:-test}
+export AWS_SECRET_ACCESS_KEY=${AWS_SECRET_ACCESS_KEY:-test}
+export AWS_DEFAULT_REGION=us-east-1
+
+ENDPOINT_URL=${ENDPOINT_URL:-http://localhost:4566}
+TABLE_NAME=${TABLE_NAME:-tweets}
+
+has_index() {
+    aws dynamodb describe-table \
+        --endpoint-url "$ENDPOINT_URL" \
+        --region us-east-1 \
+        --table-name "$TABLE_NAME" \
+        --query "Table.GlobalSecondaryIndexes[?IndexName=='$1'].IndexName" \
+        --output text | grep -q "$1"
+}
+
+# DynamoDB creates or deletes one index per update, so each change is awaited before the next
+wait_for_indexes() {
+    until [ "$(aws dynamodb describe-table \
+        --endpoint-url "$ENDPOINT_URL" \
+        --region us-east-1 \
+        --table-name "$TABLE_NAME" \
+        --query "length(Table.GlobalSecondaryIndexes[?IndexStatus!='ACTIVE'] || \`[]\`)" \
+        --output text)" = "0" ]; do
+        sleep 5
+    done
+}
+
+if has_index user_id-created_at-index; then
+    echo "user_id-created_at-index already exists"
+else
+    echo "Creating user_id-created_at-index..."
+    aws dynamodb update-table \
+        --endpoint-url "$ENDPOINT_URL" \
+        --region us-east-1 \
+        --table-name "$TABLE_NAME" \
+        --attribute-definitions \
+            AttributeName=user_id,AttributeType=S \
+            AttributeName=created_at,AttributeType=S \
+        --global-secondary-index-updates \
+            "[
+                {
+                    \"Create\": {
+                        \"IndexName\": \"user_id-created_at-index\",
+                        \"KeySchema\": [
+                            {\"AttributeName\":\"user_id\",\"KeyType\":\"HASH\"},
+                            {\"AttributeName\":\"created_at\",\"KeyType\":\"RANGE\"}
+                        ],
+                        \"Projection\": {
+                            \"ProjectionType\":\"ALL\"
+                        },
+                        \"ProvisionedThroughput\": {
+                            \"ReadCapacityUnits\": 5,
+                            \"WriteCapacityUnits\": 5
+                        }
+                    }
+                }
+            ]" > /dev/null
+    echo "Waiting for user_id-created_at-index to be backfilled..."
+    wait_for_indexes
+fi
+
+if has_index user_id-index; then
+    echo "Deleting user_id-index..."
+    aws dynamodb update-table \
+        --endpoint-url "$ENDPOINT_URL" \
+        --region us-east-1 \
+        --table-name "$TABLE_NAME" \
+        --global-secondary-index-updates \
+            "[{\"Delete\": {\"IndexName\": \"user_id-index\"}}]" > /dev/null
+    wait_for_indexes
+fi
+
+echo "Table $TABLE_NAME is migrated"
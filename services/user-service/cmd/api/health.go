@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/lisandro/challenge/pkg/health"
+	"gorm.io/gorm"
+)
+
+// newHealthChecker checks the dependencies of the service for the readiness probe. Redis is
+// required too: follows and unfollows fail when their cached lists cannot be invalidated.
+func newHealthChecker(db *gorm.DB, rdb *redis.Client, timeout time.Duration) *health.Checker {
+	return health.NewChecker(timeout,
+		health.Dependency{
+			Name: "postgres",
+			Check: func(ctx context.Context) error {
+				sqlDB, err := db.DB()
+				if err != nil {
+					return err
+				}
+				return sqlDB.PingContext(ctx)
+			},
+		},
+		health.Dependency{
+			Name: "redis",
+			Check: func(ctx context.Context) error {
+				return rdb.Ping(ctx).Err()
+			},
+		},
+	)
+}
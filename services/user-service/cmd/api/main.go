@@ -1,16 +1,22 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"crypto/rand"
 	"log"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/lisandro/challenge/pkg/eventauth"
 	"github.com/lisandro/challenge/services/user-service/config"
+	"github.com/lisandro/challenge/services/user-service/internal/client"
 	_ "github.com/lisandro/challenge/services/user-service/docs" // This is important!
 	"github.com/lisandro/challenge/services/user-service/internal/delivery/http"
+	"github.com/lisandro/challenge/services/user-service/internal/events"
 	"github.com/lisandro/challenge/services/user-service/internal/repository"
 	pgRepo "github.com/lisandro/challenge/services/user-service/internal/repository/postgres"
 	redisRepo "github.com/lisandro/challenge/services/user-service/internal/repository/redis"
@@ -40,15 +46,7 @@ func main() {
 	log.Println("Starting user service...")
 
 	// Initialize PostgreSQL connection
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
-		getEnvOrDefault("DB_HOST", "localhost"),
-		getEnvOrDefault("DB_USER", "user_service"),
-		getEnvOrDefault("DB_PASSWORD", "user_service_pass"),
-		getEnvOrDefault("DB_NAME", "user_service_db"),
-		getEnvOrDefault("DB_PORT", "5432"),
-	)
-
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(config.DatabaseDSN()), &gorm.Config{})
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -67,7 +65,10 @@ func main() {
 
 	// Initialize repositories
 	pgRepository := pgRepo.NewPostgresRepository(db)
-	redisRepository := redisRepo.NewRedisRepository(rdb)
+	redisRepository := redisRepo.NewRedisRepository(rdb, redisRepo.Config{
+		UserTTL:   getDurationOrDefault("CACHE_USER_TTL", time.Hour),
+		FollowTTL: getDurationOrDefault("CACHE_FOLLOW_TTL", 15*time.Minute),
+	})
 	
 	// Initialize composite repository
 	userRepo := repository.NewCompositeRepository(pgRepository, redisRepository)
@@ -75,8 +76,68 @@ func main() {
 	// Initialize usecase with its dependencies
 	userUsecase := usecase.NewUserUsecase(userRepo)
 
+	// Initialize data exports, which read the tweets of the user from the tweet service
+	tweetClient := client.NewTweetClient(getEnvOrDefault("TWEET_SERVICE_URL", "http://localhost:8081"), 30*time.Second)
+	exportUsecase := usecase.NewExportUsecase(pgRepo.NewExportRepository(db), userRepo, tweetClient, usecase.ExportConfig{
+		SigningKey: exportSigningKey(),
+		Retention:  getDurationOrDefault("EXPORT_RETENTION", 7*24*time.Hour),
+		StaleAfter: getDurationOrDefault("EXPORT_STALE_AFTER", 15*time.Minute),
+	})
+
 	// Initialize HTTP server with its dependencies
-	server := http.NewServer(userUsecase)
+	checker := newHealthChecker(db, rdb, getDurationOrDefault("HEALTH_CHECK_TIMEOUT", 2*time.Second))
+	server := http.NewServer(userUsecase, exportUsecase, checker, getDurationOrDefault("REQUEST_TIMEOUT", 10*time.Second))
+
+	// Background workers run until ctx is canceled on shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var workers sync.WaitGroup
+
+	// Deliver user events to the services that keep data about users
+	subscribers, err := events.ParseSubscribers(getEnvOrDefault("EVENT_SUBSCRIBERS",
+		"tweet-service=http://localhost:8081/api/v1/internal/events,timeline-service=http://localhost:8082/api/v1/internal/events"))
+	if err != nil {
+		log.Fatalf("Failed to parse event subscribers: %v", err)
+	}
+	eventSecret, err := eventauth.SecretFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to load the event signing secret: %v", err)
+	}
+	dispatcher := events.NewDispatcher(pgRepo.NewEventRepository(db), subscribers, events.Config{
+		BatchSize:     100,
+		RetryDelay:    getDurationOrDefault("EVENT_RETRY_DELAY", time.Minute),
+		Timeout:       10 * time.Second,
+		SigningSecret: eventSecret,
+	})
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		dispatcher.Run(ctx, getDurationOrDefault("EVENT_DISPATCH_INTERVAL", 5*time.Second))
+	}()
+
+	// Permanently delete users whose deactivation grace period has expired
+	workers.Add(1)
+	go runEvery(ctx, &workers, getDurationOrDefault("USER_PURGE_INTERVAL", time.Hour), func(ctx context.Context) {
+		purged, err := userUsecase.PurgeDeactivatedUsers(ctx)
+		if err != nil {
+			log.Printf("Failed to purge deactivated users: %v", err)
+		}
+		if purged > 0 {
+			log.Printf("Purged %d deactivated users", purged)
+		}
+	})
+
+	// Generate requested data exports and expire the old ones
+	workers.Add(1)
+	go runEvery(ctx, &workers, getDurationOrDefault("EXPORT_PROCESS_INTERVAL", 10*time.Second), func(ctx context.Context) {
+		processed, err := exportUsecase.ProcessPendingExports(ctx)
+		if err != nil {
+			log.Printf("Failed to process data exports: %v", err)
+		}
+		if processed > 0 {
+			log.Printf("Processed %d data exports", processed)
+		}
+	})
 
 	// Start server in a goroutine
 	go func() {
@@ -92,6 +153,79 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), getDurationOrDefault("SHUTDOWN_TIMEOUT", 20*time.Second))
+	defer cancelShutdown()
+
+	// Stop taking requests and wait for the ones in flight
+	if err := server.Shutdown(shutdownCtx, getDurationOrDefault("SHUTDOWN_DRAIN_DELAY", 5*time.Second)); err != nil {
+		log.Printf("Failed to wait for requests in flight: %v", err)
+	}
+
+	// Stop the background jobs; the work of a canceled job is picked up again after a restart
+	cancel()
+	if err := waitGroup(shutdownCtx, &workers); err != nil {
+		log.Printf("Failed to wait for background workers: %v", err)
+	}
+
+	if err := rdb.Close(); err != nil {
+		log.Printf("Failed to close Redis connection: %v", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			log.Printf("Failed to close database connection: %v", err)
+		}
+	}
+	log.Println("Server stopped")
+}
+
+// waitGroup waits for wg until ctx is done
+func waitGroup(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// runEvery runs job right away and then every interval until ctx is done, and then marks
+// itself done in workers
+func runEvery(ctx context.Context, workers *sync.WaitGroup, interval time.Duration, job func(ctx context.Context)) {
+	defer workers.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		job(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// exportSigningKey returns the key that signs export download links. Without EXPORT_SIGNING_KEY
+// a random key is used, so links stop working on restart and are not shared between replicas.
+func exportSigningKey() []byte {
+	if key := os.Getenv("EXPORT_SIGNING_KEY"); key != "" {
+		return []byte(key)
+	}
+
+	log.Println("EXPORT_SIGNING_KEY is not set, using a random key for export download links")
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		log.Fatalf("Failed to generate export signing key: %v", err)
+	}
+	return key
 }
 
 func getEnvOrDefault(key, defaultValue string) string {
@@ -99,4 +233,17 @@ func getEnvOrDefault(key, defaultValue string) string {
 		return value
 	}
 	return defaultValue
-} 
\ No newline at end of file
+}
+
+func getDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid duration %q for %s, using default %s", value, key, defaultValue)
+		return defaultValue
+	}
+	return duration
+}
@@ -0,0 +1,24 @@
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+// DatabaseDSN builds the PostgreSQL connection string from the DB_* environment variables
+func DatabaseDSN() string {
+	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
+		getEnvOrDefault("DB_HOST", "localhost"),
+		getEnvOrDefault("DB_USER", "user_service"),
+		getEnvOrDefault("DB_PASSWORD", "user_service_pass"),
+		getEnvOrDefault("DB_NAME", "user_service_db"),
+		getEnvOrDefault("DB_PORT", "5432"),
+	)
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
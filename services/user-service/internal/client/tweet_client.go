@@ -0,0 +1,84 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/lisandro/challenge/pkg/metrics"
+	"github.com/lisandro/challenge/services/user-service/internal/domain"
+)
+
+// tweetsPageSize is the number of tweets requested per page from the tweet service
+const tweetsPageSize = 1000
+
+// TweetClient reads tweets from the tweet service HTTP API
+type TweetClient struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewTweetClient creates a new client of the tweet service at baseURL, e.g. http://localhost:8081
+func NewTweetClient(baseURL string, timeout time.Duration) *TweetClient {
+	return &TweetClient{
+		baseURL: baseURL,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// userTweetsPage is a page of GET /api/v1/tweets/users/{userID}
+type userTweetsPage struct {
+	Tweets     []domain.Tweet `json:"tweets"`
+	NextCursor string         `json:"next_cursor"`
+}
+
+// GetUserTweets returns every tweet written by a user, following the pages of the tweet service
+func (c *TweetClient) GetUserTweets(ctx context.Context, userID string) ([]domain.Tweet, error) {
+	tweets := []domain.Tweet{}
+	cursor := ""
+	for {
+		page, err := c.getUserTweetsPage(ctx, userID, cursor)
+		if err != nil {
+			return nil, err
+		}
+		tweets = append(tweets, page.Tweets...)
+		if page.NextCursor == "" {
+			return tweets, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+func (c *TweetClient) getUserTweetsPage(ctx context.Context, userID, cursor string) (*userTweetsPage, error) {
+	query := url.Values{"limit": {fmt.Sprint(tweetsPageSize)}}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	endpoint := fmt.Sprintf("%s/api/v1/tweets/users/%s?%s", c.baseURL, url.PathEscape(userID), query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	if err != nil {
+		metrics.ObserveClientRequest("tweet-service", start, 0, err)
+		return nil, fmt.Errorf("failed to get tweets of user %s: %w", userID, err)
+	}
+	metrics.ObserveClientRequest("tweet-service", start, resp.StatusCode, nil)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get tweets of user %s: tweet service answered %s", userID, resp.Status)
+	}
+
+	var page userTweetsPage
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("failed to decode tweets of user %s: %w", userID, err)
+	}
+	return &page, nil
+}
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lisandro/challenge/services/user-service/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTweetClient_GetUserTweets(t *testing.T) {
+	t.Run("follows every page", func(t *testing.T) {
+		var cursors []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/v1/tweets/users/user1", r.URL.Path)
+			assert.Equal(t, "1000", r.URL.Query().Get("limit"))
+			cursor := r.URL.Query().Get("cursor")
+			cursors = append(cursors, cursor)
+
+			page := userTweetsPage{Tweets: []domain.Tweet{{ID: "tweet1", UserID: "user1", Content: "first"}}, NextCursor: "tweet1"}
+			if cursor == "tweet1" {
+				page = userTweetsPage{Tweets: []domain.Tweet{{ID: "tweet2", UserID: "user1", Content: "second"}}}
+			}
+			json.NewEncoder(w).Encode(page)
+		}))
+		defer server.Close()
+
+		tweets, err := NewTweetClient(server.URL, time.Second).GetUserTweets(context.Background(), "user1")
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"", "tweet1"}, cursors)
+		require.Len(t, tweets, 2)
+		assert.Equal(t, "tweet1", tweets[0].ID)
+		assert.Equal(t, "tweet2", tweets[1].ID)
+	})
+
+	t.Run("user without tweets", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"tweets":[]}`))
+		}))
+		defer server.Close()
+
+		tweets, err := NewTweetClient(server.URL, time.Second).GetUserTweets(context.Background(), "user1")
+
+		require.NoError(t, err)
+		assert.Empty(t, tweets)
+	})
+
+	t.Run("error status", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		_, err := NewTweetClient(server.URL, time.Second).GetUserTweets(context.Background(), "user1")
+
+		assert.ErrorContains(t, err, "500")
+	})
+}
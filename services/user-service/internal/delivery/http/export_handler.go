@@ -0,0 +1,110 @@
+package http
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/lisandro/challenge/services/user-service/internal/domain"
+)
+
+type ExportHandler struct {
+	exportUsecase domain.ExportUsecase
+}
+
+// NewExportHandler creates a new data export handler with its dependencies
+func NewExportHandler(eu domain.ExportUsecase) *ExportHandler {
+	return &ExportHandler{
+		exportUsecase: eu,
+	}
+}
+
+// RequestExport godoc
+// @Summary Request an export of the current user's data
+// @Description Queue the generation of a zip archive with the profile, follow graph and tweets of the current user. If an export is already in progress, that export is returned.
+// @Tags exports
+// @Accept json
+// @Produce json
+// @Param X-User-ID header string true "ID of the current user"
+// @Success 202 {object} map[string]domain.DataExport
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/me/exports [post]
+func (h *ExportHandler) RequestExport(c *fiber.Ctx) error {
+	userID := c.Get("X-User-ID")
+
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User ID is required",
+		})
+	}
+
+	log.Println("Requesting data export for user:", userID)
+	export, err := h.exportUsecase.RequestExport(c.UserContext(), userID)
+	if err != nil {
+		return errorResponse(c, err, "Failed to request export")
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"export": export,
+	})
+}
+
+// GetExport godoc
+// @Summary Get the status of a data export
+// @Description Get the status of an export of the current user, with a download link once it is ready
+// @Tags exports
+// @Accept json
+// @Produce json
+// @Param exportID path string true "ID of the export"
+// @Param X-User-ID header string true "ID of the current user"
+// @Success 200 {object} map[string]domain.DataExport
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/me/exports/{exportID} [get]
+func (h *ExportHandler) GetExport(c *fiber.Ctx) error {
+	userID := c.Get("X-User-ID")
+
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User ID is required",
+		})
+	}
+
+	export, err := h.exportUsecase.GetExport(c.UserContext(), userID, c.Params("exportID"))
+	if err != nil {
+		return errorResponse(c, err, "Failed to get export")
+	}
+
+	return c.JSON(fiber.Map{
+		"export": export,
+	})
+}
+
+// DownloadExport godoc
+// @Summary Download a data export
+// @Description Download the zip archive of a ready export. The link is signed and expires with the export, so it does not require the X-User-ID header.
+// @Tags exports
+// @Produce application/zip
+// @Param exportID path string true "ID of the export"
+// @Param expires query int true "Expiry of the link as a Unix timestamp"
+// @Param signature query string true "Signature of the link"
+// @Success 200 {file} file
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/exports/{exportID}/download [get]
+func (h *ExportHandler) DownloadExport(c *fiber.Ctx) error {
+	exportID := c.Params("exportID")
+
+	archive, err := h.exportUsecase.GetArchive(c.UserContext(), exportID, int64(c.QueryInt("expires")), c.Query("signature"))
+	if err != nil {
+		return errorResponse(c, err, "Failed to download export")
+	}
+
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="export-%s.zip"`, exportID))
+	return c.Send(archive)
+}
@@ -0,0 +1,206 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/lisandro/challenge/services/user-service/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockExportUsecase is a mock implementation of domain.ExportUsecase
+type MockExportUsecase struct {
+	mock.Mock
+}
+
+func (m *MockExportUsecase) RequestExport(ctx context.Context, userID string) (*domain.DataExport, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(*domain.DataExport), args.Error(1)
+}
+
+func (m *MockExportUsecase) GetExport(ctx context.Context, userID, exportID string) (*domain.DataExport, error) {
+	args := m.Called(ctx, userID, exportID)
+	return args.Get(0).(*domain.DataExport), args.Error(1)
+}
+
+func (m *MockExportUsecase) GetArchive(ctx context.Context, exportID string, expires int64, signature string) ([]byte, error) {
+	args := m.Called(ctx, exportID, expires, signature)
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+func (m *MockExportUsecase) ProcessPendingExports(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func setupExportTest() (*fiber.App, *MockExportUsecase) {
+	app := fiber.New()
+	mockUsecase := new(MockExportUsecase)
+	RegisterExportRoutes(app, NewExportHandler(mockUsecase))
+	return app, mockUsecase
+}
+
+func TestExportHandler_RequestExport(t *testing.T) {
+	tests := []struct {
+		name           string
+		userID         string
+		mockExport     *domain.DataExport
+		mockError      error
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "export queued",
+			userID:         "user1",
+			mockExport:     &domain.DataExport{ID: "export1", UserID: "user1", Status: domain.ExportPending},
+			expectedStatus: fiber.StatusAccepted,
+		},
+		{
+			name:           "unknown user",
+			userID:         "user9",
+			mockExport:     (*domain.DataExport)(nil),
+			mockError:      domain.ErrUserNotFound,
+			expectedStatus: fiber.StatusNotFound,
+			expectedError:  "User not found",
+		},
+		{
+			name:           "missing user ID",
+			expectedStatus: fiber.StatusUnauthorized,
+			expectedError:  "User ID is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mockUsecase := setupExportTest()
+			if tt.userID != "" {
+				mockUsecase.On("RequestExport", mock.Anything, tt.userID).Return(tt.mockExport, tt.mockError)
+			}
+
+			req := httptest.NewRequest("POST", "/api/v1/users/me/exports", nil)
+			if tt.userID != "" {
+				req.Header.Set("X-User-ID", tt.userID)
+			}
+			resp, _ := app.Test(req)
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			var body map[string]interface{}
+			json.NewDecoder(resp.Body).Decode(&body)
+			if tt.expectedError != "" {
+				assert.Equal(t, tt.expectedError, body["error"])
+			} else {
+				assert.Equal(t, "export1", body["export"].(map[string]interface{})["id"])
+				assert.Equal(t, "pending", body["export"].(map[string]interface{})["status"])
+			}
+
+			mockUsecase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestExportHandler_GetExport(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockExport     *domain.DataExport
+		mockError      error
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "ready export",
+			mockExport:     &domain.DataExport{ID: "export1", UserID: "user1", Status: domain.ExportReady, DownloadURL: "/api/v1/users/exports/export1/download?expires=1&signature=abc"},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "export not found",
+			mockExport:     (*domain.DataExport)(nil),
+			mockError:      domain.ErrExportNotFound,
+			expectedStatus: fiber.StatusNotFound,
+			expectedError:  "Export not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mockUsecase := setupExportTest()
+			mockUsecase.On("GetExport", mock.Anything, "user1", "export1").Return(tt.mockExport, tt.mockError)
+
+			req := httptest.NewRequest("GET", "/api/v1/users/me/exports/export1", nil)
+			req.Header.Set("X-User-ID", "user1")
+			resp, _ := app.Test(req)
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			var body map[string]interface{}
+			json.NewDecoder(resp.Body).Decode(&body)
+			if tt.expectedError != "" {
+				assert.Equal(t, tt.expectedError, body["error"])
+			} else {
+				assert.Equal(t, tt.mockExport.DownloadURL, body["export"].(map[string]interface{})["download_url"])
+			}
+
+			mockUsecase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestExportHandler_DownloadExport(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockArchive    []byte
+		mockError      error
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "valid link",
+			mockArchive:    []byte("zip content"),
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "invalid link",
+			mockArchive:    []byte(nil),
+			mockError:      domain.ErrInvalidDownloadLink,
+			expectedStatus: fiber.StatusForbidden,
+			expectedError:  "Download link is invalid or has expired",
+		},
+		{
+			name:           "archive error",
+			mockArchive:    []byte(nil),
+			mockError:      errors.New("database error"),
+			expectedStatus: fiber.StatusInternalServerError,
+			expectedError:  "Failed to download export",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mockUsecase := setupExportTest()
+			mockUsecase.On("GetArchive", mock.Anything, "export1", int64(1700000000), "abc").Return(tt.mockArchive, tt.mockError)
+
+			req := httptest.NewRequest("GET", "/api/v1/users/exports/export1/download?expires=1700000000&signature=abc", nil)
+			resp, _ := app.Test(req)
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if tt.expectedError != "" {
+				var body map[string]interface{}
+				json.NewDecoder(resp.Body).Decode(&body)
+				assert.Equal(t, tt.expectedError, body["error"])
+			} else {
+				content, _ := io.ReadAll(resp.Body)
+				assert.Equal(t, tt.mockArchive, content)
+				assert.Equal(t, "application/zip", resp.Header.Get("Content-Type"))
+				assert.Equal(t, `attachment; filename="export-export1.zip"`, resp.Header.Get("Content-Disposition"))
+			}
+
+			mockUsecase.AssertExpectations(t)
+		})
+	}
+}
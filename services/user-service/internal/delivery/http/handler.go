@@ -1,12 +1,30 @@
 package http
 
 import (
+	"errors"
 	"log"
+	"net/url"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/lisandro/challenge/services/user-service/internal/domain"
 )
 
+// maxRelationshipIDs caps how many users can be looked up in a single relationships request
+const maxRelationshipIDs = 100
+
+// maxBatchUserIDs caps how many users can be looked up in a single batch request
+const maxBatchUserIDs = 100
+
+// Page sizes of the user listing and search endpoints
+const (
+	defaultUsersPageSize = 50
+	maxUsersPageSize     = 200
+	defaultSearchLimit   = 10
+	maxSearchLimit       = 50
+	maxSearchQueryLength = 100
+)
+
 type UserHandler struct {
 	userUsecase domain.UserUsecase
 }
@@ -19,23 +37,82 @@ func NewUserHandler(uu domain.UserUsecase) *UserHandler {
 }
 
 // GetAllUsers godoc
-// @Summary Get all users
-// @Description Get a list of all users in the system
+// @Summary List users
+// @Description Admin listing of the active users in the system, ordered by ID and paginated with a cursor
 // @Tags users
 // @Accept json
 // @Produce json
-// @Success 200 {object} map[string][]domain.User
+// @Param after query string false "Cursor returned as next_cursor by the previous page"
+// @Param limit query int false "Number of users per page (default 50, max 200)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /users [get]
 func (h *UserHandler) GetAllUsers(c *fiber.Ctx) error {
-	log.Println("Getting all users")
-	users, err := h.userUsecase.GetAllUsers()
+	limit := c.QueryInt("limit", defaultUsersPageSize)
+	if limit < 1 || limit > maxUsersPageSize {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid limit",
+		})
+	}
+
+	afterID := c.Query("after")
+	log.Printf("Getting users after %q with limit %d", afterID, limit)
+	users, err := h.userUsecase.GetAllUsers(c.UserContext(), afterID, limit)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to get users",
 		})
 	}
 
+	response := fiber.Map{
+		"users": users,
+	}
+	if len(users) == limit {
+		response["next_cursor"] = users[len(users)-1].ID
+	}
+	return c.JSON(response)
+}
+
+// SearchUsers godoc
+// @Summary Search users
+// @Description Typeahead search on username and display name. Users followed by the current user rank first.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param q query string true "Search text"
+// @Param limit query int false "Maximum number of results (default 10, max 50)"
+// @Param X-User-ID header string false "ID of the current user"
+// @Success 200 {object} map[string][]domain.User
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/search [get]
+func (h *UserHandler) SearchUsers(c *fiber.Ctx) error {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "q parameter is required",
+		})
+	}
+
+	if len(query) > maxSearchQueryLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "q parameter is too long",
+		})
+	}
+
+	limit := c.QueryInt("limit", defaultSearchLimit)
+	if limit < 1 || limit > maxSearchLimit {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid limit",
+		})
+	}
+
+	users, err := h.userUsecase.SearchUsers(c.UserContext(), c.Get("X-User-ID"), query, limit)
+	if err != nil {
+		return errorResponse(c, err, "Failed to search users")
+	}
+
 	return c.JSON(fiber.Map{
 		"users": users,
 	})
@@ -50,6 +127,7 @@ func (h *UserHandler) GetAllUsers(c *fiber.Ctx) error {
 // @Param user body domain.CreateUserRequest true "User information"
 // @Success 201 {object} map[string]domain.User
 // @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /users [post]
 func (h *UserHandler) CreateUser(c *fiber.Ctx) error {
@@ -62,11 +140,9 @@ func (h *UserHandler) CreateUser(c *fiber.Ctx) error {
 	}
 
 	log.Printf("Creating new user: %+v", req)
-	user, err := h.userUsecase.CreateUser(req)
+	user, err := h.userUsecase.CreateUser(c.UserContext(), req)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create user",
-		})
+		return errorResponse(c, err, "Failed to create user")
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
@@ -74,6 +150,85 @@ func (h *UserHandler) CreateUser(c *fiber.Ctx) error {
 	})
 }
 
+// GetUserByUsername godoc
+// @Summary Get a user by username
+// @Description Get an active user by their username. A username given up within the last 30 days redirects to the current username of its former owner.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param username path string true "Username"
+// @Success 200 {object} map[string]domain.User
+// @Success 302 "Redirect to the current username"
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/by-username/{username} [get]
+func (h *UserHandler) GetUserByUsername(c *fiber.Ctx) error {
+	username := c.Params("username")
+
+	user, err := h.userUsecase.GetUserByUsername(c.UserContext(), username)
+	if err != nil {
+		return errorResponse(c, err, "Failed to get user")
+	}
+
+	if user.Username != username {
+		return c.Redirect("/api/v1/users/by-username/"+url.PathEscape(user.Username), fiber.StatusFound)
+	}
+
+	return c.JSON(fiber.Map{
+		"user": user,
+	})
+}
+
+// UpdateUser godoc
+// @Summary Update the current user
+// @Description Change the username of the current user. A username can be changed 3 times every 30 days, and the previous username stays reserved and redirects to the user for 30 days.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param X-User-ID header string true "ID of the current user"
+// @Param user body domain.UpdateUserRequest true "Fields to update"
+// @Success 200 {object} map[string]domain.User
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 422 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/me [patch]
+func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
+	userID := c.Get("X-User-ID")
+
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User ID is required",
+		})
+	}
+
+	var req domain.UpdateUserRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.Username == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "username is required",
+		})
+	}
+
+	log.Printf("Changing username of user %s to %q", userID, req.Username)
+	user, err := h.userUsecase.ChangeUsername(c.UserContext(), userID, req.Username)
+	if err != nil {
+		return errorResponse(c, err, "Failed to update user")
+	}
+
+	return c.JSON(fiber.Map{
+		"user": user,
+	})
+}
+
 // Follow godoc
 // @Summary Follow a user
 // @Description Follow another user by their ID
@@ -84,6 +239,9 @@ func (h *UserHandler) CreateUser(c *fiber.Ctx) error {
 // @Param X-User-ID header string true "ID of the current user"
 // @Success 200 {object} map[string]interface{}
 // @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 422 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /users/{followedID}/follow [post]
 func (h *UserHandler) Follow(c *fiber.Ctx) error {
@@ -96,26 +254,8 @@ func (h *UserHandler) Follow(c *fiber.Ctx) error {
 		})
 	}
 
-	// Check if the user is already following the target user
-	following, err := h.userUsecase.GetFollowing(followerID)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to check following status",
-		})
-	}
-
-	for _, user := range following {
-		if user.ID == followedID {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "User is already following this user",
-			})
-		}
-	}
-
-	if err := h.userUsecase.Follow(followerID, followedID); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to follow user",
-		})
+	if err := h.userUsecase.Follow(c.UserContext(), followerID, followedID); err != nil {
+		return errorResponse(c, err, "Failed to follow user")
 	}
 
 	return c.SendStatus(fiber.StatusOK)
@@ -131,6 +271,7 @@ func (h *UserHandler) Follow(c *fiber.Ctx) error {
 // @Param X-User-ID header string true "ID of the current user"
 // @Success 200 {object} map[string]interface{}
 // @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /users/{followedID}/follow [delete]
 func (h *UserHandler) Unfollow(c *fiber.Ctx) error {
@@ -143,10 +284,8 @@ func (h *UserHandler) Unfollow(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.userUsecase.Unfollow(followerID, followedID); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to unfollow user",
-		})
+	if err := h.userUsecase.Unfollow(c.UserContext(), followerID, followedID); err != nil {
+		return errorResponse(c, err, "Failed to unfollow user")
 	}
 
 	return c.SendStatus(fiber.StatusOK)
@@ -172,7 +311,7 @@ func (h *UserHandler) GetFollowing(c *fiber.Ctx) error {
 		})
 	}
 	log.Println("Getting following list for user:", userID)
-	following, err := h.userUsecase.GetFollowing(userID)
+	following, err := h.userUsecase.GetFollowing(c.UserContext(), userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to get following list",
@@ -204,7 +343,7 @@ func (h *UserHandler) GetFollowers(c *fiber.Ctx) error {
 		})
 	}
 	log.Println("Getting followers list for user:", userID)
-	followers, err := h.userUsecase.GetFollowers(userID)
+	followers, err := h.userUsecase.GetFollowers(c.UserContext(), userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to get followers list",
@@ -214,4 +353,210 @@ func (h *UserHandler) GetFollowers(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{
 		"followers": followers,
 	})
-} 
\ No newline at end of file
+} 
+
+// GetRelationships godoc
+// @Summary Get relationships with users
+// @Description Get the follow state between the current user and each of the given users
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param ids query string true "Comma separated list of user IDs"
+// @Param X-User-ID header string true "ID of the current user"
+// @Success 200 {object} map[string][]domain.Relationship
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/relationships [get]
+func (h *UserHandler) GetRelationships(c *fiber.Ctx) error {
+	userID := c.Get("X-User-ID")
+
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User ID is required",
+		})
+	}
+
+	var ids []string
+	for _, id := range strings.Split(c.Query("ids"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+
+	if len(ids) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ids parameter is required",
+		})
+	}
+
+	if len(ids) > maxRelationshipIDs {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Too many ids requested",
+		})
+	}
+
+	log.Printf("Getting relationships for user %s with %d users", userID, len(ids))
+	relationships, err := h.userUsecase.GetRelationships(c.UserContext(), userID, ids)
+	if err != nil {
+		return errorResponse(c, err, "Failed to get relationships")
+	}
+
+	return c.JSON(fiber.Map{
+		"relationships": relationships,
+	})
+}
+
+// GetUsers godoc
+// @Summary Get users by ID
+// @Description Get the profiles of many users in one request. Unknown and deactivated users are left out, and the order of the result is unspecified.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param ids query string true "Comma separated list of user IDs"
+// @Success 200 {object} map[string][]domain.User
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/batch [get]
+func (h *UserHandler) GetUsers(c *fiber.Ctx) error {
+	var ids []string
+	for _, id := range strings.Split(c.Query("ids"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+
+	if len(ids) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ids parameter is required",
+		})
+	}
+
+	if len(ids) > maxBatchUserIDs {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Too many ids requested",
+		})
+	}
+
+	users, err := h.userUsecase.GetUsers(c.UserContext(), ids)
+	if err != nil {
+		return errorResponse(c, err, "Failed to get users")
+	}
+
+	return c.JSON(fiber.Map{
+		"users": users,
+	})
+}
+
+// DeactivateUser godoc
+// @Summary Deactivate the current user
+// @Description Hide the current user until they reactivate. The account is permanently deleted if it is not reactivated within 30 days.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param X-User-ID header string true "ID of the current user"
+// @Success 204
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/me/deactivate [post]
+func (h *UserHandler) DeactivateUser(c *fiber.Ctx) error {
+	userID := c.Get("X-User-ID")
+
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User ID is required",
+		})
+	}
+
+	log.Println("Deactivating user:", userID)
+	if err := h.userUsecase.DeactivateUser(c.UserContext(), userID); err != nil {
+		return errorResponse(c, err, "Failed to deactivate user")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ReactivateUser godoc
+// @Summary Reactivate the current user
+// @Description Restore a deactivated user within 30 days of the deactivation
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param X-User-ID header string true "ID of the current user"
+// @Success 204
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/me/reactivate [post]
+func (h *UserHandler) ReactivateUser(c *fiber.Ctx) error {
+	userID := c.Get("X-User-ID")
+
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User ID is required",
+		})
+	}
+
+	log.Println("Reactivating user:", userID)
+	if err := h.userUsecase.ReactivateUser(c.UserContext(), userID); err != nil {
+		return errorResponse(c, err, "Failed to reactivate user")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// DeleteUser godoc
+// @Summary Delete the current user
+// @Description Permanently delete the current user, their follow relationships and, asynchronously, their tweets
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param X-User-ID header string true "ID of the current user"
+// @Success 204
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/me [delete]
+func (h *UserHandler) DeleteUser(c *fiber.Ctx) error {
+	userID := c.Get("X-User-ID")
+
+	if userID == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User ID is required",
+		})
+	}
+
+	log.Println("Deleting user:", userID)
+	if err := h.userUsecase.DeleteUser(c.UserContext(), userID); err != nil {
+		return errorResponse(c, err, "Failed to delete user")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// errorResponse writes the status and message of a domain error, or a 500 with
+// the fallback message for any other error
+func errorResponse(c *fiber.Ctx, err error, fallback string) error {
+	status := fiber.StatusInternalServerError
+	message := fallback
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		status, message = fiber.StatusNotFound, err.Error()
+	case errors.Is(err, domain.ErrConflict):
+		status, message = fiber.StatusConflict, err.Error()
+	case errors.Is(err, domain.ErrInvalid):
+		status, message = fiber.StatusUnprocessableEntity, err.Error()
+	case errors.Is(err, domain.ErrForbidden):
+		status, message = fiber.StatusForbidden, err.Error()
+	case errors.Is(err, domain.ErrRateLimited):
+		status, message = fiber.StatusTooManyRequests, err.Error()
+	default:
+		log.Printf("%s: %v", fallback, err)
+	}
+
+	return c.Status(status).JSON(fiber.Map{
+		"error": message,
+	})
+}
@@ -1,12 +1,17 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/lisandro/challenge/pkg/health"
 	"github.com/lisandro/challenge/services/user-service/internal/domain"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -17,41 +22,91 @@ type MockUserUsecase struct {
 	mock.Mock
 }
 
-func (m *MockUserUsecase) Follow(followerID, followedID string) error {
-	args := m.Called(followerID, followedID)
+func (m *MockUserUsecase) Follow(ctx context.Context, followerID, followedID string) error {
+	args := m.Called(ctx, followerID, followedID)
 	return args.Error(0)
 }
 
-func (m *MockUserUsecase) Unfollow(followerID, followedID string) error {
-	args := m.Called(followerID, followedID)
+func (m *MockUserUsecase) Unfollow(ctx context.Context, followerID, followedID string) error {
+	args := m.Called(ctx, followerID, followedID)
 	return args.Error(0)
 }
 
-func (m *MockUserUsecase) GetFollowing(userID string) ([]domain.User, error) {
-	args := m.Called(userID)
+func (m *MockUserUsecase) GetFollowing(ctx context.Context, userID string) ([]domain.User, error) {
+	args := m.Called(ctx, userID)
 	return args.Get(0).([]domain.User), args.Error(1)
 }
 
-func (m *MockUserUsecase) GetFollowers(userID string) ([]domain.User, error) {
-	args := m.Called(userID)
+func (m *MockUserUsecase) GetFollowers(ctx context.Context, userID string) ([]domain.User, error) {
+	args := m.Called(ctx, userID)
 	return args.Get(0).([]domain.User), args.Error(1)
 }
 
-func (m *MockUserUsecase) CreateUser(req domain.CreateUserRequest) (*domain.User, error) {
-	args := m.Called(req)
+func (m *MockUserUsecase) IsFollowing(ctx context.Context, followerID, followedID string) (bool, error) {
+	args := m.Called(ctx, followerID, followedID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserUsecase) GetRelationships(ctx context.Context, userID string, ids []string) ([]domain.Relationship, error) {
+	args := m.Called(ctx, userID, ids)
+	return args.Get(0).([]domain.Relationship), args.Error(1)
+}
+
+func (m *MockUserUsecase) CreateUser(ctx context.Context, req domain.CreateUserRequest) (*domain.User, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *MockUserUsecase) GetAllUsers(ctx context.Context, afterID string, limit int) ([]domain.User, error) {
+	args := m.Called(ctx, afterID, limit)
+	return args.Get(0).([]domain.User), args.Error(1)
+}
+
+func (m *MockUserUsecase) SearchUsers(ctx context.Context, userID, query string, limit int) ([]domain.User, error) {
+	args := m.Called(ctx, userID, query, limit)
+	return args.Get(0).([]domain.User), args.Error(1)
+}
+
+func (m *MockUserUsecase) GetUser(ctx context.Context, id string) (*domain.User, error) {
+	args := m.Called(ctx, id)
 	return args.Get(0).(*domain.User), args.Error(1)
 }
 
-func (m *MockUserUsecase) GetAllUsers() ([]domain.User, error) {
-	args := m.Called()
+func (m *MockUserUsecase) GetUsers(ctx context.Context, ids []string) ([]domain.User, error) {
+	args := m.Called(ctx, ids)
 	return args.Get(0).([]domain.User), args.Error(1)
 }
 
-func (m *MockUserUsecase) GetUser(id string) (*domain.User, error) {
-	args := m.Called(id)
+func (m *MockUserUsecase) GetUserByUsername(ctx context.Context, username string) (*domain.User, error) {
+	args := m.Called(ctx, username)
 	return args.Get(0).(*domain.User), args.Error(1)
 }
 
+func (m *MockUserUsecase) ChangeUsername(ctx context.Context, id, username string) (*domain.User, error) {
+	args := m.Called(ctx, id, username)
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *MockUserUsecase) DeactivateUser(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserUsecase) ReactivateUser(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserUsecase) DeleteUser(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserUsecase) PurgeDeactivatedUsers(ctx context.Context, ) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
 
 func setupTest() (*fiber.App, *MockUserUsecase, *UserHandler) {
 	app := fiber.New()
@@ -60,6 +115,32 @@ func setupTest() (*fiber.App, *MockUserUsecase, *UserHandler) {
 	return app, mockUsecase, handler
 }
 
+func TestServer_ShutdownFailsReadinessFirst(t *testing.T) {
+	server := NewServer(new(MockUserUsecase), new(MockExportUsecase), health.NewChecker(time.Second), time.Minute)
+
+	resp, err := server.app.Test(httptest.NewRequest(fiber.MethodGet, "/readyz", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	// The server keeps serving while it drains, but is no longer ready
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error)
+	go func() {
+		stopped <- server.Shutdown(ctx, time.Hour)
+	}()
+	assert.Eventually(t, func() bool {
+		resp, err := server.app.Test(httptest.NewRequest(fiber.MethodGet, "/readyz", nil))
+		return err == nil && resp.StatusCode == fiber.StatusServiceUnavailable
+	}, time.Second, 10*time.Millisecond)
+
+	resp, err = server.app.Test(httptest.NewRequest(fiber.MethodGet, "/livez", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	cancel()
+	assert.NoError(t, <-stopped)
+}
+
 func TestUserHandler_Follow(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -101,12 +182,32 @@ func TestUserHandler_Follow(t *testing.T) {
 			name:           "already following",
 			followerID:     "user1",
 			followedID:     "user2",
-			mockError:      nil,
-			expectedStatus: fiber.StatusBadRequest,
+			mockError:      domain.ErrAlreadyFollowing,
+			expectedStatus: fiber.StatusConflict,
 			expectedBody: map[string]interface{}{
 				"error": "User is already following this user",
 			},
 		},
+		{
+			name:           "followed user not found",
+			followerID:     "user1",
+			followedID:     "user9",
+			mockError:      domain.ErrUserNotFound,
+			expectedStatus: fiber.StatusNotFound,
+			expectedBody: map[string]interface{}{
+				"error": "User not found",
+			},
+		},
+		{
+			name:           "self follow",
+			followerID:     "user1",
+			followedID:     "user1",
+			mockError:      domain.ErrSelfFollow,
+			expectedStatus: fiber.StatusUnprocessableEntity,
+			expectedBody: map[string]interface{}{
+				"error": "Users cannot follow themselves",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -114,11 +215,8 @@ func TestUserHandler_Follow(t *testing.T) {
 			app, mockUsecase, handler := setupTest()
 			app.Post("/:followedID/follow", handler.Follow)
 
-			if tt.name == "already following" {
-				mockUsecase.On("GetFollowing", tt.followerID).Return([]domain.User{{ID: tt.followedID, Username: "user2"}}, nil)
-			} else if tt.followerID != "" {
-				mockUsecase.On("GetFollowing", tt.followerID).Return([]domain.User{}, nil)
-				mockUsecase.On("Follow", tt.followerID, tt.followedID).Return(tt.mockError)
+			if tt.followerID != "" {
+				mockUsecase.On("Follow", mock.Anything, tt.followerID, tt.followedID).Return(tt.mockError)
 			}
 
 			req := httptest.NewRequest("POST", "/"+tt.followedID+"/follow", nil)
@@ -178,6 +276,16 @@ func TestUserHandler_Unfollow(t *testing.T) {
 				"error": "Failed to unfollow user",
 			},
 		},
+		{
+			name:           "not following",
+			followerID:     "user1",
+			followedID:     "user2",
+			mockError:      domain.ErrNotFollowing,
+			expectedStatus: fiber.StatusNotFound,
+			expectedBody: map[string]interface{}{
+				"error": "User is not following this user",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -186,7 +294,7 @@ func TestUserHandler_Unfollow(t *testing.T) {
 			app.Delete("/:followedID/follow", handler.Unfollow)
 
 			if tt.followerID != "" {
-				mockUsecase.On("Unfollow", tt.followerID, tt.followedID).Return(tt.mockError)
+				mockUsecase.On("Unfollow", mock.Anything, tt.followerID, tt.followedID).Return(tt.mockError)
 			}
 
 			req := httptest.NewRequest("DELETE", "/"+tt.followedID+"/follow", nil)
@@ -259,7 +367,7 @@ func TestUserHandler_GetFollowing(t *testing.T) {
 			app.Get("/following", handler.GetFollowing)
 
 			if tt.userID != "" {
-				mockUsecase.On("GetFollowing", tt.userID).Return(tt.mockUsers, tt.mockError)
+				mockUsecase.On("GetFollowing", mock.Anything, tt.userID).Return(tt.mockUsers, tt.mockError)
 			}
 
 			req := httptest.NewRequest("GET", "/following", nil)
@@ -345,7 +453,7 @@ func TestUserHandler_GetFollowers(t *testing.T) {
 			app.Get("/followers", handler.GetFollowers)
 
 			if tt.userID != "" {
-				mockUsecase.On("GetFollowers", tt.userID).Return(tt.mockUsers, tt.mockError)
+				mockUsecase.On("GetFollowers", mock.Anything, tt.userID).Return(tt.mockUsers, tt.mockError)
 			}
 
 			req := httptest.NewRequest("GET", "/followers", nil)
@@ -379,4 +487,627 @@ func TestUserHandler_GetFollowers(t *testing.T) {
 			mockUsecase.AssertExpectations(t)
 		})
 	}
-} 
\ No newline at end of file
+}
+
+func TestUserHandler_GetRelationships(t *testing.T) {
+	mockRelationships := []domain.Relationship{
+		{UserID: "user2", Following: true, FollowedBy: false},
+		{UserID: "user3", Following: false, FollowedBy: true},
+	}
+
+	tests := []struct {
+		name              string
+		userID            string
+		query             string
+		expectedIDs       []string
+		mockRelationships []domain.Relationship
+		mockError         error
+		expectedStatus    int
+		expectedError     string
+	}{
+		{
+			name:              "successful get relationships",
+			userID:            "user1",
+			query:             "user2, user3",
+			expectedIDs:       []string{"user2", "user3"},
+			mockRelationships: mockRelationships,
+			mockError:         nil,
+			expectedStatus:    fiber.StatusOK,
+		},
+		{
+			name:           "missing user ID",
+			userID:         "",
+			query:          "user2",
+			expectedStatus: fiber.StatusUnauthorized,
+			expectedError:  "User ID is required",
+		},
+		{
+			name:           "missing ids",
+			userID:         "user1",
+			query:          "",
+			expectedStatus: fiber.StatusBadRequest,
+			expectedError:  "ids parameter is required",
+		},
+		{
+			name:           "too many ids",
+			userID:         "user1",
+			query:          strings.Repeat("user2,", maxRelationshipIDs+1),
+			expectedStatus: fiber.StatusBadRequest,
+			expectedError:  "Too many ids requested",
+		},
+		{
+			name:              "usecase error",
+			userID:            "user1",
+			query:             "user2",
+			expectedIDs:       []string{"user2"},
+			mockRelationships: nil,
+			mockError:         errors.New("database error"),
+			expectedStatus:    fiber.StatusInternalServerError,
+			expectedError:     "Failed to get relationships",
+		},
+		{
+			name:              "malformed user ID",
+			userID:            "not-a-uuid",
+			query:             "user2",
+			expectedIDs:       []string{"user2"},
+			mockRelationships: nil,
+			mockError:         domain.ErrUserNotFound,
+			expectedStatus:    fiber.StatusNotFound,
+			expectedError:     "User not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mockUsecase, handler := setupTest()
+			app.Get("/relationships", handler.GetRelationships)
+
+			if tt.expectedIDs != nil {
+				mockUsecase.On("GetRelationships", mock.Anything, tt.userID, tt.expectedIDs).Return(tt.mockRelationships, tt.mockError)
+			}
+
+			req := httptest.NewRequest("GET", "/relationships?ids="+url.QueryEscape(tt.query), nil)
+			if tt.userID != "" {
+				req.Header.Set("X-User-ID", tt.userID)
+			}
+
+			resp, _ := app.Test(req)
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			var body map[string]interface{}
+			json.NewDecoder(resp.Body).Decode(&body)
+
+			if tt.expectedStatus == fiber.StatusOK {
+				relationships, ok := body["relationships"].([]interface{})
+				assert.True(t, ok)
+				assert.Len(t, relationships, len(tt.mockRelationships))
+				for i, r := range relationships {
+					relationship, ok := r.(map[string]interface{})
+					assert.True(t, ok)
+					assert.Equal(t, tt.mockRelationships[i].UserID, relationship["user_id"])
+					assert.Equal(t, tt.mockRelationships[i].Following, relationship["following"])
+					assert.Equal(t, tt.mockRelationships[i].FollowedBy, relationship["followed_by"])
+				}
+			} else if tt.expectedError != "" {
+				errMsg, ok := body["error"].(string)
+				assert.True(t, ok)
+				assert.Equal(t, tt.expectedError, errMsg)
+			}
+
+			mockUsecase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserHandler_GetUsers(t *testing.T) {
+	mockUsers := []domain.User{
+		{ID: "user2", Username: "bob", DisplayName: "Bob", AvatarURL: "https://example.com/bob.png"},
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedIDs    []string
+		mockUsers      []domain.User
+		mockError      error
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "successful get users",
+			query:          "user2, user3",
+			expectedIDs:    []string{"user2", "user3"},
+			mockUsers:      mockUsers,
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:        "valid and malformed ids",
+			query:       "6f1c3e0a-3b7a-4d1e-9a39-1f6f2a8c9d01, not-a-uuid",
+			expectedIDs: []string{"6f1c3e0a-3b7a-4d1e-9a39-1f6f2a8c9d01", "not-a-uuid"},
+			mockUsers: []domain.User{
+				{ID: "6f1c3e0a-3b7a-4d1e-9a39-1f6f2a8c9d01", Username: "bob", DisplayName: "Bob", AvatarURL: "https://example.com/bob.png"},
+			},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "missing ids",
+			query:          "",
+			expectedStatus: fiber.StatusBadRequest,
+			expectedError:  "ids parameter is required",
+		},
+		{
+			name:           "too many ids",
+			query:          strings.Repeat("user2,", maxBatchUserIDs+1),
+			expectedStatus: fiber.StatusBadRequest,
+			expectedError:  "Too many ids requested",
+		},
+		{
+			name:           "usecase error",
+			query:          "user2",
+			expectedIDs:    []string{"user2"},
+			mockUsers:      []domain.User(nil),
+			mockError:      errors.New("database error"),
+			expectedStatus: fiber.StatusInternalServerError,
+			expectedError:  "Failed to get users",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mockUsecase, handler := setupTest()
+			app.Get("/batch", handler.GetUsers)
+
+			if tt.expectedIDs != nil {
+				mockUsecase.On("GetUsers", mock.Anything, tt.expectedIDs).Return(tt.mockUsers, tt.mockError)
+			}
+
+			req := httptest.NewRequest("GET", "/batch?ids="+url.QueryEscape(tt.query), nil)
+			resp, _ := app.Test(req)
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			var body map[string]interface{}
+			json.NewDecoder(resp.Body).Decode(&body)
+
+			if tt.expectedStatus == fiber.StatusOK {
+				users, ok := body["users"].([]interface{})
+				assert.True(t, ok)
+				assert.Len(t, users, len(tt.mockUsers))
+				user := users[0].(map[string]interface{})
+				assert.Equal(t, "https://example.com/bob.png", user["avatar_url"])
+			} else if tt.expectedError != "" {
+				assert.Equal(t, tt.expectedError, body["error"])
+			}
+
+			mockUsecase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserHandler_GetAllUsers(t *testing.T) {
+	tests := []struct {
+		name               string
+		query              string
+		expectedAfterID    string
+		expectedLimit      int
+		mockUsers          []domain.User
+		mockError          error
+		expectedStatus     int
+		expectedNextCursor interface{}
+		expectedError      string
+	}{
+		{
+			name:               "full page returns next cursor",
+			query:              "?limit=2",
+			expectedLimit:      2,
+			mockUsers:          []domain.User{{ID: "user1", Username: "alice"}, {ID: "user2", Username: "bob"}},
+			expectedStatus:     fiber.StatusOK,
+			expectedNextCursor: "user2",
+		},
+		{
+			name:            "last page has no next cursor",
+			query:           "?after=user2",
+			expectedAfterID: "user2",
+			expectedLimit:   defaultUsersPageSize,
+			mockUsers:       []domain.User{{ID: "user3", Username: "charlie"}},
+			expectedStatus:  fiber.StatusOK,
+		},
+		{
+			name:           "limit too large",
+			query:          "?limit=1000",
+			expectedStatus: fiber.StatusBadRequest,
+			expectedError:  "Invalid limit",
+		},
+		{
+			name:           "usecase error",
+			expectedLimit:  defaultUsersPageSize,
+			mockUsers:      nil,
+			mockError:      errors.New("database error"),
+			expectedStatus: fiber.StatusInternalServerError,
+			expectedError:  "Failed to get users",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mockUsecase, handler := setupTest()
+			app.Get("/users", handler.GetAllUsers)
+
+			if tt.expectedLimit != 0 {
+				mockUsecase.On("GetAllUsers", mock.Anything, tt.expectedAfterID, tt.expectedLimit).Return(tt.mockUsers, tt.mockError)
+			}
+
+			resp, _ := app.Test(httptest.NewRequest("GET", "/users"+tt.query, nil))
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			var body map[string]interface{}
+			json.NewDecoder(resp.Body).Decode(&body)
+
+			if tt.expectedStatus == fiber.StatusOK {
+				users, ok := body["users"].([]interface{})
+				assert.True(t, ok)
+				assert.Len(t, users, len(tt.mockUsers))
+				assert.Equal(t, tt.expectedNextCursor, body["next_cursor"])
+			} else {
+				assert.Equal(t, tt.expectedError, body["error"])
+			}
+
+			mockUsecase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserHandler_SearchUsers(t *testing.T) {
+	tests := []struct {
+		name           string
+		userID         string
+		query          string
+		expectedQuery  string
+		expectedLimit  int
+		mockUsers      []domain.User
+		mockError      error
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "successful search",
+			userID:         "user1",
+			query:          "?q=+al+",
+			expectedQuery:  "al",
+			expectedLimit:  defaultSearchLimit,
+			mockUsers:      []domain.User{{ID: "user2", Username: "alice", DisplayName: "Alice"}},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "anonymous search with limit",
+			query:          "?q=bob&limit=5",
+			expectedQuery:  "bob",
+			expectedLimit:  5,
+			mockUsers:      []domain.User{},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "missing query",
+			query:          "?q=+",
+			expectedStatus: fiber.StatusBadRequest,
+			expectedError:  "q parameter is required",
+		},
+		{
+			name:           "query too long",
+			query:          "?q=" + strings.Repeat("a", maxSearchQueryLength+1),
+			expectedStatus: fiber.StatusBadRequest,
+			expectedError:  "q parameter is too long",
+		},
+		{
+			name:           "invalid limit",
+			query:          "?q=al&limit=0",
+			expectedStatus: fiber.StatusBadRequest,
+			expectedError:  "Invalid limit",
+		},
+		{
+			name:           "malformed caller searches without follow boost",
+			userID:         "invalid",
+			query:          "?q=al",
+			expectedQuery:  "al",
+			expectedLimit:  defaultSearchLimit,
+			mockUsers:      []domain.User{{ID: "user2", Username: "alice", DisplayName: "Alice"}},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "usecase error",
+			query:          "?q=al",
+			expectedQuery:  "al",
+			expectedLimit:  defaultSearchLimit,
+			mockError:      errors.New("database error"),
+			expectedStatus: fiber.StatusInternalServerError,
+			expectedError:  "Failed to search users",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mockUsecase, handler := setupTest()
+			app.Get("/users/search", handler.SearchUsers)
+
+			if tt.expectedQuery != "" {
+				mockUsecase.On("SearchUsers", mock.Anything, tt.userID, tt.expectedQuery, tt.expectedLimit).Return(tt.mockUsers, tt.mockError)
+			}
+
+			req := httptest.NewRequest("GET", "/users/search"+tt.query, nil)
+			if tt.userID != "" {
+				req.Header.Set("X-User-ID", tt.userID)
+			}
+
+			resp, _ := app.Test(req)
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			var body map[string]interface{}
+			json.NewDecoder(resp.Body).Decode(&body)
+
+			if tt.expectedStatus == fiber.StatusOK {
+				users, ok := body["users"].([]interface{})
+				assert.True(t, ok)
+				assert.Len(t, users, len(tt.mockUsers))
+			} else {
+				assert.Equal(t, tt.expectedError, body["error"])
+			}
+
+			mockUsecase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserHandler_AccountLifecycle(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		usecaseMethod  string
+		userID         string
+		mockError      error
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "deactivate",
+			method:         "POST",
+			path:           "/users/me/deactivate",
+			usecaseMethod:  "DeactivateUser",
+			userID:         "user1",
+			expectedStatus: fiber.StatusNoContent,
+		},
+		{
+			name:           "deactivate unknown user",
+			method:         "POST",
+			path:           "/users/me/deactivate",
+			usecaseMethod:  "DeactivateUser",
+			userID:         "user1",
+			mockError:      domain.ErrUserNotFound,
+			expectedStatus: fiber.StatusNotFound,
+			expectedError:  "User not found",
+		},
+		{
+			name:           "reactivate",
+			method:         "POST",
+			path:           "/users/me/reactivate",
+			usecaseMethod:  "ReactivateUser",
+			userID:         "user1",
+			expectedStatus: fiber.StatusNoContent,
+		},
+		{
+			name:           "reactivate after grace period",
+			method:         "POST",
+			path:           "/users/me/reactivate",
+			usecaseMethod:  "ReactivateUser",
+			userID:         "user1",
+			mockError:      domain.ErrReactivationExpired,
+			expectedStatus: fiber.StatusConflict,
+			expectedError:  "Reactivation window has expired",
+		},
+		{
+			name:           "delete",
+			method:         "DELETE",
+			path:           "/users/me",
+			usecaseMethod:  "DeleteUser",
+			userID:         "user1",
+			expectedStatus: fiber.StatusNoContent,
+		},
+		{
+			name:           "delete error",
+			method:         "DELETE",
+			path:           "/users/me",
+			usecaseMethod:  "DeleteUser",
+			userID:         "user1",
+			mockError:      errors.New("database error"),
+			expectedStatus: fiber.StatusInternalServerError,
+			expectedError:  "Failed to delete user",
+		},
+		{
+			name:           "missing user ID",
+			method:         "DELETE",
+			path:           "/users/me",
+			expectedStatus: fiber.StatusUnauthorized,
+			expectedError:  "User ID is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mockUsecase, handler := setupTest()
+			app.Post("/users/me/deactivate", handler.DeactivateUser)
+			app.Post("/users/me/reactivate", handler.ReactivateUser)
+			app.Delete("/users/me", handler.DeleteUser)
+
+			if tt.usecaseMethod != "" {
+				mockUsecase.On(tt.usecaseMethod, mock.Anything, tt.userID).Return(tt.mockError)
+			}
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.userID != "" {
+				req.Header.Set("X-User-ID", tt.userID)
+			}
+
+			resp, _ := app.Test(req)
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if tt.expectedError != "" {
+				var body map[string]interface{}
+				json.NewDecoder(resp.Body).Decode(&body)
+				assert.Equal(t, tt.expectedError, body["error"])
+			}
+
+			mockUsecase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserHandler_GetUserByUsername(t *testing.T) {
+	tests := []struct {
+		name             string
+		username         string
+		mockUser         *domain.User
+		mockError        error
+		expectedStatus   int
+		expectedLocation string
+		expectedError    string
+	}{
+		{
+			name:           "current username",
+			username:       "alice",
+			mockUser:       &domain.User{ID: "user1", Username: "alice"},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:             "previous username redirects",
+			username:         "alice",
+			mockUser:         &domain.User{ID: "user1", Username: "alice_new"},
+			expectedStatus:   fiber.StatusFound,
+			expectedLocation: "/api/v1/users/by-username/alice_new",
+		},
+		{
+			name:           "unknown username",
+			username:       "nobody",
+			mockUser:       (*domain.User)(nil),
+			mockError:      domain.ErrUserNotFound,
+			expectedStatus: fiber.StatusNotFound,
+			expectedError:  "User not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mockUsecase, handler := setupTest()
+			app.Get("/users/by-username/:username", handler.GetUserByUsername)
+			mockUsecase.On("GetUserByUsername", mock.Anything, tt.username).Return(tt.mockUser, tt.mockError)
+
+			resp, _ := app.Test(httptest.NewRequest("GET", "/users/by-username/"+tt.username, nil))
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			assert.Equal(t, tt.expectedLocation, resp.Header.Get("Location"))
+
+			var body map[string]interface{}
+			json.NewDecoder(resp.Body).Decode(&body)
+			if tt.expectedError != "" {
+				assert.Equal(t, tt.expectedError, body["error"])
+			}
+			if tt.expectedStatus == fiber.StatusOK {
+				assert.Equal(t, "user1", body["user"].(map[string]interface{})["id"])
+			}
+
+			mockUsecase.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserHandler_UpdateUser(t *testing.T) {
+	tests := []struct {
+		name           string
+		userID         string
+		body           string
+		expectCall     bool
+		mockError      error
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "username changed",
+			userID:         "user1",
+			body:           `{"username":"alice_new"}`,
+			expectCall:     true,
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "username taken",
+			userID:         "user1",
+			body:           `{"username":"alice_new"}`,
+			expectCall:     true,
+			mockError:      domain.ErrUsernameTaken,
+			expectedStatus: fiber.StatusConflict,
+			expectedError:  "Username is already taken",
+		},
+		{
+			name:           "too many changes",
+			userID:         "user1",
+			body:           `{"username":"alice_new"}`,
+			expectCall:     true,
+			mockError:      domain.ErrUsernameChangeLimit,
+			expectedStatus: fiber.StatusTooManyRequests,
+			expectedError:  "Username was changed too many times recently",
+		},
+		{
+			name:           "invalid username",
+			userID:         "user1",
+			body:           `{"username":"alice_new"}`,
+			expectCall:     true,
+			mockError:      domain.ErrInvalidUsername,
+			expectedStatus: fiber.StatusUnprocessableEntity,
+			expectedError:  "Username must be 3 to 50 letters, digits or underscores",
+		},
+		{
+			name:           "missing username",
+			userID:         "user1",
+			body:           `{}`,
+			expectedStatus: fiber.StatusBadRequest,
+			expectedError:  "username is required",
+		},
+		{
+			name:           "missing user ID",
+			body:           `{"username":"alice_new"}`,
+			expectedStatus: fiber.StatusUnauthorized,
+			expectedError:  "User ID is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mockUsecase, handler := setupTest()
+			app.Patch("/users/me", handler.UpdateUser)
+			if tt.expectCall {
+				mockUsecase.On("ChangeUsername", mock.Anything, tt.userID, "alice_new").
+					Return(&domain.User{ID: tt.userID, Username: "alice_new"}, tt.mockError)
+			}
+
+			req := httptest.NewRequest("PATCH", "/users/me", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.userID != "" {
+				req.Header.Set("X-User-ID", tt.userID)
+			}
+			resp, _ := app.Test(req)
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			var body map[string]interface{}
+			json.NewDecoder(resp.Body).Decode(&body)
+			if tt.expectedError != "" {
+				assert.Equal(t, tt.expectedError, body["error"])
+			} else {
+				assert.Equal(t, "alice_new", body["user"].(map[string]interface{})["username"])
+			}
+
+			mockUsecase.AssertExpectations(t)
+		})
+	}
+}
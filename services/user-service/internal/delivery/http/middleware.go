@@ -0,0 +1,24 @@
+package http
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// requestContext gives each request a context that is canceled after timeout, so that a slow
+// query does not hold a request and its database connection forever. Handlers pass
+// c.UserContext() down to the use cases and repositories.
+//
+// fasthttp does not report clients that disconnect, so the deadline is what stops the work
+// of an abandoned request.
+func requestContext(timeout time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.UserContext(), timeout)
+		defer cancel()
+
+		c.SetUserContext(ctx)
+		return c.Next()
+	}
+}
@@ -2,7 +2,9 @@ package http
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	"github.com/gofiber/swagger"
+	"github.com/lisandro/challenge/pkg/health"
 )
 
 // RegisterRoutes registers all the user routes
@@ -14,16 +16,46 @@ func RegisterRoutes(app *fiber.App, handler *UserHandler) {
 	users := api.Group("/users")
 
 	// User CRUD operations
-	// @Summary Get all users
-	// @Description Get a list of all users in the system
+	// @Summary List users
+	// @Description Admin listing of the users in the system, ordered by ID and paginated with a cursor
 	// @Tags users
 	// @Accept json
 	// @Produce json
-	// @Success 200 {object} map[string][]domain.User
+	// @Param after query string false "Cursor returned as next_cursor by the previous page"
+	// @Param limit query int false "Number of users per page (default 50, max 200)"
+	// @Success 200 {object} map[string]interface{}
+	// @Failure 400 {object} map[string]string
 	// @Failure 500 {object} map[string]string
 	// @Router /users [get]
 	users.Get("/", handler.GetAllUsers)
 
+	// @Summary Search users
+	// @Description Typeahead search on username and display name. Users followed by the current user rank first.
+	// @Tags users
+	// @Accept json
+	// @Produce json
+	// @Param q query string true "Search text"
+	// @Param limit query int false "Maximum number of results (default 10, max 50)"
+	// @Header 200 {string} X-User-ID "ID of the current user"
+	// @Success 200 {object} map[string][]domain.User
+	// @Failure 400 {object} map[string]string
+	// @Failure 404 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /users/search [get]
+	users.Get("/search", handler.SearchUsers)
+
+	// @Summary Get users by ID
+	// @Description Get the profiles of many users in one request. Unknown and deactivated users are left out, and the order of the result is unspecified.
+	// @Tags users
+	// @Accept json
+	// @Produce json
+	// @Param ids query string true "Comma separated list of user IDs"
+	// @Success 200 {object} map[string][]domain.User
+	// @Failure 400 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /users/batch [get]
+	users.Get("/batch", handler.GetUsers)
+
 	// @Summary Create a new user
 	// @Description Create a new user with the provided information
 	// @Tags users
@@ -32,10 +64,82 @@ func RegisterRoutes(app *fiber.App, handler *UserHandler) {
 	// @Param user body domain.CreateUserRequest true "User information"
 	// @Success 201 {object} map[string]domain.User
 	// @Failure 400 {object} map[string]string
+	// @Failure 409 {object} map[string]string
 	// @Failure 500 {object} map[string]string
 	// @Router /users [post]
 	users.Post("/", handler.CreateUser)
 
+	// @Summary Get a user by username
+	// @Description Get an active user by their username. A username given up within the last 30 days redirects to the current username of its former owner.
+	// @Tags users
+	// @Accept json
+	// @Produce json
+	// @Param username path string true "Username"
+	// @Success 200 {object} map[string]domain.User
+	// @Success 302 "Redirect to the current username"
+	// @Failure 404 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /users/by-username/{username} [get]
+	users.Get("/by-username/:username", handler.GetUserByUsername)
+
+	// @Summary Update the current user
+	// @Description Change the username of the current user. A username can be changed 3 times every 30 days, and the previous username stays reserved and redirects to the user for 30 days.
+	// @Tags users
+	// @Accept json
+	// @Produce json
+	// @Header 200 {string} X-User-ID "ID of the current user"
+	// @Param user body domain.UpdateUserRequest true "Fields to update"
+	// @Success 200 {object} map[string]domain.User
+	// @Failure 400 {object} map[string]string
+	// @Failure 401 {object} map[string]string
+	// @Failure 404 {object} map[string]string
+	// @Failure 409 {object} map[string]string
+	// @Failure 422 {object} map[string]string
+	// @Failure 429 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /users/me [patch]
+	users.Patch("/me", handler.UpdateUser)
+
+	// Account lifecycle
+	// @Summary Deactivate the current user
+	// @Description Hide the current user until they reactivate. The account is permanently deleted if it is not reactivated within 30 days.
+	// @Tags users
+	// @Accept json
+	// @Produce json
+	// @Header 200 {string} X-User-ID "ID of the current user"
+	// @Success 204
+	// @Failure 401 {object} map[string]string
+	// @Failure 404 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /users/me/deactivate [post]
+	users.Post("/me/deactivate", handler.DeactivateUser)
+
+	// @Summary Reactivate the current user
+	// @Description Restore a deactivated user within 30 days of the deactivation
+	// @Tags users
+	// @Accept json
+	// @Produce json
+	// @Header 200 {string} X-User-ID "ID of the current user"
+	// @Success 204
+	// @Failure 401 {object} map[string]string
+	// @Failure 404 {object} map[string]string
+	// @Failure 409 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /users/me/reactivate [post]
+	users.Post("/me/reactivate", handler.ReactivateUser)
+
+	// @Summary Delete the current user
+	// @Description Permanently delete the current user, their follow relationships and, asynchronously, their tweets
+	// @Tags users
+	// @Accept json
+	// @Produce json
+	// @Header 200 {string} X-User-ID "ID of the current user"
+	// @Success 204
+	// @Failure 401 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /users/me [delete]
+	users.Delete("/me", handler.DeleteUser)
+
 	// Following functionality
 	// @Summary Follow a user
 	// @Description Follow another user by their ID
@@ -46,6 +150,9 @@ func RegisterRoutes(app *fiber.App, handler *UserHandler) {
 	// @Header 200 {string} X-User-ID "ID of the current user"
 	// @Success 200 {object} map[string]interface{}
 	// @Failure 401 {object} map[string]string
+	// @Failure 404 {object} map[string]string
+	// @Failure 409 {object} map[string]string
+	// @Failure 422 {object} map[string]string
 	// @Failure 500 {object} map[string]string
 	// @Router /users/{followedID}/follow [post]
 	users.Post("/:followedID/follow", handler.Follow)
@@ -59,6 +166,7 @@ func RegisterRoutes(app *fiber.App, handler *UserHandler) {
 	// @Header 200 {string} X-User-ID "ID of the current user"
 	// @Success 200 {object} map[string]interface{}
 	// @Failure 401 {object} map[string]string
+	// @Failure 404 {object} map[string]string
 	// @Failure 500 {object} map[string]string
 	// @Router /users/{followedID}/follow [delete]
 	users.Delete("/:followedID/follow", handler.Unfollow)
@@ -86,4 +194,72 @@ func RegisterRoutes(app *fiber.App, handler *UserHandler) {
 	// @Failure 500 {object} map[string]string
 	// @Router /users/followers [get]
 	users.Get("/followers", handler.GetFollowers)
-} 
\ No newline at end of file
+
+	// @Summary Get relationships with users
+	// @Description Get the follow state between the current user and each of the given users
+	// @Tags users
+	// @Accept json
+	// @Produce json
+	// @Param ids query string true "Comma separated list of user IDs"
+	// @Header 200 {string} X-User-ID "ID of the current user"
+	// @Success 200 {object} map[string][]domain.Relationship
+	// @Failure 400 {object} map[string]string
+	// @Failure 401 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /users/relationships [get]
+	users.Get("/relationships", handler.GetRelationships)
+}
+
+// RegisterExportRoutes registers the data export routes
+func RegisterExportRoutes(app *fiber.App, handler *ExportHandler) {
+	users := app.Group("/api/v1/users")
+
+	// @Summary Request an export of the current user's data
+	// @Description Queue the generation of a zip archive with the profile, follow graph and tweets of the current user. If an export is already in progress, that export is returned.
+	// @Tags exports
+	// @Accept json
+	// @Produce json
+	// @Header 200 {string} X-User-ID "ID of the current user"
+	// @Success 202 {object} map[string]domain.DataExport
+	// @Failure 401 {object} map[string]string
+	// @Failure 404 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /users/me/exports [post]
+	users.Post("/me/exports", handler.RequestExport)
+
+	// @Summary Get the status of a data export
+	// @Description Get the status of an export of the current user, with a download link once it is ready
+	// @Tags exports
+	// @Accept json
+	// @Produce json
+	// @Param exportID path string true "ID of the export"
+	// @Header 200 {string} X-User-ID "ID of the current user"
+	// @Success 200 {object} map[string]domain.DataExport
+	// @Failure 401 {object} map[string]string
+	// @Failure 404 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /users/me/exports/{exportID} [get]
+	users.Get("/me/exports/:exportID", handler.GetExport)
+
+	// @Summary Download a data export
+	// @Description Download the zip archive of a ready export. The link is signed and expires with the export, so it does not require the X-User-ID header.
+	// @Tags exports
+	// @Produce application/zip
+	// @Param exportID path string true "ID of the export"
+	// @Param expires query int true "Expiry of the link as a Unix timestamp"
+	// @Param signature query string true "Signature of the link"
+	// @Success 200 {file} file
+	// @Failure 403 {object} map[string]string
+	// @Failure 404 {object} map[string]string
+	// @Failure 500 {object} map[string]string
+	// @Router /users/exports/{exportID}/download [get]
+	users.Get("/exports/:exportID/download", handler.DownloadExport)
+}
+
+// RegisterHealthRoutes registers the liveness and readiness probes. /health is the readiness
+// probe under the name it had before.
+func RegisterHealthRoutes(app *fiber.App, checker *health.Checker) {
+	app.Get("/livez", adaptor.HTTPHandlerFunc(checker.Livez))
+	app.Get("/readyz", adaptor.HTTPHandlerFunc(checker.Readyz))
+	app.Get("/health", adaptor.HTTPHandlerFunc(checker.Readyz))
+}
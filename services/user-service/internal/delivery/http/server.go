@@ -1,20 +1,30 @@
 package http
 
 import (
+	"context"
 	"log"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/swagger"
+	"github.com/lisandro/challenge/pkg/health"
+	"github.com/lisandro/challenge/pkg/metrics/fibermetrics"
 	"github.com/lisandro/challenge/services/user-service/internal/domain"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 type Server struct {
-    app         *fiber.App
-    userHandler *UserHandler
+    app           *fiber.App
+    userHandler   *UserHandler
+    exportHandler *ExportHandler
+    checker       *health.Checker
 }
 
-func NewServer(uu domain.UserUsecase) *Server {
+// NewServer creates the HTTP server. Requests are canceled after requestTimeout, and checker
+// tells whether the service is ready.
+func NewServer(uu domain.UserUsecase, eu domain.ExportUsecase, checker *health.Checker, requestTimeout time.Duration) *Server {
     // Create Fiber app with custom config
     app := fiber.New(fiber.Config{
         DisableStartupMessage: true,
@@ -27,22 +37,47 @@ func NewServer(uu domain.UserUsecase) *Server {
         TimeZone: "Local",
     }))
 
+    // Record the rate, errors and duration of requests, served on /metrics
+    app.Use(fibermetrics.New())
+    app.Get("/metrics", adaptor.HTTPHandler(promhttp.Handler()))
+
+    // Cancel the downstream work of requests that take too long
+    app.Use(requestContext(requestTimeout))
+
     // Add Swagger UI
     app.Get("/swagger/*", swagger.HandlerDefault)
     
     // Create handlers with dependencies
     userHandler := NewUserHandler(uu)
+    exportHandler := NewExportHandler(eu)
     
     // Register routes
     RegisterRoutes(app, userHandler)
+    RegisterExportRoutes(app, exportHandler)
+    RegisterHealthRoutes(app, checker)
     
     return &Server{
-        app:         app,
-        userHandler: userHandler,
+        app:           app,
+        userHandler:   userHandler,
+        exportHandler: exportHandler,
+        checker:       checker,
     }
 }
 
 func (s *Server) Start(address string) error {
     log.Printf("Starting server on %s", address)
     return s.app.Listen(address)
-} 
\ No newline at end of file
+}
+
+// Shutdown stops the server gracefully. The readiness probe fails right away while requests
+// are still served for drainDelay, so that load balancers stop sending new ones, and the
+// requests in flight are then waited for until ctx is done.
+func (s *Server) Shutdown(ctx context.Context, drainDelay time.Duration) error {
+    s.checker.StartShutdown()
+
+    select {
+    case <-time.After(drainDelay):
+    case <-ctx.Done():
+    }
+    return s.app.ShutdownWithContext(ctx)
+}
@@ -0,0 +1,54 @@
+package domain
+
+import "errors"
+
+// Kinds of domain errors, used by the delivery layer to choose a response status
+var (
+	ErrNotFound    = errors.New("not found")
+	ErrConflict    = errors.New("conflict")
+	ErrInvalid     = errors.New("invalid")
+	ErrForbidden   = errors.New("forbidden")
+	ErrRateLimited = errors.New("rate limited")
+)
+
+// Errors returned by the user repository and usecase
+var (
+	ErrUserNotFound        = newError(ErrNotFound, "User not found")
+	ErrNotFollowing        = newError(ErrNotFound, "User is not following this user")
+	ErrAlreadyFollowing    = newError(ErrConflict, "User is already following this user")
+	ErrSelfFollow          = newError(ErrInvalid, "Users cannot follow themselves")
+	ErrReactivationExpired = newError(ErrConflict, "Reactivation window has expired")
+	ErrUsernameTaken       = newError(ErrConflict, "Username is already taken")
+	ErrInvalidUsername     = newError(ErrInvalid, "Username must be 3 to 50 letters, digits or underscores")
+	ErrUsernameChangeLimit = newError(ErrRateLimited, "Username was changed too many times recently")
+	ErrInvalidAvatarURL    = newError(ErrInvalid, "Avatar URL must be an absolute http or https URL")
+)
+
+// Errors returned by the data export repository and usecase
+var (
+	ErrExportNotFound      = newError(ErrNotFound, "Export not found")
+	ErrExportNotReady      = newError(ErrConflict, "Export is not ready")
+	ErrInvalidDownloadLink = newError(ErrForbidden, "Download link is invalid or has expired")
+)
+
+// Error is a domain error of a given kind with a message that is safe to return to clients
+type Error struct {
+	kind    error
+	message string
+}
+
+func newError(kind error, message string) *Error {
+	return &Error{
+		kind:    kind,
+		message: message,
+	}
+}
+
+func (e *Error) Error() string {
+	return e.message
+}
+
+// Unwrap exposes the kind so that errors.Is(err, ErrNotFound) matches every not found error
+func (e *Error) Unwrap() error {
+	return e.kind
+}
@@ -0,0 +1,27 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Types of the events published by the user service
+const (
+	EventUserDeleted = "user.deleted"
+	EventUserRenamed = "user.renamed"
+)
+
+// Event is a change to a user that other services react to
+type Event struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	UserID     string    `json:"user_id"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// EventRepository tracks the delivery of recorded events to each subscriber
+type EventRepository interface {
+	GetPendingEvents(ctx context.Context, subscriber string, limit int) ([]Event, error)
+	MarkEventDelivered(ctx context.Context, eventID, subscriber string) error
+	MarkEventFailed(ctx context.Context, eventID, subscriber, reason string, retryAt time.Time) error
+}
@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// ExportStatus is the state of a data export job
+type ExportStatus string
+
+// States of a data export. An export moves from pending to running to either ready
+// or failed, and a ready export becomes expired once its archive is deleted.
+const (
+	ExportPending ExportStatus = "pending"
+	ExportRunning ExportStatus = "running"
+	ExportReady   ExportStatus = "ready"
+	ExportFailed  ExportStatus = "failed"
+	ExportExpired ExportStatus = "expired"
+)
+
+// DataExport is a request of a user for an archive of their data
+type DataExport struct {
+	ID          string       `json:"id"`
+	UserID      string       `json:"user_id"`
+	Status      ExportStatus `json:"status"`
+	Error       string       `json:"error,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	StartedAt   *time.Time   `json:"started_at,omitempty"`
+	CompletedAt *time.Time   `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time   `json:"expires_at,omitempty"`
+	DownloadURL string       `json:"download_url,omitempty"`
+}
+
+// Tweet is a tweet written by a user, as returned by the tweet service
+type Tweet struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TweetClient reads the tweets of a user from the tweet service
+type TweetClient interface {
+	GetUserTweets(ctx context.Context, userID string) ([]Tweet, error)
+}
+
+// ExportRepository represents the data export's repository contract
+type ExportRepository interface {
+	CreateExport(ctx context.Context, userID string) (*DataExport, error)
+	GetExport(ctx context.Context, id string) (*DataExport, error)
+	ClaimPendingExport(ctx context.Context, staleBefore time.Time) (*DataExport, error)
+	CompleteExport(ctx context.Context, id string, archive []byte, expiresAt time.Time) error
+	FailExport(ctx context.Context, id, reason string) error
+	GetExportArchive(ctx context.Context, id string) ([]byte, error)
+	ExpireExports(ctx context.Context, before time.Time) (int, error)
+}
+
+// ExportUsecase represents the data export's business logic contract
+type ExportUsecase interface {
+	RequestExport(ctx context.Context, userID string) (*DataExport, error)
+	GetExport(ctx context.Context, userID, exportID string) (*DataExport, error)
+	GetArchive(ctx context.Context, exportID string, expires int64, signature string) ([]byte, error)
+	ProcessPendingExports(ctx context.Context) (int, error)
+}
@@ -1,33 +1,86 @@
 package domain
 
+import (
+	"context"
+	"time"
+)
+
 // User represents a user in the system
 type User struct {
-    ID       string `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-    Username string `json:"username" gorm:"type:varchar(255);unique;not null"`
+    ID          string `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+    Username    string `json:"username" gorm:"type:varchar(255);unique;not null"`
+    DisplayName string `json:"display_name" gorm:"type:varchar(255);not null;default:''"`
+    AvatarURL   string `json:"avatar_url" gorm:"type:varchar(2048);not null;default:''"`
 }
 
 // CreateUserRequest represents the request to create a new user
 type CreateUserRequest struct {
-    Username string `json:"username" validate:"required,min=3,max=50"`
+    Username    string `json:"username" validate:"required,min=3,max=50"`
+    DisplayName string `json:"display_name" validate:"max=100"`
+    AvatarURL   string `json:"avatar_url" validate:"omitempty,url,max=2048"`
+}
+
+// UpdateUserRequest represents the request to update the current user
+type UpdateUserRequest struct {
+    Username string `json:"username"`
+}
+
+// UsernamePolicy limits how often a user can change their username and how long a
+// previous username stays reserved for them
+type UsernamePolicy struct {
+    // MaxChanges is the number of changes allowed within Window
+    MaxChanges int
+    Window     time.Duration
+    // Reservation is how long a previous username redirects to its former owner
+    // and cannot be claimed by anyone else
+    Reservation time.Duration
+}
+
+// Relationship describes how the current user relates to another user
+type Relationship struct {
+    UserID     string `json:"user_id"`
+    Following  bool   `json:"following"`
+    FollowedBy bool   `json:"followed_by"`
 }
 
 // UserRepository represents the user's repository contract
 type UserRepository interface {
-    GetAllUsers() ([]User, error)
-    CreateUser(req CreateUserRequest) (*User, error)
-    Follow(followerID, followedID string) error
-    Unfollow(followerID, followedID string) error
-    GetFollowing(userID string) ([]User, error)
-    GetFollowers(userID string) ([]User, error)
-	GetUser(id string) (*User, error)
+    GetAllUsers(ctx context.Context, afterID string, limit int) ([]User, error)
+    SearchUsers(ctx context.Context, userID, query string, limit int) ([]User, error)
+    CreateUser(ctx context.Context, req CreateUserRequest) (*User, error)
+    Follow(ctx context.Context, followerID, followedID string) error
+    Unfollow(ctx context.Context, followerID, followedID string) error
+    GetFollowing(ctx context.Context, userID string) ([]User, error)
+    GetFollowers(ctx context.Context, userID string) ([]User, error)
+    GetRelationships(ctx context.Context, userID string, ids []string) ([]Relationship, error)
+    IsFollowing(ctx context.Context, followerID, followedID string) (bool, error)
+	GetUser(ctx context.Context, id string) (*User, error)
+    GetUsers(ctx context.Context, ids []string) ([]User, error)
+    GetUserByUsername(ctx context.Context, username string) (*User, error)
+    GetUserByPreviousUsername(ctx context.Context, username string, at time.Time) (*User, error)
+    ChangeUsername(ctx context.Context, id, username string, at time.Time, policy UsernamePolicy) (*User, error)
+    DeactivateUser(ctx context.Context, id string, at time.Time) error
+    ReactivateUser(ctx context.Context, id string, deactivatedAfter time.Time) error
+    DeleteUser(ctx context.Context, id string) error
+    GetDeactivatedUserIDs(ctx context.Context, deactivatedBefore time.Time, limit int) ([]string, error)
 }
 
 // UserUsecase represents the user's business logic contract
 type UserUsecase interface {
-    GetAllUsers() ([]User, error)
-    CreateUser(req CreateUserRequest) (*User, error)
-    Follow(followerID, followedID string) error
-    Unfollow(followerID, followedID string) error
-    GetFollowing(userID string) ([]User, error)
-    GetFollowers(userID string) ([]User, error)
+    GetAllUsers(ctx context.Context, afterID string, limit int) ([]User, error)
+    SearchUsers(ctx context.Context, userID, query string, limit int) ([]User, error)
+    CreateUser(ctx context.Context, req CreateUserRequest) (*User, error)
+    Follow(ctx context.Context, followerID, followedID string) error
+    Unfollow(ctx context.Context, followerID, followedID string) error
+    GetFollowing(ctx context.Context, userID string) ([]User, error)
+    GetFollowers(ctx context.Context, userID string) ([]User, error)
+    GetRelationships(ctx context.Context, userID string, ids []string) ([]Relationship, error)
+    IsFollowing(ctx context.Context, followerID, followedID string) (bool, error)
+    GetUsers(ctx context.Context, ids []string) ([]User, error)
+    GetUserByUsername(ctx context.Context, username string) (*User, error)
+    ChangeUsername(ctx context.Context, id, username string) (*User, error)
+    DeactivateUser(ctx context.Context, id string) error
+    ReactivateUser(ctx context.Context, id string) error
+    DeleteUser(ctx context.Context, id string) error
+    PurgeDeactivatedUsers(ctx context.Context) (int, error)
 } 
\ No newline at end of file
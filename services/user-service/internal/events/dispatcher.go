@@ -0,0 +1,144 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lisandro/challenge/pkg/eventauth"
+	"github.com/lisandro/challenge/pkg/metrics"
+	"github.com/lisandro/challenge/services/user-service/internal/domain"
+)
+
+// Subscriber is a service that receives user events over HTTP
+type Subscriber struct {
+	Name string
+	URL  string
+}
+
+// Config holds the delivery settings of a Dispatcher
+type Config struct {
+	BatchSize  int
+	RetryDelay time.Duration
+	Timeout    time.Duration
+	// SigningSecret signs every delivery, so that subscribers can tell it comes from this service
+	SigningSecret []byte
+}
+
+// Dispatcher delivers recorded events to every subscriber. Delivery is at least once:
+// an event is retried until the subscriber answers with a 2xx status, so subscribers
+// must handle the same event more than once.
+type Dispatcher struct {
+	repo        domain.EventRepository
+	subscribers []Subscriber
+	config      Config
+	client      *http.Client
+}
+
+// NewDispatcher creates a new event dispatcher
+func NewDispatcher(repo domain.EventRepository, subscribers []Subscriber, config Config) *Dispatcher {
+	return &Dispatcher{
+		repo:        repo,
+		subscribers: subscribers,
+		config:      config,
+		client:      &http.Client{Timeout: config.Timeout},
+	}
+}
+
+// ParseSubscribers parses a comma separated list of name=url pairs
+func ParseSubscribers(value string) ([]Subscriber, error) {
+	var subscribers []Subscriber
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, url, ok := strings.Cut(pair, "=")
+		if !ok || name == "" || url == "" {
+			return nil, fmt.Errorf("invalid subscriber %q, expected name=url", pair)
+		}
+		subscribers = append(subscribers, Subscriber{Name: name, URL: url})
+	}
+	return subscribers, nil
+}
+
+// Run dispatches pending events every interval until ctx is done
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.DispatchPending(ctx); err != nil {
+			log.Printf("Failed to dispatch events: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchPending delivers one batch of pending events to each subscriber and returns
+// how many deliveries succeeded. Failed deliveries are scheduled for a retry.
+func (d *Dispatcher) DispatchPending(ctx context.Context) (int, error) {
+	delivered := 0
+	for _, subscriber := range d.subscribers {
+		events, err := d.repo.GetPendingEvents(ctx, subscriber.Name, d.config.BatchSize)
+		if err != nil {
+			return delivered, err
+		}
+
+		for _, event := range events {
+			if err := d.deliver(ctx, subscriber, event); err != nil {
+				log.Printf("Failed to deliver event %s to %s: %v", event.ID, subscriber.Name, err)
+				retryAt := time.Now().Add(d.config.RetryDelay)
+				if err := d.repo.MarkEventFailed(ctx, event.ID, subscriber.Name, err.Error(), retryAt); err != nil {
+					return delivered, err
+				}
+				continue
+			}
+
+			if err := d.repo.MarkEventDelivered(ctx, event.ID, subscriber.Name); err != nil {
+				return delivered, err
+			}
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+// deliver posts an event to a subscriber
+func (d *Dispatcher) deliver(ctx context.Context, subscriber Subscriber, event domain.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscriber.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	eventauth.Sign(req, d.config.SigningSecret, body)
+
+	start := time.Now()
+	resp, err := d.client.Do(req)
+	if err != nil {
+		metrics.ObserveClientRequest(subscriber.Name, start, 0, err)
+		return err
+	}
+	metrics.ObserveClientRequest(subscriber.Name, start, resp.StatusCode, nil)
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lisandro/challenge/pkg/eventauth"
+	"github.com/lisandro/challenge/services/user-service/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockEventRepository is a mock implementation of domain.EventRepository
+type MockEventRepository struct {
+	mock.Mock
+}
+
+func (m *MockEventRepository) GetPendingEvents(ctx context.Context, subscriber string, limit int) ([]domain.Event, error) {
+	args := m.Called(ctx, subscriber, limit)
+	return args.Get(0).([]domain.Event), args.Error(1)
+}
+
+func (m *MockEventRepository) MarkEventDelivered(ctx context.Context, eventID, subscriber string) error {
+	args := m.Called(ctx, eventID, subscriber)
+	return args.Error(0)
+}
+
+func (m *MockEventRepository) MarkEventFailed(ctx context.Context, eventID, subscriber, reason string, retryAt time.Time) error {
+	args := m.Called(ctx, eventID, subscriber, reason, retryAt)
+	return args.Error(0)
+}
+
+func TestDispatcher_DispatchPending(t *testing.T) {
+	event := domain.Event{
+		ID:         "event1",
+		Type:       domain.EventUserDeleted,
+		UserID:     "user1",
+		OccurredAt: time.Date(2024, 6, 7, 22, 4, 25, 0, time.UTC),
+	}
+
+	var received []domain.Event
+	secret := []byte("secret")
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Subscribers can tell the event comes from this service
+		require.NoError(t, eventauth.VerifyRequest(secret, r))
+		var body domain.Event
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		received = append(received, body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer healthy.Close()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	repo := new(MockEventRepository)
+	repo.On("GetPendingEvents", mock.Anything, "healthy", 10).Return([]domain.Event{event}, nil)
+	repo.On("GetPendingEvents", mock.Anything, "failing", 10).Return([]domain.Event{event}, nil)
+	repo.On("MarkEventDelivered", mock.Anything, "event1", "healthy").Return(nil)
+	repo.On("MarkEventFailed", mock.Anything, "event1", "failing", "unexpected status code 500", mock.MatchedBy(func(retryAt time.Time) bool {
+		return retryAt.After(time.Now())
+	})).Return(nil)
+
+	dispatcher := NewDispatcher(repo, []Subscriber{
+		{Name: "healthy", URL: healthy.URL},
+		{Name: "failing", URL: failing.URL},
+	}, Config{BatchSize: 10, RetryDelay: time.Minute, Timeout: time.Second, SigningSecret: secret})
+
+	delivered, err := dispatcher.DispatchPending(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+	assert.Equal(t, []domain.Event{event}, received)
+	repo.AssertExpectations(t)
+}
+
+func TestParseSubscribers(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expected    []Subscriber
+		expectError bool
+	}{
+		{
+			name:  "multiple subscribers",
+			value: "tweet-service=http://tweets/events, timeline-service=http://timeline/events",
+			expected: []Subscriber{
+				{Name: "tweet-service", URL: "http://tweets/events"},
+				{Name: "timeline-service", URL: "http://timeline/events"},
+			},
+		},
+		{
+			name:     "empty value",
+			value:    "",
+			expected: nil,
+		},
+		{
+			name:        "missing url",
+			value:       "tweet-service",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subscribers, err := ParseSubscribers(tt.value)
+
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, subscribers)
+		})
+	}
+}
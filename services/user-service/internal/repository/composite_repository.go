@@ -1,38 +1,93 @@
 package repository
 
 import (
+	"context"
+	"errors"
 	"log"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/lisandro/challenge/services/user-service/internal/domain"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/sync/singleflight"
 )
 
+var cacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "cache_lookups_total",
+	Help: "Lookups in the Redis cache, by cached entry and result (hit or miss).",
+}, []string{"cache", "result"})
+
+// recordLookup counts a lookup in the cache. A cache that fails counts as a miss, since the
+// entry is read from persistent storage either way.
+func recordLookup(cache string, err error) {
+	result := "hit"
+	if err != nil {
+		result = "miss"
+	}
+	cacheLookups.WithLabelValues(cache, result).Inc()
+}
+
 // PersistentRepository defines the interface for persistent storage (e.g., PostgreSQL)
 type PersistentRepository interface {
-	Follow(followerID, followedID string) error
-	Unfollow(followerID, followedID string) error
-	GetFollowing(userID string) ([]domain.User, error)
-	GetFollowers(userID string) ([]domain.User, error)
-	GetUser(id string) (*domain.User, error)
-	GetAllUsers() ([]domain.User, error)
-	CreateUser(req domain.CreateUserRequest) (*domain.User, error)
+	Follow(ctx context.Context, followerID, followedID string) error
+	Unfollow(ctx context.Context, followerID, followedID string) error
+	GetFollowing(ctx context.Context, userID string) ([]domain.User, error)
+	GetFollowers(ctx context.Context, userID string) ([]domain.User, error)
+	GetRelationships(ctx context.Context, userID string, ids []string) ([]domain.Relationship, error)
+	IsFollowing(ctx context.Context, followerID, followedID string) (bool, error)
+	GetUser(ctx context.Context, id string) (*domain.User, error)
+	GetUsers(ctx context.Context, ids []string) ([]domain.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*domain.User, error)
+	GetUserByPreviousUsername(ctx context.Context, username string, at time.Time) (*domain.User, error)
+	ChangeUsername(ctx context.Context, id, username string, at time.Time, policy domain.UsernamePolicy) (*domain.User, error)
+	GetAllUsers(ctx context.Context, afterID string, limit int) ([]domain.User, error)
+	SearchUsers(ctx context.Context, userID, query string, limit int) ([]domain.User, error)
+	CreateUser(ctx context.Context, req domain.CreateUserRequest) (*domain.User, error)
+	DeactivateUser(ctx context.Context, id string, at time.Time) error
+	ReactivateUser(ctx context.Context, id string, deactivatedAfter time.Time) error
+	DeleteUser(ctx context.Context, id string) error
+	GetDeactivatedUserIDs(ctx context.Context, deactivatedBefore time.Time, limit int) ([]string, error)
 }
 
 // CacheRepository defines the interface for caching storage (e.g., Redis)
 type CacheRepository interface {
-	GetCachedUser(id string) (*domain.User, error)
-	CacheUser(user *domain.User) error
-	InvalidateUserCache(userID string) error
-	GetCachedFollowing(userID string) ([]domain.User, error)
-	CacheFollowing(userID string, following []domain.User) error
-	InvalidateFollowingCache(userID string) error
-	GetCachedFollowers(userID string) ([]domain.User, error)
-	CacheFollowers(userID string, followers []domain.User) error
-	InvalidateFollowersCache(userID string) error
+	GetCachedUser(ctx context.Context, id string) (*domain.User, error)
+	CacheUser(ctx context.Context, user *domain.User) error
+	InvalidateUserCache(ctx context.Context, userID string) error
+	GetCachedFollowing(ctx context.Context, userID string) ([]domain.User, []string, error)
+	CacheFollowing(ctx context.Context, userID string, following []domain.User) error
+	InvalidateFollowingCache(ctx context.Context, userID string) error
+	GetCachedFollowers(ctx context.Context, userID string) ([]domain.User, []string, error)
+	CacheFollowers(ctx context.Context, userID string, followers []domain.User) error
+	InvalidateFollowersCache(ctx context.Context, userID string) error
+	IsFollowingCached(ctx context.Context, followerID, followedID string) (bool, error)
+	GetCachedRelationships(ctx context.Context, userID string, ids []string) ([]domain.Relationship, error)
 }
 
 type compositeRepository struct {
 	persistent PersistentRepository
 	cache      CacheRepository
+	// loads collapses concurrent cache misses for the same key into a single persistent read
+	loads singleflight.Group
+
+	// fills holds the cache fills in progress by key, so that invalidations can stop them
+	mu    sync.Mutex
+	fills map[string]map[*fill]struct{}
+}
+
+// fill is a cache entry being read from persistent storage to be written to the cache.
+// A fill that read storage before an invalidation must not write what it read afterwards,
+// or the old entry would be served until it expires.
+//
+// Every cache write of what was read from storage goes through a fill of the key it writes:
+// "user:<id>" for profiles, including those loaded to hydrate a cached follow list, and
+// "following:<id>" or "followers:<id>" for follow lists, which hold only IDs. Changes to
+// storage stop the fills of every key they affect before dropping or rewriting it.
+type fill struct {
+	mu    sync.Mutex
+	stale bool
 }
 
 // NewCompositeRepository creates a new composite repository that combines persistent and cache storage
@@ -40,126 +95,458 @@ func NewCompositeRepository(persistent PersistentRepository, cache CacheReposito
 	return &compositeRepository{
 		persistent: persistent,
 		cache:      cache,
+		fills:      make(map[string]map[*fill]struct{}),
+	}
+}
+
+// startFill registers a fill of the cache entry key. It must be called before reading storage.
+func (r *compositeRepository) startFill(key string) *fill {
+	f := &fill{}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fills[key] == nil {
+		r.fills[key] = make(map[*fill]struct{})
+	}
+	r.fills[key][f] = struct{}{}
+	return f
+}
+
+// finishFill runs write unless the entry was invalidated since the fill started, and
+// unregisters the fill. write is nil when the read failed.
+func (r *compositeRepository) finishFill(key string, f *fill, write func()) {
+	if write != nil {
+		f.mu.Lock()
+		if f.stale {
+			log.Printf("Skipping cache write of %s: it was invalidated while being loaded", key)
+		} else {
+			write()
+		}
+		f.mu.Unlock()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.fills[key], f)
+	if len(r.fills[key]) == 0 {
+		delete(r.fills, key)
+	}
+}
+
+// stopFills keeps the fills of the cache entry key in progress from writing it, and makes
+// later callers load it again instead of waiting for a load that read the old value. It
+// must be called after storage has changed and before the entry is dropped or rewritten:
+// a fill that is writing when it is called finishes first, so the write is then undone.
+// Since only fills write what they read, no entry read before the change is written after it.
+func (r *compositeRepository) stopFills(key string) {
+	r.mu.Lock()
+	fills := make([]*fill, 0, len(r.fills[key]))
+	for f := range r.fills[key] {
+		fills = append(fills, f)
 	}
+	r.mu.Unlock()
+
+	for _, f := range fills {
+		f.mu.Lock()
+		f.stale = true
+		f.mu.Unlock()
+	}
+	r.loads.Forget(key)
+}
+
+// invalidateFollowing drops a user's cached following list, stopping the fills in progress
+func (r *compositeRepository) invalidateFollowing(ctx context.Context, userID string) error {
+	r.stopFills(followingKey(userID))
+	return r.cache.InvalidateFollowingCache(ctx, userID)
 }
 
-func (r *compositeRepository) Follow(followerID, followedID string) error {
+// invalidateFollowers drops a user's cached followers list, stopping the fills in progress
+func (r *compositeRepository) invalidateFollowers(ctx context.Context, userID string) error {
+	r.stopFills(followersKey(userID))
+	return r.cache.InvalidateFollowersCache(ctx, userID)
+}
+
+func followingKey(userID string) string { return "following:" + userID }
+func followersKey(userID string) string { return "followers:" + userID }
+func userKey(id string) string          { return "user:" + id }
+
+func (r *compositeRepository) Follow(ctx context.Context, followerID, followedID string) error {
 	// First update persistent storage
-	if err := r.persistent.Follow(followerID, followedID); err != nil {
+	if err := r.persistent.Follow(ctx, followerID, followedID); err != nil {
 		return err
 	}
-	
-	// Invalidate cache
-	return r.cache.InvalidateFollowingCache(followerID)
+
+	// Invalidate both sides of the edge
+	return r.invalidateFollowEdge(ctx, followerID, followedID)
 }
 
-func (r *compositeRepository) Unfollow(followerID, followedID string) error {
+func (r *compositeRepository) Unfollow(ctx context.Context, followerID, followedID string) error {
 	// First update persistent storage
-	if err := r.persistent.Unfollow(followerID, followedID); err != nil {
+	if err := r.persistent.Unfollow(ctx, followerID, followedID); err != nil {
 		return err
 	}
-	
-	// Invalidate cache
-	return r.cache.InvalidateFollowingCache(followerID)
+
+	// Invalidate both sides of the edge
+	return r.invalidateFollowEdge(ctx, followerID, followedID)
+}
+
+// invalidateFollowEdge drops the follower's following list and the followed user's followers
+// list. It runs after storage has changed, so it is not canceled with the request.
+func (r *compositeRepository) invalidateFollowEdge(ctx context.Context, followerID, followedID string) error {
+	ctx = context.WithoutCancel(ctx)
+	return errors.Join(
+		r.invalidateFollowing(ctx, followerID),
+		r.invalidateFollowers(ctx, followedID),
+	)
 }
 
-func (r *compositeRepository) GetFollowing(userID string) ([]domain.User, error) {
+func (r *compositeRepository) GetFollowing(ctx context.Context, userID string) ([]domain.User, error) {
 	log.Printf("Getting following list for user %s", userID)
-	
+
 	// Try cache first
-	following, err := r.cache.GetCachedFollowing(userID)
+	following, missing, err := r.cache.GetCachedFollowing(ctx, userID)
+	recordLookup("following", err)
 	if err == nil {
-		log.Printf("Cache HIT: Found following list for user %s with %d users", userID, len(following))
-		return following, nil
+		log.Printf("Cache HIT: Found following list for user %s with %d users", userID, len(following)+len(missing))
+		return r.hydrate(ctx, following, missing)
 	}
 	log.Printf("Cache MISS: No following list found in cache for user %s, error: %v", userID, err)
 
-	// On cache miss, get from persistent storage
-	following, err = r.persistent.GetFollowing(userID)
+	// On cache miss, get from persistent storage once for all concurrent callers
+	result, err := r.load(ctx, followingKey(userID), func(ctx context.Context) (interface{}, error) {
+		following, err := r.persistent.GetFollowing(ctx, userID)
+		if err != nil {
+			log.Printf("Error getting following from persistent storage for user %s: %v", userID, err)
+			return nil, err
+		}
+		log.Printf("Retrieved %d following users from persistent storage for user %s", len(following), userID)
+		return following, nil
+	}, func(ctx context.Context, result interface{}) {
+		// Update cache, including empty lists so they don't keep hitting persistent storage
+		if err := r.cache.CacheFollowing(ctx, userID, result.([]domain.User)); err != nil {
+			log.Printf("Failed to cache following list for user %s: %v", userID, err)
+		} else {
+			log.Printf("Successfully cached following list for user %s", userID)
+		}
+	})
 	if err != nil {
-		log.Printf("Error getting following from persistent storage for user %s: %v", userID, err)
 		return nil, err
 	}
-	log.Printf("Retrieved %d following users from persistent storage for user %s", len(following), userID)
 
-	// Update cache
-	if err := r.cache.CacheFollowing(userID, following); err != nil {
-		log.Printf("Failed to cache following list for user %s: %v", userID, err)
-	} else {
-		log.Printf("Successfully cached following list for user %s", userID)
-	}
+	return result.([]domain.User), nil
+}
 
-	return following, nil
+// load reads an entry from persistent storage once for all concurrent callers, and writes it
+// to the cache with write unless it was invalidated meanwhile. The read is shared, so it runs
+// without the cancellation of the caller that started it, while each caller stops waiting
+// when its own context is done.
+func (r *compositeRepository) load(ctx context.Context, key string, read func(ctx context.Context) (interface{}, error), write func(ctx context.Context, result interface{})) (interface{}, error) {
+	results := r.loads.DoChan(key, func() (interface{}, error) {
+		ctx := context.WithoutCancel(ctx)
+		f := r.startFill(key)
+		result, err := read(ctx)
+		if err != nil {
+			r.finishFill(key, f, nil)
+			return nil, err
+		}
+		r.finishFill(key, f, func() { write(ctx, result) })
+		return result, nil
+	})
+	select {
+	case result := <-results:
+		return result.Val, result.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 }
 
-func (r *compositeRepository) GetUser(id string) (*domain.User, error) {
+func (r *compositeRepository) GetUser(ctx context.Context, id string) (*domain.User, error) {
 	// Try cache first
-	user, err := r.cache.GetCachedUser(id)
+	user, err := r.cache.GetCachedUser(ctx, id)
+	recordLookup("users", err)
 	if err == nil {
 		return user, nil
 	}
 
-	// On cache miss, get from persistent storage
-	user, err = r.persistent.GetUser(id)
+	// On cache miss, get from persistent storage once for all concurrent callers
+	result, err := r.load(ctx, userKey(id), func(ctx context.Context) (interface{}, error) {
+		return r.persistent.GetUser(ctx, id)
+	}, func(ctx context.Context, result interface{}) {
+		if err := r.cache.CacheUser(ctx, result.(*domain.User)); err != nil {
+			log.Printf("Failed to cache user %s: %v", id, err)
+		}
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Update cache
-	if err := r.cache.CacheUser(user); err != nil {
-		// Log error but don't fail the request
-		// log.Printf("Failed to cache user: %v", err)
+	return result.(*domain.User), nil
+}
+
+// GetUsers returns the active users with the given IDs, reading the cached profiles first
+// and loading the rest from persistent storage in a single query
+func (r *compositeRepository) GetUsers(ctx context.Context, ids []string) ([]domain.User, error) {
+	users := make([]domain.User, 0, len(ids))
+	var missing []string
+	for _, id := range ids {
+		user, err := r.cache.GetCachedUser(ctx, id)
+		recordLookup("users", err)
+		if err != nil {
+			missing = append(missing, id)
+			continue
+		}
+		users = append(users, *user)
+	}
+	if len(missing) == 0 {
+		return users, nil
 	}
 
-	return user, nil
+	loaded, err := r.loadUsers(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	return append(users, loaded...), nil
 }
 
-func (r *compositeRepository) GetFollowers(userID string) ([]domain.User, error) {
+// loadUsers reads the active users with the given IDs from persistent storage and caches
+// their profiles, unless they were invalidated meanwhile. Users that were not found are not cached.
+func (r *compositeRepository) loadUsers(ctx context.Context, ids []string) ([]domain.User, error) {
+	fills := make(map[string]*fill, len(ids))
+	for _, id := range ids {
+		fills[id] = r.startFill(userKey(id))
+	}
+	loaded, err := r.persistent.GetUsers(ctx, ids)
+	if err != nil {
+		for id, f := range fills {
+			r.finishFill(userKey(id), f, nil)
+		}
+		return nil, err
+	}
+	for i := range loaded {
+		user := &loaded[i]
+		if f, ok := fills[user.ID]; ok {
+			r.finishFill(userKey(user.ID), f, func() {
+				if err := r.cache.CacheUser(ctx, user); err != nil {
+					log.Printf("Failed to cache user %s: %v", user.ID, err)
+				}
+			})
+			delete(fills, user.ID)
+		}
+	}
+	for id, f := range fills {
+		r.finishFill(userKey(id), f, nil)
+	}
+	return loaded, nil
+}
+
+// hydrate completes a cached follow list with the profiles that are not cached, loading
+// them from persistent storage, and keeps the list in ID order
+func (r *compositeRepository) hydrate(ctx context.Context, users []domain.User, missing []string) ([]domain.User, error) {
+	if len(missing) == 0 {
+		return users, nil
+	}
+	loaded, err := r.loadUsers(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	users = append(users, loaded...)
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
+func (r *compositeRepository) GetFollowers(ctx context.Context, userID string) ([]domain.User, error) {
 	log.Printf("Getting followers list for user %s", userID)
-	
+
 	// Try cache first
-	followers, err := r.cache.GetCachedFollowers(userID)
+	followers, missing, err := r.cache.GetCachedFollowers(ctx, userID)
+	recordLookup("followers", err)
 	if err == nil {
-		log.Printf("Cache HIT: Found followers list for user %s with %d users", userID, len(followers))
-		return followers, nil
+		log.Printf("Cache HIT: Found followers list for user %s with %d users", userID, len(followers)+len(missing))
+		return r.hydrate(ctx, followers, missing)
 	}
 	log.Printf("Cache MISS: No followers list found in cache for user %s, error: %v", userID, err)
 
-	// On cache miss, get from persistent storage
-	followers, err = r.persistent.GetFollowers(userID)
+	// On cache miss, get from persistent storage once for all concurrent callers
+	result, err := r.load(ctx, followersKey(userID), func(ctx context.Context) (interface{}, error) {
+		followers, err := r.persistent.GetFollowers(ctx, userID)
+		if err != nil {
+			log.Printf("Error getting followers from persistent storage for user %s: %v", userID, err)
+			return nil, err
+		}
+		log.Printf("Retrieved %d followers from persistent storage for user %s", len(followers), userID)
+		return followers, nil
+	}, func(ctx context.Context, result interface{}) {
+		// Update cache, including empty lists so they don't keep hitting persistent storage
+		if err := r.cache.CacheFollowers(ctx, userID, result.([]domain.User)); err != nil {
+			log.Printf("Failed to cache followers list for user %s: %v", userID, err)
+		} else {
+			log.Printf("Successfully cached followers list for user %s", userID)
+		}
+	})
 	if err != nil {
-		log.Printf("Error getting followers from persistent storage for user %s: %v", userID, err)
 		return nil, err
 	}
-	log.Printf("Retrieved %d followers from persistent storage for user %s", len(followers), userID)
 
-	// Update cache
-	if err := r.cache.CacheFollowers(userID, followers); err != nil {
-		log.Printf("Failed to cache followers list for user %s: %v", userID, err)
-	} else {
-		log.Printf("Successfully cached followers list for user %s", userID)
+	return result.([]domain.User), nil
+}
+
+func (r *compositeRepository) GetRelationships(ctx context.Context, userID string, ids []string) ([]domain.Relationship, error) {
+	log.Printf("Getting relationships for user %s with %d users", userID, len(ids))
+
+	// Answer from the cached follow sets when both sides are warm
+	relationships, err := r.cache.GetCachedRelationships(ctx, userID, ids)
+	recordLookup("relationships", err)
+	if err == nil {
+		log.Printf("Cache HIT: Resolved relationships for user %s from cached follow sets", userID)
+		return relationships, nil
 	}
+	log.Printf("Cache MISS: Follow sets not cached for user %s, error: %v", userID, err)
 
-	return followers, nil
+	return r.persistent.GetRelationships(ctx, userID, ids)
 }
 
-func (r *compositeRepository) CreateUser(req domain.CreateUserRequest) (*domain.User, error) {
+func (r *compositeRepository) IsFollowing(ctx context.Context, followerID, followedID string) (bool, error) {
+	// Try the cached following set first
+	following, err := r.cache.IsFollowingCached(ctx, followerID, followedID)
+	recordLookup("following", err)
+	if err == nil {
+		return following, nil
+	}
+
+	return r.persistent.IsFollowing(ctx, followerID, followedID)
+}
+
+func (r *compositeRepository) CreateUser(ctx context.Context, req domain.CreateUserRequest) (*domain.User, error) {
 	// Create user in persistent storage
-	user, err := r.persistent.CreateUser(req)
+	user, err := r.persistent.CreateUser(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 
 	// Cache the newly created user
-	if err := r.cache.CacheUser(user); err != nil {
+	if err := r.cache.CacheUser(ctx, user); err != nil {
 		log.Printf("Failed to cache newly created user: %v", err)
 	}
 
 	return user, nil
 }
 
-func (r *compositeRepository) GetAllUsers() ([]domain.User, error) {
+func (r *compositeRepository) GetAllUsers(ctx context.Context, afterID string, limit int) ([]domain.User, error) {
 	// For GetAllUsers, we'll go directly to persistent storage
 	// as caching all users might not be efficient
-	return r.persistent.GetAllUsers()
-} 
\ No newline at end of file
+	return r.persistent.GetAllUsers(ctx, afterID, limit)
+}
+
+func (r *compositeRepository) SearchUsers(ctx context.Context, userID, query string, limit int) ([]domain.User, error) {
+	// Search results depend on the query and the caller's follow graph,
+	// so they are always served by persistent storage
+	return r.persistent.SearchUsers(ctx, userID, query, limit)
+}
+
+func (r *compositeRepository) GetUserByUsername(ctx context.Context, username string) (*domain.User, error) {
+	// Profiles are cached by ID only, so lookups by username go to persistent storage
+	return r.persistent.GetUserByUsername(ctx, username)
+}
+
+func (r *compositeRepository) GetUserByPreviousUsername(ctx context.Context, username string, at time.Time) (*domain.User, error) {
+	return r.persistent.GetUserByPreviousUsername(ctx, username, at)
+}
+
+func (r *compositeRepository) ChangeUsername(ctx context.Context, id, username string, at time.Time, policy domain.UsernamePolicy) (*domain.User, error) {
+	user, err := r.persistent.ChangeUsername(ctx, id, username, at, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cached follow lists hold IDs hydrated from the profile entries, so refreshing
+	// the profile is enough for every listing to show the new username. Storage has
+	// changed, so the cache is refreshed even when the request was canceled.
+	ctx = context.WithoutCancel(ctx)
+	r.stopFills(userKey(id))
+	if err := r.cache.CacheUser(ctx, user); err != nil {
+		log.Printf("Failed to cache renamed user %s: %v", id, err)
+		return user, r.cache.InvalidateUserCache(ctx, id)
+	}
+	return user, nil
+}
+
+func (r *compositeRepository) DeactivateUser(ctx context.Context, id string, at time.Time) error {
+	if err := r.persistent.DeactivateUser(ctx, id, at); err != nil {
+		return err
+	}
+
+	// Deactivated users are hidden from every listing, so drop every cached entry they appear in
+	ctx = context.WithoutCancel(ctx)
+	return r.invalidateUser(ctx, id, r.relatedUserIDs(ctx, id))
+}
+
+func (r *compositeRepository) ReactivateUser(ctx context.Context, id string, deactivatedAfter time.Time) error {
+	if err := r.persistent.ReactivateUser(ctx, id, deactivatedAfter); err != nil {
+		return err
+	}
+
+	// Listings cached while the user was deactivated leave them out
+	ctx = context.WithoutCancel(ctx)
+	return r.invalidateUser(ctx, id, r.relatedUserIDs(ctx, id))
+}
+
+func (r *compositeRepository) DeleteUser(ctx context.Context, id string) error {
+	// Follow edges are gone once the user is deleted, so collect the related users first
+	related := r.relatedUserIDs(ctx, id)
+
+	if err := r.persistent.DeleteUser(ctx, id); err != nil {
+		return err
+	}
+
+	return r.invalidateUser(ctx, id, related)
+}
+
+func (r *compositeRepository) GetDeactivatedUserIDs(ctx context.Context, deactivatedBefore time.Time, limit int) ([]string, error) {
+	return r.persistent.GetDeactivatedUserIDs(ctx, deactivatedBefore, limit)
+}
+
+// relatedUsers holds the IDs of the users on the other side of a user's follow edges
+type relatedUsers struct {
+	following []string
+	followers []string
+}
+
+// relatedUserIDs returns the users that a user follows and is followed by, read from
+// persistent storage. Failures are logged and skipped since the result only drives invalidation.
+func (r *compositeRepository) relatedUserIDs(ctx context.Context, id string) relatedUsers {
+	var related relatedUsers
+	if following, err := r.persistent.GetFollowing(ctx, id); err != nil {
+		log.Printf("Failed to get following list of user %s for invalidation: %v", id, err)
+	} else {
+		for _, user := range following {
+			related.following = append(related.following, user.ID)
+		}
+	}
+	if followers, err := r.persistent.GetFollowers(ctx, id); err != nil {
+		log.Printf("Failed to get followers list of user %s for invalidation: %v", id, err)
+	} else {
+		for _, user := range followers {
+			related.followers = append(related.followers, user.ID)
+		}
+	}
+	return related
+}
+
+// invalidateUser drops a user's cached profile and follow lists, and the follow lists of
+// the related users that contain them. It runs after storage has changed, so it is not
+// canceled with the request: skipping it would keep serving stale entries.
+func (r *compositeRepository) invalidateUser(ctx context.Context, id string, related relatedUsers) error {
+	ctx = context.WithoutCancel(ctx)
+	r.stopFills(userKey(id))
+	errs := []error{
+		r.cache.InvalidateUserCache(ctx, id),
+		r.invalidateFollowing(ctx, id),
+		r.invalidateFollowers(ctx, id),
+	}
+	for _, followedID := range related.following {
+		errs = append(errs, r.invalidateFollowers(ctx, followedID))
+	}
+	for _, followerID := range related.followers {
+		errs = append(errs, r.invalidateFollowing(ctx, followerID))
+	}
+	return errors.Join(errs...)
+}
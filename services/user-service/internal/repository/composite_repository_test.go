@@ -0,0 +1,565 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/lisandro/challenge/services/user-service/internal/domain"
+	redisRepo "github.com/lisandro/challenge/services/user-service/internal/repository/redis"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockPersistentRepository is a mock implementation of PersistentRepository
+type MockPersistentRepository struct {
+	mock.Mock
+}
+
+func (m *MockPersistentRepository) Follow(ctx context.Context, followerID, followedID string) error {
+	args := m.Called(ctx, followerID, followedID)
+	return args.Error(0)
+}
+
+func (m *MockPersistentRepository) Unfollow(ctx context.Context, followerID, followedID string) error {
+	args := m.Called(ctx, followerID, followedID)
+	return args.Error(0)
+}
+
+func (m *MockPersistentRepository) GetFollowing(ctx context.Context, userID string) ([]domain.User, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]domain.User), args.Error(1)
+}
+
+func (m *MockPersistentRepository) GetFollowers(ctx context.Context, userID string) ([]domain.User, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]domain.User), args.Error(1)
+}
+
+func (m *MockPersistentRepository) GetRelationships(ctx context.Context, userID string, ids []string) ([]domain.Relationship, error) {
+	args := m.Called(ctx, userID, ids)
+	return args.Get(0).([]domain.Relationship), args.Error(1)
+}
+
+func (m *MockPersistentRepository) IsFollowing(ctx context.Context, followerID, followedID string) (bool, error) {
+	args := m.Called(ctx, followerID, followedID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPersistentRepository) GetUserByUsername(ctx context.Context, username string) (*domain.User, error) {
+	args := m.Called(ctx, username)
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *MockPersistentRepository) GetUserByPreviousUsername(ctx context.Context, username string, at time.Time) (*domain.User, error) {
+	args := m.Called(ctx, username, at)
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *MockPersistentRepository) ChangeUsername(ctx context.Context, id, username string, at time.Time, policy domain.UsernamePolicy) (*domain.User, error) {
+	args := m.Called(ctx, id, username, at, policy)
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *MockPersistentRepository) GetUser(ctx context.Context, id string) (*domain.User, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *MockPersistentRepository) GetUsers(ctx context.Context, ids []string) ([]domain.User, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).([]domain.User), args.Error(1)
+}
+
+func (m *MockPersistentRepository) GetAllUsers(ctx context.Context, afterID string, limit int) ([]domain.User, error) {
+	args := m.Called(ctx, afterID, limit)
+	return args.Get(0).([]domain.User), args.Error(1)
+}
+
+func (m *MockPersistentRepository) SearchUsers(ctx context.Context, userID, query string, limit int) ([]domain.User, error) {
+	args := m.Called(ctx, userID, query, limit)
+	return args.Get(0).([]domain.User), args.Error(1)
+}
+
+func (m *MockPersistentRepository) CreateUser(ctx context.Context, req domain.CreateUserRequest) (*domain.User, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *MockPersistentRepository) DeactivateUser(ctx context.Context, id string, at time.Time) error {
+	args := m.Called(ctx, id, at)
+	return args.Error(0)
+}
+
+func (m *MockPersistentRepository) ReactivateUser(ctx context.Context, id string, deactivatedAfter time.Time) error {
+	args := m.Called(ctx, id, deactivatedAfter)
+	return args.Error(0)
+}
+
+func (m *MockPersistentRepository) DeleteUser(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockPersistentRepository) GetDeactivatedUserIDs(ctx context.Context, deactivatedBefore time.Time, limit int) ([]string, error) {
+	args := m.Called(ctx, deactivatedBefore, limit)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func setupTest(t *testing.T) (*miniredis.Miniredis, *MockPersistentRepository, domain.UserRepository) {
+	server := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: server.Addr()})
+	cache := redisRepo.NewRedisRepository(client, redisRepo.Config{
+		UserTTL:   time.Hour,
+		FollowTTL: time.Minute,
+	})
+	persistent := new(MockPersistentRepository)
+	return server, persistent, NewCompositeRepository(persistent, cache)
+}
+
+func TestCompositeRepository_FollowInvalidatesBothSides(t *testing.T) {
+	tests := []struct {
+		name   string
+		action func(repo domain.UserRepository) error
+		method string
+	}{
+		{
+			name:   "follow",
+			action: func(repo domain.UserRepository) error { return repo.Follow(context.Background(), "user1", "user2") },
+			method: "Follow",
+		},
+		{
+			name:   "unfollow",
+			action: func(repo domain.UserRepository) error { return repo.Unfollow(context.Background(), "user1", "user2") },
+			method: "Unfollow",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, persistent, repo := setupTest(t)
+			persistent.On("GetFollowing", mock.Anything, "user1").Return([]domain.User{}, nil).Once()
+			persistent.On("GetFollowers", mock.Anything, "user2").Return([]domain.User{}, nil).Once()
+			persistent.On(tt.method, mock.Anything, "user1", "user2").Return(nil)
+
+			// Warm both sides of the edge
+			_, err := repo.GetFollowing(context.Background(), "user1")
+			assert.NoError(t, err)
+			_, err = repo.GetFollowers(context.Background(), "user2")
+			assert.NoError(t, err)
+			assert.True(t, server.Exists("following:user1"))
+			assert.True(t, server.Exists("followers:user2"))
+
+			assert.NoError(t, tt.action(repo))
+
+			assert.False(t, server.Exists("following:user1"))
+			assert.False(t, server.Exists("followers:user2"))
+			persistent.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCompositeRepository_FollowPersistentError(t *testing.T) {
+	server, persistent, repo := setupTest(t)
+	persistent.On("GetFollowing", mock.Anything, "user1").Return([]domain.User{}, nil).Once()
+	persistent.On("Follow", mock.Anything, "user1", "user2").Return(errors.New("database error"))
+
+	_, err := repo.GetFollowing(context.Background(), "user1")
+	assert.NoError(t, err)
+
+	assert.Error(t, repo.Follow(context.Background(), "user1", "user2"))
+	assert.True(t, server.Exists("following:user1"))
+	persistent.AssertExpectations(t)
+}
+
+func TestCompositeRepository_GetFollowing_CachesEmptyResult(t *testing.T) {
+	server, persistent, repo := setupTest(t)
+	persistent.On("GetFollowing", mock.Anything, "user1").Return([]domain.User{}, nil).Once()
+
+	for i := 0; i < 3; i++ {
+		following, err := repo.GetFollowing(context.Background(), "user1")
+		assert.NoError(t, err)
+		assert.Empty(t, following)
+	}
+
+	assert.Equal(t, time.Minute, server.TTL("following:user1"))
+	persistent.AssertNumberOfCalls(t, "GetFollowing", 1)
+}
+
+func TestCompositeRepository_GetFollowers_ExpiresAfterTTL(t *testing.T) {
+	server, persistent, repo := setupTest(t)
+	followers := []domain.User{{ID: "user2", Username: "bob"}}
+	persistent.On("GetFollowers", mock.Anything, "user1").Return(followers, nil).Twice()
+
+	_, err := repo.GetFollowers(context.Background(), "user1")
+	assert.NoError(t, err)
+
+	server.FastForward(time.Minute)
+
+	cached, err := repo.GetFollowers(context.Background(), "user1")
+	assert.NoError(t, err)
+	assert.Equal(t, followers, cached)
+	persistent.AssertNumberOfCalls(t, "GetFollowers", 2)
+}
+
+func TestCompositeRepository_GetFollowing_SingleFlight(t *testing.T) {
+	_, persistent, repo := setupTest(t)
+	following := []domain.User{{ID: "user2", Username: "bob"}}
+	persistent.On("GetFollowing", mock.Anything, "user1").
+		Run(func(args mock.Arguments) {
+			// Hold the load open so every caller queues behind it
+			time.Sleep(50 * time.Millisecond)
+		}).
+		Return(following, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := repo.GetFollowing(context.Background(), "user1")
+			assert.NoError(t, err)
+			assert.Equal(t, following, result)
+		}()
+	}
+	wg.Wait()
+
+	persistent.AssertNumberOfCalls(t, "GetFollowing", 1)
+}
+
+func TestCompositeRepository_GetFollowing_CanceledCallerLeavesSharedLoad(t *testing.T) {
+	server, persistent, repo := setupTest(t)
+	following := []domain.User{{ID: "user2", Username: "bob"}}
+	loading := make(chan struct{})
+	persistent.On("GetFollowing", mock.Anything, "user1").
+		Run(func(args mock.Arguments) {
+			close(loading)
+			time.Sleep(50 * time.Millisecond)
+			// The load does not inherit the cancellation of the caller that started it
+			assert.NoError(t, args.Get(0).(context.Context).Err())
+		}).
+		Return(following, nil).Once()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	other := make(chan []domain.User)
+	go func() {
+		result, err := repo.GetFollowing(ctx, "user1")
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Nil(t, result)
+		<-loading
+		result, err = repo.GetFollowing(context.Background(), "user1")
+		assert.NoError(t, err)
+		other <- result
+	}()
+	<-loading
+	cancel()
+
+	assert.Equal(t, following, <-other)
+	assert.True(t, server.Exists("following:user1"))
+	persistent.AssertNumberOfCalls(t, "GetFollowing", 1)
+}
+
+func TestCompositeRepository_GetFollowing_FillInterleavedWithFollow(t *testing.T) {
+	server, persistent, repo := setupTest(t)
+	before := []domain.User{}
+	after := []domain.User{{ID: "user2", Username: "bob"}}
+	loading := make(chan struct{})
+	release := make(chan struct{})
+	// The first load reads storage before the follow and returns after its invalidation
+	persistent.On("GetFollowing", mock.Anything, "user1").
+		Run(func(args mock.Arguments) {
+			close(loading)
+			<-release
+		}).
+		Return(before, nil).Once()
+	persistent.On("GetFollowing", mock.Anything, "user1").Return(after, nil).Once()
+	persistent.On("GetFollowers", mock.Anything, "user2").Return(after, nil).Maybe()
+	persistent.On("Follow", mock.Anything, "user1", "user2").Return(nil)
+	// The cached list holds IDs, so its profiles are loaded on the first hit
+	persistent.On("GetUsers", mock.Anything, []string{"user2"}).Return(after, nil).Once()
+
+	stale := make(chan []domain.User)
+	go func() {
+		result, err := repo.GetFollowing(context.Background(), "user1")
+		assert.NoError(t, err)
+		stale <- result
+	}()
+	<-loading
+	assert.NoError(t, repo.Follow(context.Background(), "user1", "user2"))
+
+	// Callers after the invalidation do not wait for the load that read the old list
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	result, err := repo.GetFollowing(ctx, "user1")
+	assert.NoError(t, err)
+	assert.Equal(t, after, result)
+
+	close(release)
+	assert.Equal(t, before, <-stale)
+
+	cached, err := repo.GetFollowing(context.Background(), "user1")
+	assert.NoError(t, err)
+	assert.Equal(t, after, cached)
+	assert.True(t, server.Exists("following:user1"))
+	persistent.AssertNumberOfCalls(t, "GetFollowing", 2)
+}
+
+func TestCompositeRepository_GetFollowers_FillInterleavedWithRename(t *testing.T) {
+	_, persistent, repo := setupTest(t)
+	loading := make(chan struct{})
+	release := make(chan struct{})
+	// The load reads the follower before the rename and returns after it
+	persistent.On("GetFollowers", mock.Anything, "user2").
+		Run(func(args mock.Arguments) {
+			close(loading)
+			<-release
+		}).
+		Return([]domain.User{{ID: "user1", Username: "alice"}}, nil).Once()
+	persistent.On("ChangeUsername", mock.Anything, "user1", "alice2", mock.AnythingOfType("time.Time"), mock.AnythingOfType("domain.UsernamePolicy")).
+		Return(&domain.User{ID: "user1", Username: "alice2"}, nil)
+
+	stale := make(chan []domain.User)
+	go func() {
+		result, err := repo.GetFollowers(context.Background(), "user2")
+		assert.NoError(t, err)
+		stale <- result
+	}()
+	<-loading
+	_, err := repo.ChangeUsername(context.Background(), "user1", "alice2", time.Now(), domain.UsernamePolicy{})
+	assert.NoError(t, err)
+	close(release)
+	assert.Equal(t, []domain.User{{ID: "user1", Username: "alice"}}, <-stale)
+
+	// The fill cached the list but not the profile it read before the rename
+	followers, err := repo.GetFollowers(context.Background(), "user2")
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.User{{ID: "user1", Username: "alice2"}}, followers)
+	persistent.AssertNumberOfCalls(t, "GetFollowers", 1)
+	persistent.AssertNotCalled(t, "GetUsers", mock.Anything, mock.Anything)
+}
+
+func TestCompositeRepository_GetFollowers_ProfileFillInterleavedWithRename(t *testing.T) {
+	_, persistent, repo := setupTest(t)
+	loading := make(chan struct{})
+	release := make(chan struct{})
+	persistent.On("GetFollowers", mock.Anything, "user2").Return([]domain.User{{ID: "user1", Username: "alice"}}, nil).Once()
+	// The profile hydrating the cached list is read before the rename and returns after it
+	persistent.On("GetUsers", mock.Anything, []string{"user1"}).
+		Run(func(args mock.Arguments) {
+			close(loading)
+			<-release
+		}).
+		Return([]domain.User{{ID: "user1", Username: "alice"}}, nil).Once()
+	persistent.On("ChangeUsername", mock.Anything, "user1", "alice2", mock.AnythingOfType("time.Time"), mock.AnythingOfType("domain.UsernamePolicy")).
+		Return(&domain.User{ID: "user1", Username: "alice2"}, nil)
+
+	_, err := repo.GetFollowers(context.Background(), "user2")
+	assert.NoError(t, err)
+
+	stale := make(chan []domain.User)
+	go func() {
+		result, err := repo.GetFollowers(context.Background(), "user2")
+		assert.NoError(t, err)
+		stale <- result
+	}()
+	<-loading
+	_, err = repo.ChangeUsername(context.Background(), "user1", "alice2", time.Now(), domain.UsernamePolicy{})
+	assert.NoError(t, err)
+	close(release)
+	assert.Equal(t, []domain.User{{ID: "user1", Username: "alice"}}, <-stale)
+
+	followers, err := repo.GetFollowers(context.Background(), "user2")
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.User{{ID: "user1", Username: "alice2"}}, followers)
+	user, err := repo.GetUser(context.Background(), "user1")
+	assert.NoError(t, err)
+	assert.Equal(t, "alice2", user.Username)
+	persistent.AssertExpectations(t)
+}
+
+func TestCompositeRepository_GetFollowing_LoadsUncachedProfiles(t *testing.T) {
+	_, persistent, repo := setupTest(t)
+	bob := domain.User{ID: "user2", Username: "bob"}
+	charlie := domain.User{ID: "user3", Username: "charlie"}
+	persistent.On("GetFollowing", mock.Anything, "user1").Return([]domain.User{bob, charlie}, nil).Once()
+	persistent.On("GetUser", mock.Anything, "user3").Return(&charlie, nil).Once()
+	persistent.On("GetUsers", mock.Anything, []string{"user2"}).Return([]domain.User{bob}, nil).Once()
+
+	_, err := repo.GetFollowing(context.Background(), "user1")
+	assert.NoError(t, err)
+	_, err = repo.GetUser(context.Background(), "user3")
+	assert.NoError(t, err)
+
+	// Only the profiles that are not cached are loaded, once
+	for i := 0; i < 2; i++ {
+		following, err := repo.GetFollowing(context.Background(), "user1")
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.User{bob, charlie}, following)
+	}
+	persistent.AssertExpectations(t)
+}
+
+func TestCompositeRepository_GetUser_CachesUser(t *testing.T) {
+	server, persistent, repo := setupTest(t)
+	user := &domain.User{ID: "user1", Username: "alice"}
+	persistent.On("GetUser", mock.Anything, "user1").Return(user, nil).Once()
+	hits, misses := cacheLookups.WithLabelValues("users", "hit"), cacheLookups.WithLabelValues("users", "miss")
+	hitsBefore, missesBefore := testutil.ToFloat64(hits), testutil.ToFloat64(misses)
+
+	for i := 0; i < 2; i++ {
+		cached, err := repo.GetUser(context.Background(), "user1")
+		assert.NoError(t, err)
+		assert.Equal(t, user, cached)
+	}
+
+	assert.Equal(t, time.Hour, server.TTL("user:user1"))
+	persistent.AssertNumberOfCalls(t, "GetUser", 1)
+	assert.Equal(t, missesBefore+1, testutil.ToFloat64(misses))
+	assert.Equal(t, hitsBefore+1, testutil.ToFloat64(hits))
+}
+
+func TestCompositeRepository_GetUsers_LoadsOnlyUncachedUsers(t *testing.T) {
+	server, persistent, repo := setupTest(t)
+	alice := domain.User{ID: "user1", Username: "alice"}
+	bob := domain.User{ID: "user2", Username: "bob"}
+	persistent.On("GetUser", mock.Anything, "user1").Return(&alice, nil).Once()
+	persistent.On("GetUsers", mock.Anything, []string{"user2", "user3"}).Return([]domain.User{bob}, nil).Once()
+
+	_, err := repo.GetUser(context.Background(), "user1")
+	assert.NoError(t, err)
+
+	users, err := repo.GetUsers(context.Background(), []string{"user1", "user2", "user3"})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []domain.User{alice, bob}, users)
+
+	// Loaded users are cached for the next lookups
+	assert.True(t, server.Exists("user:user2"))
+	users, err = repo.GetUsers(context.Background(), []string{"user2"})
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.User{bob}, users)
+
+	persistent.AssertExpectations(t)
+}
+
+func TestCompositeRepository_IsFollowing(t *testing.T) {
+	_, persistent, repo := setupTest(t)
+	persistent.On("IsFollowing", mock.Anything, "user1", "user2").Return(true, nil).Once()
+	persistent.On("GetFollowing", mock.Anything, "user1").Return([]domain.User{{ID: "user2", Username: "bob"}}, nil).Once()
+
+	// Cold cache falls back to persistent storage
+	following, err := repo.IsFollowing(context.Background(), "user1", "user2")
+	assert.NoError(t, err)
+	assert.True(t, following)
+
+	// Warm cache answers from the following set
+	_, err = repo.GetFollowing(context.Background(), "user1")
+	assert.NoError(t, err)
+
+	following, err = repo.IsFollowing(context.Background(), "user1", "user2")
+	assert.NoError(t, err)
+	assert.True(t, following)
+
+	following, err = repo.IsFollowing(context.Background(), "user1", "user3")
+	assert.NoError(t, err)
+	assert.False(t, following)
+
+	persistent.AssertExpectations(t)
+}
+
+func TestCompositeRepository_GetRelationships(t *testing.T) {
+	_, persistent, repo := setupTest(t)
+	ids := []string{"user2", "user3"}
+	persistentRelationships := []domain.Relationship{
+		{UserID: "user2", Following: true, FollowedBy: false},
+		{UserID: "user3", Following: false, FollowedBy: false},
+	}
+	persistent.On("GetRelationships", mock.Anything, "user1", ids).Return(persistentRelationships, nil).Once()
+	persistent.On("GetFollowing", mock.Anything, "user1").Return([]domain.User{{ID: "user2", Username: "bob"}}, nil).Once()
+	persistent.On("GetFollowers", mock.Anything, "user1").Return([]domain.User{{ID: "user3", Username: "charlie"}}, nil).Once()
+
+	// Cold cache falls back to persistent storage
+	relationships, err := repo.GetRelationships(context.Background(), "user1", ids)
+	assert.NoError(t, err)
+	assert.Equal(t, persistentRelationships, relationships)
+
+	// Warm cache answers from the follow sets
+	_, err = repo.GetFollowing(context.Background(), "user1")
+	assert.NoError(t, err)
+	_, err = repo.GetFollowers(context.Background(), "user1")
+	assert.NoError(t, err)
+
+	relationships, err = repo.GetRelationships(context.Background(), "user1", ids)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Relationship{
+		{UserID: "user2", Following: true, FollowedBy: false},
+		{UserID: "user3", Following: false, FollowedBy: true},
+	}, relationships)
+
+	persistent.AssertExpectations(t)
+}
+
+func TestCompositeRepository_DeleteUserInvalidatesRelatedUsers(t *testing.T) {
+	server, persistent, repo := setupTest(t)
+	persistent.On("GetFollowing", mock.Anything, "user1").Return([]domain.User{{ID: "user2", Username: "bob"}}, nil)
+	persistent.On("GetFollowers", mock.Anything, "user1").Return([]domain.User{{ID: "user3", Username: "charlie"}}, nil)
+	persistent.On("GetFollowers", mock.Anything, "user2").Return([]domain.User{{ID: "user1", Username: "alice"}}, nil).Once()
+	persistent.On("GetFollowing", mock.Anything, "user3").Return([]domain.User{{ID: "user1", Username: "alice"}}, nil).Once()
+	persistent.On("GetUser", mock.Anything, "user1").Return(&domain.User{ID: "user1", Username: "alice"}, nil).Once()
+	persistent.On("DeleteUser", mock.Anything, "user1").Return(nil)
+
+	// Warm the deleted user's profile and the lists that contain them
+	_, err := repo.GetUser(context.Background(), "user1")
+	assert.NoError(t, err)
+	_, err = repo.GetFollowers(context.Background(), "user2")
+	assert.NoError(t, err)
+	_, err = repo.GetFollowing(context.Background(), "user3")
+	assert.NoError(t, err)
+	assert.True(t, server.Exists("user:user1"))
+
+	assert.NoError(t, repo.DeleteUser(context.Background(), "user1"))
+
+	assert.False(t, server.Exists("user:user1"))
+	assert.False(t, server.Exists("followers:user2"))
+	assert.False(t, server.Exists("following:user3"))
+	persistent.AssertExpectations(t)
+}
+
+func TestCompositeRepository_DeactivateUserError(t *testing.T) {
+	server, persistent, repo := setupTest(t)
+	persistent.On("GetUser", mock.Anything, "user1").Return(&domain.User{ID: "user1", Username: "alice"}, nil).Once()
+	persistent.On("DeactivateUser", mock.Anything, "user1", mock.AnythingOfType("time.Time")).Return(domain.ErrUserNotFound)
+
+	_, err := repo.GetUser(context.Background(), "user1")
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, repo.DeactivateUser(context.Background(), "user1", time.Now()), domain.ErrUserNotFound)
+	assert.True(t, server.Exists("user:user1"))
+	persistent.AssertExpectations(t)
+}
+
+func TestCompositeRepository_ChangeUsernameRefreshesFollowLists(t *testing.T) {
+	_, persistent, repo := setupTest(t)
+	persistent.On("GetFollowers", mock.Anything, "user2").Return([]domain.User{{ID: "user1", Username: "alice"}}, nil).Once()
+	persistent.On("ChangeUsername", mock.Anything, "user1", "alice2", mock.AnythingOfType("time.Time"), mock.AnythingOfType("domain.UsernamePolicy")).
+		Return(&domain.User{ID: "user1", Username: "alice2"}, nil)
+
+	// Warm a list that contains the renamed user
+	_, err := repo.GetFollowers(context.Background(), "user2")
+	assert.NoError(t, err)
+
+	_, err = repo.ChangeUsername(context.Background(), "user1", "alice2", time.Now(), domain.UsernamePolicy{})
+	assert.NoError(t, err)
+
+	followers, err := repo.GetFollowers(context.Background(), "user2")
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.User{{ID: "user1", Username: "alice2"}}, followers)
+	user, err := repo.GetUser(context.Background(), "user1")
+	assert.NoError(t, err)
+	assert.Equal(t, "alice2", user.Username)
+	persistent.AssertExpectations(t)
+}
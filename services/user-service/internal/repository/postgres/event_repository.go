@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/lisandro/challenge/services/user-service/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// userEvent represents the database model for a recorded user event
+type userEvent struct {
+	ID         string `gorm:"type:uuid;primaryKey"`
+	Type       string
+	UserID     string `gorm:"type:uuid"`
+	OccurredAt time.Time
+}
+
+func (userEvent) TableName() string {
+	return "user_events"
+}
+
+// eventDelivery represents the database model for the delivery state of an event to a subscriber
+type eventDelivery struct {
+	EventID       string `gorm:"type:uuid;primaryKey"`
+	Subscriber    string `gorm:"primaryKey"`
+	Attempts      int
+	LastError     string
+	NextAttemptAt time.Time
+	DeliveredAt   *time.Time
+}
+
+func (eventDelivery) TableName() string {
+	return "user_event_deliveries"
+}
+
+// EventRepository handles the delivery state of user events in PostgreSQL
+type EventRepository struct {
+	db *gorm.DB
+}
+
+// NewEventRepository creates a new PostgreSQL event repository
+func NewEventRepository(db *gorm.DB) *EventRepository {
+	return &EventRepository{
+		db: db,
+	}
+}
+
+// GetPendingEvents returns up to limit events, oldest first, that have not been delivered
+// to subscriber and are not waiting for a retry
+func (r *EventRepository) GetPendingEvents(ctx context.Context, subscriber string, limit int) ([]domain.Event, error) {
+	var rows []userEvent
+	err := r.db.WithContext(ctx).Model(&userEvent{}).
+		Joins("LEFT JOIN user_event_deliveries ON user_event_deliveries.event_id = user_events.id AND user_event_deliveries.subscriber = ?", subscriber).
+		Where("user_event_deliveries.event_id IS NULL OR (user_event_deliveries.delivered_at IS NULL AND user_event_deliveries.next_attempt_at <= ?)", time.Now()).
+		Order("user_events.occurred_at, user_events.id").
+		Limit(limit).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]domain.Event, 0, len(rows))
+	for _, row := range rows {
+		events = append(events, domain.Event{
+			ID:         row.ID,
+			Type:       row.Type,
+			UserID:     row.UserID,
+			OccurredAt: row.OccurredAt,
+		})
+	}
+	return events, nil
+}
+
+// MarkEventDelivered records that subscriber has acknowledged the event
+func (r *EventRepository) MarkEventDelivered(ctx context.Context, eventID, subscriber string) error {
+	now := time.Now()
+	return r.recordAttempt(ctx, eventDelivery{
+		EventID:       eventID,
+		Subscriber:    subscriber,
+		NextAttemptAt: now,
+		DeliveredAt:   &now,
+	}, "delivered_at")
+}
+
+// MarkEventFailed records a failed delivery attempt and schedules the next one at retryAt
+func (r *EventRepository) MarkEventFailed(ctx context.Context, eventID, subscriber, reason string, retryAt time.Time) error {
+	return r.recordAttempt(ctx, eventDelivery{
+		EventID:       eventID,
+		Subscriber:    subscriber,
+		LastError:     reason,
+		NextAttemptAt: retryAt,
+	}, "last_error", "next_attempt_at")
+}
+
+// recordAttempt upserts the delivery row of an attempt, overwriting the given columns
+// and counting the attempt
+func (r *EventRepository) recordAttempt(ctx context.Context, delivery eventDelivery, columns ...string) error {
+	delivery.Attempts = 1
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "event_id"}, {Name: "subscriber"}},
+		DoUpdates: append(clause.AssignmentColumns(columns), clause.Assignment{
+			Column: clause.Column{Name: "attempts"},
+			Value:  gorm.Expr("user_event_deliveries.attempts + 1"),
+		}),
+	}).Create(&delivery).Error
+}
@@ -0,0 +1,178 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lisandro/challenge/services/user-service/internal/domain"
+	"gorm.io/gorm"
+)
+
+// exportColumns are the columns of data_exports without the archive, which is only
+// read when it is downloaded
+var exportColumns = []string{"id", "user_id", "status", "error", "created_at", "started_at", "completed_at", "expires_at"}
+
+// dataExport represents the database model for a data export job
+type dataExport struct {
+	ID          string `gorm:"type:uuid;primaryKey"`
+	UserID      string `gorm:"type:uuid"`
+	Status      domain.ExportStatus
+	Error       string
+	Archive     []byte
+	CreatedAt   time.Time
+	StartedAt   *time.Time
+	CompletedAt *time.Time
+	ExpiresAt   *time.Time
+}
+
+func (dataExport) TableName() string {
+	return "data_exports"
+}
+
+func (e dataExport) toDomain() *domain.DataExport {
+	return &domain.DataExport{
+		ID:          e.ID,
+		UserID:      e.UserID,
+		Status:      e.Status,
+		Error:       e.Error,
+		CreatedAt:   e.CreatedAt,
+		StartedAt:   e.StartedAt,
+		CompletedAt: e.CompletedAt,
+		ExpiresAt:   e.ExpiresAt,
+	}
+}
+
+// ExportRepository handles the data export jobs in PostgreSQL
+type ExportRepository struct {
+	db *gorm.DB
+}
+
+// NewExportRepository creates a new PostgreSQL export repository
+func NewExportRepository(db *gorm.DB) *ExportRepository {
+	return &ExportRepository{
+		db: db,
+	}
+}
+
+// CreateExport creates a pending export for a user. If the user already has a pending
+// or running export, that export is returned instead of creating another one.
+func (r *ExportRepository) CreateExport(ctx context.Context, userID string) (*domain.DataExport, error) {
+	row := dataExport{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		Status:    domain.ExportPending,
+		CreatedAt: time.Now(),
+	}
+	err := r.db.WithContext(ctx).Omit("archive").Create(&row).Error
+	if isPgError(err, uniqueViolation) {
+		return r.getActiveExport(ctx, userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return row.toDomain(), nil
+}
+
+// getActiveExport returns the pending or running export of a user
+func (r *ExportRepository) getActiveExport(ctx context.Context, userID string) (*domain.DataExport, error) {
+	var row dataExport
+	err := r.db.WithContext(ctx).Select(exportColumns).
+		Where("user_id = ? AND status IN ?", userID, []domain.ExportStatus{domain.ExportPending, domain.ExportRunning}).
+		Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrExportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return row.toDomain(), nil
+}
+
+// GetExport returns an export without its archive, or domain.ErrExportNotFound
+func (r *ExportRepository) GetExport(ctx context.Context, id string) (*domain.DataExport, error) {
+	var row dataExport
+	err := r.db.WithContext(ctx).Select(exportColumns).Where("id = ?", id).Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || isPgError(err, invalidTextRepresentation) {
+		return nil, domain.ErrExportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return row.toDomain(), nil
+}
+
+// ClaimPendingExport marks the oldest pending export as running and returns it. Exports that
+// have been running since before staleBefore are claimed again, so a job whose worker died
+// is eventually retried. Concurrent workers never claim the same export. It returns nil when
+// there is nothing to claim.
+func (r *ExportRepository) ClaimPendingExport(ctx context.Context, staleBefore time.Time) (*domain.DataExport, error) {
+	var rows []dataExport
+	err := r.db.WithContext(ctx).Raw(`UPDATE data_exports SET status = ?, started_at = ?
+		WHERE id = (
+			SELECT id FROM data_exports
+			WHERE status = ? OR (status = ? AND started_at < ?)
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, user_id, status, error, created_at, started_at, completed_at, expires_at`,
+		domain.ExportRunning, time.Now(), domain.ExportPending, domain.ExportRunning, staleBefore).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	return rows[0].toDomain(), nil
+}
+
+// CompleteExport stores the archive of a running export and marks it ready until expiresAt
+func (r *ExportRepository) CompleteExport(ctx context.Context, id string, archive []byte, expiresAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&dataExport{}).
+		Where("id = ? AND status = ?", id, domain.ExportRunning).
+		Updates(map[string]interface{}{
+			"status":       domain.ExportReady,
+			"archive":      archive,
+			"completed_at": time.Now(),
+			"expires_at":   expiresAt,
+		}).Error
+}
+
+// FailExport marks a running export as failed with the reason of the failure
+func (r *ExportRepository) FailExport(ctx context.Context, id, reason string) error {
+	return r.db.WithContext(ctx).Model(&dataExport{}).
+		Where("id = ? AND status = ?", id, domain.ExportRunning).
+		Updates(map[string]interface{}{
+			"status":       domain.ExportFailed,
+			"error":        reason,
+			"completed_at": time.Now(),
+		}).Error
+}
+
+// GetExportArchive returns the archive of a ready export, or domain.ErrExportNotFound
+func (r *ExportRepository) GetExportArchive(ctx context.Context, id string) ([]byte, error) {
+	var row dataExport
+	err := r.db.WithContext(ctx).Select("archive").Where("id = ? AND status = ?", id, domain.ExportReady).Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || isPgError(err, invalidTextRepresentation) {
+		return nil, domain.ErrExportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return row.Archive, nil
+}
+
+// ExpireExports deletes the archives of the ready exports that expired before the given
+// time and returns how many exports expired
+func (r *ExportRepository) ExpireExports(ctx context.Context, before time.Time) (int, error) {
+	result := r.db.WithContext(ctx).Model(&dataExport{}).
+		Where("status = ? AND expires_at <= ?", domain.ExportReady, before).
+		Updates(map[string]interface{}{
+			"status":  domain.ExportExpired,
+			"archive": nil,
+		})
+	return int(result.RowsAffected), result.Error
+}
@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations_Embedded(t *testing.T) {
+	migrations, err := loadMigrations(migrationFiles, "migrations")
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i, migration := range migrations {
+		assert.Equal(t, int64(i+1), migration.Version, "migration versions must be contiguous")
+		assert.NotEmpty(t, migration.Up)
+		assert.NotEmpty(t, migration.Down)
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	tests := []struct {
+		name          string
+		files         fstest.MapFS
+		expected      []Migration
+		expectedError string
+	}{
+		{
+			name: "sorted by version",
+			files: fstest.MapFS{
+				"migrations/0010_second.up.sql":   {Data: []byte("up 10")},
+				"migrations/0010_second.down.sql": {Data: []byte("down 10")},
+				"migrations/0002_first.up.sql":    {Data: []byte("up 2")},
+				"migrations/0002_first.down.sql":  {Data: []byte("down 2")},
+			},
+			expected: []Migration{
+				{Version: 2, Name: "first", Up: "up 2", Down: "down 2"},
+				{Version: 10, Name: "second", Up: "up 10", Down: "down 10"},
+			},
+		},
+		{
+			name: "missing down file",
+			files: fstest.MapFS{
+				"migrations/0001_first.up.sql": {Data: []byte("up")},
+			},
+			expectedError: "migration 0001_first must have both up and down files",
+		},
+		{
+			name: "conflicting names",
+			files: fstest.MapFS{
+				"migrations/0001_first.up.sql":   {Data: []byte("up")},
+				"migrations/0001_other.down.sql": {Data: []byte("down")},
+			},
+			expectedError: `migration version 1 has conflicting names "first" and "other"`,
+		},
+		{
+			name: "invalid file name",
+			files: fstest.MapFS{
+				"migrations/first.sql": {Data: []byte("up")},
+			},
+			expectedError: `invalid migration file name "first.sql"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := loadMigrations(tt.files, "migrations")
+
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, migrations)
+		})
+	}
+}
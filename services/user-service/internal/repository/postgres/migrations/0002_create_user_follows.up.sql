@@ -1,14 +1,3 @@
--- Enable UUID extension if not already enabled
-CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
-
--- Create users table
-CREATE TABLE IF NOT EXISTS users (
-    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-    username VARCHAR(255) NOT NULL,
-    CONSTRAINT idx_users_username UNIQUE (username)
-);
-
--- Create user_follows table
 CREATE TABLE IF NOT EXISTS user_follows (
     follower_id UUID NOT NULL,
     followed_id UUID NOT NULL,
@@ -16,4 +5,4 @@ CREATE TABLE IF NOT EXISTS user_follows (
     PRIMARY KEY (follower_id, followed_id),
     FOREIGN KEY (follower_id) REFERENCES users(id) ON DELETE CASCADE,
     FOREIGN KEY (followed_id) REFERENCES users(id) ON DELETE CASCADE
-); 
\ No newline at end of file
+);
@@ -0,0 +1,2 @@
+-- Index follow edges by followed user so follower listings don't scan the table
+CREATE INDEX IF NOT EXISTS idx_user_follows_followed_id ON user_follows (followed_id, follower_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_event_deliveries;
+DROP TABLE IF EXISTS user_events;
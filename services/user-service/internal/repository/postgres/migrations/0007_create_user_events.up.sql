@@ -0,0 +1,18 @@
+-- Outbox of user events. Events are written in the same transaction as the change
+-- they describe and delivered to every subscriber until it acknowledges them.
+CREATE TABLE IF NOT EXISTS user_events (
+    id UUID PRIMARY KEY,
+    type VARCHAR(255) NOT NULL,
+    user_id UUID NOT NULL,
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS user_event_deliveries (
+    event_id UUID NOT NULL REFERENCES user_events(id) ON DELETE CASCADE,
+    subscriber VARCHAR(255) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (event_id, subscriber)
+);
@@ -0,0 +1,17 @@
+-- Data export jobs. The archive is kept in the row until the export expires.
+CREATE TABLE IF NOT EXISTS data_exports (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    archive BYTEA,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    started_at TIMESTAMP WITH TIME ZONE,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE
+);
+
+-- A user has at most one export waiting for or being generated
+CREATE UNIQUE INDEX IF NOT EXISTS idx_data_exports_active_user_id ON data_exports(user_id) WHERE status IN ('pending', 'running');
+
+CREATE INDEX IF NOT EXISTS idx_data_exports_status_created_at ON data_exports(status, created_at);
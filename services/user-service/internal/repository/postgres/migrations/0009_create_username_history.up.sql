@@ -0,0 +1,12 @@
+-- Previous usernames of each user. A previous username redirects to its former owner
+-- and cannot be claimed by anyone else until reserved_until.
+CREATE TABLE IF NOT EXISTS username_history (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    username VARCHAR(255) NOT NULL,
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    reserved_until TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_username_history_username ON username_history(username, reserved_until);
+CREATE INDEX IF NOT EXISTS idx_username_history_user_id_changed_at ON username_history(user_id, changed_at);
@@ -1,10 +1,42 @@
 package postgres
 
 import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/lisandro/challenge/services/user-service/internal/domain"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// followPageSize is the number of rows fetched per query when listing follow relationships
+const followPageSize = 1000
+
+// Postgres error codes that are translated into domain errors
+const (
+	uniqueViolation           = "23505"
+	foreignKeyViolation       = "23503"
+	invalidTextRepresentation = "22P02"
+)
+
+// usernameChange represents the database model for a previous username of a user
+type usernameChange struct {
+	ID            string `gorm:"type:uuid;primaryKey"`
+	UserID        string `gorm:"type:uuid"`
+	Username      string
+	ChangedAt     time.Time
+	ReservedUntil time.Time
+}
+
+func (usernameChange) TableName() string {
+	return "username_history"
+}
+
 // PostgresRepository handles user data persistence in PostgreSQL
 type PostgresRepository struct {
 	db *gorm.DB
@@ -17,75 +49,523 @@ func NewPostgresRepository(db *gorm.DB) *PostgresRepository {
 	}
 }
 
-func (r *PostgresRepository) Follow(followerID, followedID string) error {
+func (r *PostgresRepository) Follow(ctx context.Context, followerID, followedID string) error {
 	follow := UserFollow{
 		FollowerID: followerID,
 		FollowedID: followedID,
 	}
-	return r.db.Create(&follow).Error
+	err := r.db.WithContext(ctx).Create(&follow).Error
+	switch {
+	case isPgError(err, uniqueViolation):
+		return domain.ErrAlreadyFollowing
+	case isPgError(err, foreignKeyViolation), isPgError(err, invalidTextRepresentation):
+		return domain.ErrUserNotFound
+	}
+	return err
 }
 
-func (r *PostgresRepository) Unfollow(followerID, followedID string) error {
-	return r.db.Where("follower_id = ? AND followed_id = ?", followerID, followedID).Delete(&UserFollow{}).Error
+func (r *PostgresRepository) Unfollow(ctx context.Context, followerID, followedID string) error {
+	result := r.db.WithContext(ctx).Where("follower_id = ? AND followed_id = ?", followerID, followedID).Delete(&UserFollow{})
+	if isPgError(result.Error, invalidTextRepresentation) {
+		return domain.ErrNotFollowing
+	}
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrNotFollowing
+	}
+	return nil
 }
 
-func (r *PostgresRepository) GetFollowing(userID string) ([]domain.User, error) {
-	var follows []UserFollow
-	if err := r.db.Where("follower_id = ?", userID).Find(&follows).Error; err != nil {
-		return nil, err
+// GetFollowing returns the list of users that a user follows
+func (r *PostgresRepository) GetFollowing(ctx context.Context, userID string) ([]domain.User, error) {
+	return r.collectPages(func(afterID string) ([]domain.User, error) {
+		return r.GetFollowingPage(ctx, userID, afterID, followPageSize)
+	})
+}
+
+// GetFollowingPage returns up to limit users that a user follows, ordered by ID
+// and starting after afterID. An empty afterID returns the first page.
+func (r *PostgresRepository) GetFollowingPage(ctx context.Context, userID, afterID string, limit int) ([]domain.User, error) {
+	query := r.db.WithContext(ctx).Model(&domain.User{}).
+		Joins("JOIN user_follows ON user_follows.followed_id = users.id").
+		Where("user_follows.follower_id = ? AND users.deactivated_at IS NULL", userID)
+	if afterID != "" {
+		query = query.Where("user_follows.followed_id > ?", afterID)
 	}
 
 	var users []domain.User
-	for _, follow := range follows {
-		var user domain.User
-		if err := r.db.First(&user, "id = ?", follow.FollowedID).Error; err != nil {
-			return nil, err
-		}
-		users = append(users, user)
+	if err := query.Order("user_follows.followed_id").Limit(limit).Find(&users).Error; err != nil {
+		return nil, err
 	}
 	return users, nil
 }
 
-func (r *PostgresRepository) GetUser(id string) (*domain.User, error) {
+func (r *PostgresRepository) GetUser(ctx context.Context, id string) (*domain.User, error) {
 	var user domain.User
-	if err := r.db.First(&user, "id = ?", id).Error; err != nil {
+	err := r.db.WithContext(ctx).First(&user, "id = ? AND deactivated_at IS NULL", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || isPgError(err, invalidTextRepresentation) {
+		return nil, domain.ErrUserNotFound
+	}
+	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
+// GetUsers returns the active users with the given IDs. Malformed IDs and IDs of missing
+// or deactivated users are skipped, and the order of the result is unspecified.
+func (r *PostgresRepository) GetUsers(ctx context.Context, ids []string) ([]domain.User, error) {
+	users := []domain.User{}
+	validIDs := wellFormedIDs(ids)
+	if len(validIDs) == 0 {
+		return users, nil
+	}
+
+	err := r.db.WithContext(ctx).Where("id IN ? AND deactivated_at IS NULL", validIDs).Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 // GetFollowers returns the list of users that follow a user
-func (r *PostgresRepository) GetFollowers(userID string) ([]domain.User, error) {
-	var follows []UserFollow
-	if err := r.db.Where("followed_id = ?", userID).Find(&follows).Error; err != nil {
+func (r *PostgresRepository) GetFollowers(ctx context.Context, userID string) ([]domain.User, error) {
+	return r.collectPages(func(afterID string) ([]domain.User, error) {
+		return r.GetFollowersPage(ctx, userID, afterID, followPageSize)
+	})
+}
+
+// GetFollowersPage returns up to limit users that follow a user, ordered by ID
+// and starting after afterID. An empty afterID returns the first page.
+func (r *PostgresRepository) GetFollowersPage(ctx context.Context, userID, afterID string, limit int) ([]domain.User, error) {
+	query := r.db.WithContext(ctx).Model(&domain.User{}).
+		Joins("JOIN user_follows ON user_follows.follower_id = users.id").
+		Where("user_follows.followed_id = ? AND users.deactivated_at IS NULL", userID)
+	if afterID != "" {
+		query = query.Where("user_follows.follower_id > ?", afterID)
+	}
+
+	var users []domain.User
+	if err := query.Order("user_follows.follower_id").Limit(limit).Find(&users).Error; err != nil {
 		return nil, err
 	}
+	return users, nil
+}
 
+// collectPages walks a keyset paginated listing until a short page is returned
+func (r *PostgresRepository) collectPages(fetch func(afterID string) ([]domain.User, error)) ([]domain.User, error) {
 	var users []domain.User
-	for _, follow := range follows {
-		var user domain.User
-		if err := r.db.First(&user, "id = ?", follow.FollowerID).Error; err != nil {
+	afterID := ""
+	for {
+		page, err := fetch(afterID)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, page...)
+		if len(page) < followPageSize {
+			return users, nil
+		}
+		afterID = page[len(page)-1].ID
+	}
+}
+
+// GetRelationships returns the follow state between a user and each of the given users
+// using a single query over both directions of the follow graph. Deactivated users are
+// hidden like in every listing, and malformed IDs cannot be followed, so both are reported
+// without relationship. It returns domain.ErrUserNotFound when userID is malformed.
+func (r *PostgresRepository) GetRelationships(ctx context.Context, userID string, ids []string) ([]domain.Relationship, error) {
+	if len(ids) == 0 {
+		return []domain.Relationship{}, nil
+	}
+
+	var follows []UserFollow
+	if validIDs := wellFormedIDs(ids); len(validIDs) > 0 {
+		err := r.db.WithContext(ctx).
+			Select("user_follows.*").
+			Joins("JOIN users ON users.id IN (user_follows.follower_id, user_follows.followed_id) AND users.id <> ?", userID).
+			Where(r.db.Where("user_follows.follower_id = ? AND user_follows.followed_id IN ?", userID, validIDs).
+				Or("user_follows.followed_id = ? AND user_follows.follower_id IN ?", userID, validIDs)).
+			Where("users.deactivated_at IS NULL").
+			Find(&follows).Error
+		if isPgError(err, invalidTextRepresentation) {
+			return nil, domain.ErrUserNotFound
+		}
+		if err != nil {
 			return nil, err
 		}
-		users = append(users, user)
 	}
-	return users, nil
+
+	following := make(map[string]bool)
+	followedBy := make(map[string]bool)
+	for _, follow := range follows {
+		if follow.FollowerID == userID {
+			following[follow.FollowedID] = true
+		}
+		if follow.FollowedID == userID {
+			followedBy[follow.FollowerID] = true
+		}
+	}
+
+	relationships := make([]domain.Relationship, 0, len(ids))
+	for _, id := range ids {
+		relationships = append(relationships, domain.Relationship{
+			UserID:     id,
+			Following:  following[id],
+			FollowedBy: followedBy[id],
+		})
+	}
+	return relationships, nil
+}
+
+// IsFollowing reports whether followerID follows followedID. It returns
+// domain.ErrUserNotFound when either ID is malformed.
+func (r *PostgresRepository) IsFollowing(ctx context.Context, followerID, followedID string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&UserFollow{}).
+		Where("follower_id = ? AND followed_id = ?", followerID, followedID).
+		Count(&count).Error
+	if isPgError(err, invalidTextRepresentation) {
+		return false, domain.ErrUserNotFound
+	}
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
 }
 
-func (r *PostgresRepository) CreateUser(req domain.CreateUserRequest) (*domain.User, error) {
+// CreateUser creates a user. It returns domain.ErrUsernameTaken when the username belongs
+// to another user or is reserved as the previous username of another user.
+func (r *PostgresRepository) CreateUser(ctx context.Context, req domain.CreateUserRequest) (*domain.User, error) {
 	user := domain.User{
-		Username: req.Username,
+		Username:    req.Username,
+		DisplayName: req.DisplayName,
+		AvatarURL:   req.AvatarURL,
+	}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockUsernames(tx, req.Username); err != nil {
+			return err
+		}
+		reserved, err := usernameReserved(tx, req.Username, "", time.Now())
+		if err != nil {
+			return err
+		}
+		if reserved {
+			return domain.ErrUsernameTaken
+		}
+		return tx.Create(&user).Error
+	})
+	if isPgError(err, uniqueViolation) {
+		return nil, domain.ErrUsernameTaken
 	}
-	if err := r.db.Create(&user).Error; err != nil {
+	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *PostgresRepository) GetAllUsers() ([]domain.User, error) {
+// GetUserByUsername returns the active user with the given username
+func (r *PostgresRepository) GetUserByUsername(ctx context.Context, username string) (*domain.User, error) {
+	var user domain.User
+	err := r.db.WithContext(ctx).Where("username = ? AND deactivated_at IS NULL", username).Take(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUserByPreviousUsername returns the active user who most recently gave up username,
+// as long as the username is still reserved for them at the given time
+func (r *PostgresRepository) GetUserByPreviousUsername(ctx context.Context, username string, at time.Time) (*domain.User, error) {
+	var user domain.User
+	err := r.db.WithContext(ctx).Model(&domain.User{}).
+		Joins("JOIN username_history ON username_history.user_id = users.id").
+		Where("username_history.username = ? AND username_history.reserved_until > ? AND users.deactivated_at IS NULL", username, at).
+		Order("username_history.changed_at DESC").
+		Take(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ChangeUsername renames an active user and reserves their previous username for them
+// until policy.Reservation after the change. It returns domain.ErrUsernameChangeLimit when
+// the user already changed it policy.MaxChanges times within policy.Window, and
+// domain.ErrUsernameTaken when the username is used or reserved by another user.
+// A user can take back one of their own reserved usernames. Renaming a user to their
+// current username does nothing.
+func (r *PostgresRepository) ChangeUsername(ctx context.Context, id, username string, at time.Time, policy domain.UsernamePolicy) (*domain.User, error) {
+	var user domain.User
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock the user so that concurrent changes are counted against the limit one at a time
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND deactivated_at IS NULL", id).
+			Take(&user).Error
+		if err != nil {
+			return err
+		}
+		if user.Username == username {
+			return nil
+		}
+
+		var changes int64
+		err = tx.Model(&usernameChange{}).
+			Where("user_id = ? AND changed_at > ?", id, at.Add(-policy.Window)).
+			Count(&changes).Error
+		if err != nil {
+			return err
+		}
+		if int(changes) >= policy.MaxChanges {
+			return domain.ErrUsernameChangeLimit
+		}
+
+		// The previous username must become reserved at the same time it is released
+		previous := user.Username
+		if err := lockUsernames(tx, previous, username); err != nil {
+			return err
+		}
+		reserved, err := usernameReserved(tx, username, id, at)
+		if err != nil {
+			return err
+		}
+		if reserved {
+			return domain.ErrUsernameTaken
+		}
+
+		if err := tx.Model(&user).Update("username", username).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&usernameChange{
+			ID:            uuid.NewString(),
+			UserID:        id,
+			Username:      previous,
+			ChangedAt:     at,
+			ReservedUntil: at.Add(policy.Reservation),
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&userEvent{
+			ID:         uuid.NewString(),
+			Type:       domain.EventUserRenamed,
+			UserID:     id,
+			OccurredAt: at,
+		}).Error
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound) || isPgError(err, invalidTextRepresentation):
+		return nil, domain.ErrUserNotFound
+	case isPgError(err, uniqueViolation):
+		return nil, domain.ErrUsernameTaken
+	case err != nil:
+		return nil, err
+	}
+	return &user, nil
+}
+
+// lockUsernames takes a transaction level advisory lock on each username, in a fixed order
+// so that transactions locking the same usernames cannot deadlock. Claiming a username and
+// reserving it as a previous username are serialized by these locks.
+func lockUsernames(tx *gorm.DB, usernames ...string) error {
+	sorted := append([]string(nil), usernames...)
+	sort.Strings(sorted)
+	for _, username := range sorted {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", username).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// usernameReserved reports whether username is reserved at the given time as the previous
+// username of a user other than userID. An empty userID checks against every user.
+func usernameReserved(tx *gorm.DB, username, userID string, at time.Time) (bool, error) {
+	query := tx.Model(&usernameChange{}).Where("username = ? AND reserved_until > ?", username, at)
+	if userID != "" {
+		query = query.Where("user_id <> ?", userID)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetAllUsers returns up to limit active users ordered by ID and starting after afterID.
+// An empty afterID returns the first page.
+func (r *PostgresRepository) GetAllUsers(ctx context.Context, afterID string, limit int) ([]domain.User, error) {
+	query := r.db.WithContext(ctx).Model(&domain.User{}).Where("deactivated_at IS NULL")
+	if afterID != "" {
+		query = query.Where("id > ?", afterID)
+	}
+
 	var users []domain.User
-	if err := r.db.Find(&users).Error; err != nil {
+	err := query.Order("id").Limit(limit).Find(&users).Error
+	if isPgError(err, invalidTextRepresentation) {
+		return []domain.User{}, nil
+	}
+	if err != nil {
 		return nil, err
 	}
 	return users, nil
-}
\ No newline at end of file
+}
+
+// SearchUsers returns up to limit users whose username or display name starts with, contains
+// or is similar to query. Users followed by userID rank first, then prefix matches, then the
+// closest fuzzy matches. An empty or malformed userID follows no one, so it skips the follow boost.
+func (r *PostgresRepository) SearchUsers(ctx context.Context, userID, query string, limit int) ([]domain.User, error) {
+	prefix := escapeLike(query) + "%"
+	contains := "%" + escapeLike(query) + "%"
+
+	search := r.db.WithContext(ctx).Model(&domain.User{})
+	ranking := ""
+	if isUUID(userID) {
+		search = search.Joins("LEFT JOIN user_follows ON user_follows.followed_id = users.id AND user_follows.follower_id = ?", userID)
+		ranking = "user_follows.follower_id IS NOT NULL DESC, "
+	}
+
+	var users []domain.User
+	err := search.
+		Where("users.deactivated_at IS NULL").
+		Where("users.username ILIKE ? OR users.display_name ILIKE ? OR users.username % ? OR users.display_name % ?",
+			contains, contains, query, query).
+		Clauses(clause.OrderBy{Expression: clause.Expr{
+			SQL: ranking + "(users.username ILIKE ? OR users.display_name ILIKE ?) DESC, " +
+				"GREATEST(similarity(users.username, ?), similarity(users.display_name, ?)) DESC, users.username",
+			Vars: []interface{}{prefix, prefix, query, query},
+		}}).
+		Limit(limit).
+		Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// DeactivateUser marks a user as deactivated at the given time. Deactivating
+// an already deactivated user keeps the original deactivation time.
+func (r *PostgresRepository) DeactivateUser(ctx context.Context, id string, at time.Time) error {
+	result := r.db.WithContext(ctx).Model(&domain.User{}).
+		Where("id = ?", id).
+		Update("deactivated_at", gorm.Expr("COALESCE(deactivated_at, ?)", at))
+	if isPgError(result.Error, invalidTextRepresentation) {
+		return domain.ErrUserNotFound
+	}
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
+
+// ReactivateUser clears the deactivation of a user deactivated after deactivatedAfter.
+// It returns domain.ErrReactivationExpired when the user was deactivated earlier than that.
+// Reactivating an active user does nothing.
+func (r *PostgresRepository) ReactivateUser(ctx context.Context, id string, deactivatedAfter time.Time) error {
+	var user struct {
+		DeactivatedAt *time.Time
+	}
+	err := r.db.WithContext(ctx).Model(&domain.User{}).Select("deactivated_at").Where("id = ?", id).Take(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || isPgError(err, invalidTextRepresentation) {
+		return domain.ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if user.DeactivatedAt == nil {
+		return nil
+	}
+
+	// The condition on deactivated_at guards against the purge deleting the user in between
+	result := r.db.WithContext(ctx).Model(&domain.User{}).
+		Where("id = ? AND deactivated_at > ?", id, deactivatedAfter).
+		Update("deactivated_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrReactivationExpired
+	}
+	return nil
+}
+
+// DeleteUser permanently deletes a user together with their follow edges, and records
+// a user deleted event in the same transaction. Deleting a missing user does nothing,
+// so a deletion can be retried safely.
+func (r *PostgresRepository) DeleteUser(ctx context.Context, id string) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Follow edges are removed by the ON DELETE CASCADE of user_follows
+		result := tx.Where("id = ?", id).Delete(&domain.User{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		return tx.Create(&userEvent{
+			ID:         uuid.NewString(),
+			Type:       domain.EventUserDeleted,
+			UserID:     id,
+			OccurredAt: time.Now(),
+		}).Error
+	})
+	if isPgError(err, invalidTextRepresentation) {
+		return nil
+	}
+	return err
+}
+
+// GetDeactivatedUserIDs returns up to limit IDs of users deactivated before deactivatedBefore,
+// oldest deactivation first
+func (r *PostgresRepository) GetDeactivatedUserIDs(ctx context.Context, deactivatedBefore time.Time, limit int) ([]string, error) {
+	var ids []string
+	err := r.db.WithContext(ctx).Model(&domain.User{}).
+		Where("deactivated_at <= ?", deactivatedBefore).
+		Order("deactivated_at").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// wellFormedIDs returns the IDs that are UUIDs. Postgres rejects a whole query when one of
+// its UUID parameters is malformed, and no user has such an ID.
+func wellFormedIDs(ids []string) []string {
+	valid := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if isUUID(id) {
+			valid = append(valid, id)
+		}
+	}
+	return valid
+}
+
+// isUUID reports whether id is a well formed user ID
+func isUUID(id string) bool {
+	_, err := uuid.Parse(id)
+	return err == nil
+}
+
+// escapeLike escapes the LIKE wildcards in s so it is matched literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// isPgError reports whether err is a Postgres error with the given code
+func isPgError(err error, code string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == code
+}
@@ -0,0 +1,447 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/lisandro/challenge/services/user-service/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// benchFollowCount is the number of follow edges seeded for the benchmark user
+const benchFollowCount = 5000
+
+// newDryRunRepository returns a repository whose queries are recorded instead of executed
+func newDryRunRepository(t *testing.T) (*PostgresRepository, *[]string) {
+	db, err := gorm.Open(postgres.Open("host=localhost"), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	require.NoError(t, err)
+
+	var queries []string
+	err = db.Callback().Query().After("gorm:query").Register("record_sql", func(tx *gorm.DB) {
+		queries = append(queries, tx.Statement.SQL.String())
+	})
+	require.NoError(t, err)
+
+	return NewPostgresRepository(db), &queries
+}
+
+func TestPostgresRepository_FollowPageQueries(t *testing.T) {
+	tests := []struct {
+		name        string
+		fetch       func(repo *PostgresRepository) error
+		expectedSQL string
+	}{
+		{
+			name: "first following page",
+			fetch: func(repo *PostgresRepository) error {
+				_, err := repo.GetFollowingPage(context.Background(), "user1", "", 50)
+				return err
+			},
+			expectedSQL: `SELECT "users"."id","users"."username","users"."display_name","users"."avatar_url" FROM "users" JOIN user_follows ON user_follows.followed_id = users.id WHERE user_follows.follower_id = $1 AND users.deactivated_at IS NULL ORDER BY user_follows.followed_id LIMIT $2`,
+		},
+		{
+			name: "following page after cursor",
+			fetch: func(repo *PostgresRepository) error {
+				_, err := repo.GetFollowingPage(context.Background(), "user1", "user9", 50)
+				return err
+			},
+			expectedSQL: `SELECT "users"."id","users"."username","users"."display_name","users"."avatar_url" FROM "users" JOIN user_follows ON user_follows.followed_id = users.id WHERE (user_follows.follower_id = $1 AND users.deactivated_at IS NULL) AND user_follows.followed_id > $2 ORDER BY user_follows.followed_id LIMIT $3`,
+		},
+		{
+			name: "followers page after cursor",
+			fetch: func(repo *PostgresRepository) error {
+				_, err := repo.GetFollowersPage(context.Background(), "user1", "user9", 50)
+				return err
+			},
+			expectedSQL: `SELECT "users"."id","users"."username","users"."display_name","users"."avatar_url" FROM "users" JOIN user_follows ON user_follows.follower_id = users.id WHERE (user_follows.followed_id = $1 AND users.deactivated_at IS NULL) AND user_follows.follower_id > $2 ORDER BY user_follows.follower_id LIMIT $3`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, queries := newDryRunRepository(t)
+
+			assert.NoError(t, tt.fetch(repo))
+			assert.Equal(t, []string{tt.expectedSQL}, *queries)
+		})
+	}
+}
+
+func TestPostgresRepository_SearchUsersQuery(t *testing.T) {
+	tests := []struct {
+		name        string
+		userID      string
+		expectedSQL string
+	}{
+		{
+			name:        "boosts users followed by the caller",
+			userID:      "6f1c3e0a-3b7a-4d1e-9a39-1f6f2a8c9d01",
+			expectedSQL: `SELECT "users"."id","users"."username","users"."display_name","users"."avatar_url" FROM "users" LEFT JOIN user_follows ON user_follows.followed_id = users.id AND user_follows.follower_id = $1 WHERE users.deactivated_at IS NULL AND (users.username ILIKE $2 OR users.display_name ILIKE $3 OR users.username % $4 OR users.display_name % $5) ORDER BY user_follows.follower_id IS NOT NULL DESC, (users.username ILIKE $6 OR users.display_name ILIKE $7) DESC, GREATEST(similarity(users.username, $8), similarity(users.display_name, $9)) DESC, users.username LIMIT $10`,
+		},
+		{
+			name:        "anonymous search skips the follow join",
+			userID:      "",
+			expectedSQL: `SELECT * FROM "users" WHERE users.deactivated_at IS NULL AND (users.username ILIKE $1 OR users.display_name ILIKE $2 OR users.username % $3 OR users.display_name % $4) ORDER BY (users.username ILIKE $5 OR users.display_name ILIKE $6) DESC, GREATEST(similarity(users.username, $7), similarity(users.display_name, $8)) DESC, users.username LIMIT $9`,
+		},
+		{
+			name:        "malformed caller ID skips the follow join",
+			userID:      "user1",
+			expectedSQL: `SELECT * FROM "users" WHERE users.deactivated_at IS NULL AND (users.username ILIKE $1 OR users.display_name ILIKE $2 OR users.username % $3 OR users.display_name % $4) ORDER BY (users.username ILIKE $5 OR users.display_name ILIKE $6) DESC, GREATEST(similarity(users.username, $7), similarity(users.display_name, $8)) DESC, users.username LIMIT $9`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, queries := newDryRunRepository(t)
+
+			_, err := repo.SearchUsers(context.Background(), tt.userID, "al", 10)
+
+			assert.NoError(t, err)
+			assert.Equal(t, []string{tt.expectedSQL}, *queries)
+		})
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	assert.Equal(t, `100\%\_done\\`, escapeLike(`100%_done\`))
+}
+
+func TestPostgresRepository_GetFollowing_SingleQuery(t *testing.T) {
+	repo, queries := newDryRunRepository(t)
+
+	_, err := repo.GetFollowing(context.Background(), "user1")
+
+	assert.NoError(t, err)
+	assert.Len(t, *queries, 1)
+}
+
+// openDB connects to the Postgres instance named by the dsnEnv environment variable and
+// migrates it, skipping the test or benchmark when the variable is not set
+func openDB(tb testing.TB, dsnEnv string) *gorm.DB {
+	dsn := os.Getenv(dsnEnv)
+	if dsn == "" {
+		tb.Skipf("%s not set, skipping Postgres test", dsnEnv)
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(tb, err)
+	require.NoError(tb, RunMigrations(db))
+	return db
+}
+
+func openBenchDB(b *testing.B) *gorm.DB {
+	return openDB(b, "USER_SERVICE_BENCH_DSN")
+}
+
+// seedFollowGraph creates a user that follows, and is followed by, benchFollowCount
+// other users. The returned function removes everything that was created.
+func seedFollowGraph(b *testing.B, db *gorm.DB) (string, func()) {
+	prefix := fmt.Sprintf("bench_%d_", os.Getpid())
+
+	root := domain.User{Username: prefix + "root"}
+	require.NoError(b, db.Create(&root).Error)
+
+	others := make([]domain.User, benchFollowCount)
+	for i := range others {
+		others[i] = domain.User{Username: fmt.Sprintf("%s%d", prefix, i)}
+	}
+	require.NoError(b, db.CreateInBatches(&others, 1000).Error)
+
+	follows := make([]UserFollow, 0, 2*len(others))
+	for _, other := range others {
+		follows = append(follows,
+			UserFollow{FollowerID: root.ID, FollowedID: other.ID},
+			UserFollow{FollowerID: other.ID, FollowedID: root.ID},
+		)
+	}
+	require.NoError(b, db.CreateInBatches(&follows, 1000).Error)
+
+	return root.ID, func() {
+		db.Where("follower_id = ? OR followed_id = ?", root.ID, root.ID).Delete(&UserFollow{})
+		db.Where("username LIKE ?", prefix+"%").Delete(&domain.User{})
+	}
+}
+
+func BenchmarkPostgresRepository_GetFollowing(b *testing.B) {
+	db := openBenchDB(b)
+	userID, cleanup := seedFollowGraph(b, db)
+	defer cleanup()
+	repo := NewPostgresRepository(db)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		users, err := repo.GetFollowing(context.Background(), userID)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(users) != benchFollowCount {
+			b.Fatalf("expected %d users, got %d", benchFollowCount, len(users))
+		}
+	}
+}
+
+func BenchmarkPostgresRepository_GetFollowers(b *testing.B) {
+	db := openBenchDB(b)
+	userID, cleanup := seedFollowGraph(b, db)
+	defer cleanup()
+	repo := NewPostgresRepository(db)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		users, err := repo.GetFollowers(context.Background(), userID)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(users) != benchFollowCount {
+			b.Fatalf("expected %d users, got %d", benchFollowCount, len(users))
+		}
+	}
+}
+
+func BenchmarkPostgresRepository_GetFollowingPage(b *testing.B) {
+	db := openBenchDB(b)
+	userID, cleanup := seedFollowGraph(b, db)
+	defer cleanup()
+	repo := NewPostgresRepository(db)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.GetFollowingPage(context.Background(), userID, "", 100); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestPostgresRepository_GetUserByPreviousUsernameQuery(t *testing.T) {
+	repo, queries := newDryRunRepository(t)
+	at := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	_, err := repo.GetUserByPreviousUsername(context.Background(), "alice", at)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		`SELECT "users"."id","users"."username","users"."display_name","users"."avatar_url" FROM "users" JOIN username_history ON username_history.user_id = users.id WHERE username_history.username = $1 AND username_history.reserved_until > $2 AND users.deactivated_at IS NULL ORDER BY username_history.changed_at DESC LIMIT $3`,
+	}, *queries)
+}
+
+func TestPostgresRepository_GetUsersQuery(t *testing.T) {
+	const (
+		userID  = "6f1c3e0a-3b7a-4d1e-9a39-1f6f2a8c9d01"
+		otherID = "0b8e2a4c-5d6f-4a1b-8c9d-2e3f4a5b6c7d"
+	)
+
+	t.Run("queries well formed IDs", func(t *testing.T) {
+		repo, queries := newDryRunRepository(t)
+
+		_, err := repo.GetUsers(context.Background(), []string{userID, "user2", otherID})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			`SELECT * FROM "users" WHERE id IN ($1,$2) AND deactivated_at IS NULL`,
+		}, *queries)
+	})
+
+	t.Run("skips the query when no ID is well formed", func(t *testing.T) {
+		repo, queries := newDryRunRepository(t)
+
+		users, err := repo.GetUsers(context.Background(), []string{"user1", "user2"})
+
+		assert.NoError(t, err)
+		assert.Empty(t, *queries)
+		assert.Empty(t, users)
+	})
+}
+
+func TestPostgresRepository_GetAllUsersQuery(t *testing.T) {
+	tests := []struct {
+		name        string
+		afterID     string
+		expectedSQL string
+	}{
+		{
+			name:        "first page",
+			expectedSQL: `SELECT * FROM "users" WHERE deactivated_at IS NULL ORDER BY id LIMIT $1`,
+		},
+		{
+			name:        "next page",
+			afterID:     "6f1c3e0a-3b7a-4d1e-9a39-1f6f2a8c9d01",
+			expectedSQL: `SELECT * FROM "users" WHERE deactivated_at IS NULL AND id > $1 ORDER BY id LIMIT $2`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, queries := newDryRunRepository(t)
+
+			_, err := repo.GetAllUsers(context.Background(), tt.afterID, 50)
+
+			assert.NoError(t, err)
+			assert.Equal(t, []string{tt.expectedSQL}, *queries)
+		})
+	}
+}
+
+func TestPostgresRepository_GetRelationshipsQuery(t *testing.T) {
+	const (
+		userID  = "6f1c3e0a-3b7a-4d1e-9a39-1f6f2a8c9d01"
+		otherID = "0b8e2a4c-5d6f-4a1b-8c9d-2e3f4a5b6c7d"
+	)
+
+	t.Run("queries well formed IDs of active users", func(t *testing.T) {
+		repo, queries := newDryRunRepository(t)
+
+		relationships, err := repo.GetRelationships(context.Background(), userID, []string{otherID, "user2"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			`SELECT user_follows.* FROM "user_follows" JOIN users ON users.id IN (user_follows.follower_id, user_follows.followed_id) AND users.id <> $1 WHERE ((user_follows.follower_id = $2 AND user_follows.followed_id IN ($3)) OR (user_follows.followed_id = $4 AND user_follows.follower_id IN ($5))) AND users.deactivated_at IS NULL`,
+		}, *queries)
+		assert.Equal(t, []domain.Relationship{{UserID: otherID}, {UserID: "user2"}}, relationships)
+	})
+
+	t.Run("skips the query when no ID is well formed", func(t *testing.T) {
+		repo, queries := newDryRunRepository(t)
+
+		relationships, err := repo.GetRelationships(context.Background(), userID, []string{"user2"})
+
+		assert.NoError(t, err)
+		assert.Empty(t, *queries)
+		assert.Equal(t, []domain.Relationship{{UserID: "user2"}}, relationships)
+	})
+}
+
+// TestPostgresRepository_SearchUsers runs the search ranking against the Postgres instance named
+// by USER_SERVICE_TEST_DSN, since the ILIKE and trigram matching cannot be checked in dry run
+func TestPostgresRepository_SearchUsers(t *testing.T) {
+	db := openDB(t, "USER_SERVICE_TEST_DSN")
+	repo := NewPostgresRepository(db)
+	ctx := context.Background()
+
+	// Usernames are unique per run so that leftovers of other runs do not match the query
+	query := fmt.Sprintf("qz%d", os.Getpid())
+	var created []string
+	create := func(username string) string {
+		user, err := repo.CreateUser(ctx, domain.CreateUserRequest{Username: username})
+		require.NoError(t, err)
+		created = append(created, user.ID)
+		return user.ID
+	}
+	t.Cleanup(func() {
+		db.Where("id IN ?", created).Delete(&domain.User{})
+	})
+
+	// The followed user only contains the query in a long username, so it is the least similar
+	viewer := create("viewer_" + query)
+	followed := create("a_long_username_" + query + "_followed")
+	prefixed := create(query + "_prefixed")
+	contained := create("b_" + query)
+	deactivated := create(query + "_deactivated")
+	require.NoError(t, repo.Follow(ctx, viewer, followed))
+	require.NoError(t, repo.DeactivateUser(ctx, deactivated, time.Now()))
+	seeded := map[string]bool{followed: true, prefixed: true, contained: true, deactivated: true}
+
+	tests := []struct {
+		name     string
+		userID   string
+		expected []string
+	}{
+		{
+			name:     "followed users rank first",
+			userID:   viewer,
+			expected: []string{followed, prefixed, contained},
+		},
+		{
+			name:     "anonymous search ranks prefix matches first",
+			userID:   "",
+			expected: []string{prefixed, contained, followed},
+		},
+		{
+			name:     "malformed caller ID searches without the follow boost",
+			userID:   "not-a-uuid",
+			expected: []string{prefixed, contained, followed},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, err := repo.SearchUsers(ctx, tt.userID, query, 10)
+
+			require.NoError(t, err)
+			var ids []string
+			for _, user := range users {
+				if seeded[user.ID] {
+					ids = append(ids, user.ID)
+				}
+			}
+			assert.Equal(t, tt.expected, ids)
+		})
+	}
+}
+
+// TestPostgresRepository_GetRelationships checks against the Postgres instance named by
+// USER_SERVICE_TEST_DSN that follows with deactivated users are hidden
+func TestPostgresRepository_GetUsers(t *testing.T) {
+	db := openDB(t, "USER_SERVICE_TEST_DSN")
+	repo := NewPostgresRepository(db)
+	ctx := context.Background()
+
+	user, err := repo.CreateUser(ctx, domain.CreateUserRequest{Username: fmt.Sprintf("batch_%d", os.Getpid())})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Where("id = ?", user.ID).Delete(&domain.User{})
+	})
+
+	// A malformed ID does not fail the lookup of the others
+	users, err := repo.GetUsers(ctx, []string{user.ID, "not-a-uuid"})
+
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, user.ID, users[0].ID)
+}
+
+func TestPostgresRepository_GetRelationships(t *testing.T) {
+	db := openDB(t, "USER_SERVICE_TEST_DSN")
+	repo := NewPostgresRepository(db)
+	ctx := context.Background()
+
+	prefix := fmt.Sprintf("rel_%d_", os.Getpid())
+	var created []string
+	create := func(username string) string {
+		user, err := repo.CreateUser(ctx, domain.CreateUserRequest{Username: prefix + username})
+		require.NoError(t, err)
+		created = append(created, user.ID)
+		return user.ID
+	}
+	t.Cleanup(func() {
+		db.Where("id IN ?", created).Delete(&domain.User{})
+	})
+
+	user := create("user")
+	friend := create("friend")
+	deactivated := create("deactivated")
+	for _, other := range []string{friend, deactivated} {
+		require.NoError(t, repo.Follow(ctx, user, other))
+		require.NoError(t, repo.Follow(ctx, other, user))
+	}
+	require.NoError(t, repo.DeactivateUser(ctx, deactivated, time.Now()))
+
+	relationships, err := repo.GetRelationships(ctx, user, []string{friend, deactivated})
+
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Relationship{
+		{UserID: friend, Following: true, FollowedBy: true},
+		{UserID: deactivated},
+	}, relationships)
+}
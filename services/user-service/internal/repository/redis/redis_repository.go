@@ -3,37 +3,56 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/lisandro/challenge/services/user-service/internal/domain"
 )
 
+// emptySetMarker is added to every cached follow set so that empty lists can be
+// cached too. It never collides with a user ID and is skipped on reads.
+const emptySetMarker = ""
+
+// ErrCacheMiss is returned when the requested entry is not in the cache
+var ErrCacheMiss = errors.New("cache miss")
+
+// Config holds the expiration times of cached entries. A zero TTL keeps entries until they are invalidated.
+type Config struct {
+	UserTTL   time.Duration
+	FollowTTL time.Duration
+}
+
 // RedisRepository handles user data caching in Redis
 type RedisRepository struct {
 	client *redis.Client
-	ctx    context.Context
+	config Config
 }
 
 // NewRedisRepository creates a new Redis repository
-func NewRedisRepository(client *redis.Client) *RedisRepository {
+func NewRedisRepository(client *redis.Client, config Config) *RedisRepository {
 	return &RedisRepository{
 		client: client,
-		ctx:    context.Background(),
+		config: config,
 	}
 }
 
-func (r *RedisRepository) CacheUser(user *domain.User) error {
+func (r *RedisRepository) CacheUser(ctx context.Context, user *domain.User) error {
 	userJSON, err := json.Marshal(user)
 	if err != nil {
 		return err
 	}
-	return r.client.Set(r.ctx, fmt.Sprintf("user:%s", user.ID), userJSON, 0).Err()
+	return r.client.Set(ctx, fmt.Sprintf("user:%s", user.ID), userJSON, r.config.UserTTL).Err()
 }
 
-func (r *RedisRepository) GetCachedUser(id string) (*domain.User, error) {
-	val, err := r.client.Get(r.ctx, fmt.Sprintf("user:%s", id)).Result()
+func (r *RedisRepository) GetCachedUser(ctx context.Context, id string) (*domain.User, error) {
+	val, err := r.client.Get(ctx, fmt.Sprintf("user:%s", id)).Result()
+	if err == redis.Nil {
+		return nil, ErrCacheMiss
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -45,124 +64,167 @@ func (r *RedisRepository) GetCachedUser(id string) (*domain.User, error) {
 	return &user, nil
 }
 
-func (r *RedisRepository) CacheFollowing(userID string, following []domain.User) error {
+func (r *RedisRepository) CacheFollowing(ctx context.Context, userID string, following []domain.User) error {
 	log.Printf("Caching following list for user %s with %d users", userID, len(following))
-	key := fmt.Sprintf("following:%s", userID)
+	return r.cacheUserSet(ctx, fmt.Sprintf("following:%s", userID), following)
+}
+
+// GetCachedFollowing returns the cached following list of a user, and the IDs in it whose profile is not cached
+func (r *RedisRepository) GetCachedFollowing(ctx context.Context, userID string) ([]domain.User, []string, error) {
+	log.Printf("Attempting to get cached following list for user %s", userID)
+	return r.getCachedUserSet(ctx, fmt.Sprintf("following:%s", userID))
+}
+
+func (r *RedisRepository) InvalidateUserCache(ctx context.Context, userID string) error {
+	return r.client.Del(ctx, fmt.Sprintf("user:%s", userID)).Err()
+}
+
+func (r *RedisRepository) InvalidateFollowingCache(ctx context.Context, userID string) error {
+	return r.client.Del(ctx, fmt.Sprintf("following:%s", userID)).Err()
+}
+
+func (r *RedisRepository) CacheFollowers(ctx context.Context, userID string, followers []domain.User) error {
+	log.Printf("Caching followers list for user %s with %d users", userID, len(followers))
+	return r.cacheUserSet(ctx, fmt.Sprintf("followers:%s", userID), followers)
+}
+
+// GetCachedFollowers returns the cached followers list of a user, and the IDs in it whose profile is not cached
+func (r *RedisRepository) GetCachedFollowers(ctx context.Context, userID string) ([]domain.User, []string, error) {
+	log.Printf("Attempting to get cached followers list for user %s", userID)
+	return r.getCachedUserSet(ctx, fmt.Sprintf("followers:%s", userID))
+}
+
+func (r *RedisRepository) InvalidateFollowersCache(ctx context.Context, userID string) error {
+	return r.client.Del(ctx, fmt.Sprintf("followers:%s", userID)).Err()
+}
+
+// IsFollowingCached reports whether followerID follows followedID using the cached following set
+func (r *RedisRepository) IsFollowingCached(ctx context.Context, followerID, followedID string) (bool, error) {
+	key := fmt.Sprintf("following:%s", followerID)
+
 	pipe := r.client.Pipeline()
-	
-	for _, user := range following {
-		userJSON, err := json.Marshal(user)
-		if err != nil {
-			log.Printf("Error marshaling user %s for cache: %v", user.ID, err)
-			return err
-		}
-		pipe.SAdd(r.ctx, key, userJSON)
+	exists := pipe.Exists(ctx, key)
+	isMember := pipe.SIsMember(ctx, key, followedID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, err
 	}
-	
-	_, err := pipe.Exec(r.ctx)
-	if err != nil {
-		log.Printf("Error executing pipeline for user %s: %v", userID, err)
+	if exists.Val() == 0 {
+		return false, ErrCacheMiss
 	}
-	return err
+	return isMember.Val(), nil
 }
 
-func (r *RedisRepository) GetCachedFollowing(userID string) ([]domain.User, error) {
-	log.Printf("Attempting to get cached following list for user %s", userID)
-	key := fmt.Sprintf("following:%s", userID)
-	
-	// Check if the key exists first
-	exists, err := r.client.Exists(r.ctx, key).Result()
-	if err != nil {
-		log.Printf("Error checking if following key exists for user %s: %v", userID, err)
-		return nil, err
+// GetCachedRelationships resolves the relationship between a user and each of ids
+// from the user's cached following and followers sets
+func (r *RedisRepository) GetCachedRelationships(ctx context.Context, userID string, ids []string) ([]domain.Relationship, error) {
+	if len(ids) == 0 {
+		return []domain.Relationship{}, nil
 	}
-	if exists == 0 {
-		log.Printf("Cache MISS: Key does not exist for user %s", userID)
-		return nil, fmt.Errorf("cache miss")
+
+	followingKey := fmt.Sprintf("following:%s", userID)
+	followersKey := fmt.Sprintf("followers:%s", userID)
+	members := make([]interface{}, len(ids))
+	for i, id := range ids {
+		members[i] = id
 	}
 
-	vals, err := r.client.SMembers(r.ctx, key).Result()
-	if err != nil {
-		log.Printf("Error getting cached following for user %s: %v", userID, err)
+	pipe := r.client.Pipeline()
+	exists := pipe.Exists(ctx, followingKey, followersKey)
+	following := pipe.SMIsMember(ctx, followingKey, members...)
+	followedBy := pipe.SMIsMember(ctx, followersKey, members...)
+	if _, err := pipe.Exec(ctx); err != nil {
 		return nil, err
 	}
-
-	var following []domain.User
-	for _, val := range vals {
-		var user domain.User
-		if err := json.Unmarshal([]byte(val), &user); err != nil {
-			log.Printf("Error unmarshaling cached user for user %s: %v", userID, err)
-			return nil, err
-		}
-		following = append(following, user)
+	if exists.Val() < 2 {
+		return nil, ErrCacheMiss
 	}
-	log.Printf("Successfully retrieved %d users from cache for user %s", len(following), userID)
-	return following, nil
-}
 
-func (r *RedisRepository) InvalidateUserCache(userID string) error {
-	return r.client.Del(r.ctx, fmt.Sprintf("user:%s", userID)).Err()
+	relationships := make([]domain.Relationship, 0, len(ids))
+	for i, id := range ids {
+		relationships = append(relationships, domain.Relationship{
+			UserID:     id,
+			Following:  following.Val()[i],
+			FollowedBy: followedBy.Val()[i],
+		})
+	}
+	return relationships, nil
 }
 
-func (r *RedisRepository) InvalidateFollowingCache(userID string) error {
-	return r.client.Del(r.ctx, fmt.Sprintf("following:%s", userID)).Err()
-}
+// cacheUserSet atomically replaces the ID set stored at key with the IDs of the given users.
+// Profiles are not written with the set: they are cached by the fills of each profile, so
+// that a listing read before a profile changed cannot write the old profile back.
+func (r *RedisRepository) cacheUserSet(ctx context.Context, key string, users []domain.User) error {
+	members := make([]interface{}, 0, len(users)+1)
+	members = append(members, emptySetMarker)
+	for i := range users {
+		members = append(members, users[i].ID)
+	}
 
-func (r *RedisRepository) CacheFollowers(userID string, followers []domain.User) error {
-	log.Printf("Caching followers list for user %s with %d users", userID, len(followers))
-	key := fmt.Sprintf("followers:%s", userID)
-	pipe := r.client.Pipeline()
-	
-	for _, user := range followers {
-		userJSON, err := json.Marshal(user)
-		if err != nil {
-			log.Printf("Error marshaling user %s for cache: %v", user.ID, err)
-			return err
-		}
-		pipe.SAdd(r.ctx, key, userJSON)
+	pipe := r.client.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.SAdd(ctx, key, members...)
+	if r.config.FollowTTL > 0 {
+		pipe.Expire(ctx, key, r.config.FollowTTL)
 	}
-	
-	_, err := pipe.Exec(r.ctx)
+
+	_, err := pipe.Exec(ctx)
 	if err != nil {
-		log.Printf("Error executing pipeline for user %s: %v", userID, err)
+		log.Printf("Error executing pipeline for key %s: %v", key, err)
 	}
 	return err
 }
 
-func (r *RedisRepository) GetCachedFollowers(userID string) ([]domain.User, error) {
-	log.Printf("Attempting to get cached followers list for user %s", userID)
-	key := fmt.Sprintf("followers:%s", userID)
-	
-	// Check if the key exists first
-	exists, err := r.client.Exists(r.ctx, key).Result()
+// getCachedUserSet reads an ID set written by cacheUserSet and hydrates it with a single MGET.
+// It returns ErrCacheMiss when the set does not exist, and the IDs of the members whose
+// profile is not cached alongside the hydrated users.
+func (r *RedisRepository) getCachedUserSet(ctx context.Context, key string) ([]domain.User, []string, error) {
+	vals, err := r.client.SMembers(ctx, key).Result()
 	if err != nil {
-		log.Printf("Error checking if followers key exists for user %s: %v", userID, err)
-		return nil, err
+		log.Printf("Error getting cached set %s: %v", key, err)
+		return nil, nil, err
+	}
+	if len(vals) == 0 {
+		log.Printf("Cache MISS: Key %s does not exist", key)
+		return nil, nil, ErrCacheMiss
+	}
+
+	// Sort to match the ID ordering of the persistent listings
+	ids := make([]string, 0, len(vals))
+	for _, val := range vals {
+		if val != emptySetMarker {
+			ids = append(ids, val)
+		}
 	}
-	if exists == 0 {
-		log.Printf("Cache MISS: Key does not exist for user %s", userID)
-		return nil, fmt.Errorf("cache miss")
+	sort.Strings(ids)
+	if len(ids) == 0 {
+		return nil, nil, nil
 	}
 
-	vals, err := r.client.SMembers(r.ctx, key).Result()
+	userKeys := make([]string, len(ids))
+	for i, id := range ids {
+		userKeys[i] = fmt.Sprintf("user:%s", id)
+	}
+	profiles, err := r.client.MGet(ctx, userKeys...).Result()
 	if err != nil {
-		log.Printf("Error getting cached followers for user %s: %v", userID, err)
-		return nil, err
+		log.Printf("Error hydrating cached set %s: %v", key, err)
+		return nil, nil, err
 	}
 
-	var followers []domain.User
-	for _, val := range vals {
+	users := make([]domain.User, 0, len(profiles))
+	var missing []string
+	for i, profile := range profiles {
+		profileJSON, ok := profile.(string)
+		if !ok {
+			missing = append(missing, ids[i])
+			continue
+		}
 		var user domain.User
-		if err := json.Unmarshal([]byte(val), &user); err != nil {
-			log.Printf("Error unmarshaling cached user for user %s: %v", userID, err)
-			return nil, err
+		if err := json.Unmarshal([]byte(profileJSON), &user); err != nil {
+			log.Printf("Error unmarshaling cached user %s: %v", ids[i], err)
+			return nil, nil, err
 		}
-		followers = append(followers, user)
+		users = append(users, user)
 	}
-	log.Printf("Successfully retrieved %d users from cache for user %s", len(followers), userID)
-	return followers, nil
+	log.Printf("Successfully retrieved %d users from cache key %s, %d profiles not cached", len(users), key, len(missing))
+	return users, missing, nil
 }
-
-func (r *RedisRepository) InvalidateFollowersCache(userID string) error {
-	return r.client.Del(r.ctx, fmt.Sprintf("followers:%s", userID)).Err()
-} 
\ No newline at end of file
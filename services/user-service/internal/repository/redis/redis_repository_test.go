@@ -0,0 +1,174 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/lisandro/challenge/services/user-service/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupTest(t *testing.T, config Config) (*miniredis.Miniredis, *RedisRepository) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	return server, NewRedisRepository(client, config)
+}
+
+func TestRedisRepository_CacheUser(t *testing.T) {
+	server, repo := setupTest(t, Config{UserTTL: time.Hour})
+	user := &domain.User{ID: "user1", Username: "alice"}
+
+	assert.NoError(t, repo.CacheUser(context.Background(), user))
+	assert.Equal(t, time.Hour, server.TTL("user:user1"))
+
+	cached, err := repo.GetCachedUser(context.Background(), "user1")
+	assert.NoError(t, err)
+	assert.Equal(t, user, cached)
+
+	server.FastForward(time.Hour)
+
+	_, err = repo.GetCachedUser(context.Background(), "user1")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+}
+
+func TestRedisRepository_CacheFollowing(t *testing.T) {
+	tests := []struct {
+		name      string
+		following []domain.User
+	}{
+		{
+			name: "non empty list",
+			following: []domain.User{
+				{ID: "user2", Username: "bob"},
+				{ID: "user3", Username: "charlie"},
+			},
+		},
+		{
+			name:      "empty list is cached",
+			following: []domain.User{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, repo := setupTest(t, Config{FollowTTL: 10 * time.Minute})
+
+			assert.NoError(t, repo.CacheFollowing(context.Background(), "user1", tt.following))
+			assert.Equal(t, 10*time.Minute, server.TTL("following:user1"))
+			for i := range tt.following {
+				assert.NoError(t, repo.CacheUser(context.Background(), &tt.following[i]))
+			}
+
+			cached, missing, err := repo.GetCachedFollowing(context.Background(), "user1")
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tt.following, cached)
+			assert.Empty(t, missing)
+		})
+	}
+}
+
+func TestRedisRepository_CacheFollowers_ReplacesPreviousList(t *testing.T) {
+	_, repo := setupTest(t, Config{})
+
+	assert.NoError(t, repo.CacheFollowers(context.Background(), "user1", []domain.User{{ID: "user2", Username: "bob"}}))
+	assert.NoError(t, repo.CacheFollowers(context.Background(), "user1", []domain.User{{ID: "user3", Username: "charlie"}}))
+	assert.NoError(t, repo.CacheUser(context.Background(), &domain.User{ID: "user3", Username: "charlie"}))
+
+	cached, missing, err := repo.GetCachedFollowers(context.Background(), "user1")
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.User{{ID: "user3", Username: "charlie"}}, cached)
+	assert.Empty(t, missing)
+}
+
+func TestRedisRepository_GetCachedFollowing_Miss(t *testing.T) {
+	_, repo := setupTest(t, Config{})
+
+	cached, missing, err := repo.GetCachedFollowing(context.Background(), "user1")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	assert.Nil(t, cached)
+	assert.Nil(t, missing)
+}
+
+func TestRedisRepository_InvalidateFollowersCache(t *testing.T) {
+	server, repo := setupTest(t, Config{})
+
+	assert.NoError(t, repo.CacheFollowers(context.Background(), "user1", []domain.User{}))
+	assert.NoError(t, repo.InvalidateFollowersCache(context.Background(), "user1"))
+
+	assert.False(t, server.Exists("followers:user1"))
+}
+
+func TestRedisRepository_CacheFollowing_StoresOnlyIDs(t *testing.T) {
+	server, repo := setupTest(t, Config{UserTTL: time.Hour})
+
+	assert.NoError(t, repo.CacheFollowing(context.Background(), "user1", []domain.User{{ID: "user2", Username: "bob"}}))
+
+	members, err := server.SMembers("following:user1")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{emptySetMarker, "user2"}, members)
+	assert.False(t, server.Exists("user:user2"))
+}
+
+func TestRedisRepository_GetCachedFollowing_HydratesLatestProfile(t *testing.T) {
+	_, repo := setupTest(t, Config{})
+
+	assert.NoError(t, repo.CacheFollowing(context.Background(), "user1", []domain.User{{ID: "user2", Username: "bob"}}))
+	assert.NoError(t, repo.CacheUser(context.Background(), &domain.User{ID: "user2", Username: "robert"}))
+
+	cached, missing, err := repo.GetCachedFollowing(context.Background(), "user1")
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.User{{ID: "user2", Username: "robert"}}, cached)
+	assert.Empty(t, missing)
+}
+
+func TestRedisRepository_GetCachedFollowing_UncachedProfiles(t *testing.T) {
+	_, repo := setupTest(t, Config{})
+
+	assert.NoError(t, repo.CacheFollowing(context.Background(), "user1", []domain.User{{ID: "user2", Username: "bob"}, {ID: "user3", Username: "charlie"}}))
+	assert.NoError(t, repo.CacheUser(context.Background(), &domain.User{ID: "user3", Username: "charlie"}))
+
+	cached, missing, err := repo.GetCachedFollowing(context.Background(), "user1")
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.User{{ID: "user3", Username: "charlie"}}, cached)
+	assert.Equal(t, []string{"user2"}, missing)
+}
+
+func TestRedisRepository_IsFollowingCached(t *testing.T) {
+	_, repo := setupTest(t, Config{})
+
+	_, err := repo.IsFollowingCached(context.Background(), "user1", "user2")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	assert.NoError(t, repo.CacheFollowing(context.Background(), "user1", []domain.User{{ID: "user2", Username: "bob"}}))
+
+	following, err := repo.IsFollowingCached(context.Background(), "user1", "user2")
+	assert.NoError(t, err)
+	assert.True(t, following)
+
+	following, err = repo.IsFollowingCached(context.Background(), "user1", "user3")
+	assert.NoError(t, err)
+	assert.False(t, following)
+}
+
+func TestRedisRepository_GetCachedRelationships(t *testing.T) {
+	_, repo := setupTest(t, Config{})
+
+	assert.NoError(t, repo.CacheFollowing(context.Background(), "user1", []domain.User{{ID: "user2", Username: "bob"}}))
+
+	// Only one side is warm
+	_, err := repo.GetCachedRelationships(context.Background(), "user1", []string{"user2"})
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	assert.NoError(t, repo.CacheFollowers(context.Background(), "user1", []domain.User{{ID: "user2", Username: "bob"}, {ID: "user3", Username: "charlie"}}))
+
+	relationships, err := repo.GetCachedRelationships(context.Background(), "user1", []string{"user2", "user3", "user4"})
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Relationship{
+		{UserID: "user2", Following: true, FollowedBy: true},
+		{UserID: "user3", Following: false, FollowedBy: true},
+		{UserID: "user4", Following: false, FollowedBy: false},
+	}, relationships)
+}
@@ -0,0 +1,90 @@
+package usecase
+
+import (
+	"archive/zip"
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"time"
+
+	"github.com/lisandro/challenge/services/user-service/internal/domain"
+)
+
+//go:embed templates/export.html
+var exportViewerTemplate string
+
+// exportViewer renders index.html, a page to browse an export without any tooling
+var exportViewer = template.Must(template.New("export").Parse(exportViewerTemplate))
+
+// exportData is the data of a user included in an export
+type exportData struct {
+	GeneratedAt time.Time
+	Profile     domain.User
+	Following   []domain.User
+	Followers   []domain.User
+	Tweets      []domain.Tweet
+}
+
+// buildExportArchive returns a zip archive with one JSON file per kind of data and
+// an HTML viewer of all of them
+func buildExportArchive(data exportData) ([]byte, error) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	files := []struct {
+		name  string
+		value interface{}
+	}{
+		{"profile.json", data.Profile},
+		{"following.json", nonNil(data.Following)},
+		{"followers.json", nonNil(data.Followers)},
+		{"tweets.json", nonNil(data.Tweets)},
+	}
+	for _, file := range files {
+		content, err := json.MarshalIndent(file.value, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", file.name, err)
+		}
+		if err := writeArchiveFile(archive, file.name, data.GeneratedAt, content); err != nil {
+			return nil, err
+		}
+	}
+
+	var page bytes.Buffer
+	if err := exportViewer.Execute(&page, data); err != nil {
+		return nil, fmt.Errorf("failed to render index.html: %w", err)
+	}
+	if err := writeArchiveFile(archive, "index.html", data.GeneratedAt, page.Bytes()); err != nil {
+		return nil, err
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close archive: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func writeArchiveFile(archive *zip.Writer, name string, modified time.Time, content []byte) error {
+	w, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modified,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+	if _, err := w.Write(content); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// nonNil returns an empty slice for nil, so empty lists are encoded as [] rather than null
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
@@ -0,0 +1,169 @@
+package usecase
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/lisandro/challenge/services/user-service/internal/domain"
+)
+
+// exportFailureReason is the error shown to users when their export could not be generated.
+// The cause is logged instead, as it may contain details of other services.
+const exportFailureReason = "Failed to generate export"
+
+// ExportConfig holds the settings of the data export usecase
+type ExportConfig struct {
+	// SigningKey signs the download links so they cannot be forged or extended
+	SigningKey []byte
+	// Retention is how long the archive of a ready export can be downloaded
+	Retention time.Duration
+	// StaleAfter is how long an export can be running before another worker retries it
+	StaleAfter time.Duration
+}
+
+// exportUsecase implements domain.ExportUsecase
+type exportUsecase struct {
+	repo   domain.ExportRepository
+	users  domain.UserRepository
+	tweets domain.TweetClient
+	config ExportConfig
+}
+
+// NewExportUsecase creates a new data export usecase instance
+func NewExportUsecase(repo domain.ExportRepository, users domain.UserRepository, tweets domain.TweetClient, config ExportConfig) domain.ExportUsecase {
+	return &exportUsecase{
+		repo:   repo,
+		users:  users,
+		tweets: tweets,
+		config: config,
+	}
+}
+
+// RequestExport queues the generation of an archive of a user's data. A user has at most
+// one export in progress, so requesting again while one is pending returns that export.
+func (u *exportUsecase) RequestExport(ctx context.Context, userID string) (*domain.DataExport, error) {
+	if _, err := u.users.GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	return u.repo.CreateExport(ctx, userID)
+}
+
+// GetExport returns the state of an export of a user, with a download link once it is ready.
+// Exports of other users are reported as domain.ErrExportNotFound.
+func (u *exportUsecase) GetExport(ctx context.Context, userID, exportID string) (*domain.DataExport, error) {
+	export, err := u.repo.GetExport(ctx, exportID)
+	if err != nil {
+		return nil, err
+	}
+	if export.UserID != userID {
+		return nil, domain.ErrExportNotFound
+	}
+
+	if export.Status == domain.ExportReady && export.ExpiresAt != nil {
+		export.DownloadURL = u.downloadURL(export.ID, export.ExpiresAt.Unix())
+	}
+	return export, nil
+}
+
+// GetArchive returns the archive of an export given a signed download link. It returns
+// domain.ErrInvalidDownloadLink when the signature does not match or the link has expired.
+func (u *exportUsecase) GetArchive(ctx context.Context, exportID string, expires int64, signature string) ([]byte, error) {
+	expected := u.sign(exportID, expires)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return nil, domain.ErrInvalidDownloadLink
+	}
+	if time.Now().Unix() > expires {
+		return nil, domain.ErrInvalidDownloadLink
+	}
+
+	return u.repo.GetExportArchive(ctx, exportID)
+}
+
+// ProcessPendingExports expires the archives past their retention, then generates pending
+// exports one at a time until none is left or ctx is done. It returns how many exports were
+// processed, whether they succeeded or failed.
+func (u *exportUsecase) ProcessPendingExports(ctx context.Context) (int, error) {
+	expired, err := u.repo.ExpireExports(ctx, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire exports: %w", err)
+	}
+	if expired > 0 {
+		log.Printf("Expired %d data exports", expired)
+	}
+
+	processed := 0
+	for ctx.Err() == nil {
+		export, err := u.repo.ClaimPendingExport(ctx, time.Now().Add(-u.config.StaleAfter))
+		if err != nil {
+			return processed, fmt.Errorf("failed to claim export: %w", err)
+		}
+		if export == nil {
+			break
+		}
+
+		if err := u.processExport(ctx, export); err != nil {
+			return processed, err
+		}
+		processed++
+	}
+	return processed, nil
+}
+
+// processExport generates and stores the archive of a claimed export, or marks it failed
+func (u *exportUsecase) processExport(ctx context.Context, export *domain.DataExport) error {
+	archive, err := u.buildArchive(ctx, export.UserID)
+	if err != nil {
+		log.Printf("Failed to generate export %s of user %s: %v", export.ID, export.UserID, err)
+		return u.repo.FailExport(ctx, export.ID, exportFailureReason)
+	}
+
+	return u.repo.CompleteExport(ctx, export.ID, archive, time.Now().Add(u.config.Retention))
+}
+
+// buildArchive collects the data of a user and packs it into an archive
+func (u *exportUsecase) buildArchive(ctx context.Context, userID string) ([]byte, error) {
+	profile, err := u.users.GetUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profile: %w", err)
+	}
+	following, err := u.users.GetFollowing(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get following: %w", err)
+	}
+	followers, err := u.users.GetFollowers(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get followers: %w", err)
+	}
+	tweets, err := u.tweets.GetUserTweets(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tweets: %w", err)
+	}
+
+	return buildExportArchive(exportData{
+		GeneratedAt: time.Now().UTC(),
+		Profile:     *profile,
+		Following:   following,
+		Followers:   followers,
+		Tweets:      tweets,
+	})
+}
+
+// downloadURL returns the signed download link of an export, valid until expires
+func (u *exportUsecase) downloadURL(exportID string, expires int64) string {
+	return fmt.Sprintf("/api/v1/users/exports/%s/download?expires=%d&signature=%s",
+		exportID, expires, u.sign(exportID, expires))
+}
+
+// sign returns the hex encoded HMAC of an export ID and the expiry of its link
+func (u *exportUsecase) sign(exportID string, expires int64) string {
+	mac := hmac.New(sha256.New, u.config.SigningKey)
+	mac.Write([]byte(exportID + ":" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,307 @@
+package usecase
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/lisandro/challenge/services/user-service/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockExportRepository is a mock implementation of domain.ExportRepository
+type MockExportRepository struct {
+	mock.Mock
+}
+
+func (m *MockExportRepository) CreateExport(ctx context.Context, userID string) (*domain.DataExport, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(*domain.DataExport), args.Error(1)
+}
+
+func (m *MockExportRepository) GetExport(ctx context.Context, id string) (*domain.DataExport, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*domain.DataExport), args.Error(1)
+}
+
+func (m *MockExportRepository) ClaimPendingExport(ctx context.Context, staleBefore time.Time) (*domain.DataExport, error) {
+	args := m.Called(ctx, staleBefore)
+	return args.Get(0).(*domain.DataExport), args.Error(1)
+}
+
+func (m *MockExportRepository) CompleteExport(ctx context.Context, id string, archive []byte, expiresAt time.Time) error {
+	args := m.Called(ctx, id, archive, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockExportRepository) FailExport(ctx context.Context, id, reason string) error {
+	args := m.Called(ctx, id, reason)
+	return args.Error(0)
+}
+
+func (m *MockExportRepository) GetExportArchive(ctx context.Context, id string) ([]byte, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+func (m *MockExportRepository) ExpireExports(ctx context.Context, before time.Time) (int, error) {
+	args := m.Called(ctx, before)
+	return args.Int(0), args.Error(1)
+}
+
+// MockTweetClient is a mock implementation of domain.TweetClient
+type MockTweetClient struct {
+	mock.Mock
+}
+
+func (m *MockTweetClient) GetUserTweets(ctx context.Context, userID string) ([]domain.Tweet, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]domain.Tweet), args.Error(1)
+}
+
+var testExportConfig = ExportConfig{
+	SigningKey: []byte("secret"),
+	Retention:  24 * time.Hour,
+	StaleAfter: 15 * time.Minute,
+}
+
+func newTestExportUsecase() (*exportUsecase, *MockExportRepository, *MockUserRepository, *MockTweetClient) {
+	repo := new(MockExportRepository)
+	users := new(MockUserRepository)
+	tweets := new(MockTweetClient)
+	u := NewExportUsecase(repo, users, tweets, testExportConfig).(*exportUsecase)
+	return u, repo, users, tweets
+}
+
+func TestExportUsecase_RequestExport(t *testing.T) {
+	t.Run("queues an export", func(t *testing.T) {
+		u, repo, users, _ := newTestExportUsecase()
+		export := &domain.DataExport{ID: "export1", UserID: "user1", Status: domain.ExportPending}
+		users.On("GetUser", mock.Anything, "user1").Return(&domain.User{ID: "user1"}, nil)
+		repo.On("CreateExport", mock.Anything, "user1").Return(export, nil)
+
+		result, err := u.RequestExport(context.Background(), "user1")
+
+		assert.NoError(t, err)
+		assert.Equal(t, export, result)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		u, repo, users, _ := newTestExportUsecase()
+		users.On("GetUser", mock.Anything, "user9").Return((*domain.User)(nil), domain.ErrUserNotFound)
+
+		_, err := u.RequestExport(context.Background(), "user9")
+
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+		repo.AssertNotCalled(t, "CreateExport", mock.Anything, mock.Anything)
+	})
+}
+
+func TestExportUsecase_GetExport(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+
+	t.Run("ready export has a download link", func(t *testing.T) {
+		u, repo, _, _ := newTestExportUsecase()
+		repo.On("GetExport", mock.Anything, "export1").Return(&domain.DataExport{ID: "export1", UserID: "user1", Status: domain.ExportReady, ExpiresAt: &expiresAt}, nil)
+
+		export, err := u.GetExport(context.Background(), "user1", "export1")
+
+		require.NoError(t, err)
+		link, err := url.Parse(export.DownloadURL)
+		require.NoError(t, err)
+		assert.Equal(t, "/api/v1/users/exports/export1/download", link.Path)
+		assert.Equal(t, strconv.FormatInt(expiresAt.Unix(), 10), link.Query().Get("expires"))
+		assert.NotEmpty(t, link.Query().Get("signature"))
+	})
+
+	t.Run("pending export has no download link", func(t *testing.T) {
+		u, repo, _, _ := newTestExportUsecase()
+		repo.On("GetExport", mock.Anything, "export1").Return(&domain.DataExport{ID: "export1", UserID: "user1", Status: domain.ExportPending}, nil)
+
+		export, err := u.GetExport(context.Background(), "user1", "export1")
+
+		require.NoError(t, err)
+		assert.Empty(t, export.DownloadURL)
+	})
+
+	t.Run("export of another user", func(t *testing.T) {
+		u, repo, _, _ := newTestExportUsecase()
+		repo.On("GetExport", mock.Anything, "export1").Return(&domain.DataExport{ID: "export1", UserID: "user2", Status: domain.ExportReady, ExpiresAt: &expiresAt}, nil)
+
+		_, err := u.GetExport(context.Background(), "user1", "export1")
+
+		assert.ErrorIs(t, err, domain.ErrExportNotFound)
+	})
+}
+
+func TestExportUsecase_GetArchive(t *testing.T) {
+	validUntil := time.Now().Add(time.Hour).Unix()
+	expired := time.Now().Add(-time.Minute).Unix()
+	u, _, _, _ := newTestExportUsecase()
+
+	tests := []struct {
+		name          string
+		exportID      string
+		expires       int64
+		signature     string
+		expectArchive bool
+		expectedError error
+	}{
+		{
+			name:          "valid link",
+			exportID:      "export1",
+			expires:       validUntil,
+			signature:     u.sign("export1", validUntil),
+			expectArchive: true,
+		},
+		{
+			name:          "expired link",
+			exportID:      "export1",
+			expires:       expired,
+			signature:     u.sign("export1", expired),
+			expectedError: domain.ErrInvalidDownloadLink,
+		},
+		{
+			name:          "extended expiry",
+			exportID:      "export1",
+			expires:       validUntil + 3600,
+			signature:     u.sign("export1", validUntil),
+			expectedError: domain.ErrInvalidDownloadLink,
+		},
+		{
+			name:          "signature of another export",
+			exportID:      "export2",
+			expires:       validUntil,
+			signature:     u.sign("export1", validUntil),
+			expectedError: domain.ErrInvalidDownloadLink,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, repo, _, _ := newTestExportUsecase()
+			if tt.expectArchive {
+				repo.On("GetExportArchive", mock.Anything, tt.exportID).Return([]byte("zip"), nil)
+			}
+
+			archive, err := u.GetArchive(context.Background(), tt.exportID, tt.expires, tt.signature)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				repo.AssertNotCalled(t, "GetExportArchive", mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, []byte("zip"), archive)
+		})
+	}
+}
+
+func TestExportUsecase_ProcessPendingExports(t *testing.T) {
+	t.Run("generates the archive of each pending export", func(t *testing.T) {
+		u, repo, users, tweets := newTestExportUsecase()
+		createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+		repo.On("ExpireExports", mock.Anything, mock.AnythingOfType("time.Time")).Return(1, nil)
+		repo.On("ClaimPendingExport", mock.Anything, mock.AnythingOfType("time.Time")).Return(&domain.DataExport{ID: "export1", UserID: "user1"}, nil).Once()
+		repo.On("ClaimPendingExport", mock.Anything, mock.AnythingOfType("time.Time")).Return((*domain.DataExport)(nil), nil).Once()
+		users.On("GetUser", mock.Anything, "user1").Return(&domain.User{ID: "user1", Username: "alice", DisplayName: "Alice"}, nil)
+		users.On("GetFollowing", mock.Anything, "user1").Return([]domain.User{{ID: "user2", Username: "bob"}}, nil)
+		users.On("GetFollowers", mock.Anything, "user1").Return([]domain.User(nil), nil)
+		tweets.On("GetUserTweets", mock.Anything, "user1").Return([]domain.Tweet{
+			{ID: "tweet1", UserID: "user1", Content: "<b>hello</b>", CreatedAt: createdAt},
+		}, nil)
+
+		var archive []byte
+		repo.On("CompleteExport", mock.Anything, "export1", mock.Anything, mock.AnythingOfType("time.Time")).
+			Run(func(args mock.Arguments) { archive = args.Get(2).([]byte) }).
+			Return(nil)
+
+		processed, err := u.ProcessPendingExports(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, processed)
+		repo.AssertExpectations(t)
+
+		files := readArchive(t, archive)
+		assert.ElementsMatch(t, []string{"profile.json", "following.json", "followers.json", "tweets.json", "index.html"}, keys(files))
+		assert.JSONEq(t, `{"id":"user1","username":"alice","display_name":"Alice","avatar_url":""}`, files["profile.json"])
+		assert.JSONEq(t, `[]`, files["followers.json"])
+		assert.JSONEq(t, `[{"id":"user2","username":"bob","display_name":"","avatar_url":""}]`, files["following.json"])
+
+		var exportedTweets []domain.Tweet
+		require.NoError(t, json.Unmarshal([]byte(files["tweets.json"]), &exportedTweets))
+		assert.Equal(t, "<b>hello</b>", exportedTweets[0].Content)
+
+		assert.Contains(t, files["index.html"], "@alice")
+		assert.Contains(t, files["index.html"], "&lt;b&gt;hello&lt;/b&gt;")
+	})
+
+	t.Run("marks the export failed when the tweets cannot be read", func(t *testing.T) {
+		u, repo, users, tweets := newTestExportUsecase()
+
+		repo.On("ExpireExports", mock.Anything, mock.AnythingOfType("time.Time")).Return(0, nil)
+		repo.On("ClaimPendingExport", mock.Anything, mock.AnythingOfType("time.Time")).Return(&domain.DataExport{ID: "export1", UserID: "user1"}, nil).Once()
+		repo.On("ClaimPendingExport", mock.Anything, mock.AnythingOfType("time.Time")).Return((*domain.DataExport)(nil), nil).Once()
+		users.On("GetUser", mock.Anything, "user1").Return(&domain.User{ID: "user1"}, nil)
+		users.On("GetFollowing", mock.Anything, "user1").Return([]domain.User{}, nil)
+		users.On("GetFollowers", mock.Anything, "user1").Return([]domain.User{}, nil)
+		tweets.On("GetUserTweets", mock.Anything, "user1").Return([]domain.Tweet(nil), errors.New("connection refused"))
+		repo.On("FailExport", mock.Anything, "export1", exportFailureReason).Return(nil)
+
+		processed, err := u.ProcessPendingExports(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, processed)
+		repo.AssertExpectations(t)
+		repo.AssertNotCalled(t, "CompleteExport", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("stops when the context is done", func(t *testing.T) {
+		u, repo, _, _ := newTestExportUsecase()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		repo.On("ExpireExports", mock.Anything, mock.AnythingOfType("time.Time")).Return(0, nil)
+
+		processed, err := u.ProcessPendingExports(ctx)
+
+		assert.NoError(t, err)
+		assert.Zero(t, processed)
+		repo.AssertNotCalled(t, "ClaimPendingExport", mock.Anything, mock.Anything)
+	})
+}
+
+// readArchive returns the content of every file of a zip archive by name
+func readArchive(t *testing.T, archive []byte) map[string]string {
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+
+	files := make(map[string]string)
+	for _, file := range reader.File {
+		r, err := file.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(r)
+		require.NoError(t, err)
+		r.Close()
+		files[file.Name] = string(content)
+	}
+	return files
+}
+
+func keys(m map[string]string) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	return result
+}
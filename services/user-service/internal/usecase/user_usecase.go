@@ -1,9 +1,35 @@
 package usecase
 
 import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
 	"github.com/lisandro/challenge/services/user-service/internal/domain"
 )
 
+// DeactivationGracePeriod is how long a deactivated user can reactivate their
+// account before it is permanently deleted
+const DeactivationGracePeriod = 30 * 24 * time.Hour
+
+// purgeBatchSize is the number of expired deactivated users deleted per batch
+const purgeBatchSize = 100
+
+// UsernameChangePolicy allows a few username changes a month, and keeps each previous
+// username redirecting to its former owner for 30 days
+var UsernameChangePolicy = domain.UsernamePolicy{
+	MaxChanges:  3,
+	Window:      30 * 24 * time.Hour,
+	Reservation: 30 * 24 * time.Hour,
+}
+
+// usernamePattern matches the usernames that can be chosen when changing a username
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,50}$`)
+
 // userUsecase implements domain.UserUsecase
 type userUsecase struct {
 	repo domain.UserRepository
@@ -16,36 +42,173 @@ func NewUserUsecase(repo domain.UserRepository) domain.UserUsecase {
 	}
 }
 
-// Follow makes a user follow another user
-func (u *userUsecase) Follow(followerID, followedID string) error {
-	return u.repo.Follow(followerID, followedID)
+// Follow makes a user follow another user. It returns domain.ErrSelfFollow,
+// domain.ErrUserNotFound or domain.ErrAlreadyFollowing when the follow is not allowed.
+func (u *userUsecase) Follow(ctx context.Context, followerID, followedID string) error {
+	if followerID == followedID {
+		return domain.ErrSelfFollow
+	}
+
+	if _, err := u.repo.GetUser(ctx, followedID); err != nil {
+		return err
+	}
+
+	following, err := u.repo.IsFollowing(ctx, followerID, followedID)
+	if err != nil {
+		return err
+	}
+	if following {
+		return domain.ErrAlreadyFollowing
+	}
+
+	return u.repo.Follow(ctx, followerID, followedID)
+}
+
+// Unfollow makes a user unfollow another user. It returns domain.ErrNotFollowing
+// when the user was not following the other user.
+func (u *userUsecase) Unfollow(ctx context.Context, followerID, followedID string) error {
+	return u.repo.Unfollow(ctx, followerID, followedID)
 }
 
-// Unfollow makes a user unfollow another user
-func (u *userUsecase) Unfollow(followerID, followedID string) error {
-	return u.repo.Unfollow(followerID, followedID)
+// IsFollowing reports whether a user follows another user
+func (u *userUsecase) IsFollowing(ctx context.Context, followerID, followedID string) (bool, error) {
+	return u.repo.IsFollowing(ctx, followerID, followedID)
 }
 
 // GetFollowing returns the list of users that a user follows
-func (u *userUsecase) GetFollowing(userID string) ([]domain.User, error) {
-	return u.repo.GetFollowing(userID)
+func (u *userUsecase) GetFollowing(ctx context.Context, userID string) ([]domain.User, error) {
+	return u.repo.GetFollowing(ctx, userID)
 }
 
 // GetFollowers returns the list of users that follow a user
-func (u *userUsecase) GetFollowers(userID string) ([]domain.User, error) {
-	return u.repo.GetFollowers(userID)
+func (u *userUsecase) GetFollowers(ctx context.Context, userID string) ([]domain.User, error) {
+	return u.repo.GetFollowers(ctx, userID)
+}
+
+// CreateUser creates a user. It returns domain.ErrInvalidAvatarURL when the avatar URL
+// is not an absolute http or https URL, or domain.ErrUsernameTaken.
+func (u *userUsecase) CreateUser(ctx context.Context, req domain.CreateUserRequest) (*domain.User, error) {
+	if req.AvatarURL != "" && !isWebURL(req.AvatarURL) {
+		return nil, domain.ErrInvalidAvatarURL
+	}
+	return u.repo.CreateUser(ctx, req)
 }
 
-func (u *userUsecase) CreateUser(req domain.CreateUserRequest) (*domain.User, error) {
-	return u.repo.CreateUser(req)
+// isWebURL reports whether raw is an absolute http or https URL
+func isWebURL(raw string) bool {
+	parsed, err := url.Parse(raw)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
 }
 
-// GetAllUsers returns all users in the system
-func (u *userUsecase) GetAllUsers() ([]domain.User, error) {
-	return u.repo.GetAllUsers()
+// GetAllUsers returns a page of active users ordered by ID, starting after afterID
+func (u *userUsecase) GetAllUsers(ctx context.Context, afterID string, limit int) ([]domain.User, error) {
+	return u.repo.GetAllUsers(ctx, afterID, limit)
+}
+
+// SearchUsers returns users matching query, boosting the users that userID follows
+func (u *userUsecase) SearchUsers(ctx context.Context, userID, query string, limit int) ([]domain.User, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return []domain.User{}, nil
+	}
+	return u.repo.SearchUsers(ctx, userID, query, limit)
 }
 
 // GetUser returns a user by ID
-func (u *userUsecase) GetUser(id string) (*domain.User, error) {
-	return u.repo.GetUser(id)
-}
\ No newline at end of file
+func (u *userUsecase) GetUser(ctx context.Context, id string) (*domain.User, error) {
+	return u.repo.GetUser(ctx, id)
+}
+
+// GetUsers returns the active users with the given IDs, skipping unknown, deactivated and
+// malformed IDs. It lets other services look up many profiles in one request.
+func (u *userUsecase) GetUsers(ctx context.Context, ids []string) ([]domain.User, error) {
+	seen := make(map[string]bool, len(ids))
+	validIDs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if seen[id] || uuid.Validate(id) != nil {
+			continue
+		}
+		seen[id] = true
+		validIDs = append(validIDs, id)
+	}
+	if len(validIDs) == 0 {
+		return []domain.User{}, nil
+	}
+
+	return u.repo.GetUsers(ctx, validIDs)
+}
+
+// GetUserByUsername returns the user with the given username. When no user has it, the user
+// who gave it up within the reservation period is returned, so that callers can redirect to
+// their current username.
+func (u *userUsecase) GetUserByUsername(ctx context.Context, username string) (*domain.User, error) {
+	user, err := u.repo.GetUserByUsername(ctx, username)
+	if !errors.Is(err, domain.ErrUserNotFound) {
+		return user, err
+	}
+	return u.repo.GetUserByPreviousUsername(ctx, username, time.Now())
+}
+
+// ChangeUsername renames a user within the limits of UsernameChangePolicy. It returns
+// domain.ErrInvalidUsername, domain.ErrUsernameTaken or domain.ErrUsernameChangeLimit
+// when the change is not allowed.
+func (u *userUsecase) ChangeUsername(ctx context.Context, id, username string) (*domain.User, error) {
+	username = strings.TrimSpace(username)
+	if !usernamePattern.MatchString(username) {
+		return nil, domain.ErrInvalidUsername
+	}
+	return u.repo.ChangeUsername(ctx, id, username, time.Now(), UsernameChangePolicy)
+}
+
+// GetRelationships returns the relationship between a user and each of the given users
+func (u *userUsecase) GetRelationships(ctx context.Context, userID string, ids []string) ([]domain.Relationship, error) {
+	seen := make(map[string]bool, len(ids))
+	uniqueIDs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		uniqueIDs = append(uniqueIDs, id)
+	}
+
+	return u.repo.GetRelationships(ctx, userID, uniqueIDs)
+}
+
+// DeactivateUser hides a user until they reactivate, or deletes them once the grace period expires
+func (u *userUsecase) DeactivateUser(ctx context.Context, id string) error {
+	return u.repo.DeactivateUser(ctx, id, time.Now())
+}
+
+// ReactivateUser restores a deactivated user within the grace period. It returns
+// domain.ErrReactivationExpired once the grace period has expired.
+func (u *userUsecase) ReactivateUser(ctx context.Context, id string) error {
+	return u.repo.ReactivateUser(ctx, id, time.Now().Add(-DeactivationGracePeriod))
+}
+
+// DeleteUser permanently deletes a user. Deleting a missing user does nothing.
+func (u *userUsecase) DeleteUser(ctx context.Context, id string) error {
+	return u.repo.DeleteUser(ctx, id)
+}
+
+// PurgeDeactivatedUsers permanently deletes the users whose grace period has expired and
+// returns how many were deleted. Users left over after an error are picked up by the next run.
+func (u *userUsecase) PurgeDeactivatedUsers(ctx context.Context) (int, error) {
+	cutoff := time.Now().Add(-DeactivationGracePeriod)
+	purged := 0
+	for {
+		ids, err := u.repo.GetDeactivatedUserIDs(ctx, cutoff, purgeBatchSize)
+		if err != nil {
+			return purged, err
+		}
+		for _, id := range ids {
+			if err := u.repo.DeleteUser(ctx, id); err != nil {
+				return purged, err
+			}
+			purged++
+		}
+		if len(ids) < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
@@ -1,8 +1,11 @@
 package usecase
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/lisandro/challenge/services/user-service/internal/domain"
 	"github.com/stretchr/testify/assert"
@@ -14,76 +17,183 @@ type MockUserRepository struct {
 	mock.Mock
 }
 
-func (m *MockUserRepository) Follow(followerID, followedID string) error {
-	args := m.Called(followerID, followedID)
+func (m *MockUserRepository) Follow(ctx context.Context, followerID, followedID string) error {
+	args := m.Called(ctx, followerID, followedID)
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) Unfollow(followerID, followedID string) error {
-	args := m.Called(followerID, followedID)
+func (m *MockUserRepository) Unfollow(ctx context.Context, followerID, followedID string) error {
+	args := m.Called(ctx, followerID, followedID)
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) GetFollowing(userID string) ([]domain.User, error) {
-	args := m.Called(userID)
+func (m *MockUserRepository) GetFollowing(ctx context.Context, userID string) ([]domain.User, error) {
+	args := m.Called(ctx, userID)
 	return args.Get(0).([]domain.User), args.Error(1)
 }
 
-func (m *MockUserRepository) GetFollowers(userID string) ([]domain.User, error) {
-	args := m.Called(userID)
+func (m *MockUserRepository) GetFollowers(ctx context.Context, userID string) ([]domain.User, error) {
+	args := m.Called(ctx, userID)
 	return args.Get(0).([]domain.User), args.Error(1)
 }
 
-func (m *MockUserRepository) CreateUser(req domain.CreateUserRequest) (*domain.User, error) {
-	args := m.Called(req)
+func (m *MockUserRepository) IsFollowing(ctx context.Context, followerID, followedID string) (bool, error) {
+	args := m.Called(ctx, followerID, followedID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) GetRelationships(ctx context.Context, userID string, ids []string) ([]domain.Relationship, error) {
+	args := m.Called(ctx, userID, ids)
+	return args.Get(0).([]domain.Relationship), args.Error(1)
+}
+
+func (m *MockUserRepository) CreateUser(ctx context.Context, req domain.CreateUserRequest) (*domain.User, error) {
+	args := m.Called(ctx, req)
 	return args.Get(0).(*domain.User), args.Error(1)
 }
 
-func (m *MockUserRepository) GetAllUsers() ([]domain.User, error) {
-	args := m.Called()
+func (m *MockUserRepository) GetAllUsers(ctx context.Context, afterID string, limit int) ([]domain.User, error) {
+	args := m.Called(ctx, afterID, limit)
+	return args.Get(0).([]domain.User), args.Error(1)
+}
+
+func (m *MockUserRepository) SearchUsers(ctx context.Context, userID, query string, limit int) ([]domain.User, error) {
+	args := m.Called(ctx, userID, query, limit)
 	return args.Get(0).([]domain.User), args.Error(1)
 }
 
-func (m *MockUserRepository) GetUser(id string) (*domain.User, error) {
-	args := m.Called(id)
+func (m *MockUserRepository) GetUser(ctx context.Context, id string) (*domain.User, error) {
+	args := m.Called(ctx, id)
 	return args.Get(0).(*domain.User), args.Error(1)
 }
 
+func (m *MockUserRepository) GetUsers(ctx context.Context, ids []string) ([]domain.User, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).([]domain.User), args.Error(1)
+}
+
+func (m *MockUserRepository) GetUserByUsername(ctx context.Context, username string) (*domain.User, error) {
+	args := m.Called(ctx, username)
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *MockUserRepository) GetUserByPreviousUsername(ctx context.Context, username string, at time.Time) (*domain.User, error) {
+	args := m.Called(ctx, username, at)
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *MockUserRepository) ChangeUsername(ctx context.Context, id, username string, at time.Time, policy domain.UsernamePolicy) (*domain.User, error) {
+	args := m.Called(ctx, id, username, at, policy)
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *MockUserRepository) DeactivateUser(ctx context.Context, id string, at time.Time) error {
+	args := m.Called(ctx, id, at)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ReactivateUser(ctx context.Context, id string, deactivatedAfter time.Time) error {
+	args := m.Called(ctx, id, deactivatedAfter)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) DeleteUser(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) GetDeactivatedUserIDs(ctx context.Context, deactivatedBefore time.Time, limit int) ([]string, error) {
+	args := m.Called(ctx, deactivatedBefore, limit)
+	return args.Get(0).([]string), args.Error(1)
+}
+
 
 func TestUserUsecase_Follow(t *testing.T) {
 	tests := []struct {
-		name        string
-		followerID  string
-		followedID  string
-		mockError   error
-		expectError bool
+		name           string
+		followerID     string
+		followedID     string
+		getUserError   error
+		isFollowing    bool
+		isFollowingErr error
+		mockError      error
+		expectFollow   bool
+		expectedError  error
+		expectError    bool
 	}{
 		{
-			name:        "successful follow",
-			followerID:  "user1",
-			followedID:  "user2",
-			mockError:   nil,
-			expectError: false,
+			name:         "successful follow",
+			followerID:   "user1",
+			followedID:   "user2",
+			mockError:    nil,
+			expectFollow: true,
+			expectError:  false,
 		},
 		{
-			name:        "repository error",
-			followerID:  "user1",
-			followedID:  "user2",
-			mockError:   errors.New("database error"),
-			expectError: true,
+			name:         "repository error",
+			followerID:   "user1",
+			followedID:   "user2",
+			mockError:    errors.New("database error"),
+			expectFollow: true,
+			expectError:  true,
+		},
+		{
+			name:          "self follow",
+			followerID:    "user1",
+			followedID:    "user1",
+			expectedError: domain.ErrSelfFollow,
+			expectError:   true,
+		},
+		{
+			name:          "followed user not found",
+			followerID:    "user1",
+			followedID:    "user9",
+			getUserError:  domain.ErrUserNotFound,
+			expectedError: domain.ErrUserNotFound,
+			expectError:   true,
+		},
+		{
+			name:          "already following",
+			followerID:    "user1",
+			followedID:    "user2",
+			isFollowing:   true,
+			expectedError: domain.ErrAlreadyFollowing,
+			expectError:   true,
+		},
+		{
+			name:           "following check error",
+			followerID:     "user1",
+			followedID:     "user2",
+			isFollowingErr: errors.New("database error"),
+			expectError:    true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockRepo := new(MockUserRepository)
-			mockRepo.On("Follow", tt.followerID, tt.followedID).Return(tt.mockError)
+			if tt.followerID != tt.followedID {
+				var followed *domain.User
+				if tt.getUserError == nil {
+					followed = &domain.User{ID: tt.followedID, Username: tt.followedID}
+				}
+				mockRepo.On("GetUser", mock.Anything, tt.followedID).Return(followed, tt.getUserError)
+				if tt.getUserError == nil {
+					mockRepo.On("IsFollowing", mock.Anything, tt.followerID, tt.followedID).Return(tt.isFollowing, tt.isFollowingErr)
+				}
+			}
+			if tt.expectFollow {
+				mockRepo.On("Follow", mock.Anything, tt.followerID, tt.followedID).Return(tt.mockError)
+			}
 
 			usecase := NewUserUsecase(mockRepo)
-			err := usecase.Follow(tt.followerID, tt.followedID)
+			err := usecase.Follow(context.Background(), tt.followerID, tt.followedID)
 
 			if tt.expectError {
 				assert.Error(t, err)
+				if tt.expectedError != nil {
+					assert.ErrorIs(t, err, tt.expectedError)
+				}
 			} else {
 				assert.NoError(t, err)
 			}
@@ -114,15 +224,22 @@ func TestUserUsecase_Unfollow(t *testing.T) {
 			mockError:   errors.New("database error"),
 			expectError: true,
 		},
+		{
+			name:        "not following",
+			followerID:  "user1",
+			followedID:  "user2",
+			mockError:   domain.ErrNotFollowing,
+			expectError: true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockRepo := new(MockUserRepository)
-			mockRepo.On("Unfollow", tt.followerID, tt.followedID).Return(tt.mockError)
+			mockRepo.On("Unfollow", mock.Anything, tt.followerID, tt.followedID).Return(tt.mockError)
 
 			usecase := NewUserUsecase(mockRepo)
-			err := usecase.Unfollow(tt.followerID, tt.followedID)
+			err := usecase.Unfollow(context.Background(), tt.followerID, tt.followedID)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -166,10 +283,10 @@ func TestUserUsecase_GetFollowing(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockRepo := new(MockUserRepository)
-			mockRepo.On("GetFollowing", tt.userID).Return(tt.mockUsers, tt.mockError)
+			mockRepo.On("GetFollowing", mock.Anything, tt.userID).Return(tt.mockUsers, tt.mockError)
 
 			usecase := NewUserUsecase(mockRepo)
-			users, err := usecase.GetFollowing(tt.userID)
+			users, err := usecase.GetFollowing(context.Background(), tt.userID)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -215,10 +332,10 @@ func TestUserUsecase_GetFollowers(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockRepo := new(MockUserRepository)
-			mockRepo.On("GetFollowers", tt.userID).Return(tt.mockUsers, tt.mockError)
+			mockRepo.On("GetFollowers", mock.Anything, tt.userID).Return(tt.mockUsers, tt.mockError)
 
 			usecase := NewUserUsecase(mockRepo)
-			users, err := usecase.GetFollowers(tt.userID)
+			users, err := usecase.GetFollowers(context.Background(), tt.userID)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -230,4 +347,317 @@ func TestUserUsecase_GetFollowers(t *testing.T) {
 			mockRepo.AssertExpectations(t)
 		})
 	}
-} 
\ No newline at end of file
+}
+
+func TestUserUsecase_GetRelationships(t *testing.T) {
+	mockRelationships := []domain.Relationship{
+		{UserID: "user2", Following: true, FollowedBy: true},
+		{UserID: "user3", Following: false, FollowedBy: false},
+	}
+
+	tests := []struct {
+		name              string
+		userID            string
+		ids               []string
+		expectedIDs       []string
+		mockRelationships []domain.Relationship
+		mockError         error
+		expectError       bool
+	}{
+		{
+			name:              "successful get relationships",
+			userID:            "user1",
+			ids:               []string{"user2", "user3"},
+			expectedIDs:       []string{"user2", "user3"},
+			mockRelationships: mockRelationships,
+			mockError:         nil,
+			expectError:       false,
+		},
+		{
+			name:              "duplicate ids are removed",
+			userID:            "user1",
+			ids:               []string{"user2", "user3", "user2"},
+			expectedIDs:       []string{"user2", "user3"},
+			mockRelationships: mockRelationships,
+			mockError:         nil,
+			expectError:       false,
+		},
+		{
+			name:              "repository error",
+			userID:            "user1",
+			ids:               []string{"user2"},
+			expectedIDs:       []string{"user2"},
+			mockRelationships: nil,
+			mockError:         errors.New("database error"),
+			expectError:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockUserRepository)
+			mockRepo.On("GetRelationships", mock.Anything, tt.userID, tt.expectedIDs).Return(tt.mockRelationships, tt.mockError)
+
+			usecase := NewUserUsecase(mockRepo)
+			relationships, err := usecase.GetRelationships(context.Background(), tt.userID, tt.ids)
+
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.Nil(t, relationships)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.mockRelationships, relationships)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserUsecase_GetUsers(t *testing.T) {
+	const user2 = "6f1c1d8e-3b0a-4c7e-9a51-0d2f4b8e7c11"
+	const user3 = "0b7e5a2c-9d14-4f63-8e2a-5c1d7f9b3a40"
+
+	t.Run("duplicate and malformed ids are skipped", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		mockUsers := []domain.User{{ID: user2, Username: "bob"}}
+		mockRepo.On("GetUsers", mock.Anything, []string{user2, user3}).Return(mockUsers, nil)
+
+		users, err := NewUserUsecase(mockRepo).GetUsers(context.Background(), []string{user2, "not-a-uuid", user3, user2})
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockUsers, users)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("no valid ids does not hit the repository", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+
+		users, err := NewUserUsecase(mockRepo).GetUsers(context.Background(), []string{"not-a-uuid"})
+
+		assert.NoError(t, err)
+		assert.Empty(t, users)
+		mockRepo.AssertNotCalled(t, "GetUsers", mock.Anything, mock.Anything)
+	})
+}
+
+func TestUserUsecase_CreateUser(t *testing.T) {
+	tests := []struct {
+		name        string
+		avatarURL   string
+		expectError error
+	}{
+		{name: "without avatar"},
+		{name: "with avatar", avatarURL: "https://example.com/alice.png"},
+		{name: "relative avatar URL", avatarURL: "/alice.png", expectError: domain.ErrInvalidAvatarURL},
+		{name: "non web avatar URL", avatarURL: "javascript:alert(1)", expectError: domain.ErrInvalidAvatarURL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockUserRepository)
+			req := domain.CreateUserRequest{Username: "alice", AvatarURL: tt.avatarURL}
+			if tt.expectError == nil {
+				mockRepo.On("CreateUser", mock.Anything, req).Return(&domain.User{ID: "user1", Username: "alice", AvatarURL: tt.avatarURL}, nil)
+			}
+
+			user, err := NewUserUsecase(mockRepo).CreateUser(context.Background(), req)
+
+			if tt.expectError != nil {
+				assert.ErrorIs(t, err, tt.expectError)
+				assert.Nil(t, user)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.avatarURL, user.AvatarURL)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserUsecase_SearchUsers(t *testing.T) {
+	t.Run("query is trimmed", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		mockUsers := []domain.User{{ID: "user2", Username: "alice"}}
+		mockRepo.On("SearchUsers", mock.Anything, "user1", "al", 10).Return(mockUsers, nil)
+
+		users, err := NewUserUsecase(mockRepo).SearchUsers(context.Background(), "user1", "  al ", 10)
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockUsers, users)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("blank query does not hit the repository", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+
+		users, err := NewUserUsecase(mockRepo).SearchUsers(context.Background(), "user1", "   ", 10)
+
+		assert.NoError(t, err)
+		assert.Empty(t, users)
+		mockRepo.AssertNotCalled(t, "SearchUsers", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestUserUsecase_ReactivateUser(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockRepo.On("ReactivateUser", mock.Anything, "user1", mock.MatchedBy(func(deactivatedAfter time.Time) bool {
+		// The cutoff is the start of the grace period
+		return time.Since(deactivatedAfter.Add(DeactivationGracePeriod)) < time.Minute
+	})).Return(domain.ErrReactivationExpired)
+
+	err := NewUserUsecase(mockRepo).ReactivateUser(context.Background(), "user1")
+
+	assert.ErrorIs(t, err, domain.ErrReactivationExpired)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUserUsecase_PurgeDeactivatedUsers(t *testing.T) {
+	fullBatch := make([]string, purgeBatchSize)
+	for i := range fullBatch {
+		fullBatch[i] = fmt.Sprintf("user%d", i)
+	}
+
+	tests := []struct {
+		name           string
+		batches        [][]string
+		deleteError    error
+		expectedPurged int
+		expectError    bool
+	}{
+		{
+			name:           "nothing to purge",
+			batches:        [][]string{{}},
+			expectedPurged: 0,
+		},
+		{
+			name:           "purges every batch",
+			batches:        [][]string{fullBatch, {"user200"}},
+			expectedPurged: purgeBatchSize + 1,
+		},
+		{
+			name:           "stops on delete error",
+			batches:        [][]string{{"user1", "user2"}},
+			deleteError:    errors.New("database error"),
+			expectedPurged: 0,
+			expectError:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockUserRepository)
+			for _, batch := range tt.batches {
+				mockRepo.On("GetDeactivatedUserIDs", mock.Anything, mock.AnythingOfType("time.Time"), purgeBatchSize).Return(batch, nil).Once()
+			}
+			mockRepo.On("DeleteUser", mock.Anything, mock.AnythingOfType("string")).Return(tt.deleteError).Maybe()
+
+			purged, err := NewUserUsecase(mockRepo).PurgeDeactivatedUsers(context.Background())
+
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedPurged, purged)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserUsecase_ChangeUsername(t *testing.T) {
+	tests := []struct {
+		name          string
+		username      string
+		expectedName  string
+		mockError     error
+		expectedError error
+	}{
+		{
+			name:         "valid username",
+			username:     "  alice_2 ",
+			expectedName: "alice_2",
+		},
+		{
+			name:          "username taken",
+			username:      "bob",
+			expectedName:  "bob",
+			mockError:     domain.ErrUsernameTaken,
+			expectedError: domain.ErrUsernameTaken,
+		},
+		{
+			name:          "too many changes",
+			username:      "alice3",
+			expectedName:  "alice3",
+			mockError:     domain.ErrUsernameChangeLimit,
+			expectedError: domain.ErrUsernameChangeLimit,
+		},
+		{
+			name:          "too short",
+			username:      "al",
+			expectedError: domain.ErrInvalidUsername,
+		},
+		{
+			name:          "invalid characters",
+			username:      "alice smith",
+			expectedError: domain.ErrInvalidUsername,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockUserRepository)
+			usecase := NewUserUsecase(mockRepo)
+			if tt.expectedName != "" {
+				mockRepo.On("ChangeUsername", mock.Anything, "user1", tt.expectedName, mock.AnythingOfType("time.Time"), UsernameChangePolicy).
+					Return(&domain.User{ID: "user1", Username: tt.expectedName}, tt.mockError)
+			}
+
+			user, err := usecase.ChangeUsername(context.Background(), "user1", tt.username)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedName, user.Username)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserUsecase_GetUserByUsername(t *testing.T) {
+	t.Run("current username", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		usecase := NewUserUsecase(mockRepo)
+		mockRepo.On("GetUserByUsername", mock.Anything, "alice").Return(&domain.User{ID: "user1", Username: "alice"}, nil)
+
+		user, err := usecase.GetUserByUsername(context.Background(), "alice")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "user1", user.ID)
+		mockRepo.AssertNotCalled(t, "GetUserByPreviousUsername", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("reserved previous username", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		usecase := NewUserUsecase(mockRepo)
+		mockRepo.On("GetUserByUsername", mock.Anything, "alice").Return((*domain.User)(nil), domain.ErrUserNotFound)
+		mockRepo.On("GetUserByPreviousUsername", mock.Anything, "alice", mock.AnythingOfType("time.Time")).Return(&domain.User{ID: "user1", Username: "alice2"}, nil)
+
+		user, err := usecase.GetUserByUsername(context.Background(), "alice")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "alice2", user.Username)
+	})
+
+	t.Run("unknown username", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		usecase := NewUserUsecase(mockRepo)
+		mockRepo.On("GetUserByUsername", mock.Anything, "nobody").Return((*domain.User)(nil), domain.ErrUserNotFound)
+		mockRepo.On("GetUserByPreviousUsername", mock.Anything, "nobody", mock.AnythingOfType("time.Time")).Return((*domain.User)(nil), domain.ErrUserNotFound)
+
+		_, err := usecase.GetUserByUsername(context.Background(), "nobody")
+
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+	})
+}
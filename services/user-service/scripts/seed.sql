@@ -1,10 +1,10 @@
 -- Insert mock users
-INSERT INTO users (id, username) VALUES
-    ('123e4567-e89b-12d3-a456-426614174000', 'john_doe'),
-    ('223e4567-e89b-12d3-a456-426614174000', 'jane_smith'),
-    ('323e4567-e89b-12d3-a456-426614174000', 'bob_wilson'),
-    ('423e4567-e89b-12d3-a456-426614174000', 'alice_johnson'),
-    ('523e4567-e89b-12d3-a456-426614174000', 'charlie_brown')
+INSERT INTO users (id, username, display_name) VALUES
+    ('123e4567-e89b-12d3-a456-426614174000', 'john_doe', 'John Doe'),
+    ('223e4567-e89b-12d3-a456-426614174000', 'jane_smith', 'Jane Smith'),
+    ('323e4567-e89b-12d3-a456-426614174000', 'bob_wilson', 'Bob Wilson'),
+    ('423e4567-e89b-12d3-a456-426614174000', 'alice_johnson', 'Alice Johnson'),
+    ('523e4567-e89b-12d3-a456-426614174000', 'charlie_brown', 'Charlie Brown')
 ON CONFLICT (id) DO NOTHING;
 
 -- Insert mock follow relationships